@@ -0,0 +1,324 @@
+// Package amanda adapts a changer to the Amanda tape changer script
+// interface.
+//
+// Amanda drives a changer by running a script with one of the arguments
+// -info, -slot SLOT, -reset, -eject, -search LABEL or -label LABEL. The
+// script prints a line starting with the slot number followed by the tape
+// device, or by an error message, and reports failures through its exit
+// code: 1 for errors Amanda may recover from, such as an empty slot, and 2
+// for fatal errors. Run implements that protocol; the chg-mtx command wraps
+// it in a script Amanda can call.
+//
+// Amanda labels are taken to be the barcodes of the volumes, as with the
+// use-barcodes setting of chg-robot. Slots are the storage slots of the
+// library; mail slots are not used.
+package amanda
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/store"
+)
+
+// Exit codes of the changer script interface.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitFatal = 2
+)
+
+// The key under which the current slot is stored.
+const storeKey = "amanda-current"
+
+// Changer serves the Amanda changer interface for one drive of a library.
+type Changer struct {
+	chgr   *mtx.Changer
+	drive  int
+	device string
+	store  store.Store
+}
+
+// New returns an adapter loading volumes into drive number drive of chgr,
+// whose tape device is device. The current slot is kept in st so that it
+// persists across invocations of the script.
+func New(chgr *mtx.Changer, drive int, device string, st store.Store) *Changer {
+	return &Changer{chgr: chgr, drive: drive, device: device, store: st}
+}
+
+// recoverable is an error after which Amanda may try another slot.
+type recoverable struct {
+	error
+}
+
+func (a *Changer) current() int {
+	buf, err := a.store.Get(storeKey)
+	if err != nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(string(buf))
+
+	return n
+}
+
+func (a *Changer) setCurrent(n int) error {
+	return a.store.Put(storeKey, []byte(strconv.Itoa(n)))
+}
+
+// occupied returns the numbers of the storage slots holding a volume,
+// counting the volume in the drive as being in its home slot.
+func occupied(status *mtx.Status, drive int) []int {
+	var nums []int
+	for _, slot := range status.Slots {
+		if slot.Type == mtx.StorageSlot && slot.Vol != nil {
+			nums = append(nums, slot.Num)
+		}
+	}
+
+	if drive < len(status.Drives) && status.Drives[drive].Vol != nil {
+		nums = append(nums, status.Drives[drive].Vol.Home)
+		sort.Ints(nums)
+	}
+
+	return nums
+}
+
+// load makes sure the volume of slot is in the drive.
+func (a *Changer) load(status *mtx.Status, slot int) error {
+	if a.drive >= len(status.Drives) {
+		return fmt.Errorf("no drive %d", a.drive)
+	}
+
+	vol := status.Drives[a.drive].Vol
+	if vol != nil && vol.Home == slot {
+		return a.setCurrent(slot)
+	}
+
+	if slot < 1 || slot > len(status.Slots) || status.Slots[slot-1].Type != mtx.StorageSlot {
+		return recoverable{fmt.Errorf("slot %d does not exist", slot)}
+	}
+
+	if status.Slots[slot-1].Vol == nil {
+		return recoverable{fmt.Errorf("slot %d is empty", slot)}
+	}
+
+	if vol != nil {
+		if err := a.chgr.Unload(mtx.SlotNum(vol.Home), mtx.DriveNum(a.drive)); err != nil {
+			return err
+		}
+	}
+
+	if err := a.chgr.Load(mtx.SlotNum(slot), mtx.DriveNum(a.drive)); err != nil {
+		return err
+	}
+
+	return a.setCurrent(slot)
+}
+
+// pick resolves a -slot argument to a slot number.
+func (a *Changer) pick(status *mtx.Status, arg string) (int, error) {
+	nums := occupied(status, a.drive)
+	if len(nums) == 0 {
+		return 0, recoverable{errors.New("no volumes in library")}
+	}
+
+	cur := a.current()
+
+	switch arg {
+	case "current":
+		if cur == 0 {
+			return nums[0], nil
+		}
+
+		return cur, nil
+	case "first":
+		return nums[0], nil
+	case "last":
+		return nums[len(nums)-1], nil
+	case "next", "advance":
+		for _, n := range nums {
+			if n > cur {
+				return n, nil
+			}
+		}
+
+		return nums[0], nil
+	case "prev":
+		for i := len(nums) - 1; i >= 0; i-- {
+			if nums[i] < cur {
+				return nums[i], nil
+			}
+		}
+
+		return nums[len(nums)-1], nil
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid slot %q", arg)
+	}
+
+	return n, nil
+}
+
+// Run performs the changer script command given by args, writing its output
+// to w, and returns the exit code.
+func (a *Changer) Run(args []string, w io.Writer) int {
+	out, err := a.run(args)
+
+	cur := a.current()
+
+	switch err.(type) {
+	case nil:
+		fmt.Fprintln(w, out)
+		return ExitOK
+	case recoverable:
+		fmt.Fprintf(w, "%d %v\n", cur, err)
+		return ExitError
+	}
+
+	fmt.Fprintf(w, "%d %v\n", cur, err)
+
+	return ExitFatal
+}
+
+func (a *Changer) run(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", errors.New("no command given")
+	}
+
+	status, err := a.chgr.Status()
+	if err != nil {
+		return "", err
+	}
+
+	ok := func(slot int) string {
+		return fmt.Sprintf("%d %s", slot, a.device)
+	}
+
+	switch args[0] {
+	case "-info":
+		cur := a.current()
+		if cur == 0 {
+			if nums := occupied(status, a.drive); len(nums) > 0 {
+				cur = nums[0]
+			}
+		}
+
+		// current slot, number of slots, can go backwards, searchable
+		return fmt.Sprintf("%d %d 1 1", cur, status.NumStorageSlots), nil
+	case "-slot":
+		if len(args) != 2 {
+			return "", errors.New("usage: -slot SLOT")
+		}
+
+		slot, err := a.pick(status, args[1])
+		if err != nil {
+			return "", err
+		}
+
+		if args[1] == "advance" {
+			return fmt.Sprintf("%d", slot), a.setCurrent(slot)
+		}
+
+		return ok(slot), a.load(status, slot)
+	case "-reset":
+		slot, err := a.pick(status, "first")
+		if err != nil {
+			return "", err
+		}
+
+		return ok(slot), a.load(status, slot)
+	case "-eject":
+		cur := a.current()
+
+		if a.drive < len(status.Drives) {
+			if vol := status.Drives[a.drive].Vol; vol != nil {
+				if err := a.chgr.Unload(mtx.SlotNum(vol.Home), mtx.DriveNum(a.drive)); err != nil {
+					return "", err
+				}
+
+				cur = vol.Home
+			}
+		}
+
+		return ok(cur), nil
+	case "-search":
+		if len(args) != 2 {
+			return "", errors.New("usage: -search LABEL")
+		}
+
+		slot := 0
+		if a.drive < len(status.Drives) {
+			if vol := status.Drives[a.drive].Vol; vol != nil && vol.Serial == args[1] {
+				slot = vol.Home
+			}
+		}
+
+		for _, s := range status.Slots {
+			if slot == 0 && s.Type == mtx.StorageSlot && s.Vol != nil && s.Vol.Serial == args[1] {
+				slot = s.Num
+			}
+		}
+
+		if slot == 0 {
+			return "", recoverable{fmt.Errorf("label %s not found", args[1])}
+		}
+
+		return ok(slot), a.load(status, slot)
+	case "-label":
+		// labels are barcodes, there is nothing to record
+		return ok(a.current()), nil
+	}
+
+	return "", fmt.Errorf("unknown command %q", args[0])
+}
+
+// Inventory writes a listing of the storage slots in the style of
+// 'amtape inventory' to w.
+func (a *Changer) Inventory(w io.Writer) error {
+	status, err := a.chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	inDrive := make(map[int]*mtx.Slot)
+	for _, drv := range status.Drives {
+		if drv.Vol != nil {
+			inDrive[drv.Vol.Home] = drv
+		}
+	}
+
+	cur := a.current()
+	for _, slot := range status.Slots {
+		if slot.Type != mtx.StorageSlot {
+			continue
+		}
+
+		line := fmt.Sprintf("slot %3d:", slot.Num)
+
+		switch drv := inDrive[slot.Num]; {
+		case slot.Vol != nil:
+			line += " label " + slot.Vol.Serial
+		case drv != nil:
+			line += fmt.Sprintf(" label %s (in drive %d)", drv.Vol.Serial, drv.Num)
+		default:
+			line += " empty"
+		}
+
+		if slot.Num == cur {
+			line += " (current)"
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,333 @@
+// Package anomaly detects inventory drift: volumes that vanish from or show
+// up in the library without passing through a mail slot, drives that report
+// a different home slot for the volume they hold, and drives that stay
+// occupied for too long.
+//
+// A Detector is fed successive status snapshots, usually by Poll, and
+// publishes an Anomaly event for every irregularity found:
+//
+//	det := anomaly.New(bus, anomaly.WithCatalog(cat), anomaly.WithDriveThreshold(12*time.Hour))
+//	go det.Poll(ctx, chgr, time.Minute)
+//
+// If a catalog is given, the first snapshot is compared against it, which
+// catches changes made while nothing was watching the library.
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/events"
+	"github.com/kbj/mtx/inventory"
+)
+
+// Kind is the kind of an anomaly.
+type Kind int
+
+const (
+	// Vanished means a volume left the library without passing through a
+	// mail slot.
+	Vanished Kind = iota
+
+	// Unexpected means a volume appeared in the library without passing
+	// through a mail slot.
+	Unexpected
+
+	// HomeChanged means a drive reports a different home slot for the
+	// volume it holds.
+	HomeChanged
+
+	// DriveOccupied means a drive has held the same volume for longer than
+	// the threshold.
+	DriveOccupied
+)
+
+var kindNames = [...]string{
+	Vanished:      "vanished",
+	Unexpected:    "unexpected",
+	HomeChanged:   "home changed",
+	DriveOccupied: "drive occupied",
+}
+
+// String returns the name of the kind.
+func (kind Kind) String() string {
+	if kind < 0 || int(kind) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(kind))
+	}
+
+	return kindNames[kind]
+}
+
+// Anomaly is published when an anomaly is detected. It implements
+// events.Event.
+type Anomaly struct {
+	Kind   Kind
+	Serial string
+
+	// Type and Num identify the element the volume is in, or was last
+	// seen in if it vanished.
+	Type mtx.SlotType
+	Num  int
+
+	// Home and PrevHome are the current and previous home slot reported
+	// for a HomeChanged anomaly.
+	Home, PrevHome int
+
+	// Since is the time the volume was loaded, for DriveOccupied anomalies.
+	Since time.Time
+}
+
+func (ev *Anomaly) String() string {
+	switch ev.Kind {
+	case Vanished:
+		return fmt.Sprintf("anomaly: volume %s vanished from %s %d", ev.Serial, ev.Type, ev.Num)
+	case Unexpected:
+		return fmt.Sprintf("anomaly: volume %s appeared unexpectedly in %s %d", ev.Serial, ev.Type, ev.Num)
+	case HomeChanged:
+		return fmt.Sprintf("anomaly: home slot of volume %s in drive %d changed from %d to %d", ev.Serial, ev.Num, ev.PrevHome, ev.Home)
+	case DriveOccupied:
+		return fmt.Sprintf("anomaly: drive %d has held volume %s since %s", ev.Num, ev.Serial, ev.Since.Format(time.RFC3339))
+	}
+
+	return fmt.Sprintf("anomaly: %s: volume %s in %s %d", ev.Kind, ev.Serial, ev.Type, ev.Num)
+}
+
+// Catalog provides the expected contents of the library. It is implemented
+// by *inventory.Catalog.
+type Catalog interface {
+	Volumes() ([]*inventory.Volume, error)
+}
+
+// occupancy tracks the volume held by a drive.
+type occupancy struct {
+	serial   string
+	since    time.Time
+	reported bool
+}
+
+// Detector detects anomalies in successive status snapshots.
+type Detector struct {
+	bus       *events.Bus
+	catalog   Catalog
+	threshold time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	last   *mtx.Status
+	drives map[int]*occupancy
+}
+
+// An Option configures a Detector.
+type Option func(det *Detector)
+
+// WithCatalog makes the detector compare the first snapshot against cat.
+func WithCatalog(cat Catalog) Option {
+	return func(det *Detector) {
+		det.catalog = cat
+	}
+}
+
+// WithDriveThreshold makes the detector report drives that hold the same
+// volume for longer than d. Each occupancy is reported once.
+func WithDriveThreshold(d time.Duration) Option {
+	return func(det *Detector) {
+		det.threshold = d
+	}
+}
+
+// New returns a detector publishing to bus. If bus is nil, anomalies are
+// only returned by Check.
+func New(bus *events.Bus, opts ...Option) *Detector {
+	det := &Detector{
+		bus:    bus,
+		now:    time.Now,
+		drives: make(map[int]*occupancy),
+	}
+
+	for _, opt := range opts {
+		opt(det)
+	}
+
+	return det
+}
+
+// Check compares status against the previous snapshot, or the catalog on
+// the first call, publishes the anomalies found and returns them.
+func (det *Detector) Check(status *mtx.Status) ([]*Anomaly, error) {
+	det.mu.Lock()
+	defer det.mu.Unlock()
+
+	var found []*Anomaly
+
+	if det.last == nil {
+		if det.catalog != nil {
+			vols, err := det.catalog.Volumes()
+			if err != nil {
+				return nil, err
+			}
+
+			found = append(found, det.compareCatalog(vols, status)...)
+		}
+	} else {
+		found = append(found, compare(det.last, status)...)
+	}
+
+	found = append(found, det.checkDrives(status)...)
+
+	// keep a copy, the caller may go on to modify status
+	det.last = status.Clone()
+
+	if det.bus != nil {
+		evs := make([]events.Event, len(found))
+		for i, a := range found {
+			evs[i] = a
+		}
+
+		det.bus.Publish(evs...)
+	}
+
+	return found, nil
+}
+
+// compare returns the anomalies in the change from old to new.
+func compare(old, new *mtx.Status) []*Anomaly {
+	var found []*Anomaly
+	for _, ev := range events.Diff(old, new) {
+		switch ev := ev.(type) {
+		case *events.VolumeDisappeared:
+			found = append(found, &Anomaly{Kind: Vanished, Serial: ev.Serial, Type: ev.Type, Num: ev.Num})
+		case *events.VolumeAppeared:
+			found = append(found, &Anomaly{Kind: Unexpected, Serial: ev.Serial, Type: ev.Type, Num: ev.Num})
+		}
+	}
+
+	for i, drv := range new.Drives {
+		if i >= len(old.Drives) || drv.Vol == nil || old.Drives[i].Vol == nil {
+			continue
+		}
+
+		prev := old.Drives[i].Vol
+		if drv.Vol.Serial == prev.Serial && drv.Vol.Home != prev.Home {
+			found = append(found, &Anomaly{
+				Kind:     HomeChanged,
+				Serial:   drv.Vol.Serial,
+				Type:     drv.Type,
+				Num:      drv.Num,
+				Home:     drv.Vol.Home,
+				PrevHome: prev.Home,
+			})
+		}
+	}
+
+	return found
+}
+
+// compareCatalog returns the differences between the catalog and status
+// that cannot be explained by volumes passing through the mail slots. Drive
+// occupancy is seeded from the time the catalog last saw a volume move.
+func (det *Detector) compareCatalog(vols []*inventory.Volume, status *mtx.Status) []*Anomaly {
+	known := make(map[string]*inventory.Volume, len(vols))
+	for _, vol := range vols {
+		known[vol.Serial] = vol
+	}
+
+	seen := make(map[string]bool)
+
+	var found []*Anomaly
+	for _, slot := range append(append([]*mtx.Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol == nil || slot.Vol.Serial == "" {
+			continue
+		}
+
+		serial := slot.Vol.Serial
+		seen[serial] = true
+
+		vol, ok := known[serial]
+		switch {
+		case slot.Type == mtx.MailSlot:
+			// entering or leaving through the front door
+		case !ok || vol.Location == nil:
+			found = append(found, &Anomaly{Kind: Unexpected, Serial: serial, Type: slot.Type, Num: slot.Num})
+		case slot.Type == mtx.DataTransferSlot && vol.Location.Type == mtx.DataTransferSlot && vol.Location.Num == slot.Num:
+			if vol.Home != slot.Vol.Home {
+				found = append(found, &Anomaly{
+					Kind:     HomeChanged,
+					Serial:   serial,
+					Type:     slot.Type,
+					Num:      slot.Num,
+					Home:     slot.Vol.Home,
+					PrevHome: vol.Home,
+				})
+			}
+
+			if !vol.LastMoved.IsZero() {
+				det.drives[slot.Num] = &occupancy{serial: serial, since: vol.LastMoved}
+			}
+		}
+	}
+
+	for _, vol := range vols {
+		if vol.Location == nil || vol.Location.Type == mtx.MailSlot || seen[vol.Serial] {
+			continue
+		}
+
+		found = append(found, &Anomaly{Kind: Vanished, Serial: vol.Serial, Type: vol.Location.Type, Num: vol.Location.Num})
+	}
+
+	return found
+}
+
+// checkDrives updates the drive occupancy and returns the drives that have
+// held their volume for longer than the threshold.
+func (det *Detector) checkDrives(status *mtx.Status) []*Anomaly {
+	now := det.now()
+
+	var found []*Anomaly
+	for _, drv := range status.Drives {
+		if drv.Vol == nil {
+			delete(det.drives, drv.Num)
+			continue
+		}
+
+		occ, ok := det.drives[drv.Num]
+		if !ok || occ.serial != drv.Vol.Serial {
+			occ = &occupancy{serial: drv.Vol.Serial, since: now}
+			det.drives[drv.Num] = occ
+		}
+
+		if det.threshold > 0 && !occ.reported && now.Sub(occ.since) >= det.threshold {
+			occ.reported = true
+			found = append(found, &Anomaly{
+				Kind:   DriveOccupied,
+				Serial: drv.Vol.Serial,
+				Type:   drv.Type,
+				Num:    drv.Num,
+				Since:  occ.since,
+			})
+		}
+	}
+
+	return found
+}
+
+// Poll checks the status of chgr every interval until ctx is done. Failed
+// status requests are skipped. Poll returns the context error.
+func (det *Detector) Poll(ctx context.Context, chgr *mtx.Changer, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if status, err := chgr.Status(); err == nil {
+			det.Check(status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
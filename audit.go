@@ -0,0 +1,99 @@
+package mtx
+
+// ExpectedVolume is a volume the library should hold according to a
+// catalog.
+type ExpectedVolume struct {
+	Serial string
+
+	// Element is where the volume should be, or nil if it may be anywhere
+	// in the library. A volume loaded in a drive counts as being in its
+	// home slot.
+	Element *Element
+}
+
+// Misplacement is a volume found elsewhere than expected.
+type Misplacement struct {
+	Serial   string
+	Expected Element
+	Found    *Slot
+}
+
+// AuditReport is the outcome of Audit. The slots are shared with the
+// audited status.
+type AuditReport struct {
+	// Missing lists the expected volumes not in the library, in the order
+	// they were expected.
+	Missing []ExpectedVolume
+
+	// Unexpected lists the slots and drives holding labeled volumes that
+	// were not expected, including further copies of a label found more
+	// than once, and Unlabeled those holding unlabeled volumes, in the
+	// order of Status.AllSlots.
+	Unexpected []*Slot
+	Unlabeled  []*Slot
+
+	// Misplaced lists the expected volumes found elsewhere than expected,
+	// in the order they were expected.
+	Misplaced []Misplacement
+}
+
+// OK reports whether the audit found no discrepancies.
+func (r *AuditReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0 && len(r.Unlabeled) == 0 && len(r.Misplaced) == 0
+}
+
+// Audit compares the volumes in status with the expected ones, e.g. the
+// volumes of a catalog, as in a physical inventory audit. Expected volumes
+// listed more than once count once, with the element of their first entry.
+func Audit(status *Status, expected []ExpectedVolume) *AuditReport {
+	r := new(AuditReport)
+
+	want := make(map[string]bool, len(expected))
+	for _, exp := range expected {
+		want[exp.Serial] = true
+	}
+
+	found := make(map[string]*Slot)
+	for slot := range status.Occupied() {
+		serial := slot.Vol.Serial
+		switch {
+		case serial == "":
+			r.Unlabeled = append(r.Unlabeled, slot)
+		case !want[serial] || found[serial] != nil:
+			r.Unexpected = append(r.Unexpected, slot)
+		default:
+			found[serial] = slot
+		}
+	}
+
+	seen := make(map[string]bool, len(expected))
+	for _, exp := range expected {
+		if seen[exp.Serial] {
+			continue
+		}
+
+		seen[exp.Serial] = true
+
+		slot := found[exp.Serial]
+		switch {
+		case slot == nil:
+			r.Missing = append(r.Missing, exp)
+		case exp.Element != nil && !slot.at(*exp.Element):
+			r.Misplaced = append(r.Misplaced, Misplacement{Serial: exp.Serial, Expected: *exp.Element, Found: slot})
+		}
+	}
+
+	return r
+}
+
+// at reports whether the volume in slot is at e, counting volumes in
+// drives as being in their home slot. Storage and mail slots are compared
+// by number alone.
+func (slot *Slot) at(e Element) bool {
+	here := slot.Element()
+	if here.IsDrive() && !e.IsDrive() {
+		here = Element{StorageSlot, slot.Vol.Home}
+	}
+
+	return elementKey(here) == elementKey(e)
+}
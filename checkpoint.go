@@ -0,0 +1,130 @@
+package mtx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/kbj/mtx/store"
+)
+
+// ErrCheckpointMismatch is matched by the errors returned by
+// ExecuteCheckpointed for a checkpoint recorded for another plan.
+var ErrCheckpointMismatch = errors.New("mtx: checkpoint of a different plan")
+
+// checkpoint is the progress of a plan recorded by ExecuteCheckpointed.
+type checkpoint struct {
+	Moves []string `json:"moves"`
+	Done  int      `json:"done"`
+
+	// InFlight is set while move Done is performed, Serial and Home then
+	// being those of the volume found at its source.
+	InFlight bool   `json:"inFlight,omitempty"`
+	Serial   string `json:"serial,omitempty"`
+	Home     int    `json:"home,omitempty"`
+}
+
+// ExecuteCheckpointed is like Execute, but records its progress in st under
+// key after every move, so that running it again with the same plan after a
+// crash or a failed move resumes from where it stopped. Only the moves not
+// yet done are checked against the policy. A move that failed, or was in
+// flight when the progress was last recorded, counts as done if the status
+// shows its volume moved, see Status.Satisfied; moves of unlabeled volumes
+// are performed again. The checkpoint is deleted once the plan is complete.
+//
+// The returned count includes moves completed by earlier runs. A
+// checkpoint of another plan under key fails with ErrCheckpointMismatch.
+func (chgr *Changer) ExecuteCheckpointed(plan *MovePlan, st store.Store, key string) (int, error) {
+	cp := checkpoint{Moves: make([]string, len(plan.Moves))}
+	for i, mv := range plan.Moves {
+		cp.Moves[i] = mv.String()
+	}
+
+	buf, err := st.Get(key)
+	switch {
+	case err == nil:
+		var prev checkpoint
+		if err := json.Unmarshal(buf, &prev); err != nil {
+			return 0, fmt.Errorf("mtx: checkpoint %s: %w", key, err)
+		}
+
+		if !slices.Equal(prev.Moves, cp.Moves) || prev.Done > len(plan.Moves) {
+			return 0, fmt.Errorf("%w: %s", ErrCheckpointMismatch, key)
+		}
+
+		cp.Done = prev.Done
+		if prev.InFlight && cp.Done < len(plan.Moves) {
+			status, err := chgr.Status()
+			if err != nil {
+				return cp.Done, err
+			}
+
+			vol := &Volume{Serial: prev.Serial, Home: prev.Home}
+			if status.Satisfied(plan.Moves[cp.Done], vol) {
+				cp.Done++
+			}
+		}
+	case !errors.Is(err, store.ErrNotFound):
+		return 0, err
+	}
+
+	for i, mv := range plan.Moves[cp.Done:] {
+		if err := chgr.CheckMove(mv); err != nil {
+			return cp.Done, fmt.Errorf("mtx: move %d (%s): %w", cp.Done+i, mv, err)
+		}
+	}
+
+	save := func() error {
+		buf, err := json.Marshal(cp)
+		if err != nil {
+			return err
+		}
+
+		return st.Put(key, buf)
+	}
+
+	for cp.Done < len(plan.Moves) {
+		mv := plan.Moves[cp.Done]
+
+		vol, err := chgr.source(mv)
+		if err != nil {
+			return cp.Done, err
+		}
+
+		cp.InFlight, cp.Serial, cp.Home = true, vol.Serial, vol.Home
+		if err := save(); err != nil {
+			return cp.Done, err
+		}
+
+		if err := chgr.Move(mv); err != nil {
+			// the move stays in flight: a robot fault may have completed
+			// it, which the next run checks
+			return cp.Done, fmt.Errorf("mtx: move %d (%s): %w", cp.Done, mv, err)
+		}
+
+		cp.Done++
+		cp.InFlight, cp.Serial, cp.Home = false, "", 0
+		if err := save(); err != nil {
+			return cp.Done, err
+		}
+	}
+
+	return cp.Done, st.Delete(key)
+}
+
+// source returns the volume at the source of mv, or an empty volume if
+// there is none.
+func (chgr *Changer) source(mv Move) (Volume, error) {
+	status, err := chgr.Status()
+	if err != nil {
+		return Volume{}, err
+	}
+
+	src, _ := mv.elements()
+	if from := status.element(src); from != nil && from.Vol != nil {
+		return *from.Vol, nil
+	}
+
+	return Volume{}, nil
+}
@@ -0,0 +1,68 @@
+package mtx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/store"
+)
+
+// refused fails the first move without performing it.
+type refused struct {
+	mtx.Interface
+	failed bool
+}
+
+func (impl *refused) Do(args ...string) ([]byte, error) {
+	if args[0] != "status" && !impl.failed {
+		impl.failed = true
+		return nil, errors.New("robot busy")
+	}
+
+	return impl.Interface.Do(args...)
+}
+
+var checkpointPlan = &mtx.MovePlan{Moves: []mtx.Move{
+	{Type: mtx.MoveTransfer, Src: 1, Dst: 6},
+	{Type: mtx.MoveTransfer, Src: 2, Dst: 7},
+}}
+
+func TestCheckpointResumeLostReply(t *testing.T) {
+	chgr := mtx.NewChanger(&lostReply{Interface: mock.New(2, 8, 1, 4)})
+	st := store.NewMemory()
+
+	if n, err := chgr.ExecuteCheckpointed(checkpointPlan, st, "plan"); n != 0 || err == nil {
+		t.Fatalf("ExecuteCheckpointed = %d, %v, want lost reply", n, err)
+	}
+
+	// the first move was performed, so it is not repeated
+	if n, err := chgr.ExecuteCheckpointed(checkpointPlan, st, "plan"); n != 2 || err != nil {
+		t.Fatalf("resumed ExecuteCheckpointed = %d, %v, want 2, nil", n, err)
+	}
+}
+
+func TestCheckpointResumeOtherVolume(t *testing.T) {
+	impl := mock.New(2, 8, 1, 4)
+	chgr := mtx.NewChanger(&refused{Interface: impl})
+	st := store.NewMemory()
+
+	if n, err := chgr.ExecuteCheckpointed(checkpointPlan, st, "plan"); n != 0 || err == nil {
+		t.Fatalf("ExecuteCheckpointed = %d, %v, want robot busy", n, err)
+	}
+
+	// another volume takes the destination and the source is emptied
+	if _, err := impl.Do("transfer", "3", "6"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := impl.Do("transfer", "1", "5"); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := chgr.ExecuteCheckpointed(checkpointPlan, st, "plan")
+	if n != 0 || !errors.Is(err, mtx.ErrEmpty) {
+		t.Fatalf("resumed ExecuteCheckpointed = %d, %v, want 0, ErrEmpty", n, err)
+	}
+}
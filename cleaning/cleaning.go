@@ -0,0 +1,355 @@
+// Package cleaning keeps track of drive cleaning and cleans drives through a
+// scheduler.
+//
+// A Manager counts the mounts of each drive, records the cleanings
+// performed and the number of uses of each cleaning cartridge, and decides
+// when a drive is due for cleaning according to a Policy. Its state is
+// persisted in a store.Store. Mounts are counted by subscribing the
+// manager to an event bus:
+//
+//	mgr, err := cleaning.New(chgr, sched, st, cleaning.DefaultPolicy)
+//	...
+//	bus.Subscribe(mgr.Handle)
+package cleaning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/events"
+	"github.com/kbj/mtx/scheduler"
+	"github.com/kbj/mtx/store"
+)
+
+var (
+	// ErrNoCleaningTape is returned by CleanDrive when the library has no
+	// usable cleaning cartridge.
+	ErrNoCleaningTape = errors.New("cleaning: no usable cleaning cartridge")
+
+	// ErrInProgress is returned by CleanDrive when the drive is already
+	// being cleaned.
+	ErrInProgress = errors.New("cleaning: drive is already being cleaned")
+)
+
+// The key under which the state is stored.
+const storeKey = "cleaning"
+
+// maxHistory is the number of cleanings kept per drive.
+const maxHistory = 100
+
+// Policy decides when drives are cleaned.
+type Policy struct {
+	// Interval is the time after which a drive is due for cleaning. Zero
+	// disables time based cleaning.
+	Interval time.Duration
+
+	// Mounts is the number of mounts after which a drive is due for
+	// cleaning. Zero disables mount based cleaning.
+	Mounts int
+
+	// MaxUses is the number of cleanings a cartridge is good for. Zero
+	// means unlimited.
+	MaxUses int
+
+	// Duration is how long a cleaning cartridge is left in the drive.
+	Duration time.Duration
+
+	// Priority is the scheduler priority of cleaning moves.
+	Priority int
+
+	// Auto makes Handle start cleaning a drive that is due as soon as it
+	// has been unloaded.
+	Auto bool
+}
+
+// DefaultPolicy cleans drives every 200 mounts with LTO universal cleaning
+// cartridges, which are good for 50 cleanings.
+var DefaultPolicy = Policy{
+	Mounts:   200,
+	MaxUses:  50,
+	Duration: 2 * time.Minute,
+}
+
+// Record describes a cleaning.
+type Record struct {
+	Time time.Time `json:"time"`
+	Tape string    `json:"tape"`
+	Err  string    `json:"err,omitempty"`
+}
+
+type driveState struct {
+	LastCleaned time.Time `json:"last_cleaned"`
+	Mounts      int       `json:"mounts"`
+	Requested   bool      `json:"requested,omitempty"`
+	History     []Record  `json:"history,omitempty"`
+}
+
+type state struct {
+	Drives map[int]*driveState `json:"drives"`
+	Tapes  map[string]int      `json:"tapes"`
+}
+
+// Manager manages the cleaning of the drives of a library.
+type Manager struct {
+	chgr   *mtx.Changer
+	sched  *scheduler.Scheduler
+	store  store.Store
+	policy Policy
+
+	mu       sync.Mutex
+	state    state
+	cleaning map[int]bool
+}
+
+// New returns a manager for the drives of chgr, moving cartridges through
+// sched. State previously saved in st is restored.
+func New(chgr *mtx.Changer, sched *scheduler.Scheduler, st store.Store, policy Policy) (*Manager, error) {
+	mgr := &Manager{
+		chgr:     chgr,
+		sched:    sched,
+		store:    st,
+		policy:   policy,
+		cleaning: make(map[int]bool),
+	}
+
+	buf, err := st.Get(storeKey)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(buf, &mgr.state); err != nil {
+			return nil, fmt.Errorf("cleaning: corrupt state: %v", err)
+		}
+	}
+
+	if mgr.state.Drives == nil {
+		mgr.state.Drives = make(map[int]*driveState)
+	}
+
+	if mgr.state.Tapes == nil {
+		mgr.state.Tapes = make(map[string]int)
+	}
+
+	return mgr, nil
+}
+
+// IsCleaningTape returns whether serial identifies a cleaning cartridge.
+func IsCleaningTape(serial string) bool {
+	return strings.HasPrefix(serial, "CLN")
+}
+
+func (mgr *Manager) save() error {
+	buf, err := json.Marshal(&mgr.state)
+	if err != nil {
+		return err
+	}
+
+	return mgr.store.Put(storeKey, buf)
+}
+
+func (mgr *Manager) drive(num int) *driveState {
+	ds, ok := mgr.state.Drives[num]
+	if !ok {
+		ds = &driveState{LastCleaned: time.Now()}
+		mgr.state.Drives[num] = ds
+	}
+
+	return ds
+}
+
+// RecordMount counts a mount of a data cartridge in drive.
+func (mgr *Manager) RecordMount(drive int) error {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	mgr.drive(drive).Mounts++
+
+	return mgr.save()
+}
+
+// RequestCleaning marks drive as due for cleaning, e.g. because it raised a
+// cleaning TapeAlert.
+func (mgr *Manager) RequestCleaning(drive int) error {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	mgr.drive(drive).Requested = true
+
+	return mgr.save()
+}
+
+// Due returns whether drive is due for cleaning.
+func (mgr *Manager) Due(drive int) bool {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	return mgr.due(drive)
+}
+
+func (mgr *Manager) due(drive int) bool {
+	ds := mgr.drive(drive)
+
+	switch {
+	case ds.Requested:
+		return true
+	case mgr.policy.Mounts > 0 && ds.Mounts >= mgr.policy.Mounts:
+		return true
+	case mgr.policy.Interval > 0 && time.Since(ds.LastCleaned) >= mgr.policy.Interval:
+		return true
+	}
+
+	return false
+}
+
+// History returns the recorded cleanings of drive, oldest first.
+func (mgr *Manager) History(drive int) []Record {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	if ds, ok := mgr.state.Drives[drive]; ok {
+		return append([]Record(nil), ds.History...)
+	}
+
+	return nil
+}
+
+// TapeUses returns the number of cleanings performed with the cartridge.
+func (mgr *Manager) TapeUses(serial string) int {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	return mgr.state.Tapes[serial]
+}
+
+// pick returns the slot of the usable cleaning cartridge with the fewest
+// uses.
+func (mgr *Manager) pick(status *mtx.Status) *mtx.Slot {
+	var best *mtx.Slot
+	for _, slot := range status.Slots {
+		if slot.Type != mtx.StorageSlot || slot.Vol == nil || !IsCleaningTape(slot.Vol.Serial) {
+			continue
+		}
+
+		uses := mgr.state.Tapes[slot.Vol.Serial]
+		if mgr.policy.MaxUses > 0 && uses >= mgr.policy.MaxUses {
+			continue
+		}
+
+		if best == nil || uses < mgr.state.Tapes[best.Vol.Serial] {
+			best = slot
+		}
+	}
+
+	return best
+}
+
+// CleanDrive cleans drive: the least used cleaning cartridge is loaded,
+// left in the drive for the policy duration and unloaded back to its slot.
+// The drive must be empty. The cleaning is recorded whether it succeeds or
+// not.
+func (mgr *Manager) CleanDrive(ctx context.Context, drive int) error {
+	status, err := mgr.chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	mgr.mu.Lock()
+	if mgr.cleaning[drive] {
+		mgr.mu.Unlock()
+		return ErrInProgress
+	}
+
+	slot := mgr.pick(status)
+	if slot == nil {
+		mgr.mu.Unlock()
+		return ErrNoCleaningTape
+	}
+
+	mgr.cleaning[drive] = true
+	mgr.mu.Unlock()
+
+	err = mgr.clean(ctx, drive, slot.Num)
+
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	delete(mgr.cleaning, drive)
+
+	rec := Record{Time: time.Now(), Tape: slot.Vol.Serial}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+
+	ds := mgr.drive(drive)
+	ds.History = append(ds.History, rec)
+	if len(ds.History) > maxHistory {
+		ds.History = ds.History[len(ds.History)-maxHistory:]
+	}
+
+	if err == nil {
+		ds.LastCleaned = rec.Time
+		ds.Mounts = 0
+		ds.Requested = false
+		mgr.state.Tapes[slot.Vol.Serial]++
+	}
+
+	if serr := mgr.save(); err == nil {
+		err = serr
+	}
+
+	return err
+}
+
+func (mgr *Manager) clean(ctx context.Context, drive, slot int) error {
+	load := mgr.sched.Submit(mtx.Move{Type: mtx.MoveLoad, Src: slot, Dst: drive}, mgr.policy.Priority)
+	if err := load.Wait(ctx); err != nil {
+		if ctx.Err() == nil || mgr.sched.Cancel(load) {
+			return err
+		}
+
+		// the load is already under way; once the cartridge is in, it is
+		// unloaded right away
+		if <-load.Done(); load.Err() != nil {
+			return err
+		}
+	}
+
+	timer := time.NewTimer(mgr.policy.Duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		// still return the cartridge, the drive is useless with it
+	}
+
+	unload := mgr.sched.Submit(mtx.Move{Type: mtx.MoveUnload, Src: drive, Dst: slot}, mgr.policy.Priority)
+	if err := unload.Wait(context.Background()); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// Handle is an events.Handler counting the mounts of data cartridges. If
+// the policy has Auto set, a drive that is due is cleaned in the background
+// once it has been unloaded.
+func (mgr *Manager) Handle(ev events.Event) {
+	switch ev := ev.(type) {
+	case *events.VolumeLoaded:
+		if !IsCleaningTape(ev.Serial) {
+			mgr.RecordMount(ev.Drive)
+		}
+	case *events.VolumeUnloaded:
+		if mgr.policy.Auto && !IsCleaningTape(ev.Serial) && mgr.Due(ev.Drive) {
+			go mgr.CleanDrive(context.Background(), ev.Drive)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package cleaning_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/cleaning"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/scheduler"
+	"github.com/kbj/mtx/store"
+)
+
+// gated holds the first move until release is closed, announcing it on
+// started.
+type gated struct {
+	mtx.Interface
+	started, release chan struct{}
+	held             bool
+}
+
+func (impl *gated) Do(args ...string) ([]byte, error) {
+	if args[0] != "status" && !impl.held {
+		impl.held = true
+		close(impl.started)
+		<-impl.release
+	}
+
+	return impl.Interface.Do(args...)
+}
+
+func newManager(t *testing.T, impl mtx.Interface) (*cleaning.Manager, *mtx.Changer, *scheduler.Scheduler) {
+	t.Helper()
+
+	chgr := mtx.NewChanger(impl)
+	sched := scheduler.New(chgr)
+
+	mgr, err := cleaning.New(chgr, sched, store.NewMemory(), cleaning.Policy{Duration: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mgr, chgr, sched
+}
+
+func TestCleanCanceledQueued(t *testing.T) {
+	// the scheduler is not run, so the load stays queued
+	mgr, _, sched := newManager(t, mock.New(2, 8, 1, 4))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := mgr.CleanDrive(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CleanDrive = %v, want deadline exceeded", err)
+	}
+
+	if n := sched.Len(); n != 0 {
+		t.Errorf("%d jobs queued, want the load canceled", n)
+	}
+}
+
+func TestCleanCanceledRunning(t *testing.T) {
+	impl := &gated{Interface: mock.New(2, 8, 1, 4), started: make(chan struct{}), release: make(chan struct{})}
+	mgr, chgr, sched := newManager(t, impl)
+
+	go sched.Run()
+	defer sched.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errc := make(chan error, 1)
+	go func() { errc <- mgr.CleanDrive(ctx, 0) }()
+
+	// cancel while the cartridge is being loaded
+	<-impl.started
+	cancel()
+	close(impl.release)
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("CleanDrive = %v, want canceled", err)
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vol := status.Drives[0].Vol; vol != nil {
+		t.Errorf("drive 0 holds %s, want the cartridge unloaded", vol.Serial)
+	}
+
+	if vol := status.Slots[7].Vol; vol == nil || !cleaning.IsCleaningTape(vol.Serial) {
+		t.Errorf("slot 8 holds %v, want the cleaning cartridge", vol)
+	}
+}
@@ -0,0 +1,125 @@
+// Command chg-mtx is an Amanda changer script driving a library through
+// the mtx package.
+//
+// Configure it as the tpchanger of an Amanda configuration. It accepts the
+// changer script arguments (-info, -slot, -reset, -eject, -search and
+// -label) and additionally "inventory", which lists the slots like
+// 'amtape inventory'. It is configured through the environment:
+//
+//	CHANGER          changer device (default /dev/changer)
+//	TAPE             tape device of the drive (default /dev/nst0)
+//	CHG_MTX_DRIVE    drive number of the tape device (default 0)
+//	CHG_MTX_STATE    directory keeping the changer state (default
+//	                 /var/lib/amanda/chg-mtx)
+//	CHG_MTX_MOCK     if set to a geometry like "1,16,0,10", simulate a
+//	                 library instead, persisted in the state directory
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/amanda"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/scsi"
+	"github.com/kbj/mtx/store"
+)
+
+func env(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return def
+}
+
+func main() {
+	dir := env("CHG_MTX_STATE", "/var/lib/amanda/chg-mtx")
+
+	st, err := store.NewDir(dir)
+	if err != nil {
+		fatal(err)
+	}
+
+	drive, err := strconv.Atoi(env("CHG_MTX_DRIVE", "0"))
+	if err != nil {
+		fatal(fmt.Errorf("invalid CHG_MTX_DRIVE: %v", err))
+	}
+
+	impl, done, err := open(dir)
+	if err != nil {
+		fatal(err)
+	}
+
+	a := amanda.New(mtx.NewChanger(impl), drive, env("TAPE", "/dev/nst0"), st)
+
+	code := amanda.ExitOK
+	if len(os.Args) > 1 && os.Args[1] == "inventory" {
+		if err := a.Inventory(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "chg-mtx: %v\n", err)
+			code = amanda.ExitFatal
+		}
+	} else {
+		code = a.Run(os.Args[1:], os.Stdout)
+	}
+
+	if err := done(); err != nil {
+		fmt.Fprintf(os.Stderr, "chg-mtx: %v\n", err)
+		code = amanda.ExitFatal
+	}
+
+	os.Exit(code)
+}
+
+func fatal(err error) {
+	fmt.Printf("0 %v\n", err)
+	os.Exit(amanda.ExitFatal)
+}
+
+// open returns the changer implementation and a function to call when done
+// with it.
+func open(dir string) (mtx.Interface, func() error, error) {
+	geom := os.Getenv("CHG_MTX_MOCK")
+	if geom == "" {
+		return scsi.New(env("CHANGER", "/dev/changer")), func() error { return nil }, nil
+	}
+
+	var drives, slots, mail, vols int
+	if _, err := fmt.Sscanf(geom, "%d,%d,%d,%d", &drives, &slots, &mail, &vols); err != nil {
+		return nil, nil, fmt.Errorf("invalid CHG_MTX_MOCK %q", geom)
+	}
+
+	path := filepath.Join(dir, "mock.json")
+	chgr := mock.New(drives, slots, mail, vols)
+
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		chgr, err = mock.Load(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, nil, err
+	}
+
+	save := func() error {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+
+		if err := chgr.Save(f); err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	}
+
+	return chgr, save, nil
+}
@@ -0,0 +1,74 @@
+// Command mtxcheck checks that a changer backend behaves like mtx.
+//
+// Usage:
+//
+//	mtxcheck [flags]
+//
+// mtxcheck performs a sequence of random moves on the changer and compares
+// the outcome of every move with a model of mtx semantics, checking the
+// consistency of the library state after each step. On the first
+// disagreement, the moves leading up to it are printed and mtxcheck exits
+// with status 1.
+//
+// The mock backend checks the simulated library used in tests. The scsi
+// backend checks the changer given by -f, e.g. an mhvtl library; it
+// rearranges the volumes in the library, so never point it at a library in
+// production use.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/mtxtest"
+	"github.com/kbj/mtx/scsi"
+)
+
+var (
+	backend  = flag.String("backend", "mock", "changer backend (mock or scsi)")
+	device   = flag.String("f", "/dev/changer", "changer device for the scsi backend")
+	mockGeom = flag.String("mock", "4,32,4,16", "mock geometry as drives,storage slots,mail slots,volumes")
+	seed     = flag.Int64("seed", 0, "random seed (0 for a time based seed)")
+	steps    = flag.Int("steps", 1000, "number of moves to perform")
+)
+
+func main() {
+	flag.Parse()
+
+	var impl mtx.Interface
+	switch *backend {
+	case "mock":
+		var drives, slots, mail, vols int
+		if _, err := fmt.Sscanf(*mockGeom, "%d,%d,%d,%d", &drives, &slots, &mail, &vols); err != nil {
+			fatal(fmt.Errorf("invalid mock geometry %q", *mockGeom))
+		}
+
+		impl = mock.New(drives, slots, mail, vols)
+	case "scsi":
+		impl = scsi.New(*device)
+	default:
+		fatal(fmt.Errorf("unknown backend %q", *backend))
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	fmt.Printf("seed %d\n", *seed)
+
+	if err := mtxtest.Verify(mtx.NewChanger(impl), rand.New(rand.NewSource(*seed)), *steps); err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("ok, %d moves\n", *steps)
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "mtxcheck: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kbj/mtx"
+)
+
+// applyFile is the YAML document read by apply. It lists either moves, in
+// mtx command syntax, or the elements volumes should be in, e.g.
+// "drive 0" or "slot 17".
+type applyFile struct {
+	Moves  []string          `yaml:"moves"`
+	Layout map[string]string `yaml:"layout"`
+}
+
+// progress records how far apply got with a plan, so that it can resume it.
+type progress struct {
+	Moves []string `json:"moves"`
+	Done  int      `json:"done"`
+}
+
+// parseElement parses an element as "drive N", "slot N", "storage N" or
+// "mail N".
+func parseElement(s string) (mtx.Element, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return mtx.Element{}, fmt.Errorf("invalid element %q", s)
+	}
+
+	num, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return mtx.Element{}, fmt.Errorf("invalid element %q", s)
+	}
+
+	switch fields[0] {
+	case "drive":
+		return mtx.DriveElement(mtx.DriveNum(num)), nil
+	case "slot", "storage", "mail":
+		return mtx.SlotElement(mtx.SlotNum(num)), nil
+	}
+
+	return mtx.Element{}, fmt.Errorf("invalid element %q", s)
+}
+
+// readApplyFile reads the plan or layout in name ("-" for stdin) and
+// returns the moves to perform.
+func readApplyFile(chgr *mtx.Changer, name string) (*mtx.MovePlan, bool, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, false, err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	var file applyFile
+
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && err != io.EOF {
+		return nil, false, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if (file.Moves == nil) == (file.Layout == nil) {
+		return nil, false, fmt.Errorf("%s: expected either moves or a layout", name)
+	}
+
+	if file.Layout != nil {
+		layout := make(mtx.Layout, len(file.Layout))
+		for serial, s := range file.Layout {
+			e, err := parseElement(s)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: %s: %v", name, serial, err)
+			}
+
+			layout[serial] = e
+		}
+
+		plan, err := chgr.PlanLayout(layout)
+
+		return plan, true, err
+	}
+
+	plan := new(mtx.MovePlan)
+	for i, s := range file.Moves {
+		mv, err := mtx.ParseMove(s)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: move %d: %v", name, i+1, err)
+		}
+
+		plan.Moves = append(plan.Moves, mv)
+	}
+
+	return plan, false, nil
+}
+
+// readProgress returns the number of moves of plan already performed
+// according to the progress file name.
+func readProgress(name string, plan *mtx.MovePlan) (int, error) {
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	var p progress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return 0, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if !slices.Equal(p.Moves, moveStrings(plan.Moves)) || p.Done > len(plan.Moves) {
+		return 0, fmt.Errorf("%s: progress of a different plan", name)
+	}
+
+	return p.Done, nil
+}
+
+func writeProgress(name string, plan *mtx.MovePlan, done int) error {
+	data, err := json.Marshal(progress{Moves: moveStrings(plan.Moves), Done: done})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, data, 0o644)
+}
+
+func moveStrings(moves []mtx.Move) []string {
+	s := make([]string, len(moves))
+	for i, mv := range moves {
+		s[i] = mv.String()
+	}
+
+	return s
+}
+
+func cmdApply(chgr *mtx.Changer, args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	dryRun := fs.Bool("n", false, "print the plan without performing it")
+	progressFile := fs.String("progress", "", "record the progress of a list of moves in `file` to resume it")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	plan, isLayout, err := readApplyFile(chgr, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if isLayout && *progressFile != "" {
+		return errors.New("-progress applies to lists of moves; apply a layout again to resume it")
+	}
+
+	var done int
+	if *progressFile != "" {
+		if done, err = readProgress(*progressFile, plan); err != nil {
+			return err
+		}
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	rest := &mtx.MovePlan{Moves: plan.Moves[done:]}
+	if err := status.CheckPlan(rest); err != nil {
+		return err
+	}
+
+	for _, mv := range rest.Moves {
+		if err := chgr.CheckMove(mv); err != nil {
+			return fmt.Errorf("%s: %w", mv, err)
+		}
+	}
+
+	text := *outputFmt == "text"
+	if text {
+		for i, mv := range plan.Moves {
+			note := ""
+			if i < done {
+				note = "\t(done)"
+			}
+
+			fmt.Printf("%d\t%s%s\n", i+1, mv, note)
+		}
+	}
+
+	if !*dryRun {
+		for ; done < len(plan.Moves); done++ {
+			mv := plan.Moves[done]
+			if text {
+				fmt.Printf("[%d/%d] %s: ", done+1, len(plan.Moves), mv)
+			}
+
+			if err = chgr.Move(mv); err != nil {
+				if text {
+					fmt.Println("failed")
+				}
+
+				err = fmt.Errorf("move %d (%s): %w", done+1, mv, err)
+				break
+			}
+
+			if text {
+				fmt.Println("ok")
+			}
+
+			if *progressFile != "" {
+				if err = writeProgress(*progressFile, plan, done+1); err != nil {
+					done++
+					break
+				}
+			}
+		}
+
+		if err == nil && *progressFile != "" {
+			if err = os.Remove(*progressFile); os.IsNotExist(err) {
+				err = nil
+			}
+		}
+	}
+
+	if !text {
+		if oerr := output(moveRecords(plan.Moves, done), nil); oerr != nil {
+			return oerr
+		}
+	}
+
+	return err
+}
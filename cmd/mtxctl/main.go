@@ -0,0 +1,269 @@
+// Command mtxctl controls an automated library changer from the shell.
+//
+// Usage:
+//
+//	mtxctl [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	status [-json] [-offset N] [-limit N]
+//	                      show the contents of the library
+//	load SLOT DRIVE       load the volume in SLOT into DRIVE
+//	unload SLOT DRIVE     unload the volume in DRIVE into SLOT (0 for home)
+//	transfer SRC DST      move the volume in slot SRC to slot DST
+//	find SERIAL...        show where the given volumes are
+//	export SERIAL...      move the given volumes to free mail slots
+//	import [-spread N]    move all volumes in mail slots to free storage slots,
+//	                      spreading them across magazines of N slots
+//	plan [-n] FILE        perform the moves listed in FILE ("-" for stdin)
+//	apply [-n] [-progress FILE] FILE
+//	                      perform the moves listed in the YAML file FILE, or the
+//	                      moves bringing volumes to the layout it describes
+//	tui [-interval D]     browse the library and perform moves interactively,
+//	                      refreshing the status every D
+//
+// A plan file lists one move per line in mtx command syntax (e.g.
+// "transfer 3 17"). Empty lines and lines starting with '#' are ignored.
+//
+// The YAML file read by apply has either a list of moves in the same syntax,
+// or a layout mapping volume serials to elements:
+//
+//	layout:
+//	  A00001L6: drive 0
+//	  A00002L6: slot 17
+//
+// apply checks the moves against the current status before performing any,
+// and reports its progress. A layout is resumed by applying it again, as its
+// moves are computed from the current status; for a list of moves, -progress
+// names a file recording the moves done, which apply skips when run again.
+//
+// With -output json, yaml or csv, commands print their results as records
+// for use by other programs instead of as text: status prints one record per
+// element, load, unload, transfer, plan and apply one per move, and find,
+// export and import one per volume. Records only ever gain fields, at the end.
+//
+// The changer is either one of the changers defined in the file given by
+// -config (defaulting to $MTX_CONFIG), selected by -changer, see package
+// config, or given by the backend flags. The backend is selected with the
+// -backend flag. The scsi backend drives the changer given by -f using the
+// 'mtx' program. The remote backend drives a changer exposed by the
+// httpserver package at the URL given by -url, authenticating with the API
+// token given by -token (defaulting to $MTX_TOKEN) if the server requires
+// one. For https URLs, -ca names the CA certificates to verify the server
+// with, and -cert and -key the client certificate for servers requiring one.
+// The mock backend simulates a library with the geometry given by -mock; if
+// -mock-state is given, the state of the simulated library is loaded from and
+// saved to that file, so it persists across invocations.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/config"
+	"github.com/kbj/mtx/httpclient"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/scsi"
+	"github.com/kbj/mtx/tlsconfig"
+)
+
+var (
+	backend   = flag.String("backend", "scsi", "changer backend (scsi, remote or mock)")
+	device    = flag.String("f", defaultDevice(), "changer device for the scsi backend")
+	remoteURL = flag.String("url", "http://localhost:8080", "server URL for the remote backend")
+	token     = flag.String("token", os.Getenv("MTX_TOKEN"), "API token for the remote backend")
+	tlsCA     = flag.String("ca", "", "CA certificates to verify the remote server with")
+	tlsCert   = flag.String("cert", "", "client certificate for the remote backend")
+	tlsKey    = flag.String("key", "", "client certificate key for the remote backend")
+	mockGeom  = flag.String("mock", "4,32,4,16", "mock geometry as drives,storage slots,mail slots,volumes")
+	mockState = flag.String("mock-state", "", "file persisting the mock library state")
+	cfgFile   = flag.String("config", os.Getenv("MTX_CONFIG"), "changer definitions `file`")
+	cfgName   = flag.String("changer", "", "changer `name` in the -config file")
+	outputFmt = flag.String("output", "text", "output `format` (text, json, yaml or csv)")
+)
+
+func defaultDevice() string {
+	if dev := os.Getenv("CHANGER"); dev != "" {
+		return dev
+	}
+
+	return "/dev/changer"
+}
+
+type command struct {
+	run   func(chgr *mtx.Changer, args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"status":   {cmdStatus, "status [-json] [-offset N] [-limit N]"},
+	"load":     {cmdLoad, "load SLOT DRIVE"},
+	"unload":   {cmdUnload, "unload SLOT DRIVE"},
+	"transfer": {cmdTransfer, "transfer SRC DST"},
+	"find":     {cmdFind, "find SERIAL..."},
+	"export":   {cmdExport, "export SERIAL..."},
+	"import":   {cmdImport, "import [-spread N]"},
+	"plan":     {cmdPlan, "plan [-n] FILE"},
+	"apply":    {cmdApply, "apply [-n] [-progress FILE] FILE"},
+	"tui":      {cmdTUI, "tui [-interval D]"},
+}
+
+// errUsage signals that a command was invoked with bad arguments.
+var errUsage = errors.New("usage")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: mtxctl [flags] <command> [arguments]\n\ncommands:\n")
+	for _, name := range []string{"status", "load", "unload", "transfer", "find", "export", "import", "plan", "apply", "tui"} {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	if !validOutput(*outputFmt) {
+		fmt.Fprintf(os.Stderr, "mtxctl: unknown output format %q\n", *outputFmt)
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "mtxctl: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	chgr, done, err := open()
+	if err != nil {
+		fatal(err)
+	}
+
+	err = cmd.run(chgr, flag.Args()[1:])
+
+	if derr := done(); derr != nil && err == nil {
+		err = derr
+	}
+
+	if err == errUsage {
+		fmt.Fprintf(os.Stderr, "usage: mtxctl %s\n", cmd.usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "mtxctl: %v\n", err)
+	os.Exit(1)
+}
+
+// open returns the changer selected by flags and a function to call when
+// done with it.
+func open() (*mtx.Changer, func() error, error) {
+	nop := func() error { return nil }
+
+	if *cfgFile != "" {
+		cfg, err := config.LoadFile(*cfgFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		chgr, err := config.NewFromConfig(cfg, *cfgName)
+
+		return chgr, nop, err
+	}
+
+	var (
+		impl mtx.Interface
+		done = nop
+		err  error
+	)
+
+	switch *backend {
+	case "scsi":
+		impl = scsi.New(*device)
+	case "remote":
+		impl, done, err = openRemote()
+	case "mock":
+		impl, done, err = openMock()
+	default:
+		err = fmt.Errorf("unknown backend %q", *backend)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mtx.NewChanger(impl), done, nil
+}
+
+func openRemote() (mtx.Interface, func() error, error) {
+	opts := []httpclient.Option{httpclient.WithToken(*token)}
+
+	if *tlsCA != "" || *tlsCert != "" {
+		cfg, err := tlsconfig.Client(*tlsCA, *tlsCert, *tlsKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		opts = append(opts, httpclient.WithTLS(cfg))
+	}
+
+	return httpclient.New(*remoteURL, opts...), func() error { return nil }, nil
+}
+
+func openMock() (mtx.Interface, func() error, error) {
+	var drives, slots, mail, vols int
+	if _, err := fmt.Sscanf(*mockGeom, "%d,%d,%d,%d", &drives, &slots, &mail, &vols); err != nil {
+		return nil, nil, fmt.Errorf("invalid mock geometry %q", *mockGeom)
+	}
+
+	if *mockState == "" {
+		return mock.New(drives, slots, mail, vols), func() error { return nil }, nil
+	}
+
+	chgr := mock.New(drives, slots, mail, vols)
+
+	f, err := os.Open(*mockState)
+	switch {
+	case err == nil:
+		chgr, err = mock.Load(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", *mockState, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, nil, err
+	}
+
+	save := func() error {
+		f, err := os.Create(*mockState)
+		if err != nil {
+			return err
+		}
+
+		if err := chgr.Save(f); err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	}
+
+	return chgr, save, nil
+}
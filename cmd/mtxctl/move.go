@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/kbj/mtx"
+)
+
+// twoInts parses exactly two integer arguments.
+func twoInts(args []string) (int, int, error) {
+	if len(args) != 2 {
+		return 0, 0, errUsage
+	}
+
+	a, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, 0, errUsage
+	}
+
+	b, err := strconv.Atoi(args[1])
+	if err != nil {
+		return 0, 0, errUsage
+	}
+
+	return a, b, nil
+}
+
+// slotAndDrive parses a slot and a drive number argument.
+func slotAndDrive(args []string) (mtx.SlotNum, mtx.DriveNum, error) {
+	a, b, err := twoInts(args)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	slot, err := mtx.NewSlotNum(a)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	drive, err := mtx.NewDriveNum(b)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return slot, drive, nil
+}
+
+func cmdLoad(chgr *mtx.Changer, args []string) error {
+	slot, drive, err := slotAndDrive(args)
+	if err != nil {
+		return err
+	}
+
+	return performed(chgr, mtx.Move{Type: mtx.MoveLoad, Src: int(slot), Dst: int(drive)})
+}
+
+func cmdUnload(chgr *mtx.Changer, args []string) error {
+	slot, drive, err := slotAndDrive(args)
+	if err != nil {
+		return err
+	}
+
+	return performed(chgr, mtx.Move{Type: mtx.MoveUnload, Src: int(drive), Dst: int(slot)})
+}
+
+func cmdTransfer(chgr *mtx.Changer, args []string) error {
+	a, b, err := twoInts(args)
+	if err != nil {
+		return err
+	}
+
+	src, err := mtx.NewSlotNum(a)
+	if err != nil {
+		return err
+	}
+
+	dst, err := mtx.NewSlotNum(b)
+	if err != nil {
+		return err
+	}
+
+	return performed(chgr, mtx.Move{Type: mtx.MoveTransfer, Src: int(src), Dst: int(dst)})
+}
+
+// performed performs the single move mv. Only the machine-readable formats
+// report it.
+func performed(chgr *mtx.Changer, mv mtx.Move) error {
+	if err := chgr.Move(mv); err != nil {
+		return err
+	}
+
+	return output(moveRecords([]mtx.Move{mv}, 1), func() error { return nil })
+}
+
+func cmdFind(chgr *mtx.Changer, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var missing int
+	records := make([]volumeRecord, 0, len(args))
+	for _, serial := range args {
+		slot, err := chgr.Find(serial)
+		if errors.Is(err, mtx.ErrVolumeNotFound) {
+			records = append(records, volumeRecord{Serial: serial, Error: "not found"})
+			missing++
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		records = append(records, volumeRecord{Serial: serial, Type: slotTypeName(slot.Type), Num: slot.Num})
+	}
+
+	err := output(records, func() error {
+		for _, rec := range records {
+			if rec.Error != "" {
+				fmt.Printf("%s\t%s\n", rec.Serial, rec.Error)
+			} else {
+				fmt.Printf("%s\t%s %d\n", rec.Serial, rec.Type, rec.Num)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d volume(s) not found", missing)
+	}
+
+	return nil
+}
+
+func cmdExport(chgr *mtx.Changer, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var failed error
+	records := make([]volumeRecord, 0, len(args))
+	for _, serial := range args {
+		slot, err := chgr.Export(serial)
+		if err != nil {
+			failed = fmt.Errorf("%s: %v", serial, err)
+			break
+		}
+
+		records = append(records, volumeRecord{Serial: serial, Type: slotTypeName(slot.Type), Num: slot.Num})
+	}
+
+	err := output(records, func() error {
+		for _, rec := range records {
+			fmt.Printf("%s\t%s %d\n", rec.Serial, rec.Type, rec.Num)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return failed
+}
+
+func cmdImport(chgr *mtx.Changer, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	spread := fs.Int("spread", 0, "spread volumes across magazines of `n` slots")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *spread < 0 {
+		return errUsage
+	}
+
+	p := mtx.FirstFree
+	if *spread > 0 {
+		p = mtx.Spread(*spread)
+	}
+
+	results, err := chgr.Import(p)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	records := make([]importRecord, len(results))
+	for i, res := range results {
+		records[i] = importRecord{Serial: res.Serial, MailSlot: res.MailSlot, Slot: res.Slot, Error: errorString(res.Err)}
+		if res.Err != nil && failed == nil {
+			failed = fmt.Errorf("%s: %v", res.Serial, res.Err)
+		}
+	}
+
+	err = output(records, func() error {
+		for _, res := range results {
+			if res.Err != nil {
+				break
+			}
+
+			fmt.Printf("%s\tstorage %d\n", res.Serial, res.Slot)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return failed
+}
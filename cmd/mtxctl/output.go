@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kbj/mtx"
+)
+
+// validOutput reports whether format is a known output format.
+func validOutput(format string) bool {
+	switch format {
+	case "text", "json", "yaml", "csv":
+		return true
+	}
+
+	return false
+}
+
+// The records printed by the commands for the machine-readable formats.
+// Their fields are the columns of the csv format, named by their tags;
+// fields are only ever added to them, at the end.
+type (
+	elementRecord struct {
+		Type     string `json:"type" yaml:"type"`
+		Num      int    `json:"num" yaml:"num"`
+		Full     bool   `json:"full" yaml:"full"`
+		Volume   string `json:"volume" yaml:"volume"`
+		Home     int    `json:"home" yaml:"home"`
+		Cleaning bool   `json:"cleaning" yaml:"cleaning"`
+	}
+
+	moveRecord struct {
+		Step int    `json:"step" yaml:"step"`
+		Type string `json:"type" yaml:"type"`
+		Src  int    `json:"src" yaml:"src"`
+		Dst  int    `json:"dst" yaml:"dst"`
+		Done bool   `json:"done" yaml:"done"`
+	}
+
+	volumeRecord struct {
+		Serial string `json:"serial" yaml:"serial"`
+		Type   string `json:"type" yaml:"type"`
+		Num    int    `json:"num" yaml:"num"`
+		Error  string `json:"error" yaml:"error"`
+	}
+
+	importRecord struct {
+		Serial   string `json:"serial" yaml:"serial"`
+		MailSlot int    `json:"mailSlot" yaml:"mailSlot"`
+		Slot     int    `json:"slot" yaml:"slot"`
+		Error    string `json:"error" yaml:"error"`
+	}
+)
+
+// moveRecords returns the records of the moves, the first done of which
+// were performed.
+func moveRecords(moves []mtx.Move, done int) []moveRecord {
+	records := make([]moveRecord, len(moves))
+	for i, mv := range moves {
+		records[i] = moveRecord{Step: i + 1, Type: mv.Type.String(), Src: mv.Src, Dst: mv.Dst, Done: i < done}
+	}
+
+	return records
+}
+
+// output writes records, a slice of one of the record types, in the format
+// selected by -output, or calls text for the text format.
+func output(records any, text func() error) error {
+	switch *outputFmt {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(records)
+	case "yaml":
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(records); err != nil {
+			return err
+		}
+
+		return enc.Close()
+	case "csv":
+		return writeCSV(os.Stdout, records)
+	}
+
+	return text()
+}
+
+// writeCSV writes records, a slice of structs, as CSV with a header line.
+func writeCSV(w io.Writer, records any) error {
+	v := reflect.ValueOf(records)
+	typ := v.Type().Elem()
+
+	cw := csv.NewWriter(w)
+
+	header := make([]string, typ.NumField())
+	for i := range header {
+		header[i], _, _ = strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+	}
+
+	cw.Write(header)
+
+	for i := 0; i < v.Len(); i++ {
+		row := make([]string, typ.NumField())
+		for j := range row {
+			row[j] = fmt.Sprint(v.Index(i).Field(j).Interface())
+		}
+
+		cw.Write(row)
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// errorString returns the message of err, or "" if it is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kbj/mtx"
+)
+
+// readPlan reads a plan with one move per line.
+func readPlan(r io.Reader) (*mtx.MovePlan, error) {
+	plan := new(mtx.MovePlan)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		mv, err := mtx.ParseMove(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+
+		plan.Moves = append(plan.Moves, mv)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func cmdPlan(chgr *mtx.Changer, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	dryRun := fs.Bool("n", false, "print the plan without performing it")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	var r io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	plan, err := readPlan(r)
+	if err != nil {
+		return err
+	}
+
+	if *outputFmt != "text" {
+		var n int
+		if !*dryRun {
+			n, err = chgr.Execute(plan)
+		}
+
+		if oerr := output(moveRecords(plan.Moves, n), nil); oerr != nil {
+			return oerr
+		}
+
+		return err
+	}
+
+	for i, mv := range plan.Moves {
+		fmt.Printf("%d\t%s\n", i+1, mv)
+	}
+
+	if *dryRun {
+		return nil
+	}
+
+	n, err := chgr.Execute(plan)
+	fmt.Printf("%d of %d moves done\n", n, len(plan.Moves))
+
+	return err
+}
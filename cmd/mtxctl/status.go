@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/httpserver"
+)
+
+func slotTypeName(typ mtx.SlotType) string {
+	switch typ {
+	case mtx.DataTransferSlot:
+		return "drive"
+	case mtx.StorageSlot:
+		return "storage"
+	case mtx.MailSlot:
+		return "mail"
+	}
+
+	return "unknown"
+}
+
+func cmdStatus(chgr *mtx.Changer, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "output the status as served by the HTTP API")
+	offset := fs.Int("offset", 0, "skip the first `n` slots")
+	limit := fs.Int("limit", 0, "show at most `n` slots (0 for all)")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *offset < 0 || *limit < 0 {
+		return errUsage
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	status.Slots = status.SlotsPage(*offset, *limit)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(httpserver.NewStatus(status))
+	}
+
+	return output(elementRecords(status), func() error {
+		return status.Format(os.Stdout, mtx.FormatOptions{})
+	})
+}
+
+// elementRecords returns the records of the elements of status, drives
+// first.
+func elementRecords(status *mtx.Status) []elementRecord {
+	records := make([]elementRecord, 0, len(status.Drives)+len(status.Slots))
+	for slot := range status.AllSlots() {
+		rec := elementRecord{Type: slotTypeName(slot.Type), Num: slot.Num}
+		if slot.Vol != nil {
+			rec.Full = true
+			rec.Volume, rec.Home = slot.Vol.Serial, slot.Vol.Home
+			rec.Cleaning = mtx.IsCleaning(slot)
+		}
+
+		records = append(records, rec)
+	}
+
+	return records
+}
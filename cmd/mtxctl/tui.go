@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kbj/mtx"
+)
+
+// ANSI escape sequences used by the terminal UI.
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	boldOn      = "\x1b[1m"
+	boldOff     = "\x1b[0m"
+)
+
+const tuiHelp = "a/d/s/m: all, drives, storage, mail  /TEXT: search  r: refresh  " +
+	"load SLOT DRIVE | unload SLOT DRIVE | transfer SRC DST  q: quit"
+
+// tui is the state of the terminal UI.
+type tui struct {
+	chgr *mtx.Changer
+	out  io.Writer
+
+	view   string // "all", "drives", "storage" or "mail"
+	search string
+	msg    string
+
+	// screen is the last drawn library table, to redraw only on changes.
+	screen string
+}
+
+func cmdTUI(chgr *mtx.Changer, args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	interval := fs.Duration("interval", 5*time.Second, "refresh the status every `d`")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *interval <= 0 {
+		return errUsage
+	}
+
+	if *outputFmt != "text" {
+		return errors.New("tui supports only the text output format")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go chgr.Refresh(ctx, *interval)
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	ui := &tui{chgr: chgr, out: os.Stdout, view: "all"}
+	ui.draw(true)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ui.draw(false)
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+
+			if quit := ui.handle(line, lines); quit {
+				fmt.Fprint(ui.out, clearScreen)
+				return nil
+			}
+
+			ui.draw(true)
+		}
+	}
+}
+
+// handle runs the command line entered by the operator, reading the
+// confirmation of moves from lines. It reports whether to quit.
+func (ui *tui) handle(line string, lines <-chan string) bool {
+	line = strings.TrimSpace(line)
+	ui.msg = ""
+
+	switch {
+	case line == "":
+	case line == "q" || line == "quit":
+		return true
+	case line == "a" || line == "d" || line == "s" || line == "m":
+		ui.view = map[string]string{"a": "all", "d": "drives", "s": "storage", "m": "mail"}[line]
+	case line == "r":
+		// redrawn below
+	case strings.HasPrefix(line, "/"):
+		ui.search = strings.TrimSpace(line[1:])
+	default:
+		mv, err := mtx.ParseMove(line)
+		if err != nil {
+			ui.msg = fmt.Sprintf("unknown command %q", line)
+			break
+		}
+
+		fmt.Fprintf(ui.out, "%s? [y/N] ", mv)
+		if answer, ok := <-lines; !ok || !strings.EqualFold(strings.TrimSpace(answer), "y") {
+			ui.msg = "cancelled"
+			break
+		}
+
+		if err := ui.chgr.Move(mv); err != nil {
+			ui.msg = err.Error()
+			break
+		}
+
+		ui.msg = mv.String() + ": done"
+	}
+
+	return false
+}
+
+// draw redraws the screen. Unless force is set, the screen is only redrawn
+// if the library changed since the last draw.
+func (ui *tui) draw(force bool) {
+	var buf bytes.Buffer
+
+	status, err := ui.chgr.Status()
+	if err != nil {
+		fmt.Fprintf(&buf, "%s\n", err)
+	} else {
+		ui.filter(status).Format(&buf, mtx.FormatOptions{HideEmpty: ui.search != ""})
+	}
+
+	if !force && buf.String() == ui.screen {
+		return
+	}
+
+	ui.screen = buf.String()
+
+	fmt.Fprint(ui.out, clearScreen)
+	fmt.Fprintf(ui.out, "%smtxctl%s  view: %s", boldOn, boldOff, ui.view)
+	if ui.search != "" {
+		fmt.Fprintf(ui.out, "  search: %s", ui.search)
+	}
+
+	fmt.Fprintf(ui.out, "  %s\n\n%s\n", time.Now().Format("15:04:05"), ui.screen)
+
+	if ui.msg != "" {
+		fmt.Fprintf(ui.out, "%s\n", ui.msg)
+	}
+
+	fmt.Fprintf(ui.out, "%s\n> ", tuiHelp)
+}
+
+// filter returns the elements of status shown by the current view and
+// search.
+func (ui *tui) filter(status *mtx.Status) *mtx.Status {
+	status = status.Clone()
+
+	match := func(slot *mtx.Slot) bool {
+		return ui.search == "" ||
+			slot.Vol != nil && strings.Contains(strings.ToUpper(slot.Vol.Serial), strings.ToUpper(ui.search))
+	}
+
+	var drives, slots []*mtx.Slot
+	if ui.view == "all" || ui.view == "drives" {
+		drives = mtx.Filter(status.Drives, match)
+	}
+
+	switch ui.view {
+	case "all":
+		slots = mtx.Filter(status.Slots, match)
+	case "storage":
+		slots = mtx.Filter(status.Slots, mtx.IsStorage, match)
+	case "mail":
+		slots = mtx.Filter(status.Slots, mtx.IsMail, match)
+	}
+
+	status.Drives, status.Slots = drives, slots
+
+	return status
+}
@@ -0,0 +1,144 @@
+package mtx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+)
+
+// mediaGenerations maps LTO data and WORM media types to their generation.
+var mediaGenerations = map[Media]int{
+	LTO1: 1, LTO2: 2, LTO3: 3, LTO4: 4, LTO5: 5, LTO6: 6, LTO7: 7, LTOM8: 7, LTO8: 8, LTO9: 9,
+	"LT": 3, "LU": 4, "LV": 5, "LW": 6, "LX": 7, "LY": 8, "LZ": 9,
+}
+
+// Compatible reports whether an LTO drive of generation gen can read the
+// cartridge with the given barcode. Up to LTO-7, drives read media of their
+// own and the two previous generations; later drives read their own and the
+// previous generation only. LTO-7 type M (M8) media is only supported by
+// LTO-8 drives. Cleaning cartridges and barcodes without a known media type
+// are assumed to be compatible.
+func Compatible(gen int, serial string) bool {
+	if len(serial) < 2 || isCleaningSerial(serial) {
+		return true
+	}
+
+	id := Media(serial[len(serial)-2:])
+
+	media, ok := mediaGenerations[id]
+	if !ok {
+		return true
+	}
+
+	switch {
+	case id == LTOM8:
+		return gen == 8
+	case gen <= 7:
+		return gen-2 <= media && media <= gen
+	}
+
+	return gen-1 <= media && media <= gen
+}
+
+// driveProduct matches the product identification of LTO drives, e.g.
+// "ULTRIUM-TD9", "ULT3580-HH8" or "Ultrium 9-SCSI".
+var driveProduct = regexp.MustCompile(`(?i)ult(?:rium|3580)[- ](?:td|hh)?(\d+)`)
+
+// ParseDriveGeneration returns the LTO generation of a drive given its
+// inquiry product identification, or 0 if it is not recognized.
+func ParseDriveGeneration(product string) int {
+	m := driveProduct.FindStringSubmatch(product)
+	if m == nil {
+		return 0
+	}
+
+	gen, _ := strconv.Atoi(m[1])
+
+	return gen
+}
+
+// InquireDriveGeneration discovers the LTO generation of a drive by running
+// the inquiry command on impl, which must address the drive itself (e.g.
+// scsi.New on the generic SCSI device of the drive) rather than the changer.
+func InquireDriveGeneration(impl Interface) (int, error) {
+	out, err := impl.Do("inquiry")
+	if err != nil {
+		return 0, err
+	}
+
+	inq, err := ParseInquiry(out)
+	if err != nil {
+		return 0, err
+	}
+
+	gen := ParseDriveGeneration(inq.Product)
+	if gen == 0 {
+		return 0, fmt.Errorf("mtx: unknown drive product %q", inq.Product)
+	}
+
+	return gen, nil
+}
+
+// WithDriveGenerations sets the LTO generations of the drives of the
+// changer. Loads of media a drive cannot read, going by the barcode of the
+// volume, fail with ErrIncompatibleMedia without being issued. Drives
+// missing from gens are not checked. To know which volume a load moves, the
+// status is consulted before every load.
+func WithDriveGenerations(gens map[DriveNum]int) Option {
+	return func(chgr *Changer) {
+		chgr.driveGens = gens
+	}
+}
+
+// WarnIncompatibleMedia overrides the refusal of loads of incompatible
+// media set up by WithDriveGenerations: such loads are performed, and a
+// warning is logged to the logger of the changer, if any.
+func WarnIncompatibleMedia() Option {
+	return func(chgr *Changer) {
+		chgr.warnIncompatible = true
+	}
+}
+
+// checkMedia checks the command given by args, if it is a load, against the
+// drive generations of the changer.
+func (chgr *Changer) checkMedia(args []string, st *lazyStatus) error {
+	if len(chgr.driveGens) == 0 {
+		return nil
+	}
+
+	mv, ok := moveArgs(args)
+	if !ok || mv.Type != MoveLoad {
+		return nil
+	}
+
+	gen, ok := chgr.driveGens[DriveNum(mv.Dst)]
+	if !ok {
+		return nil
+	}
+
+	status, err := st.get()
+	if err != nil {
+		return err
+	}
+
+	slot := status.element(SlotElement(SlotNum(mv.Src)))
+	if slot == nil || slot.Vol == nil || Compatible(gen, slot.Vol.Serial) {
+		return nil
+	}
+
+	if chgr.warnIncompatible {
+		if chgr.logger != nil {
+			chgr.logger.LogAttrs(context.Background(), slog.LevelWarn, "loading incompatible media",
+				slog.String("serial", slot.Vol.Serial),
+				slog.Int("drive", mv.Dst),
+				slog.Int("generation", gen),
+			)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s in LTO-%d drive %d", ErrIncompatibleMedia, slot.Vol.Serial, gen, mv.Dst)
+}
@@ -0,0 +1,311 @@
+// Package config loads the definitions of named library changers from a
+// YAML file, so that setups with several libraries need not be spelled out
+// in flags or code:
+//
+//	changers:
+//	  library-a:
+//	    device: /dev/sg3
+//	    program: /usr/local/bin/mtx
+//	    timeout: 10m
+//	    retry:
+//	      attempts: 3
+//	      backoff: 2s
+//	  library-b:
+//	    backend: remote
+//	    url: https://tapehost:8443
+//	    token: secret
+//	    ca: /etc/mtx/ca.crt
+//	    partition:
+//	      slots: [[1, 40], [81, 84]]
+//	      drives: [0, 1]
+//
+// NewFromConfig returns a changer for one of them:
+//
+//	cfg, err := config.LoadFile("/etc/mtx/changers.yaml")
+//	...
+//	chgr, err := config.NewFromConfig(cfg, "library-a")
+//
+// NewFromEnv returns the changer defined by environment variables instead.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/httpclient"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/retry"
+	"github.com/kbj/mtx/scsi"
+	"github.com/kbj/mtx/tlsconfig"
+)
+
+// ErrUnknownChanger is matched by the errors returned for changer names
+// the configuration does not define.
+var ErrUnknownChanger = errors.New("mtx/config: unknown changer")
+
+// Config is a set of named changer definitions.
+type Config struct {
+	Changers map[string]*Changer `yaml:"changers"`
+}
+
+// Changer defines a library changer and how to reach it.
+type Changer struct {
+	// Backend is "scsi", "remote" or "mock". Defaults to "scsi".
+	Backend string `yaml:"backend"`
+
+	// Device and Program are the changer device and the path of the 'mtx'
+	// program for the scsi backend. Program defaults to /usr/bin/mtx.
+	Device  string `yaml:"device"`
+	Program string `yaml:"program"`
+
+	// URL and Token are the server URL and API token for the remote
+	// backend. CA, Cert and Key name the files of the CA certificates to
+	// verify the server with and of the client certificate, see
+	// tlsconfig.Client.
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+	CA    string `yaml:"ca"`
+	Cert  string `yaml:"cert"`
+	Key   string `yaml:"key"`
+
+	// Mock is the geometry of the mock backend as drives, storage slots,
+	// mail slots and volumes, e.g. "4,32,4,16".
+	Mock string `yaml:"mock"`
+
+	// Timeout bounds every command of the scsi and remote backends, e.g.
+	// "10m". Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Retry, if set, makes failed commands retried, see retry.Policy.
+	Retry *Retry `yaml:"retry"`
+
+	// Partition, if set, restricts the elements moves may use, see
+	// mtx.WithPolicy.
+	Partition *Partition `yaml:"partition"`
+}
+
+// Retry configures the retrying of failed commands.
+type Retry struct {
+	Attempts int           `yaml:"attempts"`
+	Backoff  time.Duration `yaml:"backoff"`
+	Moves    bool          `yaml:"moves"`
+}
+
+// Partition is the share of a library a changer may use: the slots in the
+// given ranges of slot numbers and the given drives. Without slots, all
+// slots are allowed; without drives, all drives are.
+type Partition struct {
+	Slots  [][2]int `yaml:"slots"`
+	Drives []int    `yaml:"drives"`
+}
+
+// Load reads a configuration from r and checks it.
+func Load(r io.Reader) (*Config, error) {
+	var cfg Config
+
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("mtx/config: %w", err)
+	}
+
+	for name, c := range cfg.Changers {
+		if c == nil {
+			c = new(Changer)
+			cfg.Changers[name] = c
+		}
+
+		if err := c.check(); err != nil {
+			return nil, fmt.Errorf("mtx/config: changer %s: %w", name, err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// LoadFile reads a configuration from the named file and checks it.
+func LoadFile(name string) (*Config, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return cfg, nil
+}
+
+// Names returns the names of the defined changers in order.
+func (cfg *Config) Names() []string {
+	names := make([]string, 0, len(cfg.Changers))
+	for name := range cfg.Changers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Lookup returns the definition of the changer with the given name. An
+// empty name selects the only changer of configurations defining one.
+func (cfg *Config) Lookup(name string) (*Changer, error) {
+	if name == "" && len(cfg.Changers) == 1 {
+		for _, c := range cfg.Changers {
+			return c, nil
+		}
+	}
+
+	c, ok := cfg.Changers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownChanger, name)
+	}
+
+	return c, nil
+}
+
+func (c *Changer) check() error {
+	switch c.Backend {
+	case "", "scsi":
+		if c.Device == "" {
+			return errors.New("no device")
+		}
+	case "remote":
+		if c.URL == "" {
+			return errors.New("no url")
+		}
+	case "mock":
+		if _, err := c.geometry(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown backend %q", c.Backend)
+	}
+
+	if c.Partition != nil {
+		for _, r := range c.Partition.Slots {
+			if r[0] > r[1] {
+				return fmt.Errorf("invalid slot range %d-%d", r[0], r[1])
+			}
+		}
+	}
+
+	return nil
+}
+
+// geometry returns the arguments of mock.New given by Mock.
+func (c *Changer) geometry() ([4]int, error) {
+	var g [4]int
+	if _, err := fmt.Sscanf(c.Mock, "%d,%d,%d,%d", &g[0], &g[1], &g[2], &g[3]); err != nil {
+		return g, fmt.Errorf("invalid mock geometry %q", c.Mock)
+	}
+
+	return g, nil
+}
+
+// Open returns the implementation of the changer, wrapped to retry failed
+// commands if so configured.
+func (c *Changer) Open() (mtx.Interface, error) {
+	var impl mtx.Interface
+
+	switch c.Backend {
+	case "", "scsi":
+		var opts []scsi.Option
+		if c.Program != "" {
+			opts = append(opts, scsi.WithProgram(c.Program))
+		}
+
+		if c.Timeout > 0 {
+			opts = append(opts, scsi.WithTimeout(c.Timeout))
+		}
+
+		impl = scsi.New(c.Device, opts...)
+	case "remote":
+		opts := []httpclient.Option{httpclient.WithToken(c.Token)}
+		if c.CA != "" || c.Cert != "" {
+			tlsCfg, err := tlsconfig.Client(c.CA, c.Cert, c.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			opts = append(opts, httpclient.WithTLS(tlsCfg))
+		}
+
+		impl = httpclient.NewWithClient(c.URL, &http.Client{Timeout: c.Timeout}, opts...)
+	case "mock":
+		g, err := c.geometry()
+		if err != nil {
+			return nil, err
+		}
+
+		impl = mock.New(g[0], g[1], g[2], g[3])
+	default:
+		return nil, fmt.Errorf("mtx/config: unknown backend %q", c.Backend)
+	}
+
+	if c.Retry != nil {
+		impl = retry.New(impl, retry.Policy{
+			Attempts: c.Retry.Attempts,
+			Backoff:  c.Retry.Backoff,
+			Moves:    c.Retry.Moves,
+		})
+	}
+
+	return impl, nil
+}
+
+// Options returns the options of mtx.NewChanger implementing the
+// definition, i.e. the policy of the partition.
+func (c *Changer) Options() []mtx.Option {
+	if c.Partition == nil {
+		return nil
+	}
+
+	var policies []mtx.Policy
+	if len(c.Partition.Slots) > 0 {
+		ranges := make([]mtx.Policy, len(c.Partition.Slots))
+		for i, r := range c.Partition.Slots {
+			ranges[i] = mtx.OnlySlots(r[0], r[1])
+		}
+
+		policies = append(policies, mtx.AnyOf(ranges...))
+	}
+
+	if len(c.Partition.Drives) > 0 {
+		policies = append(policies, mtx.OnlyDrives(c.Partition.Drives...))
+	}
+
+	return []mtx.Option{mtx.WithPolicy(mtx.AllOf(policies...))}
+}
+
+// NewFromConfig returns a changer for the definition with the given name,
+// see Config.Lookup. The options are applied after those of the definition.
+func NewFromConfig(cfg *Config, name string, opts ...mtx.Option) (*mtx.Changer, error) {
+	c, err := cfg.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.newChanger(opts)
+}
+
+func (c *Changer) newChanger(opts []mtx.Option) (*mtx.Changer, error) {
+	impl, err := c.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	return mtx.NewChanger(impl, append(c.Options(), opts...)...), nil
+}
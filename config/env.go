@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kbj/mtx"
+)
+
+// FromEnv returns the changer definition given by the environment, for
+// containers and CI jobs configured through it:
+//
+//	MTX_CONFIG       configuration file; if set, the changer named by
+//	                 MTX_CHANGER is taken from it and the variables below
+//	                 are ignored
+//	MTX_BACKEND      scsi, remote or mock (default scsi)
+//	MTX_DEVICE       changer device (default $CHANGER, then /dev/changer)
+//	MTX_PROGRAM      path of the 'mtx' program
+//	MTX_URL          server URL of the remote backend
+//	MTX_TOKEN        API token of the remote backend
+//	MTX_CA, MTX_CERT, MTX_KEY
+//	                 TLS files of the remote backend
+//	MTX_MOCK         mock geometry (default "4,32,4,16")
+//	MTX_TIMEOUT      command timeout, e.g. "10m"
+//	MTX_RETRIES      number of times queries are tried at most, waiting a
+//	                 second before the first retry
+func FromEnv() (*Changer, error) {
+	getenv := os.Getenv
+
+	if name := getenv("MTX_CONFIG"); name != "" {
+		cfg, err := LoadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return cfg.Lookup(getenv("MTX_CHANGER"))
+	}
+
+	env := func(name, def string) string {
+		if v := getenv(name); v != "" {
+			return v
+		}
+
+		return def
+	}
+
+	c := &Changer{
+		Backend: env("MTX_BACKEND", "scsi"),
+		Device:  env("MTX_DEVICE", env("CHANGER", "/dev/changer")),
+		Program: getenv("MTX_PROGRAM"),
+		URL:     getenv("MTX_URL"),
+		Token:   getenv("MTX_TOKEN"),
+		CA:      getenv("MTX_CA"),
+		Cert:    getenv("MTX_CERT"),
+		Key:     getenv("MTX_KEY"),
+		Mock:    env("MTX_MOCK", "4,32,4,16"),
+	}
+
+	if v := getenv("MTX_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("mtx/config: invalid MTX_TIMEOUT: %w", err)
+		}
+
+		c.Timeout = d
+	}
+
+	if v := getenv("MTX_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("mtx/config: invalid MTX_RETRIES: %w", err)
+		}
+
+		c.Retry = &Retry{Attempts: n, Backoff: time.Second}
+	}
+
+	if err := c.check(); err != nil {
+		return nil, fmt.Errorf("mtx/config: environment: %w", err)
+	}
+
+	return c, nil
+}
+
+// NewFromEnv returns the changer defined by the environment, see FromEnv.
+// The options are applied after those of the definition.
+func NewFromEnv(opts ...mtx.Option) (*mtx.Changer, error) {
+	c, err := FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.newChanger(opts)
+}
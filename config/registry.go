@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/kbj/mtx"
+)
+
+// Registry hands out the changers of a configuration by name, so that
+// services can refer to "library-a" instead of holding on to device paths.
+//
+// Backends are only opened once a changer performs its first command, and
+// are opened again after a command fails for a reason other than a
+// permanent one (see mtx.IsPermanent), e.g. a lost connection or a device
+// that went away. Mock backends are kept, as opening one again would reset
+// the simulated library. The changers returned by Get stay valid
+// throughout.
+type Registry struct {
+	cfg  *Config
+	opts []mtx.Option
+
+	mu       sync.Mutex
+	changers map[string]*mtx.Changer
+}
+
+// NewRegistry returns a registry of the changers defined by cfg. The
+// options are applied to every changer after those of its definition.
+func NewRegistry(cfg *Config, opts ...mtx.Option) *Registry {
+	return &Registry{
+		cfg:      cfg,
+		opts:     opts,
+		changers: make(map[string]*mtx.Changer),
+	}
+}
+
+// Names returns the names of the changers in order.
+func (reg *Registry) Names() []string {
+	return reg.cfg.Names()
+}
+
+// Get returns the changer with the given name, creating it on first use.
+// Unknown names fail with an error matching ErrUnknownChanger.
+func (reg *Registry) Get(name string) (*mtx.Changer, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if chgr, ok := reg.changers[name]; ok {
+		return chgr, nil
+	}
+
+	c, err := reg.cfg.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	impl := &lazy{open: c.Open, reopen: c.Backend != "mock"}
+	chgr := mtx.NewChanger(impl, append(c.Options(), reg.opts...)...)
+	reg.changers[name] = chgr
+
+	return chgr, nil
+}
+
+// contextDoer is implemented by backends that accept a context.
+type contextDoer interface {
+	DoContext(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// lazy is an mtx.Interface opening its backend on first use, and again
+// after failures that may have left it unusable.
+type lazy struct {
+	open   func() (mtx.Interface, error)
+	reopen bool
+
+	mu   sync.Mutex
+	impl mtx.Interface
+}
+
+func (l *lazy) Do(args ...string) ([]byte, error) {
+	return l.DoContext(context.Background(), args...)
+}
+
+func (l *lazy) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	impl, err := l.get()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if cimpl, ok := impl.(contextDoer); ok {
+		out, err = cimpl.DoContext(ctx, args...)
+	} else {
+		out, err = impl.Do(args...)
+	}
+
+	if err != nil && l.reopen && !mtx.IsPermanent(err) {
+		l.drop(impl)
+	}
+
+	return out, err
+}
+
+// get returns the backend, opening it if needed.
+func (l *lazy) get() (mtx.Interface, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.impl == nil {
+		impl, err := l.open()
+		if err != nil {
+			return nil, err
+		}
+
+		l.impl = impl
+	}
+
+	return l.impl, nil
+}
+
+// drop closes impl, if it is still the backend, so that the next command
+// opens it again.
+func (l *lazy) drop(impl mtx.Interface) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.impl != impl {
+		return
+	}
+
+	l.impl = nil
+
+	if c, ok := impl.(io.Closer); ok {
+		c.Close()
+	}
+}
@@ -0,0 +1,235 @@
+// Package devmap correlates the data transfer elements of a changer with the
+// tape device nodes of the host.
+//
+// mtx numbers drives by element order, which has no relation to the order in
+// which the kernel names /dev/nst* and /dev/sg* devices. The only reliable
+// link is the drive serial number: the changer reports it for each data
+// transfer element when asked for device identifiers (DVCID) in READ ELEMENT
+// STATUS, and the kernel exposes the unit serial number of each tape device
+// in sysfs. The former is read with 'sg_raw' from sg3_utils.
+package devmap
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kbj/mtx/scsi"
+)
+
+// Drive maps a drive number of a changer to its device nodes.
+type Drive struct {
+	// Num is the drive number as used by mtx.
+	Num int
+
+	// Serial is the serial number reported by the changer.
+	Serial string
+
+	// Tape and Generic are the non-rewinding tape device and the scsi
+	// generic device of the drive, e.g. /dev/nst0 and /dev/sg3. Both are
+	// empty if the drive is not attached to this host.
+	Tape    string
+	Generic string
+}
+
+// TapeDevice is a tape drive attached to the host.
+type TapeDevice struct {
+	Tape    string
+	Generic string
+	Serial  string
+}
+
+// ErrAmbiguous is returned by Match if a drive serial matches more than one
+// device.
+var ErrAmbiguous = errors.New("devmap: drive serial matches several devices")
+
+// Map returns the device nodes of the drives of the changer at the given
+// scsi generic device.
+func Map(ctx context.Context, changer string) ([]Drive, error) {
+	serials, err := ElementSerials(ctx, scsi.ExecExecutor{}, changer)
+	if err != nil {
+		return nil, err
+	}
+
+	devs, err := TapeDevices("/sys")
+	if err != nil {
+		return nil, err
+	}
+
+	return Match(serials, devs)
+}
+
+// Match pairs drive serials, indexed by drive number, with devices. A device
+// matches if its serial equals the drive serial or ends it; changers often
+// report a T10 identifier made of the vendor, product and serial number.
+func Match(serials []string, devs []TapeDevice) ([]Drive, error) {
+	drives := make([]Drive, len(serials))
+
+	for i, serial := range serials {
+		drives[i] = Drive{Num: i, Serial: serial}
+		if serial == "" {
+			continue
+		}
+
+		found := false
+		for _, dev := range devs {
+			if dev.Serial == "" || !strings.HasSuffix(serial, dev.Serial) {
+				continue
+			}
+
+			if found {
+				return nil, fmt.Errorf("%w: drive %d (%s)", ErrAmbiguous, i, serial)
+			}
+
+			found = true
+			drives[i].Tape = dev.Tape
+			drives[i].Generic = dev.Generic
+		}
+	}
+
+	return drives, nil
+}
+
+var tapeRegexp = regexp.MustCompile(`^nst\d+$`)
+
+// TapeDevices lists the tape devices of the host from the sysfs mounted at
+// root, usually "/sys". The serial number is read from the unit serial
+// number VPD page exported by the kernel.
+func TapeDevices(root string) ([]TapeDevice, error) {
+	entries, err := os.ReadDir(filepath.Join(root, "class", "scsi_tape"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var devs []TapeDevice
+	for _, entry := range entries {
+		if !tapeRegexp.MatchString(entry.Name()) {
+			continue
+		}
+
+		dir := filepath.Join(root, "class", "scsi_tape", entry.Name(), "device")
+		dev := TapeDevice{Tape: "/dev/" + entry.Name()}
+
+		if sg, err := os.ReadDir(filepath.Join(dir, "scsi_generic")); err == nil && len(sg) > 0 {
+			dev.Generic = "/dev/" + sg[0].Name()
+		}
+
+		if page, err := os.ReadFile(filepath.Join(dir, "vpd_pg80")); err == nil {
+			dev.Serial = parseUnitSerial(page)
+		}
+
+		devs = append(devs, dev)
+	}
+
+	return devs, nil
+}
+
+// parseUnitSerial returns the serial number from a unit serial number VPD
+// page (0x80).
+func parseUnitSerial(page []byte) string {
+	if len(page) < 4 || page[1] != 0x80 {
+		return ""
+	}
+
+	n := int(binary.BigEndian.Uint16(page[2:4]))
+	if len(page) < 4+n {
+		n = len(page) - 4
+	}
+
+	return strings.TrimSpace(string(page[4 : 4+n]))
+}
+
+// The READ ELEMENT STATUS command for all data transfer elements with the
+// DVCID bit set and a 64k allocation length.
+var readElementStatus = []string{"b8", "04", "00", "00", "ff", "ff", "01", "01", "00", "00", "00", "00"}
+
+// ElementSerials returns the drive serial numbers reported by the changer at
+// the given scsi generic device, indexed by drive number. A serial is empty
+// if the changer did not report one for that drive.
+func ElementSerials(ctx context.Context, exec scsi.Executor, changer string) ([]string, error) {
+	args := append([]string{"-b", "-r", "65536", changer}, readElementStatus...)
+
+	out, stderr, code, err := exec.Run(ctx, "/usr/bin/sg_raw", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 0 {
+		return nil, &scsi.ExitError{Code: code, Stderr: stderr}
+	}
+
+	return ParseElementStatus(out)
+}
+
+// ParseElementStatus returns the device identifiers of the data transfer
+// elements in READ ELEMENT STATUS data, in element order.
+func ParseElementStatus(data []byte) ([]string, error) {
+	if len(data) < 8 {
+		return nil, errors.New("devmap: short element status data")
+	}
+
+	n := int(data[5])<<16 | int(data[6])<<8 | int(data[7])
+	if len(data) > 8+n {
+		data = data[:8+n]
+	}
+	data = data[8:]
+
+	var serials []string
+	for len(data) >= 8 {
+		typ := data[0] & 0x0f
+		pvoltag := data[1]&0x80 != 0
+		avoltag := data[1]&0x40 != 0
+		dlen := int(binary.BigEndian.Uint16(data[2:4]))
+		plen := int(data[5])<<16 | int(data[6])<<8 | int(data[7])
+
+		page := data[8:]
+		if len(page) < plen {
+			return nil, errors.New("devmap: truncated element status page")
+		}
+		data = page[plen:]
+		page = page[:plen]
+
+		if typ != 4 || dlen == 0 {
+			continue
+		}
+
+		for ; len(page) >= dlen; page = page[dlen:] {
+			serials = append(serials, deviceID(page[:dlen], pvoltag, avoltag))
+		}
+	}
+
+	return serials, nil
+}
+
+// deviceID extracts the device identifier of a data transfer element
+// descriptor.
+func deviceID(desc []byte, pvoltag, avoltag bool) string {
+	off := 12
+	if pvoltag {
+		off += 36
+	}
+	if avoltag {
+		off += 36
+	}
+
+	if len(desc) < off+4 {
+		return ""
+	}
+
+	n := int(desc[off+3])
+	id := desc[off+4:]
+	if len(id) > n {
+		id = id[:n]
+	}
+
+	return strings.TrimSpace(strings.TrimRight(string(id), "\x00"))
+}
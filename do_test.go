@@ -0,0 +1,47 @@
+package mtx_test
+
+import (
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+)
+
+// queries counts the status queries of the wrapped implementation.
+type queries struct {
+	mtx.Interface
+	n int
+}
+
+func (impl *queries) Do(args ...string) ([]byte, error) {
+	if args[0] == "status" {
+		impl.n++
+	}
+
+	return impl.Interface.Do(args...)
+}
+
+func TestDoSingleStatus(t *testing.T) {
+	impl := &queries{Interface: mock.New(2, 8, 1, 4)}
+	chgr := mtx.NewChanger(impl,
+		mtx.WithPolicy(mtx.OnlySlots(1, 8)),
+		mtx.WithDriveGenerations(map[mtx.DriveNum]int{0: 6, 1: 6}),
+		mtx.WithIdempotentMoves(),
+		mtx.WithHistory(10),
+	)
+
+	for _, mv := range []mtx.Move{
+		{Type: mtx.MoveLoad, Src: 1, Dst: 0},
+		{Type: mtx.MoveUnload, Src: 0, Dst: 0},
+		{Type: mtx.MoveTransfer, Src: 2, Dst: 6},
+	} {
+		impl.n = 0
+		if err := chgr.Move(mv); err != nil {
+			t.Fatalf("Move(%s): %v", mv, err)
+		}
+
+		if impl.n != 1 {
+			t.Errorf("Move(%s) queried the status %d times, want once", mv, impl.n)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package drive controls tape drives through the Linux st driver by using
+// the 'mt' program.
+//
+// Together with a changer it completes the mount cycle of a volume:
+//
+//	chgr := mtx.NewChanger(scsi.New("/dev/sg4"))
+//	drv := drive.New("/dev/nst0")
+//
+//	if _, err := drive.Mount(ctx, chgr, drv, slot, 0); err != nil {
+//		...
+//	}
+//	// read or write /dev/nst0
+//	if err := drive.Unmount(ctx, chgr, drv, 0, slot); err != nil {
+//		...
+//	}
+package drive
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/scsi"
+)
+
+// Drive is a tape drive managed by the 'mt' program.
+type Drive struct {
+	path string
+	prog string
+	exec scsi.Executor
+}
+
+// New returns a drive using 'mt' on the device at path, which should be a
+// non-rewinding device such as /dev/nst0.
+func New(path string) *Drive {
+	return NewWithExecutor(path, scsi.ExecExecutor{})
+}
+
+// NewWithExecutor returns a drive that runs 'mt' through the given executor.
+func NewWithExecutor(path string, exec scsi.Executor) *Drive {
+	return &Drive{
+		path: path,
+		prog: "/usr/bin/mt",
+		exec: exec,
+	}
+}
+
+// Path returns the device path of the drive.
+func (drv *Drive) Path() string {
+	return drv.path
+}
+
+// run runs 'mt' with the given arguments. A non-zero exit code is returned
+// as a *scsi.ExitError.
+func (drv *Drive) run(ctx context.Context, args ...string) ([]byte, error) {
+	params := append([]string{"-f", drv.path}, args...)
+
+	out, stderr, code, err := drv.exec.Run(ctx, drv.prog, params...)
+	if err != nil {
+		return out, err
+	}
+
+	if code != 0 {
+		return out, &scsi.ExitError{Code: code, Stderr: stderr}
+	}
+
+	return out, nil
+}
+
+// Rewind rewinds the tape.
+func (drv *Drive) Rewind(ctx context.Context) error {
+	_, err := drv.run(ctx, "rewind")
+	return err
+}
+
+// Offline rewinds the tape and takes the drive offline, ejecting the tape
+// on drives that support it. Many libraries require this before the
+// changer can unload the drive.
+func (drv *Drive) Offline(ctx context.Context) error {
+	_, err := drv.run(ctx, "offline")
+	return err
+}
+
+// SetBlockSize sets the block size of the drive. A size of 0 selects
+// variable block mode.
+func (drv *Drive) SetBlockSize(ctx context.Context, size int) error {
+	_, err := drv.run(ctx, "setblk", strconv.Itoa(size))
+	return err
+}
+
+var tellRegexp = regexp.MustCompile(`At block (\d+)`)
+
+// Position returns the current logical block position of the tape.
+func (drv *Drive) Position(ctx context.Context) (int, error) {
+	out, err := drv.run(ctx, "tell")
+	if err != nil {
+		return 0, err
+	}
+
+	m := tellRegexp.FindSubmatch(out)
+	if m == nil {
+		return 0, fmt.Errorf("drive: unexpected 'mt tell' output: %q", out)
+	}
+
+	return strconv.Atoi(string(m[1]))
+}
+
+// Status is the status of a drive as reported by 'mt status'.
+type Status struct {
+	// File, Block and Partition give the position of the tape. File and
+	// Block are -1 if the position is unknown, e.g. without a tape.
+	File, Block, Partition int
+
+	// BlockSize is the block size of the drive; 0 means variable.
+	BlockSize int
+
+	Density     int
+	DensityName string
+
+	// Flags holds the general status bits, e.g. "ONLINE" or "WR_PROT".
+	Flags []string
+}
+
+// Has returns whether the status bit flag is set.
+func (status *Status) Has(flag string) bool {
+	for _, f := range status.Flags {
+		if f == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Online returns whether a tape is loaded and the drive is ready.
+func (status *Status) Online() bool {
+	return status.Has("ONLINE")
+}
+
+// WriteProtected returns whether the loaded tape is write protected.
+func (status *Status) WriteProtected() bool {
+	return status.Has("WR_PROT")
+}
+
+var (
+	positionRegexp  = regexp.MustCompile(`File number=(-?\d+), block number=(-?\d+), partition=(-?\d+)`)
+	blockSizeRegexp = regexp.MustCompile(`Tape block size (\d+) bytes`)
+	densityRegexp   = regexp.MustCompile(`Density code 0x([0-9a-fA-F]+)(?: \((.*)\))?`)
+	flagsRegexp     = regexp.MustCompile(`General status bits on \([0-9a-fA-F]+\):\s*\n(.*)`)
+)
+
+// ParseStatus parses the output of 'mt status'.
+func ParseStatus(out []byte) (*Status, error) {
+	m := positionRegexp.FindSubmatch(out)
+	if m == nil {
+		return nil, fmt.Errorf("drive: unexpected 'mt status' output: %q", out)
+	}
+
+	status := &Status{}
+	status.File, _ = strconv.Atoi(string(m[1]))
+	status.Block, _ = strconv.Atoi(string(m[2]))
+	status.Partition, _ = strconv.Atoi(string(m[3]))
+
+	if m := blockSizeRegexp.FindSubmatch(out); m != nil {
+		status.BlockSize, _ = strconv.Atoi(string(m[1]))
+	}
+
+	if m := densityRegexp.FindSubmatch(out); m != nil {
+		density, _ := strconv.ParseInt(string(m[1]), 16, 0)
+		status.Density = int(density)
+		status.DensityName = string(m[2])
+	}
+
+	if m := flagsRegexp.FindSubmatch(out); m != nil {
+		status.Flags = strings.Fields(string(m[1]))
+	}
+
+	return status, nil
+}
+
+// Status returns the status of the drive.
+func (drv *Drive) Status(ctx context.Context) (*Status, error) {
+	out, err := drv.run(ctx, "status")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseStatus(out)
+}
+
+// WaitReady polls the drive every interval until it reports ONLINE and
+// returns its status. Failing status requests are retried, since drives
+// commonly fail them while loading a tape. If ctx is done first, the context
+// error is returned.
+func (drv *Drive) WaitReady(ctx context.Context, interval time.Duration) (*Status, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := drv.Status(ctx)
+		if err == nil && status.Online() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DefaultPollInterval is the interval at which Mount polls the drive.
+const DefaultPollInterval = time.Second
+
+// Mount loads the volume in slot into drive number drivenum of chgr, drv
+// being the corresponding tape device, and waits for the drive to become
+// ready.
+func Mount(ctx context.Context, chgr *mtx.Changer, drv *Drive, slot, drivenum int) (*Status, error) {
+	if err := chgr.Load(mtx.SlotNum(slot), mtx.DriveNum(drivenum)); err != nil {
+		return nil, err
+	}
+
+	return drv.WaitReady(ctx, DefaultPollInterval)
+}
+
+// Unmount takes drv offline and unloads its volume, drive number drivenum
+// of chgr, into slot.
+func Unmount(ctx context.Context, chgr *mtx.Changer, drv *Drive, drivenum, slot int) error {
+	if err := drv.Offline(ctx); err != nil {
+		return err
+	}
+
+	return chgr.Unload(mtx.SlotNum(slot), mtx.DriveNum(drivenum))
+}
@@ -0,0 +1,68 @@
+package mtx
+
+import "fmt"
+
+// Enclosure is the physical state of a library. The mtx program does not
+// report it, so it is only known for backends that can query the library
+// otherwise, e.g. through a vendor management interface.
+type Enclosure struct {
+	// DoorOpen reports whether the library door is open. While it is, the
+	// robot does not move.
+	DoorOpen bool
+
+	// StationOpen reports whether the import/export station is open. While
+	// it is, the robot cannot access the mail slots.
+	StationOpen bool
+
+	// Magazines lists the removable magazines of the library, if any.
+	Magazines []Magazine
+}
+
+// Magazine is a removable magazine of storage slots.
+type Magazine struct {
+	// First and Last are the numbers of the first and last slot of the
+	// magazine.
+	First, Last int
+
+	Present bool
+}
+
+// Contains reports whether the magazine holds the slot numbered num.
+func (mag Magazine) Contains(num int) bool {
+	return mag.First <= num && num <= mag.Last
+}
+
+// Blocked returns an error explaining why the robot cannot perform mv, if
+// the enclosure state tells: it matches ErrDoorOpen, ErrStationOpen or
+// ErrMagazineMissing. Blocked returns nil if nothing is known to be in the
+// way, in particular if the enclosure state is unknown.
+func (status *Status) Blocked(mv Move) error {
+	enc := status.Enclosure
+	if enc == nil {
+		return nil
+	}
+
+	if enc.DoorOpen {
+		return ErrDoorOpen
+	}
+
+	src, dst := mv.elements()
+	for _, e := range []Element{src, dst} {
+		slot := status.element(e)
+		if slot == nil || slot.Type == DataTransferSlot {
+			continue
+		}
+
+		if slot.Type == MailSlot && enc.StationOpen {
+			return fmt.Errorf("%w: slot %d", ErrStationOpen, slot.Num)
+		}
+
+		for _, mag := range enc.Magazines {
+			if mag.Contains(slot.Num) && !mag.Present {
+				return fmt.Errorf("%w: slot %d", ErrMagazineMissing, slot.Num)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package mtx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrEmpty is matched by command errors caused by an empty source
+	// element.
+	ErrEmpty = errors.New("mtx: element is empty")
+
+	// ErrFull is matched by command errors caused by an occupied
+	// destination element.
+	ErrFull = errors.New("mtx: element is full")
+
+	// ErrInvalidElement is matched by command errors caused by an element
+	// number the library does not have.
+	ErrInvalidElement = errors.New("mtx: invalid element")
+
+	// ErrParse is matched by errors returned for malformed status output.
+	ErrParse = errors.New("mtx: malformed status")
+
+	// ErrMultipleChangers is matched by the *ParseError returned by
+	// ParseStatus for output reporting more than one changer.
+	ErrMultipleChangers = errors.New("mtx: status reports more than one changer")
+
+	// ErrInvalidMove is matched by errors returned for malformed moves.
+	ErrInvalidMove = errors.New("mtx: invalid move")
+
+	// ErrVolumeLoaded is matched by errors returned when a volume cannot be
+	// moved because it is loaded in a drive.
+	ErrVolumeLoaded = errors.New("mtx: volume is loaded")
+
+	// ErrNoMailSlot is returned when an export finds no empty mail slot.
+	ErrNoMailSlot = errors.New("mtx: no empty mail slot")
+
+	// ErrNoStorageSlot is returned when an import finds no empty storage
+	// slot.
+	ErrNoStorageSlot = errors.New("mtx: no empty storage slot")
+
+	// ErrNotAllowed is matched by errors returned for moves forbidden by
+	// the policy of the changer.
+	ErrNotAllowed = errors.New("mtx: element not allowed by policy")
+
+	// ErrReadOnly is matched by the errors returned for commands that
+	// could change the library by the implementation returned by ReadOnly.
+	ErrReadOnly = errors.New("mtx: changer is read-only")
+
+	// ErrIncompatibleMedia is matched by errors returned for loads of
+	// media the drive cannot read, see WithDriveGenerations.
+	ErrIncompatibleMedia = errors.New("mtx: incompatible media")
+
+	// ErrDoorOpen, ErrStationOpen and ErrMagazineMissing are matched by
+	// the errors returned by Status.Blocked.
+	ErrDoorOpen        = errors.New("mtx: library door is open")
+	ErrStationOpen     = errors.New("mtx: import/export station is open")
+	ErrMagazineMissing = errors.New("mtx: magazine is missing")
+
+	// ErrUnhealthy is matched by the errors returned by Health.Err.
+	ErrUnhealthy = errors.New("mtx: unhealthy")
+)
+
+// IsPermanent reports whether err is a failure that trying the command again
+// cannot fix, such as an empty source element, a forbidden move or a
+// cancelled context, as opposed to e.g. a busy robot or a lost connection.
+func IsPermanent(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, target := range []error{
+		ErrEmpty, ErrFull, ErrInvalidElement, ErrInvalidMove,
+		ErrNotAllowed, ErrReadOnly, ErrIncompatibleMedia,
+		context.Canceled, context.DeadlineExceeded,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	// the failures of backends are recognized by their messages
+	cerr := commandError(nil, err).(*CommandError)
+
+	return cerr.Kind != nil
+}
+
+// CommandError is returned when a command fails. It wraps the error of the
+// backend, so e.g. a *scsi.ExitError can still be retrieved with errors.As.
+type CommandError struct {
+	Args []string
+
+	// Kind is ErrEmpty, ErrFull or ErrInvalidElement if the failure was
+	// recognized, and nil otherwise. errors.Is matches it.
+	Kind error
+
+	// Type and Num identify the element the failure refers to. Num is -1
+	// if it is unknown. mtx does not tell storage and mail slots apart in
+	// its errors, so both are reported as StorageSlot.
+	Type SlotType
+	Num  int
+
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	return "mtx: " + strings.Join(e.Args, " ") + ": " + e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of the error.
+func (e *CommandError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// The failures recognized in the messages of mtx and the mock.
+var commandFailures = []struct {
+	re   *regexp.Regexp
+	typ  SlotType
+	kind error
+}{
+	{regexp.MustCompile(`source Element Address (\d+) is Empty`), StorageSlot, ErrEmpty},
+	{regexp.MustCompile(`destination Element Address (\d+) is Already Full`), StorageSlot, ErrFull},
+	{regexp.MustCompile(`Storage Element (\d+) is Already Full`), StorageSlot, ErrFull},
+	{regexp.MustCompile(`Data Transfer Element (\d+) is Empty`), DataTransferSlot, ErrEmpty},
+	{regexp.MustCompile(`Drive (\d+) Full`), DataTransferSlot, ErrFull},
+	{regexp.MustCompile(`Invalid <slotno> argument '(\d+)'`), StorageSlot, ErrInvalidElement},
+	{regexp.MustCompile(`Invalid <drvno> argument '(\d+)'`), DataTransferSlot, ErrInvalidElement},
+}
+
+// commandError wraps the error of a failed command in a *CommandError.
+// Errors that already are command errors are returned as is.
+func commandError(args []string, err error) error {
+	var cerr *CommandError
+	if err == nil || errors.As(err, &cerr) {
+		return err
+	}
+
+	cerr = &CommandError{
+		Args: append([]string(nil), args...),
+		Num:  -1,
+		Err:  err,
+	}
+
+	msg := err.Error()
+	for _, f := range commandFailures {
+		m := f.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+
+		cerr.Kind, cerr.Type = f.kind, f.typ
+		cerr.Num, _ = strconv.Atoi(m[1])
+
+		break
+	}
+
+	return cerr
+}
+
+// ParseError is returned for status output that cannot be parsed. It
+// matches ErrParse.
+type ParseError struct {
+	// Line is the line number, starting at 1.
+	Line int
+	Text string
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("mtx: status line %d: %v: %q", e.Line, e.Err, e.Text)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrParse.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrParse
+}
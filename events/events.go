@@ -0,0 +1,398 @@
+// Package events turns changer activity into typed library events and
+// publishes them to subscribers.
+//
+// Events are derived from two sources: the completion of every command run
+// through a Changer, and the differences between consecutive status
+// reports. Wrapping the backend of a changer is enough to get both:
+//
+//	bus := events.NewBus()
+//	bus.Subscribe(func(ev events.Event) { log.Println(ev) })
+//
+//	chgr := mtx.NewChanger(events.New(scsi.New("/dev/sg4"), bus))
+//
+// and events.Changer.Poll can be used to drive status polling.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbj/mtx"
+)
+
+// Event is a library event. It is one of the types defined in this package.
+type Event interface {
+	String() string
+}
+
+// CommandCompleted is published after every command.
+type CommandCompleted struct {
+	Args     []string      `json:"args"`
+	Duration time.Duration `json:"duration"`
+	Err      error         `json:"-"`
+}
+
+func (ev *CommandCompleted) String() string {
+	if ev.Err != nil {
+		return fmt.Sprintf("command %q failed after %v: %v", strings.Join(ev.Args, " "), ev.Duration, ev.Err)
+	}
+
+	return fmt.Sprintf("command %q completed in %v", strings.Join(ev.Args, " "), ev.Duration)
+}
+
+// VolumeLoaded is published when a volume moved from a slot into a drive.
+type VolumeLoaded struct {
+	Serial string `json:"serial"`
+	Slot   int    `json:"slot"`
+	Drive  int    `json:"drive"`
+}
+
+func (ev *VolumeLoaded) String() string {
+	return fmt.Sprintf("volume %s loaded from slot %d into drive %d", ev.Serial, ev.Slot, ev.Drive)
+}
+
+// VolumeUnloaded is published when a volume moved from a drive into a slot.
+type VolumeUnloaded struct {
+	Serial string `json:"serial"`
+	Drive  int    `json:"drive"`
+	Slot   int    `json:"slot"`
+}
+
+func (ev *VolumeUnloaded) String() string {
+	return fmt.Sprintf("volume %s unloaded from drive %d into slot %d", ev.Serial, ev.Drive, ev.Slot)
+}
+
+// VolumeMoved is published when a volume moved between storage slots.
+type VolumeMoved struct {
+	Serial string `json:"serial"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+}
+
+func (ev *VolumeMoved) String() string {
+	return fmt.Sprintf("volume %s moved from slot %d to slot %d", ev.Serial, ev.From, ev.To)
+}
+
+// VolumeExported is published when a volume moved into a mail slot.
+type VolumeExported struct {
+	Serial   string `json:"serial"`
+	Slot     int    `json:"slot"`
+	MailSlot int    `json:"mailSlot"`
+}
+
+func (ev *VolumeExported) String() string {
+	return fmt.Sprintf("volume %s exported from slot %d to mail slot %d", ev.Serial, ev.Slot, ev.MailSlot)
+}
+
+// VolumeImported is published when a volume moved out of a mail slot.
+type VolumeImported struct {
+	Serial   string `json:"serial"`
+	MailSlot int    `json:"mailSlot"`
+	Slot     int    `json:"slot"`
+}
+
+func (ev *VolumeImported) String() string {
+	return fmt.Sprintf("volume %s imported from mail slot %d to slot %d", ev.Serial, ev.MailSlot, ev.Slot)
+}
+
+// MailSlotInserted is published when an operator put a volume into a mail
+// slot. Serial is empty if the volume is unlabeled.
+type MailSlotInserted struct {
+	Slot   int    `json:"slot"`
+	Serial string `json:"serial"`
+}
+
+func (ev *MailSlotInserted) String() string {
+	return fmt.Sprintf("volume %s inserted into mail slot %d", ev.Serial, ev.Slot)
+}
+
+// MailSlotRemoved is published when an operator took a volume out of a mail
+// slot.
+type MailSlotRemoved struct {
+	Slot   int    `json:"slot"`
+	Serial string `json:"serial"`
+}
+
+func (ev *MailSlotRemoved) String() string {
+	return fmt.Sprintf("volume %s removed from mail slot %d", ev.Serial, ev.Slot)
+}
+
+// VolumeAppeared is published when a volume showed up in a storage slot or
+// drive without passing through a mail slot, e.g. after a magazine was
+// replaced.
+type VolumeAppeared struct {
+	Serial string       `json:"serial"`
+	Type   mtx.SlotType `json:"type"`
+	Num    int          `json:"num"`
+}
+
+func (ev *VolumeAppeared) String() string {
+	return fmt.Sprintf("volume %s appeared in %s %d", ev.Serial, ev.Type, ev.Num)
+}
+
+// VolumeDisappeared is published when a volume vanished from a storage slot
+// or drive.
+type VolumeDisappeared struct {
+	Serial string       `json:"serial"`
+	Type   mtx.SlotType `json:"type"`
+	Num    int          `json:"num"`
+}
+
+func (ev *VolumeDisappeared) String() string {
+	return fmt.Sprintf("volume %s disappeared from %s %d", ev.Serial, ev.Type, ev.Num)
+}
+
+// DriveEmptied is published when a drive that held a volume became empty.
+type DriveEmptied struct {
+	Drive int `json:"drive"`
+}
+
+func (ev *DriveEmptied) String() string {
+	return fmt.Sprintf("drive %d emptied", ev.Drive)
+}
+
+// A Handler receives events.
+type Handler func(ev Event)
+
+// Bus delivers published events to its subscribers.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[int]Handler
+	next int
+}
+
+// NewBus returns a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]Handler)}
+}
+
+// Subscribe registers fn to receive all events published after the call.
+// The returned function removes the subscription.
+func (bus *Bus) Subscribe(fn Handler) (cancel func()) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	id := bus.next
+	bus.next++
+	bus.subs[id] = fn
+
+	return func() {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+
+		delete(bus.subs, id)
+	}
+}
+
+// Publish delivers evs, in order, to every subscriber. Handlers are called
+// sequentially from the publishing goroutine, in subscription order.
+func (bus *Bus) Publish(evs ...Event) {
+	if len(evs) == 0 {
+		return
+	}
+
+	bus.mu.Lock()
+	ids := make([]int, 0, len(bus.subs))
+	for id := range bus.subs {
+		ids = append(ids, id)
+	}
+	handlers := make([]Handler, 0, len(ids))
+	sort.Ints(ids)
+	for _, id := range ids {
+		handlers = append(handlers, bus.subs[id])
+	}
+	bus.mu.Unlock()
+
+	for _, ev := range evs {
+		for _, fn := range handlers {
+			fn(ev)
+		}
+	}
+}
+
+// Diff returns the events that explain the change from old to new. Volumes
+// are tracked by serial; unlabeled volumes only produce mail slot and drive
+// events.
+func Diff(old, new *mtx.Status) []Event {
+	oldLoc := locations(old)
+	newLoc := locations(new)
+
+	var evs []Event
+
+	for _, slot := range elements(new) {
+		if slot.Vol == nil {
+			continue
+		}
+
+		serial := slot.Vol.Serial
+		if serial == "" {
+			if slot.Type == mtx.MailSlot && isEmpty(old, slot) {
+				evs = append(evs, &MailSlotInserted{Slot: slot.Num})
+			}
+
+			continue
+		}
+
+		if newLoc[serial] != slot {
+			// duplicate serial, only the first occurrence is tracked
+			continue
+		}
+
+		prev, ok := oldLoc[serial]
+		switch {
+		case !ok && slot.Type == mtx.MailSlot:
+			evs = append(evs, &MailSlotInserted{Slot: slot.Num, Serial: serial})
+		case !ok:
+			evs = append(evs, &VolumeAppeared{Serial: serial, Type: slot.Type, Num: slot.Num})
+		case prev.Type == slot.Type && prev.Num == slot.Num:
+			// not moved
+		case slot.Type == mtx.DataTransferSlot && prev.Type != mtx.DataTransferSlot:
+			evs = append(evs, &VolumeLoaded{Serial: serial, Slot: prev.Num, Drive: slot.Num})
+		case prev.Type == mtx.DataTransferSlot && slot.Type != mtx.DataTransferSlot:
+			evs = append(evs, &VolumeUnloaded{Serial: serial, Drive: prev.Num, Slot: slot.Num})
+		case slot.Type == mtx.MailSlot && prev.Type != mtx.MailSlot:
+			evs = append(evs, &VolumeExported{Serial: serial, Slot: prev.Num, MailSlot: slot.Num})
+		case prev.Type == mtx.MailSlot && slot.Type != mtx.MailSlot:
+			evs = append(evs, &VolumeImported{Serial: serial, MailSlot: prev.Num, Slot: slot.Num})
+		default:
+			evs = append(evs, &VolumeMoved{Serial: serial, From: prev.Num, To: slot.Num})
+		}
+	}
+
+	for _, slot := range elements(old) {
+		if slot.Vol == nil {
+			continue
+		}
+
+		serial := slot.Vol.Serial
+		if serial == "" {
+			if slot.Type == mtx.MailSlot && isEmpty(new, slot) {
+				evs = append(evs, &MailSlotRemoved{Slot: slot.Num})
+			}
+
+			continue
+		}
+
+		if oldLoc[serial] != slot {
+			continue
+		}
+
+		if _, ok := newLoc[serial]; ok {
+			continue
+		}
+
+		if slot.Type == mtx.MailSlot {
+			evs = append(evs, &MailSlotRemoved{Slot: slot.Num, Serial: serial})
+		} else {
+			evs = append(evs, &VolumeDisappeared{Serial: serial, Type: slot.Type, Num: slot.Num})
+		}
+	}
+
+	for _, drive := range old.Drives {
+		if drive.Vol != nil && isEmpty(new, drive) {
+			evs = append(evs, &DriveEmptied{Drive: drive.Num})
+		}
+	}
+
+	return evs
+}
+
+func elements(status *mtx.Status) []*mtx.Slot {
+	return append(append([]*mtx.Slot(nil), status.Drives...), status.Slots...)
+}
+
+// locations maps the serials of the labeled volumes in status to the first
+// element they occur in.
+func locations(status *mtx.Status) map[string]*mtx.Slot {
+	locs := make(map[string]*mtx.Slot)
+	for _, slot := range elements(status) {
+		if slot.Vol == nil || slot.Vol.Serial == "" {
+			continue
+		}
+
+		if _, ok := locs[slot.Vol.Serial]; !ok {
+			locs[slot.Vol.Serial] = slot
+		}
+	}
+
+	return locs
+}
+
+// isEmpty returns whether the element corresponding to slot is empty in
+// status. Elements missing from status count as empty.
+func isEmpty(status *mtx.Status, slot *mtx.Slot) bool {
+	for _, s := range elements(status) {
+		if s.Type == slot.Type && s.Num == slot.Num {
+			return s.Vol == nil
+		}
+	}
+
+	return true
+}
+
+// Changer wraps an mtx.Interface and publishes events for the commands run
+// through it.
+type Changer struct {
+	impl mtx.Interface
+	bus  *Bus
+
+	mu   sync.Mutex
+	last *mtx.Status
+}
+
+// New returns a Changer publishing to bus.
+func New(impl mtx.Interface, bus *Bus) *Changer {
+	return &Changer{impl: impl, bus: bus}
+}
+
+// Do implements mtx.Interface. A CommandCompleted event is published for
+// every command. Successful status commands are additionally compared
+// against the previous status and the differences are published; the first
+// status only establishes the baseline.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	start := time.Now()
+	out, err := chgr.impl.Do(args...)
+
+	evs := []Event{&CommandCompleted{
+		Args:     append([]string(nil), args...),
+		Duration: time.Since(start),
+		Err:      err,
+	}}
+
+	if err == nil && len(args) > 0 && args[0] == "status" {
+		if status, perr := mtx.ParseStatus(out); perr == nil {
+			chgr.mu.Lock()
+			if chgr.last != nil {
+				evs = append(evs, Diff(chgr.last, status)...)
+			}
+			chgr.last = status
+			chgr.mu.Unlock()
+		}
+	}
+
+	chgr.bus.Publish(evs...)
+
+	return out, err
+}
+
+// Poll requests the status every interval until ctx is done, publishing the
+// changes. Failed status requests are reported through CommandCompleted
+// events and do not stop polling. Poll returns the context error.
+func (chgr *Changer) Poll(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		chgr.Do("status")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
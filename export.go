@@ -0,0 +1,198 @@
+package mtx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Mover performs moves. It is implemented by *Changer, and by
+// *scheduler.Scheduler to queue the moves with other robot operations.
+type Mover interface {
+	Move(mv Move) error
+}
+
+// ExportOptions configures ExportSet.
+type ExportOptions struct {
+	// Mover performs the transfers. If nil, the changer performs them
+	// itself.
+	Mover Mover
+
+	// Eject makes ExportSet open the import/export station after every
+	// batch.
+	Eject bool
+
+	// OnBatch, if set, is called with the mail slots of every batch once
+	// it is ready for the operator to take out.
+	OnBatch func(batch int, slots []*Slot)
+
+	// PollInterval is the interval at which the mail slots are checked
+	// while waiting for the operator to empty them. Defaults to 10
+	// seconds.
+	PollInterval time.Duration
+}
+
+// ExportResult is the outcome of exporting a single volume.
+type ExportResult struct {
+	Serial string
+
+	// MailSlot is the mail slot the volume was exported to, and Batch the
+	// eject cycle, counting from 0, it was exported in. MailSlot is 0 if
+	// the volume was not exported.
+	MailSlot int
+	Batch    int
+
+	Err error
+}
+
+// ExportSet moves the volumes with the given serials to the mail slots. If
+// the set does not fit the import/export station, it is exported in
+// batches: after each batch ExportSet waits for the operator to empty the
+// mail slots it used before moving on.
+//
+// Every volume is checked before anything is moved. Volumes that cannot be
+// exported, because they are not in the library, are loaded in a drive, are
+// in a slot forbidden by the policy of the changer or are listed twice, get
+// an error in their result and are skipped. Mail slots forbidden by the
+// policy are not used. Volumes already in a mail slot count as exported in
+// the first batch.
+//
+// The error is non-nil only if the export could not be carried out as a
+// whole, e.g. because the library has no mail slots or ctx was done; the
+// results gathered so far are returned with it.
+func (chgr *Changer) ExportSet(ctx context.Context, serials []string, opts ExportOptions) ([]ExportResult, error) {
+	mover := opts.Mover
+	if mover == nil {
+		mover = chgr
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	status, err := chgr.statusContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(Filter(status.MailSlots(), chgr.allowedSlot)) == 0 {
+		return nil, ErrNoMailSlot
+	}
+
+	results := make([]ExportResult, len(serials))
+	seen := make(map[string]bool)
+
+	var pending []int
+	for i, serial := range serials {
+		res := &results[i]
+		res.Serial = serial
+
+		slot := status.Find(serial)
+		switch {
+		case seen[serial]:
+			res.Err = fmt.Errorf("mtx: volume %s listed more than once", serial)
+		case slot == nil:
+			res.Err = ErrVolumeNotFound
+		case slot.Type == DataTransferSlot:
+			res.Err = fmt.Errorf("%w: %s in drive %d", ErrVolumeLoaded, serial, slot.Num)
+		case slot.Type == MailSlot:
+			res.MailSlot = slot.Num
+		case !chgr.allowedSlot(slot):
+			res.Err = fmt.Errorf("%w: %s", ErrNotAllowed, slot.Element())
+		default:
+			pending = append(pending, i)
+		}
+
+		seen[serial] = true
+	}
+
+	for batch := 0; len(pending) > 0; {
+		free := Filter(status.Slots, IsMail, IsEmpty, chgr.allowedSlot)
+		if len(free) == 0 {
+			if status, err = chgr.waitMailSlots(ctx, interval); err != nil {
+				return results, err
+			}
+
+			free = Filter(status.Slots, IsMail, IsEmpty, chgr.allowedSlot)
+		}
+
+		var used []*Slot
+		for len(pending) > 0 && len(used) < len(free) {
+			res := &results[pending[0]]
+			pending = pending[1:]
+
+			src := status.Find(res.Serial)
+			if src == nil {
+				res.Err = ErrVolumeNotFound
+				continue
+			}
+
+			dst := free[len(used)]
+			if err := mover.Move(Move{Type: MoveTransfer, Src: src.Num, Dst: dst.Num}); err != nil {
+				res.Err = err
+				continue
+			}
+
+			res.MailSlot, res.Batch = dst.Num, batch
+			dst.Vol = src.Vol
+			used = append(used, dst)
+		}
+
+		if len(used) == 0 {
+			continue
+		}
+
+		if opts.Eject {
+			if _, err := chgr.Do("eject"); err != nil {
+				return results, err
+			}
+		}
+
+		if opts.OnBatch != nil {
+			opts.OnBatch(batch, used)
+		}
+
+		batch++
+
+		if len(pending) > 0 {
+			if status, err = chgr.waitEmptied(ctx, interval, used); err != nil {
+				return results, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Find returns the element holding the volume with the given serial, or nil
+// if it is not in status. Drives are searched before slots.
+func (status *Status) Find(serial string) *Slot {
+	for slot := range status.Occupied() {
+		if slot.Vol.Serial == serial {
+			return slot
+		}
+	}
+
+	return nil
+}
+
+// waitMailSlots polls the status until a mail slot is empty.
+func (chgr *Changer) waitMailSlots(ctx context.Context, interval time.Duration) (*Status, error) {
+	return chgr.waitFor(ctx, interval, func(status *Status) bool {
+		return len(Filter(status.Slots, IsMail, IsEmpty, chgr.allowedSlot)) > 0
+	})
+}
+
+// waitEmptied polls the status until the given mail slots are empty.
+func (chgr *Changer) waitEmptied(ctx context.Context, interval time.Duration, slots []*Slot) (*Status, error) {
+	return chgr.waitFor(ctx, interval, func(status *Status) bool {
+		for _, slot := range slots {
+			if s := status.element(slot.Element()); s != nil && s.Vol != nil {
+				return false
+			}
+		}
+
+		return true
+	})
+}
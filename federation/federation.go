@@ -0,0 +1,239 @@
+// Package federation aggregates several libraries into a single logical
+// inventory.
+//
+// Each member library is identified by a name. Volumes are looked up across
+// all members, operations are routed to the member holding the volume, and
+// the status of all members can be merged into one view.
+package federation
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kbj/mtx"
+)
+
+var (
+	// ErrUnknownLibrary is returned for operations on a library that is not
+	// a member of the federation.
+	ErrUnknownLibrary = errors.New("federation: unknown library")
+
+	// ErrNoDrive is returned by LoadVolume when the library holding the
+	// volume has no empty drive.
+	ErrNoDrive = errors.New("federation: no empty drive")
+)
+
+// Slot is a slot of a member library.
+type Slot struct {
+	// Library is the name of the member library.
+	Library string
+
+	mtx.Slot
+}
+
+// String returns a textual representation of the slot.
+func (slot *Slot) String() string {
+	return slot.Library + ":" + slot.Slot.String()
+}
+
+// Status is the merged status of the member libraries.
+type Status struct {
+	// Libraries holds the status of each member by name.
+	Libraries map[string]*mtx.Status
+
+	// Drives and Slots hold the elements of all members, in the order the
+	// members were added.
+	Drives []*Slot
+	Slots  []*Slot
+}
+
+type member struct {
+	name string
+	chgr *mtx.Changer
+}
+
+// Federation is a set of libraries.
+type Federation struct {
+	mu      sync.RWMutex
+	members []member
+}
+
+// New returns an empty federation.
+func New() *Federation {
+	return &Federation{}
+}
+
+// Add adds a library under the given name.
+func (fed *Federation) Add(name string, chgr *mtx.Changer) error {
+	fed.mu.Lock()
+	defer fed.mu.Unlock()
+
+	for _, m := range fed.members {
+		if m.name == name {
+			return fmt.Errorf("federation: library %q already added", name)
+		}
+	}
+
+	fed.members = append(fed.members, member{name: name, chgr: chgr})
+
+	return nil
+}
+
+// Libraries returns the names of the member libraries.
+func (fed *Federation) Libraries() []string {
+	fed.mu.RLock()
+	defer fed.mu.RUnlock()
+
+	names := make([]string, len(fed.members))
+	for i, m := range fed.members {
+		names[i] = m.name
+	}
+
+	return names
+}
+
+// Changer returns the changer of the named library.
+func (fed *Federation) Changer(name string) (*mtx.Changer, error) {
+	fed.mu.RLock()
+	defer fed.mu.RUnlock()
+
+	for _, m := range fed.members {
+		if m.name == name {
+			return m.chgr, nil
+		}
+	}
+
+	return nil, ErrUnknownLibrary
+}
+
+// Status queries all member libraries concurrently and returns the merged
+// status. If any library fails, the first error in member order is
+// returned.
+func (fed *Federation) Status() (*Status, error) {
+	fed.mu.RLock()
+	members := append([]member(nil), fed.members...)
+	fed.mu.RUnlock()
+
+	statuses := make([]*mtx.Status, len(members))
+	errs := make([]error, len(members))
+
+	var wg sync.WaitGroup
+	for i, m := range members {
+		wg.Add(1)
+		go func(i int, m member) {
+			defer wg.Done()
+			statuses[i], errs[i] = m.chgr.Status()
+		}(i, m)
+	}
+	wg.Wait()
+
+	status := &Status{Libraries: make(map[string]*mtx.Status)}
+	for i, m := range members {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("federation: %s: %v", m.name, errs[i])
+		}
+
+		status.Libraries[m.name] = statuses[i]
+
+		for _, slot := range statuses[i].Drives {
+			status.Drives = append(status.Drives, &Slot{Library: m.name, Slot: *slot})
+		}
+
+		for _, slot := range statuses[i].Slots {
+			status.Slots = append(status.Slots, &Slot{Library: m.name, Slot: *slot})
+		}
+	}
+
+	return status, nil
+}
+
+// Find returns the location of the volume with the given serial in any
+// member library, checking drives before slots. It returns
+// mtx.ErrVolumeNotFound if no library holds the volume.
+func (fed *Federation) Find(serial string) (*Slot, error) {
+	status, err := fed.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, slot := range append(append([]*Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol != nil && slot.Vol.Serial == serial {
+			return slot, nil
+		}
+	}
+
+	return nil, mtx.ErrVolumeNotFound
+}
+
+// Load loads the volume in slot of the named library into drive.
+func (fed *Federation) Load(library string, slot, drive int) error {
+	chgr, err := fed.Changer(library)
+	if err != nil {
+		return err
+	}
+
+	return chgr.Load(mtx.SlotNum(slot), mtx.DriveNum(drive))
+}
+
+// Unload unloads drive of the named library into slot.
+func (fed *Federation) Unload(library string, slot, drive int) error {
+	chgr, err := fed.Changer(library)
+	if err != nil {
+		return err
+	}
+
+	return chgr.Unload(mtx.SlotNum(slot), mtx.DriveNum(drive))
+}
+
+// Transfer moves a volume between two slots of the named library.
+func (fed *Federation) Transfer(library string, src, dst int) error {
+	chgr, err := fed.Changer(library)
+	if err != nil {
+		return err
+	}
+
+	return chgr.Transfer(mtx.SlotNum(src), mtx.SlotNum(dst))
+}
+
+// LoadVolume loads the volume with the given serial into the first empty
+// drive of the library holding it and returns that drive. If the volume is
+// already in a drive, that drive is returned.
+func (fed *Federation) LoadVolume(serial string) (*Slot, error) {
+	status, err := fed.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var src *Slot
+	for _, slot := range append(append([]*Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol != nil && slot.Vol.Serial == serial {
+			src = slot
+			break
+		}
+	}
+
+	if src == nil {
+		return nil, mtx.ErrVolumeNotFound
+	}
+
+	if src.Type == mtx.DataTransferSlot {
+		return src, nil
+	}
+
+	for _, drv := range status.Drives {
+		if drv.Library != src.Library || drv.Vol != nil {
+			continue
+		}
+
+		if err := fed.Load(src.Library, src.Num, drv.Num); err != nil {
+			return nil, err
+		}
+
+		drv.Vol = &mtx.Volume{Serial: serial, Home: src.Num}
+
+		return drv, nil
+	}
+
+	return nil, ErrNoDrive
+}
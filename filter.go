@@ -0,0 +1,173 @@
+package mtx
+
+import (
+	"sort"
+	"strings"
+)
+
+// Media identifies a media type by the last two characters of a barcode
+// label, e.g. "L8" in "A00001L8".
+type Media string
+
+// LTO media types.
+const (
+	LTO1  Media = "L1"
+	LTO2  Media = "L2"
+	LTO3  Media = "L3"
+	LTO4  Media = "L4"
+	LTO5  Media = "L5"
+	LTO6  Media = "L6"
+	LTO7  Media = "L7"
+	LTOM8 Media = "M8"
+	LTO8  Media = "L8"
+	LTO9  Media = "L9"
+)
+
+// A Predicate reports whether a slot matches a condition.
+type Predicate func(slot *Slot) bool
+
+// Filter returns the slots matching all of the given predicates, in order.
+func Filter(slots []*Slot, preds ...Predicate) []*Slot {
+	out := make([]*Slot, 0)
+
+next:
+	for _, slot := range slots {
+		for _, pred := range preds {
+			if !pred(slot) {
+				continue next
+			}
+		}
+
+		out = append(out, slot)
+	}
+
+	return out
+}
+
+// IsEmpty reports whether the slot holds no volume.
+func IsEmpty(slot *Slot) bool {
+	return slot.Vol == nil
+}
+
+// IsFull reports whether the slot holds a volume.
+func IsFull(slot *Slot) bool {
+	return slot.Vol != nil
+}
+
+// IsDrive reports whether the slot is a data transfer element.
+func IsDrive(slot *Slot) bool {
+	return slot.Type == DataTransferSlot
+}
+
+// IsStorage reports whether the slot is a storage slot.
+func IsStorage(slot *Slot) bool {
+	return slot.Type == StorageSlot
+}
+
+// IsMail reports whether the slot is a mail slot.
+func IsMail(slot *Slot) bool {
+	return slot.Type == MailSlot
+}
+
+// IsCleaning reports whether the slot holds a cleaning cartridge, going by
+// the "CLN" prefix of its label.
+func IsCleaning(slot *Slot) bool {
+	return slot.Vol != nil && isCleaningSerial(slot.Vol.Serial)
+}
+
+func isCleaningSerial(serial string) bool {
+	return strings.HasPrefix(serial, "CLN")
+}
+
+// IsUnlabeled reports whether the slot holds a volume without a label.
+func IsUnlabeled(slot *Slot) bool {
+	return slot.Vol != nil && slot.Vol.Serial == ""
+}
+
+// HasMedia returns a predicate matching slots holding a volume of one of
+// the given media types.
+func HasMedia(media ...Media) Predicate {
+	return func(slot *Slot) bool {
+		if slot.Vol == nil || len(slot.Vol.Serial) < 2 {
+			return false
+		}
+
+		suffix := Media(slot.Vol.Serial[len(slot.Vol.Serial)-2:])
+		for _, m := range media {
+			if suffix == m {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// HasSerial returns a predicate matching slots holding one of the given
+// volumes.
+func HasSerial(serials ...string) Predicate {
+	set := make(map[string]bool, len(serials))
+	for _, serial := range serials {
+		set[serial] = true
+	}
+
+	return func(slot *Slot) bool {
+		return slot.Vol != nil && set[slot.Vol.Serial]
+	}
+}
+
+// HasPrefix returns a predicate matching slots holding a volume whose
+// serial starts with prefix.
+func HasPrefix(prefix string) Predicate {
+	return func(slot *Slot) bool {
+		return slot.Vol != nil && strings.HasPrefix(slot.Vol.Serial, prefix)
+	}
+}
+
+// Not returns a predicate matching the slots pred does not match.
+func Not(pred Predicate) Predicate {
+	return func(slot *Slot) bool {
+		return !pred(slot)
+	}
+}
+
+// Or returns a predicate matching the slots matched by any of preds.
+func Or(preds ...Predicate) Predicate {
+	return func(slot *Slot) bool {
+		for _, pred := range preds {
+			if pred(slot) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// SortByNum sorts slots in place by element type (drives, storage slots,
+// mail slots) and number.
+func SortByNum(slots []*Slot) {
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Type != slots[j].Type {
+			return slots[i].Type < slots[j].Type
+		}
+
+		return slots[i].Num < slots[j].Num
+	})
+}
+
+// SortBySerial sorts slots in place by the serial of the volume they hold.
+// Empty slots sort last; ties keep their order.
+func SortBySerial(slots []*Slot) {
+	sort.SliceStable(slots, func(i, j int) bool {
+		a, b := slots[i].Vol, slots[j].Vol
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		}
+
+		return a.Serial < b.Serial
+	})
+}
@@ -0,0 +1,92 @@
+package mtx
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// FormatOptions control the output of Status.Format.
+type FormatOptions struct {
+	// NoHeader omits the line naming the columns.
+	NoHeader bool
+
+	// HideEmpty omits empty slots. Drives are always shown.
+	HideEmpty bool
+
+	// Cleaning reports whether the volume with the given serial is a
+	// cleaning cartridge. If nil, serials starting with "CLN" are.
+	Cleaning func(serial string) bool
+}
+
+// Format writes the elements of status as an aligned table, drives first,
+// then storage and mail slots. Empty elements show "-" as their volume.
+// Mail slots, cleaning cartridges and volumes with invalid labels are
+// marked in the last column.
+func (status *Status) Format(w io.Writer, opts FormatOptions) error {
+	cleaning := opts.Cleaning
+	if cleaning == nil {
+		cleaning = isCleaningSerial
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	if !opts.NoHeader {
+		fmt.Fprintf(tw, "TYPE\tNUM\tVOLUME\tHOME\tNOTES\n")
+	}
+
+	for _, slot := range status.Drives {
+		formatSlot(tw, slot, cleaning)
+	}
+
+	for _, slot := range status.Slots {
+		if opts.HideEmpty && slot.Vol == nil {
+			continue
+		}
+
+		formatSlot(tw, slot, cleaning)
+	}
+
+	return tw.Flush()
+}
+
+func formatSlot(w io.Writer, slot *Slot, cleaning func(string) bool) {
+	var notes []string
+	if slot.Type == MailSlot {
+		notes = append(notes, "import/export")
+	}
+
+	if slot.Vol == nil {
+		fmt.Fprintf(w, "%s\t%d\t-\t\t%s\n", slotTypeName(slot.Type), slot.Num, strings.Join(notes, ", "))
+		return
+	}
+
+	serial := slot.Vol.Serial
+	if serial == "" {
+		serial = "(unlabeled)"
+	}
+
+	if slot.Vol.Serial != "" && cleaning(slot.Vol.Serial) {
+		notes = append(notes, "cleaning")
+	}
+
+	if slot.Vol.LabelErr != nil {
+		notes = append(notes, "invalid label")
+	}
+
+	fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", slotTypeName(slot.Type), slot.Num, serial, slot.Vol.Home, strings.Join(notes, ", "))
+}
+
+func slotTypeName(typ SlotType) string {
+	switch typ {
+	case DataTransferSlot:
+		return "drive"
+	case StorageSlot:
+		return "storage"
+	case MailSlot:
+		return "mail"
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,157 @@
+package mtx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Check is the result of a single health check.
+type Check struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// Health is the result of HealthCheck.
+type Health struct {
+	Healthy  bool          `json:"healthy"`
+	Checks   []Check       `json:"checks"`
+	Duration time.Duration `json:"duration"`
+
+	// Status is the status retrieved by the check, if it could be parsed.
+	Status *Status `json:"-"`
+}
+
+// Err returns an error describing the failed checks, or nil if the changer
+// is healthy.
+func (h *Health) Err() error {
+	if h.Healthy {
+		return nil
+	}
+
+	var msgs []string
+	for _, c := range h.Checks {
+		if !c.OK {
+			msgs = append(msgs, c.Name+": "+c.Message)
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnhealthy, strings.Join(msgs, "; "))
+}
+
+func (h *Health) add(name string, err error) bool {
+	c := Check{Name: name, OK: err == nil}
+	if err != nil {
+		c.Message = err.Error()
+	}
+
+	h.Checks = append(h.Checks, c)
+
+	return c.OK
+}
+
+// contextDoer is implemented by backends that support cancellation.
+type contextDoer interface {
+	DoContext(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// doContext performs the operation, returning early with the context error
+// if ctx is done first. Backends that support cancellation are cancelled;
+// for others, and when logging is enabled so the command still goes through
+// Do, the operation keeps running in the background.
+func (chgr *Changer) doContext(ctx context.Context, args ...string) ([]byte, error) {
+	if ctx.Done() == nil {
+		// never cancelled
+		return chgr.Do(args...)
+	}
+
+	if impl, ok := chgr.Interface.(contextDoer); ok && chgr.logger == nil {
+		if err := chgr.throttle(ctx, args); err != nil {
+			return nil, commandError(args, err)
+		}
+
+		out, err := impl.DoContext(ctx, args...)
+		return out, commandError(args, err)
+	}
+
+	type result struct {
+		out []byte
+		err error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		out, err := chgr.Do(args...)
+		ch <- result{out, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.out, r.err
+	case <-ctx.Done():
+		return nil, commandError(args, ctx.Err())
+	}
+}
+
+// HealthCheck verifies that the changer is usable without moving anything:
+// the device must answer a status request, the output must parse, and the
+// element counts must agree with the header. It is cheap enough to back
+// liveness and readiness probes.
+func (chgr *Changer) HealthCheck(ctx context.Context) *Health {
+	start := time.Now()
+	h := &Health{}
+
+	defer func() {
+		h.Duration = time.Since(start)
+	}()
+
+	status, err := chgr.queryStatus(ctx)
+	if errors.Is(err, ErrParse) {
+		h.add("reachable", nil)
+		h.add("parseable", err)
+
+		return h
+	}
+
+	if !h.add("reachable", err) || !h.add("parseable", nil) {
+		return h
+	}
+
+	h.Status = status
+
+	if !h.add("counts", checkCounts(status)) {
+		return h
+	}
+
+	h.Healthy = true
+
+	return h
+}
+
+// checkCounts verifies that the elements of status match its header.
+func checkCounts(status *Status) error {
+	switch {
+	case len(status.Drives) != status.MaxDrives:
+		return fmt.Errorf("%d drives reported, header says %d", len(status.Drives), status.MaxDrives)
+	case len(status.Slots) != status.NumSlots:
+		return fmt.Errorf("%d slots reported, header says %d", len(status.Slots), status.NumSlots)
+	case status.NumMailSlots < 0 || status.NumMailSlots > status.NumSlots:
+		return fmt.Errorf("%d import/export slots out of %d slots", status.NumMailSlots, status.NumSlots)
+	}
+
+	mail := 0
+	for _, slot := range status.Slots {
+		if slot.Type == MailSlot {
+			mail++
+		}
+	}
+
+	if mail != status.NumMailSlots {
+		return fmt.Errorf("%d import/export slots reported, header says %d", mail, status.NumMailSlots)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package mtx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Movement is a recorded move of a volume.
+type Movement struct {
+	Time     time.Time
+	From, To Element
+
+	// By is the initiator of the move, see WithInitiator and MoveBy.
+	By string
+}
+
+// WithHistory makes the changer record the last size movements of every
+// volume moved through it, see History. To know which volume a command
+// moves, the status is consulted before every move; unless a status is
+// cached by Refresh, that costs a status query.
+func WithHistory(size int) Option {
+	return func(chgr *Changer) {
+		chgr.historySize = size
+		chgr.history = make(map[string][]Movement)
+	}
+}
+
+// WithInitiator sets the initiator recorded for the movements of the
+// changer, e.g. the user or service on whose behalf it is used. Moves made
+// with MoveBy record their own initiator instead.
+func WithInitiator(by string) Option {
+	return func(chgr *Changer) {
+		chgr.initiator = by
+	}
+}
+
+// MoveBy performs a single move like Move, recording by as its initiator,
+// e.g. the authenticated client of a server shared by several users.
+func (chgr *Changer) MoveBy(mv Move, by string) error {
+	if mv.Type < 0 || int(mv.Type) >= len(moveTypeNames) {
+		return fmt.Errorf("%w: unknown move type %d", ErrInvalidMove, int(mv.Type))
+	}
+
+	_, err := chgr.doBy(by, strings.Fields(mv.String())...)
+
+	return err
+}
+
+// History returns the recorded movements of the volume with the given
+// serial, oldest first. It is empty unless the changer was created with
+// WithHistory. Moves of unlabeled volumes are not recorded.
+func (chgr *Changer) History(serial string) []Movement {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	return append([]Movement(nil), chgr.history[serial]...)
+}
+
+// pendingMove is a move command about to be performed.
+type pendingMove struct {
+	serial   string
+	from, to Element
+}
+
+// resolveMove returns the volume and elements involved in the move command
+// given by args, or nil if args is not a move of a labeled volume or the
+// status cannot be retrieved.
+func (chgr *Changer) resolveMove(args []string, st *lazyStatus) *pendingMove {
+	mv, ok := moveArgs(args)
+	if !ok {
+		return nil
+	}
+
+	src, dst := mv.elements()
+
+	status, err := st.get()
+	if err != nil {
+		return nil
+	}
+
+	from := status.element(src)
+	if from == nil || from.Vol == nil || from.Vol.Serial == "" {
+		return nil
+	}
+
+	if mv.Type == MoveUnload && dst.Num == 0 {
+		// slot 0 is the home slot of the volume
+		dst.Num = from.Vol.Home
+	}
+
+	to := status.element(dst)
+	if to == nil {
+		return nil
+	}
+
+	return &pendingMove{serial: from.Vol.Serial, from: from.Element(), to: to.Element()}
+}
+
+// element returns the slot of status identified by e. Storage and mail
+// slots are looked up by number alone.
+func (status *Status) element(e Element) *Slot {
+	slots := status.Slots
+	if e.IsDrive() {
+		slots = status.Drives
+	}
+
+	for _, slot := range slots {
+		if slot.Num == e.Num {
+			return slot
+		}
+	}
+
+	return nil
+}
+
+// record adds the completed move, initiated by by, to the history of its
+// volume.
+func (chgr *Changer) record(mv *pendingMove, by string) {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	hist := append(chgr.history[mv.serial], Movement{
+		Time: time.Now(),
+		From: mv.from,
+		To:   mv.to,
+		By:   by,
+	})
+
+	if len(hist) > chgr.historySize {
+		hist = hist[len(hist)-chgr.historySize:]
+	}
+
+	chgr.history[mv.serial] = hist
+}
@@ -0,0 +1,46 @@
+package mtx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+)
+
+func TestMoveBy(t *testing.T) {
+	chgr := mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithHistory(10), mtx.WithInitiator("backup"))
+
+	if err := chgr.Transfer(1, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.MoveBy(mtx.Move{Type: mtx.MoveLoad, Src: 6, Dst: 0}, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.MoveBy(mtx.Move{Type: mtx.MoveUnload, Src: 0, Dst: 1}, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	hist := chgr.History("S00000L6")
+
+	want := []string{"backup", "alice", "bob"}
+	if len(hist) != len(want) {
+		t.Fatalf("got %d movements, want %d", len(hist), len(want))
+	}
+
+	for i, by := range want {
+		if hist[i].By != by {
+			t.Errorf("movement %d by %q, want %q", i, hist[i].By, by)
+		}
+	}
+
+	if hist[2].To != (mtx.Element{Type: mtx.StorageSlot, Num: 1}) {
+		t.Errorf("unloaded to %v, want storage slot 1", hist[2].To)
+	}
+
+	if err := chgr.MoveBy(mtx.Move{Type: 7}, "alice"); !errors.Is(err, mtx.ErrInvalidMove) {
+		t.Errorf("MoveBy with unknown type = %v, want ErrInvalidMove", err)
+	}
+}
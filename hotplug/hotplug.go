@@ -0,0 +1,217 @@
+// Package hotplug watches for medium changers being added to or removed from
+// the system.
+//
+// On Linux the kernel announces device changes through a netlink socket
+// (the same source udev consumes). A Monitor listens on that socket and
+// notifies registered callbacks whenever a medium changer appears or
+// disappears, so long-running daemons can pick up a re-zoned library or a
+// reseated SAS cable without restarting.
+package hotplug
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Action defines the kind of hotplug event.
+type Action int
+
+const (
+	Add Action = iota
+	Remove
+)
+
+// String returns a textual representation of the action.
+func (a Action) String() string {
+	switch a {
+	case Add:
+		return "add"
+	case Remove:
+		return "remove"
+	}
+
+	return "unknown"
+}
+
+// scsiTypeMediumChanger is the SCSI peripheral device type of a medium
+// changer as reported in sysfs.
+const scsiTypeMediumChanger = "8"
+
+// Event describes a medium changer being added or removed.
+type Event struct {
+	// Action is the kind of event.
+	Action Action
+
+	// Subsystem is the kernel subsystem that generated the event, typically
+	// "scsi_generic" or "scsi_changer".
+	Subsystem string
+
+	// DevPath is the sysfs path of the device (relative to /sys).
+	DevPath string
+
+	// Device is the device node of the changer, e.g. /dev/sg3 or /dev/sch0.
+	Device string
+}
+
+// Handler is a function called for every event.
+type Handler func(ev *Event)
+
+// Monitor watches the kernel for medium changer hotplug events.
+type Monitor struct {
+	conn conn
+
+	mu       sync.Mutex
+	handlers []Handler
+
+	// known keeps track of device paths that have been identified as medium
+	// changers, since sysfs is already gone when the remove event arrives.
+	known map[string]bool
+
+	sysfs string
+}
+
+// conn is a source of raw kernel uevent messages.
+type conn interface {
+	// Receive returns the next message. It returns errClosed once the
+	// connection has been closed.
+	Receive() ([]byte, error)
+
+	Close() error
+}
+
+// New returns a new monitor listening for kernel uevents.
+func New() (*Monitor, error) {
+	c, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Monitor{
+		conn:  c,
+		known: make(map[string]bool),
+		sysfs: "/sys",
+	}, nil
+}
+
+// Register adds a handler that is called for every event. Handlers are
+// called sequentially from the goroutine running Run.
+func (mon *Monitor) Register(fn Handler) {
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+
+	mon.handlers = append(mon.handlers, fn)
+}
+
+// Run receives events until the monitor is closed. It returns nil if the
+// monitor was closed and an error if receiving failed.
+func (mon *Monitor) Run() error {
+	for {
+		msg, err := mon.conn.Receive()
+		if err != nil {
+			if err == errClosed {
+				return nil
+			}
+
+			return err
+		}
+
+		ev := mon.parse(msg)
+		if ev == nil {
+			continue
+		}
+
+		mon.mu.Lock()
+		handlers := make([]Handler, len(mon.handlers))
+		copy(handlers, mon.handlers)
+		mon.mu.Unlock()
+
+		for _, fn := range handlers {
+			fn(ev)
+		}
+	}
+}
+
+// Close stops the monitor. A blocked Run returns shortly after.
+func (mon *Monitor) Close() error {
+	return mon.conn.Close()
+}
+
+// parse decodes a kernel uevent message and returns an event if it concerns
+// a medium changer.
+func (mon *Monitor) parse(msg []byte) *Event {
+	// a uevent is a "action@devpath" header followed by NUL separated
+	// KEY=VALUE pairs
+	fields := bytes.Split(msg, []byte{0})
+	if len(fields) < 2 || !bytes.Contains(fields[0], []byte("@")) {
+		return nil
+	}
+
+	env := make(map[string]string)
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(string(field), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		env[kv[0]] = kv[1]
+	}
+
+	ev := &Event{
+		Subsystem: env["SUBSYSTEM"],
+		DevPath:   env["DEVPATH"],
+	}
+
+	if name := env["DEVNAME"]; name != "" {
+		ev.Device = filepath.Join("/dev", name)
+	}
+
+	switch env["ACTION"] {
+	case "add":
+		ev.Action = Add
+	case "remove":
+		ev.Action = Remove
+	default:
+		return nil
+	}
+
+	switch ev.Subsystem {
+	case "scsi_changer":
+		// always a medium changer
+	case "scsi_generic":
+		if ev.Action == Add && !mon.isChanger(ev.DevPath) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+
+	if ev.Action == Remove {
+		if ev.Subsystem == "scsi_generic" && !mon.known[ev.DevPath] {
+			return nil
+		}
+
+		delete(mon.known, ev.DevPath)
+
+		return ev
+	}
+
+	mon.known[ev.DevPath] = true
+
+	return ev
+}
+
+// isChanger checks the SCSI peripheral device type of the device in sysfs.
+func (mon *Monitor) isChanger(devpath string) bool {
+	buf, err := os.ReadFile(filepath.Join(mon.sysfs, devpath, "device", "type"))
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(buf)) == scsiTypeMediumChanger
+}
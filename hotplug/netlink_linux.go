@@ -0,0 +1,86 @@
+package hotplug
+
+import (
+	"errors"
+	"sync/atomic"
+	"syscall"
+)
+
+var errClosed = errors.New("hotplug: monitor closed")
+
+// kernelGroup is the netlink multicast group on which the kernel broadcasts
+// uevents.
+const kernelGroup = 1
+
+type netlinkConn struct {
+	fd     int
+	closed int32
+	buf    []byte
+}
+
+func dial() (conn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC,
+		syscall.NETLINK_KOBJECT_UEVENT,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: kernelGroup,
+	}
+
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// use a receive timeout so that Close is noticed by a blocked Receive
+	tv := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	return &netlinkConn{fd: fd, buf: make([]byte, 64*1024)}, nil
+}
+
+func (c *netlinkConn) Receive() ([]byte, error) {
+	for {
+		if atomic.LoadInt32(&c.closed) != 0 {
+			return nil, errClosed
+		}
+
+		n, from, err := syscall.Recvfrom(c.fd, c.buf, 0)
+		if err != nil {
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+
+			if atomic.LoadInt32(&c.closed) != 0 {
+				return nil, errClosed
+			}
+
+			return nil, err
+		}
+
+		// only accept messages originating from the kernel
+		if nl, ok := from.(*syscall.SockaddrNetlink); !ok || nl.Pid != 0 {
+			continue
+		}
+
+		msg := make([]byte, n)
+		copy(msg, c.buf[:n])
+
+		return msg, nil
+	}
+}
+
+func (c *netlinkConn) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+
+	return syscall.Close(c.fd)
+}
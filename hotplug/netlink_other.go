@@ -0,0 +1,11 @@
+//go:build !linux
+
+package hotplug
+
+import "errors"
+
+var errClosed = errors.New("hotplug: monitor closed")
+
+func dial() (conn, error) {
+	return nil, errors.New("hotplug: not supported on this platform")
+}
@@ -0,0 +1,225 @@
+// Package httpclient implements the mtx.Interface for a library changer
+// exposed by the httpserver package on a remote host.
+package httpclient
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/httpserver"
+)
+
+// Changer represents a library changer on a remote host.
+type Changer struct {
+	url    string
+	client *http.Client
+	token  string
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithToken authenticates to the server with the given API token, see
+// httpserver.WithTokens.
+func WithToken(token string) Option {
+	return func(chgr *Changer) {
+		chgr.token = token
+	}
+}
+
+// WithTLS uses the TLS configuration cfg, e.g. as returned by
+// tlsconfig.Client, for https URLs. It applies to the transport of the
+// HTTP client given to NewWithClient, if that is an *http.Transport, and
+// to the default transport otherwise.
+func WithTLS(cfg *tls.Config) Option {
+	return func(chgr *Changer) {
+		base, ok := chgr.client.Transport.(*http.Transport)
+		if !ok {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+
+		transport := base.Clone()
+		transport.TLSClientConfig = cfg
+
+		client := *chgr.client
+		client.Transport = transport
+		chgr.client = &client
+	}
+}
+
+// New returns a new changer implementation talking to the server at url
+// (e.g. "http://tapehost:8080").
+func New(url string, opts ...Option) *Changer {
+	return NewWithClient(url, http.DefaultClient, opts...)
+}
+
+// NewWithClient returns a new changer implementation using the given HTTP
+// client.
+func NewWithClient(url string, client *http.Client, opts ...Option) *Changer {
+	chgr := &Changer{
+		url:    strings.TrimRight(url, "/"),
+		client: client,
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
+	}
+
+	return chgr
+}
+
+// Do performs the given operation on the remote changer. The status, load,
+// unload and transfer commands are supported. The output of status is
+// rendered in the format of 'mtx status'.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	if len(args) < 1 {
+		return nil, errors.New("no command given")
+	}
+
+	cmd := args[0]
+
+	if cmd == "status" {
+		status, err := chgr.Status()
+		if err != nil {
+			return nil, err
+		}
+
+		return mtx.RenderStatus("remote", status), nil
+	}
+
+	if len(args) != 3 {
+		return nil, errors.New("wrong number of arguments")
+	}
+
+	a, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := strconv.Atoi(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	switch cmd {
+	case "load", "unload":
+		return nil, chgr.post("/"+cmd, &httpserver.LoadRequest{Slot: a, Drive: b}, nil)
+	case "transfer":
+		return nil, chgr.post("/transfer", &httpserver.TransferRequest{Src: a, Dst: b}, nil)
+	}
+
+	return nil, errors.New("mtx/httpclient: unknown or unsupported mtx command")
+}
+
+// Status implements mtx.StatusProvider, returning the status reported by the
+// server without rendering it in the format of 'mtx status'.
+func (chgr *Changer) Status() (*mtx.Status, error) {
+	var status httpserver.Status
+	if err := chgr.get("/status", &status); err != nil {
+		return nil, err
+	}
+
+	return status.MtxStatus(), nil
+}
+
+// SlotsPage returns up to limit slots, starting at index offset, and the
+// total number of slots in the library. A limit of zero returns all slots
+// from offset on.
+func (chgr *Changer) SlotsPage(offset, limit int) ([]*mtx.Slot, int, error) {
+	q := url.Values{}
+	q.Set("offset", strconv.Itoa(offset))
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+
+	resp, err := chgr.send(http.MethodGet, "/slots?"+q.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, _ := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+
+	var page []*httpserver.Slot
+	if err := decode(resp, &page); err != nil {
+		return nil, 0, err
+	}
+
+	slots := make([]*mtx.Slot, 0, len(page))
+	for _, slot := range page {
+		slots = append(slots, slot.MtxSlot())
+	}
+
+	return slots, total, nil
+}
+
+func (chgr *Changer) get(path string, v interface{}) error {
+	resp, err := chgr.send(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	return decode(resp, v)
+}
+
+func (chgr *Changer) post(path string, body, v interface{}) error {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := chgr.send(http.MethodPost, path, buf)
+	if err != nil {
+		return err
+	}
+
+	return decode(resp, v)
+}
+
+// send sends a request with the given JSON body, if any, to the server.
+func (chgr *Changer) send(method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, chgr.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if chgr.token != "" {
+		req.Header.Set("Authorization", "Bearer "+chgr.token)
+	}
+
+	return chgr.client.Do(req)
+}
+
+// decode reads the response, decoding a successful body into v (if non-nil)
+// and turning error responses into errors.
+func decode(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e httpserver.Error
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("mtx/httpclient: %s", resp.Status)
+		}
+
+		return errors.New(e.Error)
+	}
+
+	if v == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
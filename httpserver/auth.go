@@ -0,0 +1,134 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Role is the set of endpoints a client may use.
+type Role int
+
+const (
+	// Viewer may query the library.
+	Viewer Role = iota
+
+	// Operator may also move volumes.
+	Operator
+)
+
+var roleNames = [...]string{
+	Viewer:   "viewer",
+	Operator: "operator",
+}
+
+// String returns a textual representation of the role.
+func (role Role) String() string {
+	if role < 0 || int(role) >= len(roleNames) {
+		return "unknown"
+	}
+
+	return roleNames[role]
+}
+
+// An Option configures a Server.
+type Option func(srv *Server)
+
+// WithTokens requires clients to authenticate with one of the given API
+// tokens, granting them the role it maps to. Tokens are passed in an
+// "Authorization: Bearer" or an X-API-Key header. Requests without a valid
+// token are answered with 401 Unauthorized, and requests for endpoints
+// beyond the role of the token with 403 Forbidden. Without tokens, the
+// server does not authenticate clients.
+func WithTokens(tokens map[string]Role) Option {
+	return func(srv *Server) {
+		srv.tokens = tokens
+	}
+}
+
+var (
+	errUnauthorized = errors.New("missing or invalid API token")
+	errForbidden    = errors.New("API token does not permit this operation")
+)
+
+// authorize wraps h to require a token granting at least role, if the
+// server authenticates clients.
+func (srv *Server) authorize(role Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if srv.tokens == nil {
+			h(w, r)
+			return
+		}
+
+		granted, ok := srv.role(requestToken(r))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mtx"`)
+			writeError(w, http.StatusUnauthorized, errUnauthorized)
+
+			return
+		}
+
+		if granted < role {
+			writeError(w, http.StatusForbidden, errForbidden)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// role returns the role of token. All tokens are compared, in constant
+// time, so that timing does not tell how much of a token was right.
+func (srv *Server) role(token string) (Role, bool) {
+	var role Role
+	found := false
+
+	for t, r := range srv.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			role, found = r, true
+		}
+	}
+
+	return role, found && token != ""
+}
+
+// requestToken returns the API token of the request, or "" if it has none.
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if scheme, token, ok := strings.Cut(auth, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+
+		return ""
+	}
+
+	return r.Header.Get("X-API-Key")
+}
+
+// initiator returns the name the moves requested by r are recorded under:
+// the fingerprint of its API token, so that the token itself is not
+// disclosed, else the common name of its client certificate, else the
+// address of the client.
+func (srv *Server) initiator(r *http.Request) string {
+	if srv.tokens != nil {
+		sum := sha256.Sum256([]byte(requestToken(r)))
+		return "token:" + hex.EncodeToString(sum[:4])
+	}
+
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		if cn := r.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return cn
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,264 @@
+// Package httpserver exposes a library changer over HTTP.
+//
+// The server provides the following endpoints, all using JSON bodies:
+//
+//	GET  /status            the status of the library
+//	GET  /slots             the storage and mail slots
+//	                        (?offset=N&limit=N for a page)
+//	GET  /volumes/{serial}  the slot or drive holding a volume
+//	POST /load              load a volume (LoadRequest)
+//	POST /unload            unload a volume (LoadRequest)
+//	POST /transfer          transfer a volume (TransferRequest)
+//	GET  /events            stream of library events (see WithEvents)
+//
+// The total number of slots is reported in the X-Total-Count header of
+// /slots responses, paginated or not.
+//
+// Successful moves are answered with 204 No Content. Errors are answered with
+// an Error body and status
+//
+//	400  for malformed requests and moves naming elements the library does
+//	     not have
+//	403  for moves forbidden by the policy of the changer or because it is
+//	     read-only
+//	404  for unknown volumes
+//	409  for moves conflicting with the state of the library, i.e. from an
+//	     empty element or to a full one, and loads of media the drive
+//	     cannot read
+//	500  if the changer fails
+//
+// With WithTokens, clients must present an API token. Tokens with the Viewer
+// role may use the GET endpoints, tokens with the Operator role all of them.
+// Requests without a valid token are answered with 401, and requests beyond
+// the role of their token with 403.
+//
+// If the changer keeps a history, see mtx.WithHistory, moves are recorded
+// under the name of the client: the fingerprint of its API token, else the
+// common name of its TLS client certificate, else its address.
+package httpserver
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/events"
+)
+
+// Server is an http.Handler exposing a library changer.
+type Server struct {
+	chgr *mtx.Changer
+	mux  *http.ServeMux
+
+	// robot operations are serialized
+	mu sync.Mutex
+
+	// roles by API token, see auth.go
+	tokens map[string]Role
+
+	// source of the event stream, see sse.go
+	bus *events.Bus
+}
+
+// New returns a new server for the given changer.
+func New(chgr *mtx.Changer, opts ...Option) *Server {
+	srv := &Server{
+		chgr: chgr,
+		mux:  http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		opt(srv)
+	}
+
+	srv.mux.HandleFunc("GET /status", srv.authorize(Viewer, srv.handleStatus))
+	srv.mux.HandleFunc("GET /slots", srv.authorize(Viewer, srv.handleSlots))
+	srv.mux.HandleFunc("GET /volumes/{serial}", srv.authorize(Viewer, srv.handleVolume))
+	srv.mux.HandleFunc("POST /load", srv.authorize(Operator, srv.handleLoad))
+	srv.mux.HandleFunc("POST /unload", srv.authorize(Operator, srv.handleUnload))
+	srv.mux.HandleFunc("POST /transfer", srv.authorize(Operator, srv.handleTransfer))
+
+	if srv.bus != nil {
+		srv.mux.HandleFunc("GET /events", srv.authorize(Viewer, srv.handleEvents))
+	}
+
+	return srv
+}
+
+// ListenAndServeTLS serves the changer on the TCP address addr over TLS
+// configured by cfg, e.g. as returned by tlsconfig.Server. It always returns
+// a non-nil error.
+func (srv *Server) ListenAndServeTLS(addr string, cfg *tls.Config) error {
+	hs := &http.Server{Addr: addr, Handler: srv, TLSConfig: cfg}
+
+	return hs.ListenAndServeTLS("", "")
+}
+
+// ServeHTTP dispatches the request to the endpoint handlers.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mux.ServeHTTP(w, r)
+}
+
+func (srv *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	status, err := srv.chgr.Status()
+	srv.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewStatus(status))
+}
+
+func (srv *Server) handleSlots(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	srv.mu.Lock()
+	status, err := srv.chgr.Status()
+	srv.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(status.Slots)))
+	writeJSON(w, http.StatusOK, NewSlots(status.SlotsPage(offset, limit)))
+}
+
+// queryInt returns the non-negative integer query parameter name, or 0 if it
+// is not given.
+func queryInt(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, v)
+	}
+
+	return n, nil
+}
+
+func (srv *Server) handleVolume(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	slot, err := srv.chgr.Find(r.PathValue("serial"))
+	srv.mu.Unlock()
+
+	if errors.Is(err, mtx.ErrVolumeNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSlot(slot))
+}
+
+func (srv *Server) handleLoad(w http.ResponseWriter, r *http.Request) {
+	var req LoadRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	srv.move(w, func() error {
+		return srv.chgr.MoveBy(mtx.Move{Type: mtx.MoveLoad, Src: req.Slot, Dst: req.Drive}, srv.initiator(r))
+	})
+}
+
+func (srv *Server) handleUnload(w http.ResponseWriter, r *http.Request) {
+	var req LoadRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	srv.move(w, func() error {
+		return srv.chgr.MoveBy(mtx.Move{Type: mtx.MoveUnload, Src: req.Drive, Dst: req.Slot}, srv.initiator(r))
+	})
+}
+
+func (srv *Server) handleTransfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	srv.move(w, func() error {
+		return srv.chgr.MoveBy(mtx.Move{Type: mtx.MoveTransfer, Src: req.Src, Dst: req.Dst}, srv.initiator(r))
+	})
+}
+
+// move performs a robot operation and writes the response. Moves rejected
+// because of the state of the library, including loads of incompatible
+// media, are reported as conflicts, moves forbidden by the policy or by a
+// read-only changer as forbidden, and moves naming elements the library does
+// not have as bad requests.
+func (srv *Server) move(w http.ResponseWriter, fn func() error) {
+	srv.mu.Lock()
+	err := fn()
+	srv.mu.Unlock()
+
+	switch {
+	case errors.Is(err, mtx.ErrEmpty), errors.Is(err, mtx.ErrFull), errors.Is(err, mtx.ErrIncompatibleMedia):
+		writeError(w, http.StatusConflict, err)
+		return
+	case errors.Is(err, mtx.ErrNotAllowed), errors.Is(err, mtx.ErrReadOnly):
+		writeError(w, http.StatusForbidden, err)
+		return
+	case errors.Is(err, mtx.ErrInvalidElement):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readJSON decodes the request body into v. On failure it writes an error
+// response and returns false.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &Error{Error: err.Error()})
+}
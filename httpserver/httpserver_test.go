@@ -0,0 +1,91 @@
+package httpserver_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/httpserver"
+	"github.com/kbj/mtx/mock"
+)
+
+func TestMoveStatusCodes(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		chgr *mtx.Changer
+		path string
+		body string
+		want int
+	}{
+		{"load", mtx.NewChanger(mock.New(2, 8, 1, 4)), "/load", `{"slot": 1, "drive": 0}`, http.StatusNoContent},
+		{"malformed", mtx.NewChanger(mock.New(2, 8, 1, 4)), "/load", `{"slot": "1"}`, http.StatusBadRequest},
+		{"empty source", mtx.NewChanger(mock.New(2, 8, 1, 4)), "/transfer", `{"src": 6, "dst": 7}`, http.StatusConflict},
+		{"invalid element", mtx.NewChanger(mock.New(2, 8, 1, 4)), "/transfer", `{"src": 1, "dst": 99}`, http.StatusBadRequest},
+		{"full destination", mtx.NewChanger(mock.New(2, 8, 1, 4)), "/transfer", `{"src": 1, "dst": 2}`, http.StatusConflict},
+		{
+			"not allowed",
+			mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithPolicy(mtx.OnlySlots(1, 4))),
+			"/transfer", `{"src": 1, "dst": 6}`,
+			http.StatusForbidden,
+		},
+		{
+			"incompatible media",
+			mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithDriveGenerations(map[mtx.DriveNum]int{0: 8})),
+			"/load", `{"slot": 1, "drive": 0}`,
+			http.StatusConflict,
+		},
+		{
+			"read-only",
+			mtx.NewChanger(mtx.ReadOnly(mock.New(2, 8, 1, 4))),
+			"/transfer", `{"src": 1, "dst": 6}`,
+			http.StatusForbidden,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httpserver.New(tc.chgr)
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+
+			if rec.Code != tc.want {
+				t.Errorf("POST %s %s = %d %s, want %d", tc.path, tc.body, rec.Code, rec.Body, tc.want)
+			}
+		})
+	}
+}
+
+func TestMoveInitiator(t *testing.T) {
+	sum := sha256.Sum256([]byte("secret"))
+
+	for _, tc := range []struct {
+		name   string
+		tokens map[string]httpserver.Role
+		want   string
+	}{
+		{"token", map[string]httpserver.Role{"secret": httpserver.Operator}, "token:" + hex.EncodeToString(sum[:4])},
+		{"address", nil, "192.0.2.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chgr := mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithHistory(10), mtx.WithInitiator("server"))
+			srv := httpserver.New(chgr, httpserver.WithTokens(tc.tokens))
+
+			req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(`{"src": 1, "dst": 6}`))
+			req.Header.Set("Authorization", "Bearer secret")
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("POST /transfer = %d %s, want %d", rec.Code, rec.Body, http.StatusNoContent)
+			}
+
+			if hist := chgr.History("S00000L6"); len(hist) != 1 || hist[0].By != tc.want {
+				t.Errorf("history = %+v, want a movement by %q", hist, tc.want)
+			}
+		})
+	}
+}
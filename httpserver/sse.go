@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/kbj/mtx/events"
+)
+
+// keepaliveInterval is the interval at which idle event streams are sent a
+// comment, so that proxies do not time them out.
+const keepaliveInterval = 30 * time.Second
+
+// eventBuffer is the number of events buffered for a client. Clients
+// falling further behind are disconnected.
+const eventBuffer = 64
+
+// WithEvents adds the endpoint GET /events, streaming the events published
+// to bus as server-sent events (text/event-stream). Every event is named
+// after its type, e.g. "VolumeLoaded", and carries an Event as data. The bus
+// is typically fed by an events.Changer wrapping the backend of the
+// changer, polled with events.Changer.Poll. Clients that cannot keep up are
+// disconnected and may reconnect.
+func WithEvents(bus *events.Bus) Option {
+	return func(srv *Server) {
+		srv.bus = bus
+	}
+}
+
+// Event is the JSON representation of a library event. Data holds the
+// fields of the event type, e.g. the serial and slots of a VolumeMoved.
+type Event struct {
+	Type    string      `json:"type"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+// CommandCompleted is the JSON representation of the data of a
+// CommandCompleted event.
+type CommandCompleted struct {
+	Args     []string      `json:"args"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// NewEvent converts ev to its JSON representation.
+func NewEvent(ev events.Event) *Event {
+	e := &Event{
+		Type:    reflect.Indirect(reflect.ValueOf(ev)).Type().Name(),
+		Message: ev.String(),
+		Data:    ev,
+	}
+
+	if cc, ok := ev.(*events.CommandCompleted); ok {
+		data := &CommandCompleted{Args: cc.Args, Duration: cc.Duration}
+		if cc.Err != nil {
+			data.Error = cc.Err.Error()
+		}
+
+		e.Data = data
+	}
+
+	return e
+}
+
+func (srv *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	ch := make(chan events.Event, eventBuffer)
+	overflow := make(chan struct{})
+
+	cancel := srv.bus.Subscribe(func(ev events.Event) {
+		select {
+		case ch <- ev:
+		default:
+			select {
+			case <-overflow:
+			default:
+				close(overflow)
+			}
+		}
+	})
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case ev := <-ch:
+			e := NewEvent(ev)
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		case <-keepalive.C:
+			fmt.Fprintf(w, ": keepalive\n\n")
+		case <-overflow:
+			return
+		case <-r.Context().Done():
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package httpserver
+
+import (
+	"github.com/kbj/mtx"
+)
+
+// Volume is the JSON representation of a volume.
+type Volume struct {
+	Serial string `json:"serial"`
+	Home   int    `json:"home"`
+}
+
+// Slot is the JSON representation of a slot. Type is one of "drive",
+// "storage" and "mail".
+type Slot struct {
+	Num    int     `json:"num"`
+	Type   string  `json:"type"`
+	Volume *Volume `json:"volume,omitempty"`
+}
+
+// Status is the JSON representation of the library status.
+type Status struct {
+	MaxDrives       int `json:"maxDrives"`
+	NumSlots        int `json:"numSlots"`
+	NumStorageSlots int `json:"numStorageSlots"`
+	NumMailSlots    int `json:"numMailSlots"`
+
+	Drives []*Slot `json:"drives"`
+	Slots  []*Slot `json:"slots"`
+
+	Enclosure *Enclosure `json:"enclosure,omitempty"`
+}
+
+// Enclosure is the JSON representation of the physical state of the
+// library.
+type Enclosure struct {
+	DoorOpen    bool        `json:"doorOpen"`
+	StationOpen bool        `json:"stationOpen"`
+	Magazines   []*Magazine `json:"magazines,omitempty"`
+}
+
+// Magazine is the JSON representation of a removable magazine.
+type Magazine struct {
+	First   int  `json:"first"`
+	Last    int  `json:"last"`
+	Present bool `json:"present"`
+}
+
+// LoadRequest is the body of POST /load and POST /unload. For unload, a Slot
+// of 0 returns the volume to its home slot.
+type LoadRequest struct {
+	Slot  int `json:"slot"`
+	Drive int `json:"drive"`
+}
+
+// TransferRequest is the body of POST /transfer.
+type TransferRequest struct {
+	Src int `json:"src"`
+	Dst int `json:"dst"`
+}
+
+// Error is the body of error responses.
+type Error struct {
+	Error string `json:"error"`
+}
+
+var slotTypeNames = map[mtx.SlotType]string{
+	mtx.DataTransferSlot: "drive",
+	mtx.StorageSlot:      "storage",
+	mtx.MailSlot:         "mail",
+}
+
+// NewSlot converts slot to its JSON representation.
+func NewSlot(slot *mtx.Slot) *Slot {
+	s := &Slot{Num: slot.Num, Type: slotTypeNames[slot.Type]}
+	if slot.Vol != nil {
+		s.Volume = &Volume{Serial: slot.Vol.Serial, Home: slot.Vol.Home}
+	}
+
+	return s
+}
+
+// NewSlots converts slots to their JSON representation.
+func NewSlots(slots []*mtx.Slot) []*Slot {
+	out := make([]*Slot, 0, len(slots))
+	for _, slot := range slots {
+		out = append(out, NewSlot(slot))
+	}
+
+	return out
+}
+
+// NewStatus converts status to its JSON representation.
+func NewStatus(status *mtx.Status) *Status {
+	return &Status{
+		MaxDrives:       status.MaxDrives,
+		NumSlots:        status.NumSlots,
+		NumStorageSlots: status.NumStorageSlots,
+		NumMailSlots:    status.NumMailSlots,
+
+		Drives: NewSlots(status.Drives),
+		Slots:  NewSlots(status.Slots),
+
+		Enclosure: NewEnclosure(status.Enclosure),
+	}
+}
+
+// NewEnclosure converts enc to its JSON representation. It returns nil if
+// enc is nil.
+func NewEnclosure(enc *mtx.Enclosure) *Enclosure {
+	if enc == nil {
+		return nil
+	}
+
+	e := &Enclosure{DoorOpen: enc.DoorOpen, StationOpen: enc.StationOpen}
+	for _, mag := range enc.Magazines {
+		e.Magazines = append(e.Magazines, &Magazine{First: mag.First, Last: mag.Last, Present: mag.Present})
+	}
+
+	return e
+}
+
+// MtxSlot converts the JSON representation back to an mtx.Slot.
+func (s *Slot) MtxSlot() *mtx.Slot {
+	slot := &mtx.Slot{Num: s.Num}
+	for typ, name := range slotTypeNames {
+		if name == s.Type {
+			slot.Type = typ
+		}
+	}
+
+	if s.Volume != nil {
+		slot.Vol = &mtx.Volume{Serial: s.Volume.Serial, Home: s.Volume.Home}
+	}
+
+	return slot
+}
+
+// MtxStatus converts the JSON representation back to an mtx.Status.
+func (s *Status) MtxStatus() *mtx.Status {
+	status := &mtx.Status{
+		MaxDrives:       s.MaxDrives,
+		NumSlots:        s.NumSlots,
+		NumStorageSlots: s.NumStorageSlots,
+		NumMailSlots:    s.NumMailSlots,
+	}
+
+	for _, slot := range s.Drives {
+		status.Drives = append(status.Drives, slot.MtxSlot())
+	}
+
+	for _, slot := range s.Slots {
+		status.Slots = append(status.Slots, slot.MtxSlot())
+	}
+
+	if e := s.Enclosure; e != nil {
+		status.Enclosure = &mtx.Enclosure{DoorOpen: e.DoorOpen, StationOpen: e.StationOpen}
+		for _, mag := range e.Magazines {
+			status.Enclosure.Magazines = append(status.Enclosure.Magazines, mtx.Magazine{
+				First: mag.First, Last: mag.Last, Present: mag.Present,
+			})
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,152 @@
+package mtx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MoveResult is the outcome of MoveIdempotent.
+type MoveResult int
+
+const (
+	// Moved means the move was performed.
+	Moved MoveResult = iota
+
+	// AlreadySatisfied means the move was not performed, as the status
+	// showed it done already.
+	AlreadySatisfied
+)
+
+var moveResultNames = [...]string{
+	Moved:            "moved",
+	AlreadySatisfied: "already satisfied",
+}
+
+// String returns the name of the result.
+func (r MoveResult) String() string {
+	if r < 0 || int(r) >= len(moveResultNames) {
+		return fmt.Sprintf("MoveResult(%d)", int(r))
+	}
+
+	return moveResultNames[r]
+}
+
+// WithIdempotentMoves makes moves through Do, and so Load, Unload, Transfer
+// and Move, succeed without being performed if the status shows them done
+// already, see Satisfied. The volume moved is the one an earlier attempt of
+// the same move found at the source, so only retried moves can be
+// satisfied; others fail as usual. This costs a status query before every
+// move unless a status is cached by Refresh.
+func WithIdempotentMoves() Option {
+	return func(chgr *Changer) {
+		chgr.idempotent = true
+	}
+}
+
+// Satisfied reports whether status shows mv done already for vol, the
+// volume found at the source of mv by an earlier status: the source is
+// empty and the destination holds a volume with the serial of vol. Unloads
+// to slot 0 are checked against the home slot of vol.
+//
+// Unlabeled volumes cannot be told apart, so Satisfied is false if vol is
+// nil or has no serial.
+func (status *Status) Satisfied(mv Move, vol *Volume) bool {
+	if vol == nil || vol.Serial == "" {
+		return false
+	}
+
+	src, dst := mv.elements()
+
+	from := status.element(src)
+	if from == nil || from.Vol != nil {
+		return false
+	}
+
+	if mv.Type == MoveUnload && dst.Num == 0 {
+		dst.Num = vol.Home
+	}
+
+	to := status.element(dst)
+
+	return to != nil && to.Vol != nil && to.Vol.Serial == vol.Serial
+}
+
+// MoveIdempotent performs mv unless the status shows it done already by an
+// earlier attempt, in which case it returns AlreadySatisfied. As for
+// WithIdempotentMoves, the first attempt of a move is always performed.
+func (chgr *Changer) MoveIdempotent(mv Move) (MoveResult, error) {
+	status, err := chgr.Status()
+	if err != nil {
+		return Moved, err
+	}
+
+	if chgr.satisfied(mv, status) {
+		return AlreadySatisfied, nil
+	}
+
+	err = chgr.Move(mv)
+	chgr.settle(mv, err)
+
+	return Moved, err
+}
+
+// satisfied reports whether status shows mv done already for the volume an
+// earlier attempt found at its source. If the source holds a volume, it is
+// remembered for later attempts instead.
+func (chgr *Changer) satisfied(mv Move, status *Status) bool {
+	src, _ := mv.elements()
+
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if from := status.element(src); from != nil && from.Vol != nil {
+		if chgr.sources == nil {
+			chgr.sources = make(map[Move]Volume)
+		}
+
+		chgr.sources[mv] = *from.Vol
+
+		return false
+	}
+
+	vol, ok := chgr.sources[mv]
+	if !ok || !status.Satisfied(mv, &vol) {
+		return false
+	}
+
+	delete(chgr.sources, mv)
+
+	return true
+}
+
+// settle forgets the volume remembered for mv once it has succeeded, or
+// failed in a way showing it was not performed. Moves that timed out may
+// have been performed and are checked by the next attempt.
+func (chgr *Changer) settle(mv Move, err error) {
+	if err != nil && (!IsPermanent(err) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return
+	}
+
+	chgr.mu.Lock()
+	delete(chgr.sources, mv)
+	chgr.mu.Unlock()
+}
+
+// checkSatisfied reports whether the changer is idempotent and the move
+// command given by args is done already, see satisfied.
+func (chgr *Changer) checkSatisfied(args []string, st *lazyStatus) bool {
+	if !chgr.idempotent {
+		return false
+	}
+
+	mv, ok := moveArgs(args)
+	if !ok {
+		return false
+	}
+
+	status, err := st.get()
+
+	return err == nil && chgr.satisfied(mv, status)
+}
@@ -0,0 +1,128 @@
+package mtx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+)
+
+// lostReply performs the commands of impl, but fails the first move after
+// it was performed, as if the reply of the robot had been lost.
+type lostReply struct {
+	mtx.Interface
+	failed bool
+}
+
+func (impl *lostReply) Do(args ...string) ([]byte, error) {
+	out, err := impl.Interface.Do(args...)
+	if err == nil && args[0] != "status" && !impl.failed {
+		impl.failed = true
+		return nil, errors.New("connection reset by peer")
+	}
+
+	return out, err
+}
+
+func TestIdempotentEmptySource(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		mv   mtx.Move
+	}{
+		// slot 6 is empty; slot 1 holds S00000L6
+		{"transfer", mtx.Move{Type: mtx.MoveTransfer, Src: 6, Dst: 1}},
+		{"load", mtx.Move{Type: mtx.MoveLoad, Src: 6, Dst: 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chgr := mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithIdempotentMoves())
+
+			if tc.mv.Type == mtx.MoveLoad {
+				// occupy the drive with a volume from another slot
+				if err := chgr.Load(1, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := chgr.Move(tc.mv); !errors.Is(err, mtx.ErrEmpty) {
+				t.Errorf("Move(%s) = %v, want ErrEmpty", tc.mv, err)
+			}
+
+			res, err := chgr.MoveIdempotent(tc.mv)
+			if !errors.Is(err, mtx.ErrEmpty) {
+				t.Errorf("MoveIdempotent(%s) = %s, %v, want ErrEmpty", tc.mv, res, err)
+			}
+		})
+	}
+}
+
+func TestIdempotentRetry(t *testing.T) {
+	chgr := mtx.NewChanger(&lostReply{Interface: mock.New(2, 8, 1, 4)}, mtx.WithIdempotentMoves())
+
+	mv := mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 6}
+	if err := chgr.Move(mv); err == nil {
+		t.Fatalf("Move(%s) succeeded, want lost reply", mv)
+	}
+
+	if err := chgr.Move(mv); err != nil {
+		t.Fatalf("retried Move(%s) = %v, want satisfied", mv, err)
+	}
+
+	// the move is settled, so another attempt is performed and fails
+	if err := chgr.Move(mv); !errors.Is(err, mtx.ErrEmpty) {
+		t.Errorf("Move(%s) = %v, want ErrEmpty", mv, err)
+	}
+}
+
+func TestIdempotentRetryUnload(t *testing.T) {
+	impl := &lostReply{Interface: mock.New(2, 8, 1, 4), failed: true}
+	chgr := mtx.NewChanger(impl, mtx.WithIdempotentMoves())
+
+	if err := chgr.Load(2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	impl.failed = false
+
+	mv := mtx.Move{Type: mtx.MoveUnload, Src: 1, Dst: 0}
+	if err := chgr.Move(mv); err == nil {
+		t.Fatalf("Move(%s) succeeded, want lost reply", mv)
+	}
+
+	res, err := chgr.MoveIdempotent(mv)
+	if err != nil || res != mtx.AlreadySatisfied {
+		t.Errorf("MoveIdempotent(%s) = %s, %v, want %s", mv, res, err, mtx.AlreadySatisfied)
+	}
+}
+
+func TestSatisfied(t *testing.T) {
+	chgr := mtx.NewChanger(mock.New(2, 8, 1, 4))
+
+	if err := chgr.Transfer(1, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mv := mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 6}
+	for _, tc := range []struct {
+		name string
+		mv   mtx.Move
+		vol  *mtx.Volume
+		want bool
+	}{
+		{"moved", mv, &mtx.Volume{Serial: "S00000L6", Home: 1}, true},
+		{"other volume", mv, &mtx.Volume{Serial: "S00001L6", Home: 2}, false},
+		{"unknown volume", mv, nil, false},
+		{"unlabeled volume", mv, &mtx.Volume{Home: 1}, false},
+		{"source full", mtx.Move{Type: mtx.MoveTransfer, Src: 2, Dst: 6}, &mtx.Volume{Serial: "S00000L6"}, false},
+		{"destination empty", mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 7}, &mtx.Volume{Serial: "S00000L6"}, false},
+	} {
+		if got := status.Satisfied(tc.mv, tc.vol); got != tc.want {
+			t.Errorf("%s: Satisfied(%s) = %t, want %t", tc.name, tc.mv, got, tc.want)
+		}
+	}
+}
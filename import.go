@@ -0,0 +1,171 @@
+package mtx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Placement chooses the storage slots imported volumes are filed in.
+type Placement interface {
+	// Place returns the slot to move the volume in the mail slot mail to,
+	// chosen among free, the empty storage slots in slot order. free is
+	// never empty. Place returns nil to leave the volume where it is.
+	Place(mail *Slot, free []*Slot) *Slot
+}
+
+// PlacementFunc adapts a function to the Placement interface.
+type PlacementFunc func(mail *Slot, free []*Slot) *Slot
+
+// Place calls fn.
+func (fn PlacementFunc) Place(mail *Slot, free []*Slot) *Slot {
+	return fn(mail, free)
+}
+
+// FirstFree files volumes in the lowest numbered empty storage slot.
+var FirstFree Placement = PlacementFunc(func(mail *Slot, free []*Slot) *Slot {
+	return free[0]
+})
+
+// Spread files volumes in the magazine with the most empty slots, so that
+// new media is spread across magazines. Magazines are runs of size storage
+// slots starting at slot 1. Within a magazine, the lowest numbered empty
+// slot is used.
+func Spread(size int) Placement {
+	if size < 1 {
+		size = 1
+	}
+
+	return PlacementFunc(func(mail *Slot, free []*Slot) *Slot {
+		count := make(map[int]int)
+		for _, slot := range free {
+			count[(slot.Num-1)/size]++
+		}
+
+		var best *Slot
+		for _, slot := range free {
+			if best == nil || count[(slot.Num-1)/size] > count[(best.Num-1)/size] {
+				best = slot
+			}
+		}
+
+		return best
+	})
+}
+
+// Home files volumes in the slot returned by home for their serial, if it
+// is empty. Unlabeled volumes, volumes for which home returns 0 and
+// volumes whose home slot is taken are placed by fallback.
+func Home(home func(serial string) int, fallback Placement) Placement {
+	return PlacementFunc(func(mail *Slot, free []*Slot) *Slot {
+		if mail.Vol.Serial != "" {
+			if num := home(mail.Vol.Serial); num != 0 {
+				for _, slot := range free {
+					if slot.Num == num {
+						return slot
+					}
+				}
+			}
+		}
+
+		return fallback.Place(mail, free)
+	})
+}
+
+// ImportResult is the outcome of importing a single volume.
+type ImportResult struct {
+	// Serial is empty for unlabeled volumes.
+	Serial   string
+	MailSlot int
+
+	// Slot is the storage slot the volume was filed in, or 0 if it was
+	// left in the mail slot.
+	Slot int
+
+	Err error
+}
+
+// Import files the volumes in the mail slots in the storage slots chosen by
+// p, in mail slot order. A nil p means FirstFree. Failed moves are recorded
+// in the results and do not stop the import; once no empty storage slot is
+// left, the remaining volumes fail with ErrNoStorageSlot. Slots forbidden by
+// the policy of the changer are neither imported from nor filled.
+func (chgr *Changer) Import(p Placement) ([]ImportResult, error) {
+	if p == nil {
+		p = FirstFree
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	free := Filter(status.Slots, IsStorage, IsEmpty, chgr.allowedSlot)
+
+	var results []ImportResult
+	for _, mail := range Filter(status.Slots, IsMail, IsFull, chgr.allowedSlot) {
+		res := ImportResult{Serial: mail.Vol.Serial, MailSlot: mail.Num}
+
+		if len(free) == 0 {
+			res.Err = ErrNoStorageSlot
+			results = append(results, res)
+
+			continue
+		}
+
+		if dst := p.Place(mail, free); dst != nil {
+			if err := chgr.Transfer(SlotNum(mail.Num), SlotNum(dst.Num)); err != nil {
+				res.Err = err
+			} else {
+				res.Slot = dst.Num
+				free = Filter(free, func(slot *Slot) bool { return slot != dst })
+			}
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// importPollInterval is the interval at which WaitForImport checks the mail
+// slots.
+const importPollInterval = 10 * time.Second
+
+// WaitForImport polls the status until all volumes with the given serials
+// are in mail slots, or, if expected is empty, until any volume is, and
+// returns the full mail slots. Mail slots forbidden by the policy of the
+// changer are ignored. It returns ctx.Err() if ctx is done first, and an
+// error if a serial is expected more than once.
+func (chgr *Changer) WaitForImport(ctx context.Context, expected []string) ([]*Slot, error) {
+	want := make(map[string]bool, len(expected))
+	for _, serial := range expected {
+		if want[serial] {
+			return nil, fmt.Errorf("mtx: serial %q expected more than once", serial)
+		}
+
+		want[serial] = true
+	}
+
+	status, err := chgr.waitFor(ctx, importPollInterval, func(status *Status) bool {
+		full := Filter(status.Slots, IsMail, IsFull, chgr.allowedSlot)
+		if len(want) == 0 {
+			return len(full) > 0
+		}
+
+		// a serial seen in several mail slots counts once
+		seen := make(map[string]bool, len(want))
+		for _, slot := range full {
+			if want[slot.Vol.Serial] {
+				seen[slot.Vol.Serial] = true
+			}
+		}
+
+		return len(seen) == len(want)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return Filter(status.Slots, IsMail, IsFull, chgr.allowedSlot), nil
+}
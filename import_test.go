@@ -0,0 +1,31 @@
+package mtx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+)
+
+func TestWaitForImport(t *testing.T) {
+	chgr := mtx.NewChanger(mock.New(2, 8, 1, 4))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// the mail slot holds S00004L6
+	slots, err := chgr.WaitForImport(ctx, []string{"S00004L6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(slots) != 1 || slots[0].Vol.Serial != "S00004L6" {
+		t.Errorf("WaitForImport = %v, want the mail slot holding S00004L6", slots)
+	}
+
+	if _, err := chgr.WaitForImport(ctx, []string{"S00004L6", "S00004L6"}); err == nil || ctx.Err() != nil {
+		t.Errorf("WaitForImport with a duplicate serial = %v, want an immediate error", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Package elements maps the element model of vendor library interfaces onto
+// the numbering used by mtx and answers mtx commands on top of it.
+//
+// Vendor management interfaces identify elements by location names or
+// element addresses. Backends list the elements of the library in an
+// Inventory, which numbers them like mtx does: drives from 0, storage slots
+// from 1 followed by the import/export slots, each in element address
+// order. Do then implements the mtx commands in terms of the inventory and a
+// single move primitive.
+package elements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/kbj/mtx"
+)
+
+// Element is an element of a library.
+type Element struct {
+	Type mtx.SlotType
+
+	// Addr orders the elements of a type, usually the SCSI element address.
+	Addr int
+
+	// ID identifies the element to the vendor interface.
+	ID string
+
+	// Full is true if the element holds a volume. Volume is the serial of
+	// that volume, which may be empty if it is unlabeled.
+	Full   bool
+	Volume string
+
+	// Source is the address of the element a volume in a drive was loaded
+	// from, or 0 if unknown.
+	Source int
+
+	num int
+}
+
+// Num returns the mtx number of the element.
+func (e *Element) Num() int {
+	return e.num
+}
+
+// Inventory is the set of elements of a library.
+type Inventory struct {
+	Drives  []*Element
+	Storage []*Element
+	Mail    []*Element
+
+	// Enclosure is the physical state of the library, if the vendor
+	// interface reports it.
+	Enclosure *mtx.Enclosure
+}
+
+// New returns the inventory of elems, numbering them like mtx.
+func New(elems []*Element) *Inventory {
+	inv := &Inventory{}
+	for _, e := range elems {
+		switch e.Type {
+		case mtx.DataTransferSlot:
+			inv.Drives = append(inv.Drives, e)
+		case mtx.StorageSlot:
+			inv.Storage = append(inv.Storage, e)
+		case mtx.MailSlot:
+			inv.Mail = append(inv.Mail, e)
+		}
+	}
+
+	for _, list := range [][]*Element{inv.Drives, inv.Storage, inv.Mail} {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Addr < list[j].Addr })
+	}
+
+	for i, e := range inv.Drives {
+		e.num = i
+	}
+
+	for i, e := range inv.slots() {
+		e.num = i + 1
+	}
+
+	return inv
+}
+
+func (inv *Inventory) slots() []*Element {
+	return append(append([]*Element(nil), inv.Storage...), inv.Mail...)
+}
+
+// Status returns the inventory as an mtx status.
+func (inv *Inventory) Status() *mtx.Status {
+	home := make(map[int]int)
+	for _, e := range inv.slots() {
+		home[e.Addr] = e.num
+	}
+
+	status := &mtx.Status{
+		MaxDrives:       len(inv.Drives),
+		NumSlots:        len(inv.Storage) + len(inv.Mail),
+		NumStorageSlots: len(inv.Storage),
+		NumMailSlots:    len(inv.Mail),
+		Enclosure:       inv.Enclosure,
+	}
+
+	for _, e := range inv.Drives {
+		slot := &mtx.Slot{Num: e.num, Type: e.Type}
+		if e.Full {
+			slot.Vol = &mtx.Volume{Serial: e.Volume, Home: home[e.Source]}
+		}
+
+		status.Drives = append(status.Drives, slot)
+	}
+
+	for _, e := range inv.slots() {
+		slot := &mtx.Slot{Num: e.num, Type: e.Type}
+		if e.Full {
+			slot.Vol = &mtx.Volume{Serial: e.Volume, Home: e.num}
+		}
+
+		status.Slots = append(status.Slots, slot)
+	}
+
+	return status
+}
+
+// Slot returns the storage or import/export slot with mtx number num.
+func (inv *Inventory) Slot(cmd string, num int) (*Element, error) {
+	slots := inv.slots()
+	if num < 1 || num > len(slots) {
+		return nil, fmt.Errorf("Invalid <slotno> argument '%d' to '%s' command", num, cmd)
+	}
+
+	return slots[num-1], nil
+}
+
+// Drive returns the drive with mtx number num.
+func (inv *Inventory) Drive(cmd string, num int) (*Element, error) {
+	if num < 0 || num >= len(inv.Drives) {
+		return nil, fmt.Errorf("Invalid <drvno> argument '%d' to '%s' command", num, cmd)
+	}
+
+	return inv.Drives[num], nil
+}
+
+// Library is the interface backends implement on top of a vendor
+// interface.
+type Library interface {
+	// Inventory returns the current elements of the library.
+	Inventory(ctx context.Context) (*Inventory, error)
+
+	// Move moves the volume in src to dst.
+	Move(ctx context.Context, src, dst *Element) error
+}
+
+// Do performs the mtx command given by args on lib. The status, load,
+// unload and transfer commands are supported; the output of status is
+// rendered in the format of 'mtx status' with device as the changer name.
+// Preconditions are checked against a fresh inventory and reported with the
+// error texts of mtx.
+func Do(ctx context.Context, lib Library, device string, args ...string) ([]byte, error) {
+	if len(args) < 1 {
+		return nil, errors.New("no command given")
+	}
+
+	inv, err := lib.Inventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := args[0]
+	if cmd == "status" {
+		return mtx.RenderStatus(device, inv.Status()), nil
+	}
+
+	if len(args) != 3 {
+		return nil, errors.New("wrong number of arguments")
+	}
+
+	a, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := strconv.Atoi(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var src, dst *Element
+
+	switch cmd {
+	case "load":
+		if src, err = inv.Slot(cmd, a); err != nil {
+			return nil, err
+		}
+
+		if dst, err = inv.Drive(cmd, b); err != nil {
+			return nil, err
+		}
+
+		if !src.Full {
+			return nil, fmt.Errorf("source Element Address %d is Empty", a)
+		}
+
+		if dst.Full {
+			return nil, fmt.Errorf("Drive %d Full (Storage Element %d loaded)", b, inv.Status().Drives[b].Vol.Home)
+		}
+	case "unload":
+		if src, err = inv.Drive(cmd, b); err != nil {
+			return nil, err
+		}
+
+		if !src.Full {
+			return nil, fmt.Errorf("Data Transfer Element %d is Empty", b)
+		}
+
+		if a == 0 {
+			a = inv.Status().Drives[b].Vol.Home
+		}
+
+		if dst, err = inv.Slot(cmd, a); err != nil {
+			return nil, err
+		}
+
+		if dst.Full {
+			return nil, fmt.Errorf("Storage Element %d is Already Full", a)
+		}
+	case "transfer":
+		if src, err = inv.Slot(cmd, a); err != nil {
+			return nil, err
+		}
+
+		if dst, err = inv.Slot(cmd, b); err != nil {
+			return nil, err
+		}
+
+		if !src.Full {
+			return nil, fmt.Errorf("source Element Address %d is Empty", a)
+		}
+
+		if dst.Full {
+			return nil, fmt.Errorf("destination Element Address %d is Already Full", b)
+		}
+	default:
+		return nil, errors.New("unknown or unsupported mtx command")
+	}
+
+	return nil, lib.Move(ctx, src, dst)
+}
@@ -0,0 +1,397 @@
+// Package inventory maintains a catalog of the volumes in a library.
+//
+// The catalog keeps the last known location of every volume ever seen, when
+// it was first and last seen, when it was last loaded into a drive and a
+// history of its movements. It is stored in an SQLite database; the caller
+// opens the database with the SQLite driver of its choice and hands it to
+// Open:
+//
+//	db, err := sql.Open("sqlite3", "/var/lib/tapes/catalog.db")
+//	...
+//	cat, err := inventory.Open(db)
+//	...
+//	status, err := chgr.Status()
+//	...
+//	err = cat.Sync(status)
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/kbj/mtx"
+)
+
+// ErrNotFound is returned when a volume is not in the catalog.
+var ErrNotFound = errors.New("inventory: volume not found")
+
+const schema = `
+CREATE TABLE IF NOT EXISTS volumes (
+	serial      TEXT PRIMARY KEY,
+	loc_type    TEXT,
+	loc_num     INTEGER,
+	home        INTEGER NOT NULL,
+	first_seen  INTEGER NOT NULL,
+	last_seen   INTEGER NOT NULL,
+	last_moved  INTEGER NOT NULL,
+	last_loaded INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS history (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	serial    TEXT NOT NULL,
+	time      INTEGER NOT NULL,
+	from_type TEXT,
+	from_num  INTEGER,
+	to_type   TEXT,
+	to_num    INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS history_serial ON history (serial, time);
+`
+
+// Location identifies an element of the library.
+type Location struct {
+	Type mtx.SlotType
+	Num  int
+}
+
+// Volume is a cataloged volume.
+type Volume struct {
+	Serial string
+
+	// Location is the last known location of the volume. It is nil if the
+	// volume is no longer in the library.
+	Location *Location
+
+	// Home is the home slot of the volume as last reported by the library.
+	Home int
+
+	FirstSeen time.Time
+	LastSeen  time.Time
+	LastMoved time.Time
+
+	// LastLoaded is the time the volume was last seen arriving in a drive,
+	// or zero if it never was.
+	LastLoaded time.Time
+}
+
+// Movement records a change of location of a volume. A nil From means the
+// volume entered the library, a nil To that it left.
+type Movement struct {
+	Serial   string
+	Time     time.Time
+	From, To *Location
+}
+
+// Catalog is an SQLite backed volume catalog.
+type Catalog struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// Open returns a catalog stored in db, creating the tables if needed.
+func Open(db *sql.DB) (*Catalog, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, err
+	}
+
+	return &Catalog{db: db, now: time.Now}, nil
+}
+
+var typeNames = map[mtx.SlotType]string{
+	mtx.DataTransferSlot: "drive",
+	mtx.StorageSlot:      "storage",
+	mtx.MailSlot:         "mail",
+}
+
+func typeOf(name string) mtx.SlotType {
+	for typ, n := range typeNames {
+		if n == name {
+			return typ
+		}
+	}
+
+	return mtx.StorageSlot
+}
+
+// nullable returns the column values of loc.
+func (loc *Location) nullable() (sql.NullString, sql.NullInt64) {
+	if loc == nil {
+		return sql.NullString{}, sql.NullInt64{}
+	}
+
+	return sql.NullString{String: typeNames[loc.Type], Valid: true},
+		sql.NullInt64{Int64: int64(loc.Num), Valid: true}
+}
+
+func location(typ sql.NullString, num sql.NullInt64) *Location {
+	if !typ.Valid {
+		return nil
+	}
+
+	return &Location{Type: typeOf(typ.String), Num: int(num.Int64)}
+}
+
+func (loc *Location) equal(other *Location) bool {
+	if loc == nil || other == nil {
+		return loc == other
+	}
+
+	return *loc == *other
+}
+
+// Sync reconciles the catalog with status. New volumes are added, moved
+// volumes are updated and volumes no longer present are marked as gone.
+// Every change of location is recorded in the history, and the load time of
+// volumes that arrived in a drive is updated. Volumes without a serial are
+// ignored, and if a serial occurs more than once, only its first occurrence
+// is considered.
+func (cat *Catalog) Sync(status *mtx.Status) error {
+	now := cat.now().UnixNano()
+
+	current := make(map[string]*mtx.Slot)
+	for _, slot := range append(append([]*mtx.Slot{}, status.Drives...), status.Slots...) {
+		if slot.Vol == nil || slot.Vol.Serial == "" {
+			continue
+		}
+
+		if _, ok := current[slot.Vol.Serial]; !ok {
+			current[slot.Vol.Serial] = slot
+		}
+	}
+
+	tx, err := cat.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	known := make(map[string]*Location)
+
+	rows, err := tx.Query(`SELECT serial, loc_type, loc_num FROM volumes`)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var serial string
+		var typ sql.NullString
+		var num sql.NullInt64
+
+		if err := rows.Scan(&serial, &typ, &num); err != nil {
+			rows.Close()
+			return err
+		}
+
+		known[serial] = location(typ, num)
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for serial, slot := range current {
+		loc := &Location{Type: slot.Type, Num: slot.Num}
+		typ, num := loc.nullable()
+
+		prev, ok := known[serial]
+		switch {
+		case !ok:
+			_, err = tx.Exec(`INSERT INTO volumes (serial, loc_type, loc_num, home, first_seen, last_seen, last_moved)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`, serial, typ, num, slot.Vol.Home, now, now, now)
+		case !prev.equal(loc):
+			_, err = tx.Exec(`UPDATE volumes SET loc_type = ?, loc_num = ?, home = ?, last_seen = ?, last_moved = ?
+				WHERE serial = ?`, typ, num, slot.Vol.Home, now, now, serial)
+		default:
+			_, err = tx.Exec(`UPDATE volumes SET home = ?, last_seen = ? WHERE serial = ?`,
+				slot.Vol.Home, now, serial)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if ok && prev.equal(loc) {
+			continue
+		}
+
+		if err := record(tx, serial, now, prev, loc); err != nil {
+			return err
+		}
+
+		if loc.Type == mtx.DataTransferSlot {
+			if _, err := tx.Exec(`UPDATE volumes SET last_loaded = ? WHERE serial = ?`, now, serial); err != nil {
+				return err
+			}
+		}
+	}
+
+	for serial, prev := range known {
+		if _, ok := current[serial]; ok || prev == nil {
+			continue
+		}
+
+		if _, err := tx.Exec(`UPDATE volumes SET loc_type = NULL, loc_num = NULL, last_moved = ?
+			WHERE serial = ?`, now, serial); err != nil {
+			return err
+		}
+
+		if err := record(tx, serial, now, prev, nil); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func record(tx *sql.Tx, serial string, now int64, from, to *Location) error {
+	fromType, fromNum := from.nullable()
+	toType, toNum := to.nullable()
+
+	_, err := tx.Exec(`INSERT INTO history (serial, time, from_type, from_num, to_type, to_num)
+		VALUES (?, ?, ?, ?, ?, ?)`, serial, now, fromType, fromNum, toType, toNum)
+
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVolume(row scanner) (*Volume, error) {
+	var vol Volume
+	var typ sql.NullString
+	var num sql.NullInt64
+	var first, last, moved int64
+	var loaded sql.NullInt64
+
+	if err := row.Scan(&vol.Serial, &typ, &num, &vol.Home, &first, &last, &moved, &loaded); err != nil {
+		return nil, err
+	}
+
+	vol.Location = location(typ, num)
+	vol.FirstSeen = time.Unix(0, first)
+	vol.LastSeen = time.Unix(0, last)
+	vol.LastMoved = time.Unix(0, moved)
+
+	if loaded.Valid {
+		vol.LastLoaded = time.Unix(0, loaded.Int64)
+	}
+
+	return &vol, nil
+}
+
+const volumeColumns = `serial, loc_type, loc_num, home, first_seen, last_seen, last_moved, last_loaded`
+
+// Volume returns the cataloged volume with the given serial.
+func (cat *Catalog) Volume(serial string) (*Volume, error) {
+	row := cat.db.QueryRow(`SELECT `+volumeColumns+` FROM volumes WHERE serial = ?`, serial)
+
+	vol, err := scanVolume(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return vol, err
+}
+
+// Volumes returns all cataloged volumes ordered by serial, including those
+// no longer in the library.
+func (cat *Catalog) Volumes() ([]*Volume, error) {
+	return cat.query(`SELECT ` + volumeColumns + ` FROM volumes ORDER BY serial`)
+}
+
+// LeastRecentlyLoaded returns up to limit volumes in the storage slots of
+// the library, least recently loaded first. Volumes never loaded come
+// first, ordered by serial. A limit of zero returns all of them.
+func (cat *Catalog) LeastRecentlyLoaded(limit int) ([]*Volume, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+
+	return cat.query(`SELECT `+volumeColumns+` FROM volumes WHERE loc_type = ?
+		ORDER BY last_loaded IS NOT NULL, last_loaded, serial LIMIT ?`, typeNames[mtx.StorageSlot], limit)
+}
+
+// query returns the volumes selected by the query.
+func (cat *Catalog) query(query string, args ...interface{}) ([]*Volume, error) {
+	rows, err := cat.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vols []*Volume
+	for rows.Next() {
+		vol, err := scanVolume(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		vols = append(vols, vol)
+	}
+
+	return vols, rows.Err()
+}
+
+// History returns the recorded movements of the volume with the given
+// serial, oldest first.
+func (cat *Catalog) History(serial string) ([]*Movement, error) {
+	rows, err := cat.db.Query(`SELECT time, from_type, from_num, to_type, to_num FROM history
+		WHERE serial = ? ORDER BY time, id`, serial)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moves []*Movement
+	for rows.Next() {
+		var t int64
+		var fromType, toType sql.NullString
+		var fromNum, toNum sql.NullInt64
+
+		if err := rows.Scan(&t, &fromType, &fromNum, &toType, &toNum); err != nil {
+			return nil, err
+		}
+
+		moves = append(moves, &Movement{
+			Serial: serial,
+			Time:   time.Unix(0, t),
+			From:   location(fromType, fromNum),
+			To:     location(toType, toNum),
+		})
+	}
+
+	return moves, rows.Err()
+}
+
+// LastSlot returns the storage slot the volume with the given serial was
+// last seen in, or 0 if it never was.
+func (cat *Catalog) LastSlot(serial string) (int, error) {
+	var num int
+	err := cat.db.QueryRow(`SELECT to_num FROM history WHERE serial = ? AND to_type = ?
+		ORDER BY time DESC, id DESC LIMIT 1`, serial, typeNames[mtx.StorageSlot]).Scan(&num)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return num, err
+}
+
+// Placement returns an mtx.Placement that files imported volumes in the
+// storage slot they were last seen in, as recorded by the catalog. Volumes
+// the catalog has no slot for, or whose slot is taken, are placed by
+// fallback.
+func (cat *Catalog) Placement(fallback mtx.Placement) mtx.Placement {
+	return mtx.Home(func(serial string) int {
+		num, err := cat.LastSlot(serial)
+		if err != nil {
+			return 0
+		}
+
+		return num
+	}, fallback)
+}
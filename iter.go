@@ -0,0 +1,89 @@
+package mtx
+
+import (
+	"context"
+	"iter"
+)
+
+// AllSlots returns an iterator over the drives and then the slots of
+// status.
+func (status *Status) AllSlots() iter.Seq[*Slot] {
+	return func(yield func(*Slot) bool) {
+		for _, slot := range status.Drives {
+			if !yield(slot) {
+				return
+			}
+		}
+
+		for _, slot := range status.Slots {
+			if !yield(slot) {
+				return
+			}
+		}
+	}
+}
+
+// Occupied returns an iterator over the drives and slots of status that hold
+// a volume, in the order of AllSlots.
+func (status *Status) Occupied() iter.Seq[*Slot] {
+	return status.Matching(IsFull)
+}
+
+// Matching returns an iterator over the drives and slots of status matching
+// all of the given predicates, in the order of AllSlots.
+func (status *Status) Matching(preds ...Predicate) iter.Seq[*Slot] {
+	return func(yield func(*Slot) bool) {
+	next:
+		for slot := range status.AllSlots() {
+			for _, pred := range preds {
+				if !pred(slot) {
+					continue next
+				}
+			}
+
+			if !yield(slot) {
+				return
+			}
+		}
+	}
+}
+
+// IterVolumes returns an iterator over the volumes in the library and the
+// elements holding them, drives first. The status is queried when iteration
+// starts; if the query fails, or ctx is done before iteration completes, the
+// error is yielded with a nil slot and iteration stops.
+func (chgr *Changer) IterVolumes(ctx context.Context) iter.Seq2[*Slot, error] {
+	return func(yield func(*Slot, error) bool) {
+		status, err := chgr.statusContext(ctx)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for slot := range status.Occupied() {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(slot, nil) {
+				return
+			}
+		}
+	}
+}
+
+// statusContext is like Status, but returns early with the context error if
+// ctx is done before the status query completes.
+func (chgr *Changer) statusContext(ctx context.Context) (*Status, error) {
+	chgr.mu.Lock()
+	if chgr.cache != nil {
+		status := chgr.cache.Clone()
+		chgr.mu.Unlock()
+
+		return status, nil
+	}
+	chgr.mu.Unlock()
+
+	return chgr.queryStatus(ctx)
+}
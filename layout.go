@@ -0,0 +1,246 @@
+package mtx
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Layout assigns volumes, by serial, to the elements they should be in.
+// Storage and mail slots are identified by number alone.
+type Layout map[string]Element
+
+// CheckPlan reports whether the moves of plan can be performed in order on
+// the library described by status: every move must take a volume from an
+// existing, full element to an existing, empty one, taking the moves before
+// it into account. Unloads to slot 0 go to the home slot of the volume.
+func (status *Status) CheckPlan(plan *MovePlan) error {
+	occ := newOccupancy(status)
+	for i, mv := range plan.Moves {
+		if err := occ.apply(mv); err != nil {
+			return fmt.Errorf("mtx: move %d (%s): %w", i, mv, err)
+		}
+	}
+
+	return nil
+}
+
+// PlanLayout returns the moves bringing the volumes of layout to their
+// elements, computed from the current status. Volumes already in place are
+// not moved. Volumes blocking each other, and volumes going from one drive
+// to another, are first moved out of the way to an empty storage slot that
+// is not in layout, is not the home slot of a loaded volume and is allowed
+// by the policy of the changer.
+//
+// The elements of layout must be empty or hold volumes of layout, so that
+// no volume outside of it has to be moved.
+func (chgr *Changer) PlanLayout(layout Layout) (*MovePlan, error) {
+	status, err := chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	return planLayout(status, layout, chgr.allowedSlot, false)
+}
+
+// planLayout computes the moves of PlanLayout. If evict is set, volumes not
+// in layout holding elements of it are moved out of the way first instead
+// of failing the plan.
+func planLayout(status *Status, layout Layout, parkable Predicate, evict bool) (*MovePlan, error) {
+	occ := newOccupancy(status)
+
+	where := make(map[string]Element)
+	for e, vol := range occ.vols {
+		if vol.Serial != "" {
+			where[vol.Serial] = e
+		}
+	}
+
+	serials := make([]string, 0, len(layout))
+	for serial := range layout {
+		serials = append(serials, serial)
+	}
+
+	slices.Sort(serials)
+
+	var evictees []Element
+	targets := make(map[Element]string)
+	for _, serial := range serials {
+		dst := elementKey(layout[serial])
+
+		if _, ok := where[serial]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrVolumeNotFound, serial)
+		}
+
+		if !occ.exists[dst] {
+			return nil, fmt.Errorf("%w: %s for %s", ErrInvalidElement, dst, serial)
+		}
+
+		if other, ok := targets[dst]; ok {
+			return nil, fmt.Errorf("%w: %s and %s both assigned to %s", ErrInvalidMove, other, serial, dst)
+		}
+
+		targets[dst] = serial
+
+		if vol := occ.vols[dst]; vol != nil {
+			if _, ok := layout[vol.Serial]; vol.Serial == "" || !ok {
+				if !evict {
+					return nil, fmt.Errorf("%w: %s for %s holds a volume not in the layout", ErrFull, dst, serial)
+				}
+
+				evictees = append(evictees, dst)
+			}
+		}
+	}
+
+	plan := new(MovePlan)
+	move := func(serial string, dst Element) {
+		mv := moveBetween(where[serial], dst)
+		occ.apply(mv)
+		plan.Moves = append(plan.Moves, mv)
+		where[serial] = dst
+	}
+
+	for _, e := range evictees {
+		park := occ.parking(status, targets, parkable)
+		if park == nil {
+			return nil, fmt.Errorf("%w to move the volume in %s out of the way", ErrNoStorageSlot, e)
+		}
+
+		mv := moveBetween(e, *park)
+		occ.apply(mv)
+		plan.Moves = append(plan.Moves, mv)
+	}
+
+	var pending []string
+	for _, serial := range serials {
+		if where[serial] != elementKey(layout[serial]) {
+			pending = append(pending, serial)
+		}
+	}
+
+	for len(pending) > 0 {
+		var blocked []string
+		for _, serial := range pending {
+			src, dst := where[serial], elementKey(layout[serial])
+			if occ.vols[dst] != nil || src.IsDrive() && dst.IsDrive() {
+				blocked = append(blocked, serial)
+				continue
+			}
+
+			move(serial, dst)
+		}
+
+		if len(blocked) == len(pending) {
+			// the volumes block each other; parking one breaks the cycle
+			park := occ.parking(status, targets, parkable)
+			if park == nil {
+				return nil, fmt.Errorf("%w to move %s out of the way", ErrNoStorageSlot, blocked[0])
+			}
+
+			move(blocked[0], *park)
+		}
+
+		pending = blocked
+	}
+
+	return plan, nil
+}
+
+// moveBetween returns the move taking a volume from src to dst.
+func moveBetween(src, dst Element) Move {
+	switch {
+	case src.IsDrive():
+		return Move{Type: MoveUnload, Src: src.Num, Dst: dst.Num}
+	case dst.IsDrive():
+		return Move{Type: MoveLoad, Src: src.Num, Dst: dst.Num}
+	}
+
+	return Move{Type: MoveTransfer, Src: src.Num, Dst: dst.Num}
+}
+
+// elementKey returns e with slots reported as storage slots, as in the
+// elements of moves.
+func elementKey(e Element) Element {
+	if !e.IsDrive() {
+		e.Type = StorageSlot
+	}
+
+	return e
+}
+
+// occupancy tracks the volumes in the elements of a status while moves are
+// checked or planned, keyed by elementKey.
+type occupancy struct {
+	vols   map[Element]*Volume
+	exists map[Element]bool
+}
+
+func newOccupancy(status *Status) *occupancy {
+	occ := &occupancy{
+		vols:   make(map[Element]*Volume),
+		exists: make(map[Element]bool),
+	}
+
+	for slot := range status.AllSlots() {
+		e := elementKey(slot.Element())
+		occ.exists[e] = true
+		if slot.Vol != nil {
+			occ.vols[e] = slot.Vol
+		}
+	}
+
+	return occ
+}
+
+// apply performs mv on the tracked elements.
+func (occ *occupancy) apply(mv Move) error {
+	src, dst := mv.elements()
+
+	if !occ.exists[src] {
+		return fmt.Errorf("%w: %s", ErrInvalidElement, src)
+	}
+
+	vol := occ.vols[src]
+	if vol == nil {
+		return fmt.Errorf("%w: %s", ErrEmpty, src)
+	}
+
+	if mv.Type == MoveUnload && dst.Num == 0 {
+		// slot 0 is the home slot of the volume
+		dst.Num = vol.Home
+	}
+
+	if !occ.exists[dst] {
+		return fmt.Errorf("%w: %s", ErrInvalidElement, dst)
+	}
+
+	if occ.vols[dst] != nil {
+		return fmt.Errorf("%w: %s", ErrFull, dst)
+	}
+
+	delete(occ.vols, src)
+	occ.vols[dst] = vol
+
+	return nil
+}
+
+// parking returns the first empty storage slot of status matching parkable
+// that is neither a target nor the home slot of a loaded volume, or nil if
+// there is none.
+func (occ *occupancy) parking(status *Status, targets map[Element]string, parkable Predicate) *Element {
+	homes := make(map[int]bool)
+	for e, vol := range occ.vols {
+		if e.IsDrive() {
+			homes[vol.Home] = true
+		}
+	}
+
+	for _, slot := range Filter(status.Slots, IsStorage, parkable) {
+		e := elementKey(slot.Element())
+		if _, ok := targets[e]; !ok && occ.vols[e] == nil && !homes[e.Num] {
+			return &e
+		}
+	}
+
+	return nil
+}
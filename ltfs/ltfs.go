@@ -0,0 +1,242 @@
+// Package ltfs mounts library volumes as LTFS filesystems.
+//
+// It drives the LTFS reference implementation programs (ltfs, mkltfs and
+// ltfsck) and combines them with a changer to mount a volume by barcode:
+//
+//	drives, err := devmap.Map(ctx, "/dev/sg4")
+//	...
+//	fs := ltfs.New()
+//	m, err := fs.MountVolume(ctx, chgr, drives, "A00001L6", "/mnt/ltfs", false)
+//	...
+//	defer fs.UnmountVolume(ctx, chgr, m)
+package ltfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/devmap"
+	"github.com/kbj/mtx/drive"
+	"github.com/kbj/mtx/scsi"
+)
+
+// ErrNoDrive is returned by MountVolume when no attached drive is free.
+var ErrNoDrive = errors.New("ltfs: no free drive")
+
+// FS runs the LTFS programs.
+type FS struct {
+	exec scsi.Executor
+
+	ltfs   string
+	mkltfs string
+	ltfsck string
+	umount string
+}
+
+// New returns an FS running the programs on the local host.
+func New() *FS {
+	return NewWithExecutor(scsi.ExecExecutor{})
+}
+
+// NewWithExecutor returns an FS running the programs through exec.
+func NewWithExecutor(exec scsi.Executor) *FS {
+	return &FS{
+		exec:   exec,
+		ltfs:   "/usr/local/bin/ltfs",
+		mkltfs: "/usr/local/bin/mkltfs",
+		ltfsck: "/usr/local/bin/ltfsck",
+		umount: "/bin/umount",
+	}
+}
+
+func (fs *FS) run(ctx context.Context, name string, args ...string) error {
+	_, stderr, code, err := fs.exec.Run(ctx, name, args...)
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return &scsi.ExitError{Code: code, Stderr: stderr}
+	}
+
+	return nil
+}
+
+// Format formats the tape in the drive at dev with LTFS, using serial as
+// its volume serial.
+func (fs *FS) Format(ctx context.Context, dev, serial string) error {
+	return fs.run(ctx, fs.mkltfs, "-d", dev, "-s", serial)
+}
+
+// Check checks the LTFS filesystem on the tape in the drive at dev. It
+// returns nil if the tape holds a consistent LTFS filesystem and a
+// *scsi.ExitError otherwise, including when the tape is not formatted.
+func (fs *FS) Check(ctx context.Context, dev string) error {
+	return fs.run(ctx, fs.ltfsck, dev)
+}
+
+// Mount mounts the tape in the drive at dev on mountpoint.
+func (fs *FS) Mount(ctx context.Context, dev, mountpoint string) error {
+	return fs.run(ctx, fs.ltfs, "-o", "devname="+dev, mountpoint)
+}
+
+// Unmount unmounts the filesystem at mountpoint. LTFS writes its index
+// before the unmount completes, which may take a while.
+func (fs *FS) Unmount(ctx context.Context, mountpoint string) error {
+	return fs.run(ctx, fs.umount, mountpoint)
+}
+
+// Mounted returns whether an LTFS filesystem is mounted on mountpoint, and
+// its source, e.g. "ltfs:/dev/sg3".
+func Mounted(mountpoint string) (bool, string, error) {
+	buf, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return false, "", err
+	}
+
+	return parseMounts(buf, mountpoint)
+}
+
+func parseMounts(buf []byte, mountpoint string) (bool, string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != mountpoint {
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "ltfs") {
+			return true, fields[0], nil
+		}
+	}
+
+	return false, "", scanner.Err()
+}
+
+// Mount describes a volume mounted by MountVolume.
+type Mount struct {
+	Serial     string
+	Mountpoint string
+
+	// Drive is the drive the volume is loaded in.
+	Drive devmap.Drive
+
+	// Home is the slot the volume is returned to by UnmountVolume.
+	Home int
+}
+
+// device returns the device node LTFS should use for drv. The LTFS sg
+// backend wants the scsi generic device.
+func device(drv devmap.Drive) string {
+	if drv.Generic != "" {
+		return drv.Generic
+	}
+
+	return drv.Tape
+}
+
+// MountVolume loads the volume with the given serial into a free drive among
+// drives, waits for the drive to become ready and mounts the volume on
+// mountpoint. If the volume is already loaded into one of drives, that drive
+// is used. If format is true, a volume that fails the LTFS check is
+// formatted first; otherwise the check error is returned and the volume is
+// left in the drive.
+func (fs *FS) MountVolume(ctx context.Context, chgr *mtx.Changer, drives []devmap.Drive, serial, mountpoint string, format bool) (*Mount, error) {
+	status, err := chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var slot *mtx.Slot
+	for _, s := range append(append([]*mtx.Slot(nil), status.Drives...), status.Slots...) {
+		if s.Vol != nil && s.Vol.Serial == serial {
+			slot = s
+			break
+		}
+	}
+
+	if slot == nil {
+		return nil, mtx.ErrVolumeNotFound
+	}
+
+	m := &Mount{Serial: serial, Mountpoint: mountpoint, Home: slot.Vol.Home}
+
+	if slot.Type == mtx.DataTransferSlot {
+		found := false
+		for _, d := range drives {
+			if d.Num == slot.Num && d.Tape != "" {
+				m.Drive, found = d, true
+				break
+			}
+		}
+
+		if !found {
+			return nil, ErrNoDrive
+		}
+	} else {
+		found := false
+		for _, d := range drives {
+			if d.Tape == "" || d.Num >= len(status.Drives) || status.Drives[d.Num].Vol != nil {
+				continue
+			}
+
+			m.Drive, found = d, true
+			break
+		}
+
+		if !found {
+			return nil, ErrNoDrive
+		}
+
+		m.Home = slot.Num
+		if err := chgr.Load(mtx.SlotNum(slot.Num), mtx.DriveNum(m.Drive.Num)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := drive.New(m.Drive.Tape).WaitReady(ctx, drive.DefaultPollInterval); err != nil {
+		return nil, err
+	}
+
+	dev := device(m.Drive)
+	if err := fs.Check(ctx, dev); err != nil {
+		if !format {
+			return nil, err
+		}
+
+		if err := fs.Format(ctx, dev, ltfsSerial(serial)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := fs.Mount(ctx, dev, mountpoint); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ltfsSerial returns the six character volume serial LTFS expects from a
+// barcode, which usually carries a two character media type suffix.
+func ltfsSerial(barcode string) string {
+	if len(barcode) > 6 {
+		return barcode[:6]
+	}
+
+	return barcode
+}
+
+// UnmountVolume unmounts a volume mounted by MountVolume, takes the drive
+// offline and returns the volume to its home slot.
+func (fs *FS) UnmountVolume(ctx context.Context, chgr *mtx.Changer, m *Mount) error {
+	if err := fs.Unmount(ctx, m.Mountpoint); err != nil {
+		return err
+	}
+
+	return drive.Unmount(ctx, chgr, drive.New(m.Drive.Tape), m.Drive.Num, m.Home)
+}
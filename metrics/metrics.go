@@ -0,0 +1,160 @@
+// Package metrics instruments a library changer with Prometheus metrics.
+//
+// A Changer wraps another mtx.Interface implementation and counts the
+// commands performed, their outcome and their latency. Whenever a status
+// command succeeds, gauges describing the occupancy of the library are
+// updated from its output. The Changer is a prometheus.Collector and must be
+// registered to be exported:
+//
+//	impl := metrics.New(scsi.New("/dev/sg3"))
+//	prometheus.MustRegister(impl)
+//	chgr := mtx.NewChanger(impl)
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kbj/mtx"
+)
+
+// Changer is an mtx.Interface collecting metrics about the wrapped
+// implementation.
+type Changer struct {
+	impl mtx.Interface
+
+	commands *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+
+	drives         prometheus.Gauge
+	drivesLoaded   prometheus.Gauge
+	slots          prometheus.Gauge
+	slotsOccupied  prometheus.Gauge
+	mailSlots      prometheus.Gauge
+	mailSlotsFree  prometheus.Gauge
+	lastStatusTime prometheus.Gauge
+}
+
+// New returns a new instrumented changer implementation wrapping impl. The
+// metrics are named mtx_*.
+func New(impl mtx.Interface) *Changer {
+	return NewWithLabels(impl, nil)
+}
+
+// NewWithLabels is like New, but adds constant labels to all metrics, which
+// is useful when instrumenting more than one library.
+func NewWithLabels(impl mtx.Interface, labels prometheus.Labels) *Changer {
+	gauge := func(name, help string) prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "mtx", Name: name, Help: help, ConstLabels: labels,
+		})
+	}
+
+	return &Changer{
+		impl: impl,
+
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "mtx",
+			Name:        "commands_total",
+			Help:        "Number of changer commands performed by command and outcome.",
+			ConstLabels: labels,
+		}, []string{"command", "outcome"}),
+
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "mtx",
+			Name:        "command_duration_seconds",
+			Help:        "Latency of changer commands.",
+			ConstLabels: labels,
+
+			// robot moves take anywhere from seconds to minutes
+			Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+		}, []string{"command"}),
+
+		drives:         gauge("drives", "Number of data transfer elements."),
+		drivesLoaded:   gauge("drives_loaded", "Number of data transfer elements holding a volume."),
+		slots:          gauge("slots", "Number of storage slots."),
+		slotsOccupied:  gauge("slots_occupied", "Number of storage slots holding a volume."),
+		mailSlots:      gauge("mail_slots", "Number of import/export slots."),
+		mailSlotsFree:  gauge("mail_slots_free", "Number of empty import/export slots."),
+		lastStatusTime: gauge("last_status_timestamp_seconds", "Time of the last successful status command."),
+	}
+}
+
+// Do performs the command using the wrapped implementation and records
+// metrics about it.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	cmd := "none"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	start := time.Now()
+	out, err := chgr.impl.Do(args...)
+	chgr.duration.WithLabelValues(cmd).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		chgr.commands.WithLabelValues(cmd, "error").Inc()
+		return out, err
+	}
+
+	chgr.commands.WithLabelValues(cmd, "success").Inc()
+
+	if cmd == "status" {
+		if status, err := mtx.ParseStatus(out); err == nil {
+			chgr.observe(status)
+		}
+	}
+
+	return out, nil
+}
+
+// observe updates the gauges from status.
+func (chgr *Changer) observe(status *mtx.Status) {
+	var loaded, occupied, mailFree int
+
+	for _, slot := range status.Drives {
+		if slot.Vol != nil {
+			loaded++
+		}
+	}
+
+	for _, slot := range status.Slots {
+		switch {
+		case slot.Type == mtx.StorageSlot && slot.Vol != nil:
+			occupied++
+		case slot.Type == mtx.MailSlot && slot.Vol == nil:
+			mailFree++
+		}
+	}
+
+	chgr.drives.Set(float64(len(status.Drives)))
+	chgr.drivesLoaded.Set(float64(loaded))
+	chgr.slots.Set(float64(status.NumStorageSlots))
+	chgr.slotsOccupied.Set(float64(occupied))
+	chgr.mailSlots.Set(float64(status.NumMailSlots))
+	chgr.mailSlotsFree.Set(float64(mailFree))
+	chgr.lastStatusTime.SetToCurrentTime()
+}
+
+func (chgr *Changer) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		chgr.commands, chgr.duration,
+		chgr.drives, chgr.drivesLoaded, chgr.slots, chgr.slotsOccupied,
+		chgr.mailSlots, chgr.mailSlotsFree, chgr.lastStatusTime,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (chgr *Changer) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range chgr.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (chgr *Changer) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range chgr.collectors() {
+		c.Collect(ch)
+	}
+}
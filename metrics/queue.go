@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// QueueWait records the time jobs spend queued by a scheduler, per priority
+// class. Its Observe method is meant for scheduler.WithWaitObserver:
+//
+//	wait := metrics.NewQueueWait(nil, nil)
+//	prometheus.MustRegister(wait)
+//	sched := scheduler.New(chgr, scheduler.WithWaitObserver(wait.Observe))
+type QueueWait struct {
+	class func(priority int) string
+	wait  *prometheus.HistogramVec
+}
+
+// NewQueueWait returns a collector of queue wait times, named
+// mtx_queue_wait_seconds and labeled by the class returned by class for
+// the priority of the job, e.g. "restore" and "reorganization". A nil
+// class labels jobs with their priority. Like in NewWithLabels, labels are
+// added to the metric.
+func NewQueueWait(class func(priority int) string, labels prometheus.Labels) *QueueWait {
+	if class == nil {
+		class = strconv.Itoa
+	}
+
+	return &QueueWait{
+		class: class,
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "mtx",
+			Name:        "queue_wait_seconds",
+			Help:        "Time jobs spent queued before being started by priority class.",
+			ConstLabels: labels,
+
+			// jobs wait behind moves taking seconds to minutes each
+			Buckets: []float64{0.1, 1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+		}, []string{"class"}),
+	}
+}
+
+// Observe records that a job of the given priority waited for wait.
+func (q *QueueWait) Observe(priority int, wait time.Duration) {
+	q.wait.WithLabelValues(q.class(priority)).Observe(wait.Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (q *QueueWait) Describe(ch chan<- *prometheus.Desc) {
+	q.wait.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (q *QueueWait) Collect(ch chan<- prometheus.Metric) {
+	q.wait.Collect(ch)
+}
@@ -0,0 +1,278 @@
+// Package mhvtl helps running integration tests against an mhvtl virtual
+// tape library.
+//
+// mhvtl emulates tape libraries at the kernel level, so it exercises the
+// real 'mtx' program and the scsi backend. A test uses it like this:
+//
+//	func TestLoad(t *testing.T) {
+//		lib := mhvtl.Require(t)
+//		chgr := lib.Changer()
+//		...
+//	}
+//
+// Require skips the test if mhvtl is not running, and restores the inventory
+// of the library as found on first use when the test finishes.
+package mhvtl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/scsi"
+)
+
+// ErrNotRunning is returned by Detect when no mhvtl changer is present.
+var ErrNotRunning = errors.New("mhvtl: no mhvtl changer found")
+
+// Library is an mhvtl library.
+type Library struct {
+	// Device is the scsi generic device of the changer, e.g. /dev/sg9.
+	Device string
+
+	chgr    *mtx.Changer
+	initial *mtx.Status
+}
+
+// Detect looks for an mhvtl changer on the host. mhvtl devices are attached
+// to a pseudo adapter; the first medium changer on one is returned.
+func Detect() (*Library, error) {
+	return detect("/sys")
+}
+
+func detect(sysfs string) (*Library, error) {
+	if _, err := os.Stat(filepath.Join(sysfs, "module", "mhvtl")); err != nil {
+		return nil, ErrNotRunning
+	}
+
+	dir := filepath.Join(sysfs, "class", "scsi_generic")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) < len(names[j]) || len(names[i]) == len(names[j]) && names[i] < names[j]
+	})
+
+	for _, name := range names {
+		buf, err := os.ReadFile(filepath.Join(dir, name, "device", "type"))
+		if err != nil || strings.TrimSpace(string(buf)) != "8" {
+			continue
+		}
+
+		path, err := filepath.EvalSymlinks(filepath.Join(dir, name, "device"))
+		if err != nil || !strings.Contains(path, "/pseudo") {
+			continue
+		}
+
+		dev := "/dev/" + name
+
+		return &Library{Device: dev, chgr: mtx.NewChanger(scsi.New(dev))}, nil
+	}
+
+	return nil, ErrNotRunning
+}
+
+// Changer returns a changer for the library.
+func (lib *Library) Changer() *mtx.Changer {
+	return lib.chgr
+}
+
+// Snapshot records the current inventory as the state restored by Reset.
+func (lib *Library) Snapshot() error {
+	status, err := lib.chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	lib.initial = status
+
+	return nil
+}
+
+// Reset moves the volumes back to where they were when Snapshot was last
+// called. Volumes that have since disappeared are ignored.
+func (lib *Library) Reset() error {
+	if lib.initial == nil {
+		return errors.New("mhvtl: no snapshot to reset to")
+	}
+
+	return Restore(lib.chgr, lib.initial)
+}
+
+// Restore moves the volumes in the library of chgr to the locations they
+// have in want. Volumes are matched by serial; unlabeled volumes and
+// volumes not in want are left where they are unless they are in the way.
+func Restore(chgr *mtx.Changer, want *mtx.Status) error {
+	status, err := chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	// empty the drives first, so every volume is in a slot
+	for _, drv := range status.Drives {
+		if drv.Vol == nil {
+			continue
+		}
+
+		slot := drv.Vol.Home
+		if slot < 1 || slot > len(status.Slots) || status.Slots[slot-1].Vol != nil {
+			if slot = freeSlot(status, nil); slot == 0 {
+				return errors.New("mhvtl: no free slot to unload drive into")
+			}
+		}
+
+		if err := chgr.Unload(mtx.SlotNum(slot), mtx.DriveNum(drv.Num)); err != nil {
+			return err
+		}
+
+		status.Slots[slot-1].Vol, drv.Vol = drv.Vol, nil
+	}
+
+	targets := make(map[int]bool)
+	for _, slot := range want.Slots {
+		if slot.Vol != nil {
+			targets[slot.Num] = true
+		}
+	}
+
+	// home slots of volumes that should end up in a drive
+	for _, drv := range want.Drives {
+		if drv.Vol != nil {
+			targets[drv.Vol.Home] = true
+		}
+	}
+
+	place := func(serial string, dst int) error {
+		src := find(status, serial)
+		if src == 0 || src == dst || dst < 1 || dst > len(status.Slots) {
+			return nil
+		}
+
+		if status.Slots[dst-1].Vol != nil {
+			// prefer a slot nothing should end up in, but make do with
+			// any; the volume in the way is placed again later if needed
+			free := freeSlot(status, targets)
+			if free == 0 {
+				free = freeSlot(status, map[int]bool{dst: true})
+			}
+
+			if free == 0 {
+				return errors.New("mhvtl: no free slot to move volume out of the way")
+			}
+
+			if err := chgr.Transfer(mtx.SlotNum(dst), mtx.SlotNum(free)); err != nil {
+				return err
+			}
+
+			status.Slots[free-1].Vol, status.Slots[dst-1].Vol = status.Slots[dst-1].Vol, nil
+		}
+
+		if err := chgr.Transfer(mtx.SlotNum(src), mtx.SlotNum(dst)); err != nil {
+			return err
+		}
+
+		status.Slots[dst-1].Vol, status.Slots[src-1].Vol = status.Slots[src-1].Vol, nil
+
+		return nil
+	}
+
+	for _, slot := range want.Slots {
+		if slot.Vol != nil && slot.Vol.Serial != "" {
+			if err := place(slot.Vol.Serial, slot.Num); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, drv := range want.Drives {
+		if drv.Vol == nil || drv.Vol.Serial == "" {
+			continue
+		}
+
+		if err := place(drv.Vol.Serial, drv.Vol.Home); err != nil {
+			return err
+		}
+
+		if src := find(status, drv.Vol.Serial); src != 0 {
+			if err := chgr.Load(mtx.SlotNum(src), mtx.DriveNum(drv.Num)); err != nil {
+				return err
+			}
+
+			status.Slots[src-1].Vol = nil
+		}
+	}
+
+	return nil
+}
+
+// find returns the slot holding the volume with the given serial, or 0.
+func find(status *mtx.Status, serial string) int {
+	for _, slot := range status.Slots {
+		if slot.Vol != nil && slot.Vol.Serial == serial {
+			return slot.Num
+		}
+	}
+
+	return 0
+}
+
+// freeSlot returns an empty storage slot not in avoid, or 0.
+func freeSlot(status *mtx.Status, avoid map[int]bool) int {
+	for _, slot := range status.Slots {
+		if slot.Type == mtx.StorageSlot && slot.Vol == nil && !avoid[slot.Num] {
+			return slot.Num
+		}
+	}
+
+	return 0
+}
+
+var (
+	once    sync.Once
+	shared  *Library
+	openErr error
+)
+
+// Require returns the mhvtl library, skipping the test if mhvtl is not
+// running. The inventory found on the first call is snapshotted, and
+// restored when each test using the library finishes, so tests always start
+// from the same state.
+func Require(t testing.TB) *Library {
+	t.Helper()
+
+	once.Do(func() {
+		shared, openErr = Detect()
+		if openErr == nil {
+			openErr = shared.Snapshot()
+		}
+	})
+
+	if openErr == ErrNotRunning {
+		t.Skip("mhvtl is not running")
+	}
+
+	if openErr != nil {
+		t.Fatalf("mhvtl: %v", openErr)
+	}
+
+	t.Cleanup(func() {
+		if err := shared.Reset(); err != nil {
+			t.Errorf("mhvtl: reset failed: %v", err)
+		}
+	})
+
+	return shared
+}
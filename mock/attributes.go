@@ -0,0 +1,95 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kbj/mtx"
+)
+
+// Attr is a set of media attributes of a mock volume.
+type Attr uint
+
+const (
+	// WORM marks write-once media. Once written, further writes fail.
+	WORM Attr = 1 << iota
+
+	// WriteProtected marks media with the write protect tab set.
+	WriteProtected
+
+	// Cleaning marks a cleaning cartridge regardless of its serial.
+	// Volumes with serials starting with "CLN" are always considered
+	// cleaning cartridges.
+	Cleaning
+
+	// UnknownLabel marks media whose barcode label cannot be read. The
+	// volume tag is not reported in status.
+	UnknownLabel
+)
+
+// Errors returned by SimulateWrite.
+var (
+	ErrWriteProtected = errors.New("mtx/mock: medium is write protected")
+	ErrWORMWritten    = errors.New("mtx/mock: write once medium already written")
+	ErrCleaningMedia  = errors.New("mtx/mock: cannot write to cleaning media")
+)
+
+// WithAttributes sets the attributes of the volume with the given serial.
+func WithAttributes(serial string, attrs Attr) Option {
+	return func(chgr *Changer) {
+		chgr.attrs[serial] = attrs
+	}
+}
+
+// SetAttributes sets the attributes of the volume with the given serial.
+func (chgr *Changer) SetAttributes(serial string, attrs Attr) {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	chgr.attrs[serial] = attrs
+}
+
+// Attributes returns the attributes of the volume with the given serial.
+func (chgr *Changer) Attributes(serial string) Attr {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	return chgr.attrs[serial]
+}
+
+// SimulateWrite simulates the application writing to the volume loaded in
+// the drive drivenum, failing as a real drive would for write protected,
+// already written WORM and cleaning media.
+func (chgr *Changer) SimulateWrite(drivenum int) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if drivenum < 0 || drivenum >= len(chgr.drives) {
+		return fmt.Errorf("mtx/mock: invalid drive %d", drivenum)
+	}
+
+	vol := chgr.drives[drivenum].Vol
+	if vol == nil {
+		return fmt.Errorf("mtx/mock: drive %d is empty", drivenum)
+	}
+
+	attrs := chgr.attrs[vol.Serial]
+
+	switch {
+	case chgr.isCleaning(vol):
+		return ErrCleaningMedia
+	case attrs&WriteProtected != 0:
+		return ErrWriteProtected
+	case attrs&WORM != 0 && chgr.written[vol.Serial]:
+		return ErrWORMWritten
+	}
+
+	chgr.written[vol.Serial] = true
+
+	return nil
+}
+
+// hasLabel reports whether the volume tag of vol can be read.
+func (chgr *Changer) hasLabel(vol *mtx.Volume) bool {
+	return chgr.barcodes && chgr.attrs[vol.Serial]&UnknownLabel == 0
+}
@@ -0,0 +1,169 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kbj/mtx"
+)
+
+// Builder constructs a mock library auto changer with a precise initial
+// layout.
+//
+// Drives are numbered from 0 and slots from 1. Drives and slots that are not
+// declared explicitly, but are numbered below the highest declared number,
+// are created empty. Undeclared slots numbered above the first declared
+// import/export slot become import/export slots; all others become storage
+// slots. Since the mock (like most libraries) numbers import/export slots
+// after storage slots, Build fails if a storage slot is declared after an
+// import/export slot.
+type Builder struct {
+	drives map[int]*mtx.Volume
+	slots  map[int]*mtx.Slot
+	opts   []Option
+
+	maxDrive int
+	maxSlot  int
+	err      error
+}
+
+// NewBuilder returns a new builder for an empty library.
+func NewBuilder() *Builder {
+	return &Builder{
+		drives:   make(map[int]*mtx.Volume),
+		slots:    make(map[int]*mtx.Slot),
+		maxDrive: -1,
+	}
+}
+
+// Drive declares an empty drive.
+func (b *Builder) Drive(num int) *Builder {
+	return b.drive(num, nil)
+}
+
+// LoadedDrive declares a drive loaded with the volume serial whose home is
+// the slot home.
+func (b *Builder) LoadedDrive(num int, serial string, home int) *Builder {
+	return b.drive(num, &mtx.Volume{Serial: serial, Home: home})
+}
+
+// Slot declares a storage slot. If serial is given, the slot holds a volume
+// with that serial.
+func (b *Builder) Slot(num int, serial ...string) *Builder {
+	return b.slot(num, mtx.StorageSlot, serial)
+}
+
+// MailSlot declares an import/export slot. If serial is given, the slot holds
+// a volume with that serial.
+func (b *Builder) MailSlot(num int, serial ...string) *Builder {
+	return b.slot(num, mtx.MailSlot, serial)
+}
+
+// Options adds options passed to the changer on Build.
+func (b *Builder) Options(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+
+	return b
+}
+
+// Build returns the mock library auto changer.
+func (b *Builder) Build() (*Changer, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	chgr := New(0, 0, 0, 0, b.opts...)
+
+	for i := 0; i <= b.maxDrive; i++ {
+		chgr.drives = append(chgr.drives, &mtx.Slot{
+			Num: i, Type: mtx.DataTransferSlot, Vol: b.drives[i],
+		})
+	}
+
+	firstMail := 0
+	for i := 1; i <= b.maxSlot; i++ {
+		slot, ok := b.slots[i]
+		if !ok {
+			slot = &mtx.Slot{Num: i, Type: mtx.StorageSlot}
+			if firstMail > 0 {
+				slot.Type = mtx.MailSlot
+			}
+		}
+
+		switch slot.Type {
+		case mtx.MailSlot:
+			if firstMail == 0 {
+				firstMail = i
+			}
+
+			chgr.numMailSlots++
+		case mtx.StorageSlot:
+			if firstMail > 0 {
+				return nil, fmt.Errorf("mtx/mock: storage slot %d follows import/export slot %d", i, firstMail)
+			}
+
+			chgr.numStorageSlots++
+		}
+
+		chgr.slots = append(chgr.slots, slot)
+	}
+
+	chgr.numDrives = len(chgr.drives)
+
+	return chgr, nil
+}
+
+func (b *Builder) drive(num int, vol *mtx.Volume) *Builder {
+	if num < 0 {
+		b.fail(fmt.Errorf("mtx/mock: invalid drive number %d", num))
+		return b
+	}
+
+	if _, ok := b.drives[num]; ok {
+		b.fail(fmt.Errorf("mtx/mock: drive %d declared twice", num))
+		return b
+	}
+
+	b.drives[num] = vol
+	if num > b.maxDrive {
+		b.maxDrive = num
+	}
+
+	return b
+}
+
+func (b *Builder) slot(num int, typ mtx.SlotType, serial []string) *Builder {
+	if num < 1 {
+		b.fail(fmt.Errorf("mtx/mock: invalid slot number %d", num))
+		return b
+	}
+
+	if _, ok := b.slots[num]; ok {
+		b.fail(fmt.Errorf("mtx/mock: slot %d declared twice", num))
+		return b
+	}
+
+	if len(serial) > 1 {
+		b.fail(errors.New("mtx/mock: more than one volume given for slot"))
+		return b
+	}
+
+	slot := &mtx.Slot{Num: num, Type: typ}
+	if len(serial) == 1 {
+		slot.Vol = &mtx.Volume{Serial: serial[0], Home: num}
+	}
+
+	b.slots[num] = slot
+	if num > b.maxSlot {
+		b.maxSlot = num
+	}
+
+	return b
+}
+
+// fail records the first error encountered; it is returned by Build.
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,55 @@
+package mock
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/kbj/mtx"
+)
+
+// ErrCleaningExpired is returned when loading a cleaning cartridge that has
+// been used up.
+var ErrCleaningExpired = errors.New("mtx/mock: expired cleaning media")
+
+// WithCleaningLimit sets the number of times a cleaning cartridge may be
+// loaded before loads fail with ErrCleaningExpired. A limit of zero (the
+// default) means cleaning cartridges never expire.
+func WithCleaningLimit(n int) Option {
+	return func(chgr *Changer) {
+		chgr.cleaningLimit = n
+	}
+}
+
+// CleaningUses returns the number of times the cleaning cartridge with the
+// given serial has been loaded.
+func (chgr *Changer) CleaningUses(serial string) int {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	return chgr.cleaningUses[serial]
+}
+
+// isCleaning reports whether vol is a cleaning cartridge.
+func (chgr *Changer) isCleaning(vol *mtx.Volume) bool {
+	if vol == nil {
+		return false
+	}
+
+	return strings.HasPrefix(vol.Serial, "CLN") || chgr.attrs[vol.Serial]&Cleaning != 0
+}
+
+// useCleaning registers a load of vol if it is a cleaning cartridge. It
+// returns ErrCleaningExpired if the cartridge is used up.
+func (chgr *Changer) useCleaning(vol *mtx.Volume) error {
+	if !chgr.isCleaning(vol) {
+		return nil
+	}
+
+	if chgr.cleaningLimit > 0 && chgr.cleaningUses[vol.Serial] >= chgr.cleaningLimit {
+		return ErrCleaningExpired
+	}
+
+	chgr.cleaningUses[vol.Serial]++
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kbj/mtx"
+)
+
+var (
+	// ErrDoorOpen is returned when the robot is asked to move while the
+	// library door is open.
+	ErrDoorOpen = errors.New("mtx/mock: library door is open")
+
+	// ErrMagazineMissing is returned when the robot tries to access a
+	// storage slot of a removed magazine.
+	ErrMagazineMissing = errors.New("mtx/mock: magazine is missing")
+)
+
+// WithMagazines groups the storage slots into removable magazines of size
+// slots each, starting at slot 1. The last magazine may be smaller. See
+// RemoveMagazine.
+func WithMagazines(size int) Option {
+	return func(chgr *Changer) {
+		chgr.magazineSize = size
+	}
+}
+
+// checkAccess returns an error if the robot cannot access any of the given
+// slots: ErrDoorOpen if the door is open, ErrStationOpen if a slot is an
+// import/export slot and the station is open, and ErrMagazineMissing if a
+// slot is in a removed magazine.
+func (chgr *Changer) checkAccess(slotnums ...int) error {
+	if chgr.doorOpen {
+		return ErrDoorOpen
+	}
+
+	for _, num := range slotnums {
+		if num > chgr.numStorageSlots {
+			if chgr.stationOpen {
+				return ErrStationOpen
+			}
+
+			continue
+		}
+
+		if chgr.magazineSize > 0 && chgr.removed[(num-1)/chgr.magazineSize] {
+			return ErrMagazineMissing
+		}
+	}
+
+	return nil
+}
+
+// enclosure returns the state of the door, magazines and import/export
+// station.
+func (chgr *Changer) enclosure() *mtx.Enclosure {
+	enc := &mtx.Enclosure{
+		DoorOpen:    chgr.doorOpen,
+		StationOpen: chgr.stationOpen,
+	}
+
+	if chgr.magazineSize <= 0 {
+		return enc
+	}
+
+	for first := 1; first <= chgr.numStorageSlots; first += chgr.magazineSize {
+		enc.Magazines = append(enc.Magazines, mtx.Magazine{
+			First:   first,
+			Last:    min(first+chgr.magazineSize-1, chgr.numStorageSlots),
+			Present: !chgr.removed[(first-1)/chgr.magazineSize],
+		})
+	}
+
+	return enc
+}
+
+// DoorOpen reports whether the library door is open.
+func (chgr *Changer) DoorOpen() bool {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	return chgr.doorOpen
+}
+
+// OpenDoor simulates the operator opening the library door, which stops
+// the robot until it is closed again.
+func (chgr *Changer) OpenDoor() {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	chgr.doorOpen = true
+
+	chgr.emit(OpDoorOpen, nil, nil, nil)
+}
+
+// CloseDoor simulates the operator closing the library door.
+func (chgr *Changer) CloseDoor() {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	chgr.doorOpen = false
+
+	chgr.emit(OpDoorClose, nil, nil, nil)
+}
+
+// RemoveMagazine simulates the operator pulling magazine n, counting from
+// 0, out of the library. The volumes in it stay in their slots, but the
+// robot cannot access them until the magazine is inserted again.
+func (chgr *Changer) RemoveMagazine(n int) error {
+	return chgr.setMagazine(n, true)
+}
+
+// InsertMagazine simulates the operator inserting magazine n again.
+func (chgr *Changer) InsertMagazine(n int) error {
+	return chgr.setMagazine(n, false)
+}
+
+func (chgr *Changer) setMagazine(n int, removed bool) error {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	if chgr.magazineSize <= 0 || n < 0 || n*chgr.magazineSize >= chgr.numStorageSlots {
+		return fmt.Errorf("mtx/mock: no magazine %d", n)
+	}
+
+	if chgr.removed == nil {
+		chgr.removed = make(map[int]bool)
+	}
+
+	chgr.removed[n] = removed
+
+	op := OpMagazineInsert
+	if removed {
+		op = OpMagazineRemove
+	}
+
+	chgr.emit(op, nil, nil, nil)
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package mock
+
+import (
+	"github.com/kbj/mtx"
+)
+
+// Op identifies the kind of state change.
+type Op int
+
+const (
+	OpLoad Op = iota
+	OpUnload
+	OpTransfer
+	OpEject
+	OpInventory
+	OpStationOpen
+	OpStationClose
+	OpOperatorInsert
+	OpOperatorRemove
+	OpDoorOpen
+	OpDoorClose
+	OpMagazineRemove
+	OpMagazineInsert
+)
+
+var opNames = [...]string{
+	OpLoad:           "load",
+	OpUnload:         "unload",
+	OpTransfer:       "transfer",
+	OpEject:          "eject",
+	OpInventory:      "inventory",
+	OpStationOpen:    "station-open",
+	OpStationClose:   "station-close",
+	OpOperatorInsert: "operator-insert",
+	OpOperatorRemove: "operator-remove",
+	OpDoorOpen:       "door-open",
+	OpDoorClose:      "door-close",
+	OpMagazineRemove: "magazine-remove",
+	OpMagazineInsert: "magazine-insert",
+}
+
+// String returns a textual representation of the operation.
+func (op Op) String() string {
+	if op < 0 || int(op) >= len(opNames) {
+		return "unknown"
+	}
+
+	return opNames[op]
+}
+
+// Location identifies an element of the library.
+type Location struct {
+	Type mtx.SlotType
+	Num  int
+}
+
+// Event describes a state change of the mock library.
+type Event struct {
+	Op Op
+
+	// Vol is a copy of the volume that moved. It is nil for changes that do
+	// not move a volume, such as opening the import/export station.
+	Vol *mtx.Volume
+
+	// From and To are the source and destination of the volume. A nil
+	// location means outside of the library (e.g. an operator removing a
+	// volume from an import/export slot).
+	From, To *Location
+}
+
+// OnChange registers fn to be called after every successful state change.
+// Handlers are called synchronously, in order, after the mock's internal
+// lock has been released, so they may inspect the mock.
+func (chgr *Changer) OnChange(fn func(ev Event)) {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	chgr.handlers = append(chgr.handlers, fn)
+}
+
+// emit queues an event for delivery when the lock is released.
+func (chgr *Changer) emit(op Op, vol *mtx.Volume, from, to *mtx.Slot) {
+	if len(chgr.handlers) == 0 {
+		return
+	}
+
+	ev := Event{Op: op}
+	if vol != nil {
+		v := *vol
+		ev.Vol = &v
+	}
+
+	if from != nil {
+		ev.From = &Location{Type: from.Type, Num: from.Num}
+	}
+
+	if to != nil {
+		ev.To = &Location{Type: to.Type, Num: to.Num}
+	}
+
+	chgr.queued = append(chgr.queued, ev)
+}
+
+// unlock releases the lock and delivers queued events.
+func (chgr *Changer) unlock() {
+	events := chgr.queued
+	handlers := chgr.handlers
+	chgr.queued = nil
+	chgr.mu.Unlock()
+
+	for _, ev := range events {
+		for _, fn := range handlers {
+			fn(ev)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package mock
+
+import (
+	"fmt"
+)
+
+// HeaderFunc formats the first line of the status output (without the
+// trailing newline). numSlots includes the import/export slots.
+type HeaderFunc func(device string, numDrives, numSlots, numMailSlots int) string
+
+// DefaultHeader formats the header as mtx does.
+func DefaultHeader(device string, numDrives, numSlots, numMailSlots int) string {
+	return fmt.Sprintf("  Storage Changer %s:%d Drives, %d Slots ( %d Import/Export )",
+		device, numDrives, numSlots, numMailSlots,
+	)
+}
+
+// CompactHeader formats the header like DefaultHeader, but leaves out the
+// Import/Export clause if there are no import/export slots, like some
+// versions of mtx do.
+func CompactHeader(device string, numDrives, numSlots, numMailSlots int) string {
+	if numMailSlots == 0 {
+		return fmt.Sprintf("  Storage Changer %s:%d Drives, %d Slots",
+			device, numDrives, numSlots,
+		)
+	}
+
+	return DefaultHeader(device, numDrives, numSlots, numMailSlots)
+}
+
+// WithDevice sets the device reported in the status header. Defaults to
+// "/dev/mock".
+func WithDevice(device string) Option {
+	return func(chgr *Changer) {
+		chgr.device = device
+	}
+}
+
+// WithHeader sets the function used to format the status header.
+func WithHeader(fn HeaderFunc) Option {
+	return func(chgr *Changer) {
+		chgr.header = fn
+		chgr.textual = true
+	}
+}
@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// WithInquiry sets the vendor, product and revision strings reported by the
+// inquiry command. The strings are padded to the SCSI field widths (8, 16
+// and 4 characters) like a real device does.
+func WithInquiry(vendor, product, revision string) Option {
+	return func(chgr *Changer) {
+		chgr.vendor = vendor
+		chgr.product = product
+		chgr.revision = revision
+	}
+}
+
+func (chgr *Changer) inquiry() ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "Product Type: Medium Changer\n")
+	fmt.Fprintf(&buf, "Vendor ID: '%-8s'\n", chgr.vendor)
+	fmt.Fprintf(&buf, "Product ID: '%-16s'\n", chgr.product)
+	fmt.Fprintf(&buf, "Revision: '%-4s'\n", chgr.revision)
+	fmt.Fprintf(&buf, "Attached Changer API: No\n")
+
+	return buf.Bytes(), nil
+}
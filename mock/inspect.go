@@ -0,0 +1,61 @@
+package mock
+
+import (
+	"github.com/kbj/mtx"
+)
+
+// copySlot returns a deep copy of slot.
+func copySlot(slot *mtx.Slot) *mtx.Slot {
+	c := *slot
+	if slot.Vol != nil {
+		vol := *slot.Vol
+		c.Vol = &vol
+	}
+
+	return &c
+}
+
+// DriveSlot returns a copy of the data transfer element n, or nil if there
+// is no such drive.
+func (chgr *Changer) DriveSlot(n int) *mtx.Slot {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if n < 0 || n >= len(chgr.drives) {
+		return nil
+	}
+
+	return copySlot(chgr.drives[n])
+}
+
+// StorageSlot returns a copy of the storage or import/export element n, or
+// nil if there is no such slot.
+func (chgr *Changer) StorageSlot(n int) *mtx.Slot {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if n < 1 || n > len(chgr.slots) {
+		return nil
+	}
+
+	return copySlot(chgr.slots[n-1])
+}
+
+// AllVolumes returns copies of all volumes currently reported by the library,
+// in drives first and then in slot order.
+func (chgr *Changer) AllVolumes() []*mtx.Volume {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	var vols []*mtx.Volume
+	for _, slots := range [][]*mtx.Slot{chgr.drives, chgr.slots} {
+		for _, slot := range slots {
+			if slot.Vol != nil {
+				vol := *slot.Vol
+				vols = append(vols, &vol)
+			}
+		}
+	}
+
+	return vols
+}
@@ -0,0 +1,92 @@
+package mock
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kbj/mtx"
+)
+
+// inventory rescans the library, making changes done out-of-band visible.
+// Use WithLatency("inventory", ...) to simulate the time a rescan takes.
+func (chgr *Changer) inventory() error {
+	slotnums := make([]int, 0, len(chgr.unscanned))
+	for slotnum := range chgr.unscanned {
+		slotnums = append(slotnums, slotnum)
+	}
+
+	sort.Ints(slotnums)
+
+	for _, slotnum := range slotnums {
+		chgr.scan(slotnum)
+	}
+
+	return nil
+}
+
+// scan makes the library see the physical contents of the slot slotnum.
+// Besides inventories, the robot scans the slots it moves volumes from or
+// to, so that moves act on the volumes actually there: a volume removed out
+// of band cannot be moved, and a volume inserted out of band fills its slot.
+func (chgr *Changer) scan(slotnum int) {
+	vol, ok := chgr.unscanned[slotnum]
+	if !ok {
+		return
+	}
+
+	delete(chgr.unscanned, slotnum)
+
+	slot := chgr.slots[slotnum-1]
+
+	// report the discovered change as volumes leaving and entering
+	if slot.Vol != nil {
+		chgr.emit(OpInventory, slot.Vol, slot, nil)
+	}
+
+	slot.Vol = vol
+
+	if vol != nil {
+		chgr.emit(OpInventory, vol, nil, slot)
+	}
+}
+
+// InsertOutOfBand simulates a volume being put into the storage slot slotnum
+// behind the library's back, e.g. by swapping a magazine. The library does
+// not report the volume until an inventory is performed or the robot visits
+// the slot.
+func (chgr *Changer) InsertOutOfBand(slotnum int, serial string) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if err := chgr.checkOutOfBand(slotnum); err != nil {
+		return err
+	}
+
+	chgr.unscanned[slotnum] = &mtx.Volume{Serial: serial, Home: slotnum}
+
+	return nil
+}
+
+// RemoveOutOfBand simulates the volume in the storage slot slotnum being
+// removed behind the library's back. The library keeps reporting the volume
+// until an inventory is performed or the robot visits the slot.
+func (chgr *Changer) RemoveOutOfBand(slotnum int) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if err := chgr.checkOutOfBand(slotnum); err != nil {
+		return err
+	}
+
+	chgr.unscanned[slotnum] = nil
+
+	return nil
+}
+
+func (chgr *Changer) checkOutOfBand(slotnum int) error {
+	if slotnum < 1 || slotnum > chgr.numStorageSlots {
+		return fmt.Errorf("mtx/mock: slot %d is not a storage slot", slotnum)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package mock_test
+
+import (
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+)
+
+// serials returns the serials in the library by element.
+func serials(t *testing.T, impl *mock.Changer) map[string]string {
+	t.Helper()
+
+	status, err := impl.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]string)
+	for slot := range status.Occupied() {
+		found[slot.Vol.Serial] = slot.Element().String()
+	}
+
+	return found
+}
+
+func TestMoveIntoSlotFilledOutOfBand(t *testing.T) {
+	impl := mock.New(2, 8, 1, 4)
+	chgr := mtx.NewChanger(impl)
+
+	if err := impl.InsertOutOfBand(6, "X00000L6"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.Transfer(1, 6); err == nil {
+		t.Fatal("transfer into a physically full slot succeeded")
+	}
+
+	if err := chgr.Load(2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.Unload(6, 0); err == nil {
+		t.Fatal("unload into a physically full slot succeeded")
+	}
+
+	if _, err := impl.Do("inventory"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := serials(t, impl)
+	for serial, want := range map[string]string{
+		"S00000L6": "slot 1",
+		"S00001L6": "drive 0",
+		"X00000L6": "slot 6",
+	} {
+		if got[serial] != want {
+			t.Errorf("%s in %q, want %q", serial, got[serial], want)
+		}
+	}
+}
+
+func TestMoveFromSlotEmptiedOutOfBand(t *testing.T) {
+	impl := mock.New(2, 8, 1, 4)
+	chgr := mtx.NewChanger(impl)
+
+	if err := impl.RemoveOutOfBand(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.Transfer(2, 6); err == nil {
+		t.Fatal("transfer from a physically empty slot succeeded")
+	}
+
+	// the robot has seen the slot empty
+	if _, ok := serials(t, impl)["S00001L6"]; ok {
+		t.Error("S00001L6 still reported after the robot found its slot empty")
+	}
+
+	// and the slot can be filled again
+	if err := chgr.Transfer(1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := impl.Do("inventory"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := serials(t, impl)["S00000L6"]; got != "slot 2" {
+		t.Errorf("S00000L6 in %q after inventory, want slot 2", got)
+	}
+}
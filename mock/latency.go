@@ -0,0 +1,67 @@
+package mock
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+type latency struct {
+	delay  time.Duration
+	jitter time.Duration
+}
+
+// WithLatency makes the mock wait delay, plus a uniformly distributed random
+// duration in [0, jitter), before performing cmd (e.g. "load" or "status").
+// Use the command "*" to set the latency of commands that do not have their
+// own.
+func WithLatency(cmd string, delay, jitter time.Duration) Option {
+	return func(chgr *Changer) {
+		chgr.latency[cmd] = latency{delay: delay, jitter: jitter}
+	}
+}
+
+// WithSeed seeds the random source used for latency jitter and malformed
+// output, making them reproducible across runs.
+func WithSeed(seed int64) Option {
+	return func(chgr *Changer) {
+		chgr.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// SetLatency changes the latency of cmd on a running mock. See WithLatency.
+func (chgr *Changer) SetLatency(cmd string, delay, jitter time.Duration) {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	chgr.latency[cmd] = latency{delay: delay, jitter: jitter}
+}
+
+// delay waits for the configured latency of cmd.
+func (chgr *Changer) delay(ctx context.Context, cmd string) error {
+	chgr.mu.Lock()
+	lat, ok := chgr.latency[cmd]
+	if !ok {
+		lat = chgr.latency["*"]
+	}
+
+	d := lat.delay
+	if lat.jitter > 0 {
+		d += time.Duration(chgr.rand.Int63n(int64(lat.jitter)))
+	}
+	chgr.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
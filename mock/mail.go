@@ -0,0 +1,132 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kbj/mtx"
+)
+
+// ErrStationOpen is returned when the robot tries to access an import/export
+// slot while the import/export station is open.
+var ErrStationOpen = errors.New("mtx/mock: import/export station is open")
+
+// eject opens the import/export station, presenting its contents to the
+// operator.
+func (chgr *Changer) eject() error {
+	if chgr.numMailSlots == 0 {
+		return errors.New("mtx/mock: library has no import/export station")
+	}
+
+	chgr.stationOpen = true
+
+	chgr.emit(OpEject, nil, nil, nil)
+
+	return nil
+}
+
+// StationOpen reports whether the import/export station is open.
+func (chgr *Changer) StationOpen() bool {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	return chgr.stationOpen
+}
+
+// OpenStation simulates the operator opening the import/export station.
+func (chgr *Changer) OpenStation() {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	chgr.stationOpen = true
+
+	chgr.emit(OpStationOpen, nil, nil, nil)
+}
+
+// CloseStation simulates the operator closing the import/export station,
+// making its slots accessible to the robot again.
+func (chgr *Changer) CloseStation() {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	chgr.stationOpen = false
+
+	chgr.emit(OpStationClose, nil, nil, nil)
+}
+
+// OperatorInsert simulates the operator placing a volume with the given
+// serial in the first empty import/export slot. It returns the number of the
+// slot used.
+//
+// Like the other operator methods, OperatorInsert works regardless of
+// whether the station is open; if it is closed, the operator is assumed to
+// open it and close it again afterwards.
+func (chgr *Changer) OperatorInsert(serial string) (int, error) {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	for _, slot := range chgr.slots[chgr.numStorageSlots:] {
+		if slot.Vol == nil {
+			slot.Vol = &mtx.Volume{Serial: serial, Home: slot.Num}
+			chgr.emit(OpOperatorInsert, slot.Vol, nil, slot)
+
+			return slot.Num, nil
+		}
+	}
+
+	return 0, errors.New("mtx/mock: no empty import/export slot")
+}
+
+// OperatorInsertAt simulates the operator placing a volume with the given
+// serial in the import/export slot slotnum.
+func (chgr *Changer) OperatorInsertAt(slotnum int, serial string) error {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	slot, err := chgr.operatorSlot(slotnum)
+	if err != nil {
+		return err
+	}
+
+	if slot.Vol != nil {
+		return fmt.Errorf("mtx/mock: import/export slot %d is occupied", slotnum)
+	}
+
+	slot.Vol = &mtx.Volume{Serial: serial, Home: slotnum}
+
+	chgr.emit(OpOperatorInsert, slot.Vol, nil, slot)
+
+	return nil
+}
+
+// OperatorRemove simulates the operator taking the volume out of the
+// import/export slot slotnum.
+func (chgr *Changer) OperatorRemove(slotnum int) (*mtx.Volume, error) {
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	slot, err := chgr.operatorSlot(slotnum)
+	if err != nil {
+		return nil, err
+	}
+
+	if slot.Vol == nil {
+		return nil, fmt.Errorf("mtx/mock: import/export slot %d is empty", slotnum)
+	}
+
+	vol := slot.Vol
+	slot.Vol = nil
+
+	chgr.emit(OpOperatorRemove, vol, slot, nil)
+
+	return vol, nil
+}
+
+// operatorSlot returns the import/export slot slotnum.
+func (chgr *Changer) operatorSlot(slotnum int) (*mtx.Slot, error) {
+	if slotnum < 1 || slotnum > len(chgr.slots) || chgr.slots[slotnum-1].Type != mtx.MailSlot {
+		return nil, fmt.Errorf("mtx/mock: slot %d is not an import/export slot", slotnum)
+	}
+
+	return chgr.slots[slotnum-1], nil
+}
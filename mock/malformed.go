@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"bytes"
+)
+
+// Quirk is a set of ways in which the mock may corrupt status output.
+type Quirk uint
+
+const (
+	// QuirkTruncated cuts element lines short.
+	QuirkTruncated Quirk = 1 << iota
+
+	// QuirkIndentation replaces the indentation of element lines with an
+	// unusual mix of spaces and tabs.
+	QuirkIndentation
+
+	// QuirkMissingVolumeTag drops the volume tag from occupied elements.
+	QuirkMissingVolumeTag
+
+	// QuirkPaddedVolumeTag pads volume tags with trailing spaces, as some
+	// versions of mtx do.
+	QuirkPaddedVolumeTag
+
+	// QuirkBlankLine inserts an empty line after the element line.
+	QuirkBlankLine
+
+	// QuirkAll enables all quirks.
+	QuirkAll = QuirkTruncated | QuirkIndentation | QuirkMissingVolumeTag |
+		QuirkPaddedVolumeTag | QuirkBlankLine
+)
+
+var volumeTag = []byte(":VolumeTag")
+
+// WithMalformed makes the mock corrupt element lines of the status output.
+// Each line is corrupted with probability rate (0 to 1) by one of the given
+// quirks chosen at random. Use WithSeed for reproducible output.
+func WithMalformed(quirks Quirk, rate float64) Option {
+	return func(chgr *Changer) {
+		chgr.quirkRate = rate
+		chgr.textual = true
+
+		chgr.quirkOrder = nil
+		for q := QuirkTruncated; q <= QuirkBlankLine; q <<= 1 {
+			if quirks&q != 0 {
+				chgr.quirkOrder = append(chgr.quirkOrder, q)
+			}
+		}
+	}
+}
+
+// mangle possibly corrupts the line (including the terminating newline)
+// starting at buf[start].
+func (chgr *Changer) mangle(buf []byte, start int) []byte {
+	if len(chgr.quirkOrder) == 0 || chgr.rand.Float64() >= chgr.quirkRate {
+		return buf
+	}
+
+	line := make([]byte, len(buf)-start-1)
+	copy(line, buf[start:len(buf)-1])
+	buf = buf[:start]
+
+	switch chgr.quirkOrder[chgr.rand.Intn(len(chgr.quirkOrder))] {
+	case QuirkTruncated:
+		line = line[:chgr.rand.Intn(len(line))]
+	case QuirkIndentation:
+		line = append([]byte(" \t  \t"), bytes.TrimLeft(line, " ")...)
+	case QuirkMissingVolumeTag:
+		if i := bytes.Index(line, volumeTag); i >= 0 {
+			line = line[:i]
+			if !bytes.HasSuffix(line, []byte(")")) {
+				line = append(bytes.TrimRight(line, " "), ' ')
+			}
+		}
+	case QuirkPaddedVolumeTag:
+		if bytes.Contains(line, volumeTag) {
+			line = append(line, "                          "...)
+		}
+	case QuirkBlankLine:
+		line = append(line, '\n')
+	}
+
+	buf = append(buf, line...)
+
+	return append(buf, '\n')
+}
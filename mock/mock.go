@@ -2,22 +2,129 @@
 package mock
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"strconv"
+	"sync"
 
 	"github.com/kbj/mtx"
 )
 
 // Changer represents a mock library auto changer.
 type Changer struct {
+	mu sync.Mutex
+
 	drives []*mtx.Slot
 	slots  []*mtx.Slot
 
 	numDrives       int
 	numStorageSlots int
 	numMailSlots    int
+
+	serial       SerialFunc
+	cleaningTape bool
+	mailVolume   bool
+	barcodes     bool
+
+	stationOpen bool
+
+	// door and magazines, see enclosure.go
+	doorOpen     bool
+	magazineSize int
+	removed      map[int]bool
+
+	device string
+	header HeaderFunc
+
+	// render and parse the status even when asked for it directly, so that
+	// custom headers and malformed output take effect
+	textual bool
+
+	vendor, product, revision string
+
+	attrs   map[string]Attr
+	written map[string]bool
+
+	cleaningLimit int
+	cleaningUses  map[string]int
+
+	// physical changes to storage slots not yet seen by the library
+	unscanned map[int]*mtx.Volume
+
+	handlers []func(ev Event)
+	queued   []Event
+
+	quirkRate  float64
+	quirkOrder []Quirk
+
+	latency map[string]latency
+	rand    *rand.Rand
+}
+
+// SerialFunc returns the serial of the i'th generated mock volume.
+type SerialFunc func(i int) string
+
+// An Option configures a mock library auto changer.
+type Option func(chgr *Changer)
+
+// WithSerialFunc sets the function used to generate volume serials.
+func WithSerialFunc(fn SerialFunc) Option {
+	return func(chgr *Changer) {
+		chgr.serial = fn
+	}
+}
+
+// WithSerialFormat generates serials consisting of prefix, a zero padded
+// sequence number and the media suffix (e.g. "L8"). The sequence number is
+// padded such that the serial, excluding the suffix, is six characters long.
+func WithSerialFormat(prefix, suffix string) Option {
+	width := 6 - len(prefix)
+	if width < 1 {
+		width = 1
+	}
+
+	return WithSerialFunc(func(i int) string {
+		return fmt.Sprintf("%s%0*d%s", prefix, width, i, suffix)
+	})
+}
+
+// WithCleaningTape controls whether a cleaning cartridge is put in the last
+// storage slot.
+func WithCleaningTape(enabled bool) Option {
+	return func(chgr *Changer) {
+		chgr.cleaningTape = enabled
+	}
+}
+
+// WithMailVolume controls whether a volume is put in the last import/export
+// slot.
+func WithMailVolume(enabled bool) Option {
+	return func(chgr *Changer) {
+		chgr.mailVolume = enabled
+	}
+}
+
+// WithBarcodeReader controls whether the library reports volume tags. With
+// the barcode reader disabled, occupied slots are reported as "Full" without
+// a VolumeTag, like libraries without (or with a broken) barcode reader.
+func WithBarcodeReader(enabled bool) Option {
+	return func(chgr *Changer) {
+		chgr.barcodes = enabled
+	}
+}
+
+func defaultSerial(i int) string {
+	return fmt.Sprintf("S%05dL6", i)
+}
+
+// NewLarge returns a mock of an enterprise scale library with 16 drives,
+// 10,000 storage slots and 40 import/export slots, three quarters of the
+// storage slots being populated. It is intended for benchmarking code that
+// processes the status of large libraries.
+func NewLarge(opts ...Option) *Changer {
+	return New(16, 10000, 40, 7500, opts...)
 }
 
 // New returns a mock library auto changer initialized with numDrives slots for
@@ -25,14 +132,38 @@ type Changer struct {
 // import/export mail slots. It populates the first numVolumes storage slots
 // with mock volumes with serials starting at S00000L6. A cleaning cartridge
 // with serial CLN000L1 is added to the last storage slot and an extra volume
-// is added to the last import/export slot.
-func New(numDrives, numStorageSlots, numMailSlots, numVolumes int) *Changer {
+// is added to the last import/export slot. The defaults may be changed with
+// the given options.
+func New(numDrives, numStorageSlots, numMailSlots, numVolumes int, opts ...Option) *Changer {
 	chgr := &Changer{
 		drives:          make([]*mtx.Slot, numDrives),
 		slots:           make([]*mtx.Slot, numStorageSlots+numMailSlots),
 		numDrives:       numDrives,
 		numStorageSlots: numStorageSlots,
 		numMailSlots:    numMailSlots,
+
+		serial:       defaultSerial,
+		cleaningTape: true,
+		mailVolume:   true,
+		barcodes:     true,
+
+		device: "/dev/mock",
+		header: DefaultHeader,
+
+		vendor:   "MOCK",
+		product:  "MTX",
+		revision: "0001",
+
+		attrs:        make(map[string]Attr),
+		written:      make(map[string]bool),
+		cleaningUses: make(map[string]int),
+		unscanned:    make(map[int]*mtx.Volume),
+		latency:      make(map[string]latency),
+		rand:         rand.New(rand.NewSource(1)),
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
 	}
 
 	for i := range chgr.drives {
@@ -45,13 +176,13 @@ func New(numDrives, numStorageSlots, numMailSlots, numVolumes int) *Changer {
 		// fill half of the storage slots with volumes
 		if i < numVolumes {
 			chgr.slots[i].Vol = &mtx.Volume{
-				Serial: fmt.Sprintf("S%05dL6", i),
+				Serial: chgr.serial(i),
 				Home:   i + 1,
 			}
 		}
 
 		// put a cleaning cartridge in the last storage slot for good measure
-		if i == numStorageSlots-1 {
+		if chgr.cleaningTape && i == numStorageSlots-1 {
 			chgr.slots[i].Vol = &mtx.Volume{
 				Serial: "CLN000L1",
 				Home:   i + 1,
@@ -63,9 +194,9 @@ func New(numDrives, numStorageSlots, numMailSlots, numVolumes int) *Changer {
 		}
 
 		// put a volume in the last mail slot
-		if i == numStorageSlots+numMailSlots-1 {
+		if chgr.mailVolume && i == numStorageSlots+numMailSlots-1 {
 			chgr.slots[i].Vol = &mtx.Volume{
-				Serial: fmt.Sprintf("S%05dL6", numVolumes),
+				Serial: chgr.serial(numVolumes),
 				Home:   i,
 			}
 		}
@@ -74,59 +205,188 @@ func New(numDrives, numStorageSlots, numMailSlots, numVolumes int) *Changer {
 	return chgr
 }
 
-func mtxSlotString(slot *mtx.Slot) string {
+// appendSlot appends the mtx status representation of the contents of slot
+// to buf.
+func (chgr *Changer) appendSlot(buf []byte, slot *mtx.Slot) []byte {
 	if slot.Vol == nil {
-		return "Empty"
+		return append(buf, "Empty"...)
 	}
 
 	if slot.Type == mtx.DataTransferSlot {
-		return fmt.Sprintf("Full (Storage Element %d Loaded):VolumeTag = %s",
-			slot.Vol.Home, slot.Vol.Serial,
-		)
+		buf = append(buf, "Full (Storage Element "...)
+		buf = strconv.AppendInt(buf, int64(slot.Vol.Home), 10)
+		buf = append(buf, " Loaded)"...)
+
+		if !chgr.hasLabel(slot.Vol) {
+			return buf
+		}
+
+		buf = append(buf, ":VolumeTag = "...)
+
+		return append(buf, slot.Vol.Serial...)
 	}
 
-	return fmt.Sprintf("Full :VolumeTag=%s", slot.Vol.Serial)
+	if !chgr.hasLabel(slot.Vol) {
+		return append(buf, "Full "...)
+	}
+
+	buf = append(buf, "Full :VolumeTag="...)
+
+	return append(buf, slot.Vol.Serial...)
+}
+
+// checkSlot validates a storage or import/export slot number given to cmd.
+// Like the other precondition errors of the mock, the error text mirrors the
+// one printed by mtx.
+func (chgr *Changer) checkSlot(cmd string, slotnum int) error {
+	if slotnum < 1 || slotnum > len(chgr.slots) {
+		return fmt.Errorf("Invalid <slotno> argument '%d' to '%s' command", slotnum, cmd)
+	}
+
+	return nil
+}
+
+// checkDrive validates a drive number given to cmd.
+func (chgr *Changer) checkDrive(cmd string, drivenum int) error {
+	if drivenum < 0 || drivenum >= len(chgr.drives) {
+		return fmt.Errorf("Invalid <drvno> argument '%d' to '%s' command", drivenum, cmd)
+	}
+
+	return nil
 }
 
 func (chgr *Changer) load(slotnum int, drivenum int) error {
+	if err := chgr.checkSlot("load", slotnum); err != nil {
+		return err
+	}
+
+	if err := chgr.checkDrive("load", drivenum); err != nil {
+		return err
+	}
+
+	if err := chgr.checkAccess(slotnum); err != nil {
+		return err
+	}
+
+	chgr.scan(slotnum)
+
 	slot := chgr.slots[slotnum-1]
+	if slot.Vol == nil {
+		return fmt.Errorf("source Element Address %d is Empty", slotnum)
+	}
+
+	if drv := chgr.drives[drivenum]; drv.Vol != nil {
+		return fmt.Errorf("Drive %d Full (Storage Element %d loaded)", drivenum, drv.Vol.Home)
+	}
+
+	if err := chgr.useCleaning(slot.Vol); err != nil {
+		return err
+	}
+
+	// like a real library, report the slot the volume was loaded from as
+	// its home
+	slot.Vol.Home = slotnum
+
 	chgr.drives[drivenum].Vol = slot.Vol
 	slot.Vol = nil
 
+	chgr.emit(OpLoad, chgr.drives[drivenum].Vol, slot, chgr.drives[drivenum])
+
 	return nil
 }
 
 func (chgr *Changer) unload(slotnum int, drivenum int) error {
+	if err := chgr.checkDrive("unload", drivenum); err != nil {
+		return err
+	}
+
 	drv := chgr.drives[drivenum]
+	if drv.Vol == nil {
+		return fmt.Errorf("Data Transfer Element %d is Empty", drivenum)
+	}
+
 	if slotnum == 0 {
 		slotnum = drv.Vol.Home
 	}
 
+	if err := chgr.checkSlot("unload", slotnum); err != nil {
+		return err
+	}
+
+	if err := chgr.checkAccess(slotnum); err != nil {
+		return err
+	}
+
+	chgr.scan(slotnum)
+
+	if chgr.slots[slotnum-1].Vol != nil {
+		return fmt.Errorf("Storage Element %d is Already Full", slotnum)
+	}
+
 	chgr.slots[slotnum-1].Vol = drv.Vol
 	drv.Vol = nil
 
+	chgr.emit(OpUnload, chgr.slots[slotnum-1].Vol, drv, chgr.slots[slotnum-1])
+
 	return nil
 }
 
 func (chgr *Changer) transfer(from, to int) error {
-	from -= 1
-	to -= 1
-	if chgr.slots[from].Vol == nil {
-		return errors.New("unable to transfer volume: no volume in slot")
+	if err := chgr.checkSlot("transfer", from); err != nil {
+		return err
+	}
+
+	if err := chgr.checkSlot("transfer", to); err != nil {
+		return err
+	}
+
+	if err := chgr.checkAccess(from, to); err != nil {
+		return err
 	}
 
-	if chgr.slots[to].Vol != nil {
-		return errors.New("unable to transfer volume: slot already occupied")
+	chgr.scan(from)
+	chgr.scan(to)
+
+	src, dst := chgr.slots[from-1], chgr.slots[to-1]
+	if src.Vol == nil {
+		return fmt.Errorf("source Element Address %d is Empty", from)
 	}
 
-	chgr.slots[to].Vol = chgr.slots[from].Vol
-	chgr.slots[from].Vol = nil
+	if dst.Vol != nil {
+		return fmt.Errorf("destination Element Address %d is Already Full", to)
+	}
+
+	dst.Vol = src.Vol
+	src.Vol = nil
+
+	chgr.emit(OpTransfer, dst.Vol, src, dst)
 
 	return nil
 }
 
 // Do simulates performaing the given mtx command.
 func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext simulates performing the given mtx command. If a latency is
+// configured for the command, DoContext waits for it to pass before
+// executing the command, returning early with the context error if ctx is
+// done first.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	if len(args) > 0 {
+		if err := chgr.delay(ctx, args[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	return chgr.do(args...)
+}
+
+func (chgr *Changer) do(args ...string) ([]byte, error) {
 	if len(args) < 1 {
 		return nil, errors.New("no command given")
 	}
@@ -134,7 +394,19 @@ func (chgr *Changer) Do(args ...string) ([]byte, error) {
 	cmd := args[0]
 
 	if cmd == "status" {
-		return chgr.status()
+		return chgr.status(nil)
+	}
+
+	if cmd == "eject" {
+		return nil, chgr.eject()
+	}
+
+	if cmd == "inventory" {
+		return nil, chgr.inventory()
+	}
+
+	if cmd == "inquiry" {
+		return chgr.inquiry()
 	}
 
 	if len(args) != 3 {
@@ -163,35 +435,128 @@ func (chgr *Changer) Do(args ...string) ([]byte, error) {
 	return nil, errors.New("mtx/mock: unknown or unsupported mtx command")
 }
 
-func (chgr *Changer) status() ([]byte, error) {
-	var tmp string
-	var buf bytes.Buffer
+// StatusRange implements mtx.RangeInterface, rendering only the elements in
+// r. Latency configured for "status" applies.
+func (chgr *Changer) StatusRange(r mtx.Range) ([]byte, error) {
+	if err := chgr.delay(context.Background(), "status"); err != nil {
+		return nil, err
+	}
+
+	chgr.mu.Lock()
+	defer chgr.unlock()
+
+	return chgr.status(&r)
+}
+
+// Status implements mtx.StatusProvider, reporting the state of the door,
+// magazines and import/export station in Status.Enclosure. If the status
+// output is customized with WithHeader or WithMalformed, the rendered output
+// is parsed instead, as if it had been read from mtx, and the enclosure
+// state is not reported. Latency configured for "status" applies.
+func (chgr *Changer) Status() (*mtx.Status, error) {
+	if err := chgr.delay(context.Background(), "status"); err != nil {
+		return nil, err
+	}
+
+	chgr.mu.Lock()
+	defer chgr.unlock()
 
-	// compose header
-	tmp = fmt.Sprintf("  Storage Changer %s:%d Drives, %d Slots ( %d Import/Export )\n",
-		"/dev/mock", chgr.numDrives, chgr.numStorageSlots+chgr.numMailSlots,
-		chgr.numMailSlots,
-	)
+	if chgr.textual {
+		out, err := chgr.status(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return mtx.ParseStatus(out)
+	}
+
+	status := &mtx.Status{
+		MaxDrives:       chgr.numDrives,
+		NumSlots:        chgr.numStorageSlots + chgr.numMailSlots,
+		NumStorageSlots: chgr.numStorageSlots,
+		NumMailSlots:    chgr.numMailSlots,
+		Drives:          make([]*mtx.Slot, len(chgr.drives)),
+		Slots:           make([]*mtx.Slot, len(chgr.slots)),
+	}
+
+	// report what the rendered output would: no labels the library cannot
+	// read, and slots as the home of the volumes they hold
+	reported := func(slot *mtx.Slot) *mtx.Slot {
+		c := copySlot(slot)
+		if slot.Vol == nil {
+			return c
+		}
+
+		if !chgr.hasLabel(slot.Vol) {
+			c.Vol.Serial = ""
+		}
+
+		if slot.Type != mtx.DataTransferSlot {
+			c.Vol.Home = slot.Num
+		}
+
+		return c
+	}
+
+	for i, drv := range chgr.drives {
+		status.Drives[i] = reported(drv)
+	}
+
+	for i, slot := range chgr.slots {
+		status.Slots[i] = reported(slot)
+	}
+
+	status.Enclosure = chgr.enclosure()
+
+	return status, nil
+}
+
+// status renders the status output, listing only the elements in r if it is
+// non-nil.
+func (chgr *Changer) status(r *mtx.Range) ([]byte, error) {
+	// roughly 64 bytes per element is plenty and avoids regrowing the buffer
+	// for large libraries
+	buf := make([]byte, 0, 64*(1+len(chgr.drives)+len(chgr.slots)))
 
 	// write header
-	_, _ = buf.WriteString(tmp)
+	buf = append(buf, chgr.header(chgr.device, chgr.numDrives,
+		chgr.numStorageSlots+chgr.numMailSlots, chgr.numMailSlots,
+	)...)
+	buf = append(buf, '\n')
 
 	// write data transfer elements
 	for i, slot := range chgr.drives {
-		tmp = fmt.Sprintf("Data Transfer Element %d:%s\n", i, mtxSlotString(slot))
-		_, _ = buf.WriteString(tmp)
+		if r != nil && !r.Contains(slot) {
+			continue
+		}
+
+		start := len(buf)
+		buf = append(buf, "Data Transfer Element "...)
+		buf = strconv.AppendInt(buf, int64(i), 10)
+		buf = append(buf, ':')
+		buf = chgr.appendSlot(buf, slot)
+		buf = append(buf, '\n')
+		buf = chgr.mangle(buf, start)
 	}
 
 	// write storage elements
 	for _, slot := range chgr.slots {
-		extra := ""
+		if r != nil && !r.Contains(slot) {
+			continue
+		}
+
+		start := len(buf)
+		buf = append(buf, "      Storage Element "...)
+		buf = strconv.AppendInt(buf, int64(slot.Num), 10)
 		if slot.Type == mtx.MailSlot {
-			extra = " IMPORT/EXPORT"
+			buf = append(buf, " IMPORT/EXPORT"...)
 		}
 
-		tmp = fmt.Sprintf("      Storage Element %d%s:%s\n", slot.Num, extra, mtxSlotString(slot))
-		_, _ = buf.WriteString(tmp)
+		buf = append(buf, ':')
+		buf = chgr.appendSlot(buf, slot)
+		buf = append(buf, '\n')
+		buf = chgr.mangle(buf, start)
 	}
 
-	return buf.Bytes(), nil
+	return buf, nil
 }
@@ -0,0 +1,102 @@
+package mock_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+)
+
+// counted counts the commands performed by the wrapped implementation.
+type counted struct {
+	mtx.Interface
+	n atomic.Int64
+}
+
+func (impl *counted) Do(args ...string) ([]byte, error) {
+	impl.n.Add(1)
+	return impl.Interface.Do(args...)
+}
+
+func TestNewLarge(t *testing.T) {
+	status, err := mtx.NewChanger(mock.NewLarge()).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.MaxDrives != 16 || status.NumStorageSlots != 10000 || status.NumMailSlots != 40 {
+		t.Errorf("geometry = %d drives, %d storage, %d mail slots, want 16, 10000, 40",
+			status.MaxDrives, status.NumStorageSlots, status.NumMailSlots)
+	}
+
+	n := 0
+	for _, slot := range status.StorageSlots() {
+		if slot.Vol != nil {
+			n++
+		}
+	}
+
+	if n < 7500 {
+		t.Errorf("%d slots occupied, want at least 7500", n)
+	}
+}
+
+// BenchmarkLargeStatus measures the generation of the status output.
+func BenchmarkLargeStatus(b *testing.B) {
+	impl := mock.NewLarge()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := impl.Do("status"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLargeChangerStatus measures a status query of a changer, i.e.
+// generating and parsing the output.
+func BenchmarkLargeChangerStatus(b *testing.B) {
+	chgr := mtx.NewChanger(mock.NewLarge())
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := chgr.Status(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLargeCachedStatus measures a status query answered from the
+// cache kept by Refresh.
+func BenchmarkLargeCachedStatus(b *testing.B) {
+	impl := &counted{Interface: mock.NewLarge()}
+	chgr := mtx.NewChanger(impl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go chgr.Refresh(ctx, time.Hour)
+
+	// wait for the first refresh; Status shares it if still in flight
+	for impl.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := chgr.Status(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := chgr.Status(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if n := impl.n.Load(); n != 1 {
+		b.Errorf("%d status queries, want 1", n)
+	}
+}
@@ -0,0 +1,221 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/kbj/mtx"
+)
+
+// savedVolume is the persisted form of a volume.
+type savedVolume struct {
+	Serial string `json:"serial"`
+	Home   int    `json:"home"`
+}
+
+// savedSlot is the persisted form of a slot.
+type savedSlot struct {
+	Num  int          `json:"num"`
+	Mail bool         `json:"mail,omitempty"`
+	Vol  *savedVolume `json:"volume,omitempty"`
+}
+
+// savedChange is the persisted form of a change to a storage slot not yet
+// seen by the library. A nil volume is a removal.
+type savedChange struct {
+	Slot int          `json:"slot"`
+	Vol  *savedVolume `json:"volume"`
+}
+
+// savedState is the persisted form of a mock library.
+type savedState struct {
+	Drives []savedSlot `json:"drives"`
+	Slots  []savedSlot `json:"slots"`
+
+	Attributes map[string]Attr `json:"attributes,omitempty"`
+	Written    []string        `json:"written,omitempty"`
+
+	CleaningUses map[string]int `json:"cleaningUses,omitempty"`
+
+	StationOpen      bool  `json:"stationOpen,omitempty"`
+	DoorOpen         bool  `json:"doorOpen,omitempty"`
+	MagazineSize     int   `json:"magazineSize,omitempty"`
+	RemovedMagazines []int `json:"removedMagazines,omitempty"`
+
+	Unscanned []savedChange `json:"unscanned,omitempty"`
+}
+
+func saveSlot(slot *mtx.Slot) savedSlot {
+	s := savedSlot{Num: slot.Num, Mail: slot.Type == mtx.MailSlot}
+	s.Vol = saveVolume(slot.Vol)
+
+	return s
+}
+
+func saveVolume(vol *mtx.Volume) *savedVolume {
+	if vol == nil {
+		return nil
+	}
+
+	return &savedVolume{Serial: vol.Serial, Home: vol.Home}
+}
+
+func (s *savedVolume) volume() *mtx.Volume {
+	if s == nil {
+		return nil
+	}
+
+	return &mtx.Volume{Serial: s.Serial, Home: s.Home}
+}
+
+func (s savedSlot) slot(typ mtx.SlotType) *mtx.Slot {
+	return &mtx.Slot{Num: s.Num, Type: typ, Vol: s.Vol.volume()}
+}
+
+// Save writes the state of the library as JSON to w: the contents of the
+// slots and drives, the attributes and written WORM media, the uses of
+// cleaning cartridges, the state of the door, magazines and import/export
+// station, and the changes not yet seen by the library. Configuration given
+// as options, such as latencies and the cleaning limit, is not saved, nor
+// are events not yet delivered.
+func (chgr *Changer) Save(w io.Writer) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	var state savedState
+
+	for _, slot := range chgr.drives {
+		state.Drives = append(state.Drives, saveSlot(slot))
+	}
+
+	for _, slot := range chgr.slots {
+		state.Slots = append(state.Slots, saveSlot(slot))
+	}
+
+	if len(chgr.attrs) > 0 {
+		state.Attributes = chgr.attrs
+	}
+
+	for serial := range chgr.written {
+		state.Written = append(state.Written, serial)
+	}
+
+	slices.Sort(state.Written)
+
+	if len(chgr.cleaningUses) > 0 {
+		state.CleaningUses = chgr.cleaningUses
+	}
+
+	state.StationOpen = chgr.stationOpen
+	state.DoorOpen = chgr.doorOpen
+	state.MagazineSize = chgr.magazineSize
+
+	for n, removed := range chgr.removed {
+		if removed {
+			state.RemovedMagazines = append(state.RemovedMagazines, n)
+		}
+	}
+
+	slices.Sort(state.RemovedMagazines)
+
+	for slotnum, vol := range chgr.unscanned {
+		state.Unscanned = append(state.Unscanned, savedChange{Slot: slotnum, Vol: saveVolume(vol)})
+	}
+
+	slices.SortFunc(state.Unscanned, func(a, b savedChange) int {
+		return a.Slot - b.Slot
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(&state)
+}
+
+// Load returns a mock library auto changer with the state previously
+// written by Save. Options that only affect the initial population of the
+// library have no effect, and a saved magazine size takes precedence over
+// WithMagazines.
+func Load(r io.Reader, opts ...Option) (*Changer, error) {
+	var state savedState
+
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, err
+	}
+
+	chgr := New(0, 0, 0, 0, opts...)
+
+	for i, s := range state.Drives {
+		if s.Num != i {
+			return nil, errors.New("mtx/mock: drives must be numbered consecutively from 0")
+		}
+
+		chgr.drives = append(chgr.drives, s.slot(mtx.DataTransferSlot))
+	}
+
+	for i, s := range state.Slots {
+		if s.Num != i+1 {
+			return nil, errors.New("mtx/mock: slots must be numbered consecutively from 1")
+		}
+
+		if s.Mail {
+			chgr.slots = append(chgr.slots, s.slot(mtx.MailSlot))
+			chgr.numMailSlots++
+
+			continue
+		}
+
+		if chgr.numMailSlots > 0 {
+			return nil, errors.New("mtx/mock: storage slot follows import/export slot")
+		}
+
+		chgr.slots = append(chgr.slots, s.slot(mtx.StorageSlot))
+		chgr.numStorageSlots++
+	}
+
+	chgr.numDrives = len(chgr.drives)
+
+	for serial, attrs := range state.Attributes {
+		chgr.attrs[serial] = attrs
+	}
+
+	for _, serial := range state.Written {
+		chgr.written[serial] = true
+	}
+
+	for serial, n := range state.CleaningUses {
+		chgr.cleaningUses[serial] = n
+	}
+
+	chgr.stationOpen = state.StationOpen
+	chgr.doorOpen = state.DoorOpen
+
+	if state.MagazineSize > 0 {
+		chgr.magazineSize = state.MagazineSize
+	}
+
+	for _, n := range state.RemovedMagazines {
+		if chgr.magazineSize <= 0 || n < 0 || n*chgr.magazineSize >= chgr.numStorageSlots {
+			return nil, fmt.Errorf("mtx/mock: no magazine %d", n)
+		}
+
+		if chgr.removed == nil {
+			chgr.removed = make(map[int]bool)
+		}
+
+		chgr.removed[n] = true
+	}
+
+	for _, c := range state.Unscanned {
+		if err := chgr.checkOutOfBand(c.Slot); err != nil {
+			return nil, err
+		}
+
+		chgr.unscanned[c.Slot] = c.Vol.volume()
+	}
+
+	return chgr, nil
+}
@@ -0,0 +1,97 @@
+package mock_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/kbj/mtx/mock"
+)
+
+func TestSaveLoad(t *testing.T) {
+	chgr := mock.New(2, 8, 1, 4, mock.WithMagazines(4), mock.WithAttributes("S00000L6", mock.WORM))
+
+	for _, args := range [][]string{
+		{"load", "8", "0"},
+		{"unload", "8", "0"},
+		{"load", "1", "1"},
+	} {
+		if _, err := chgr.Do(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := chgr.SimulateWrite(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.RemoveMagazine(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.InsertOutOfBand(5, "X00000L6"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chgr.RemoveOutOfBand(2); err != nil {
+		t.Fatal(err)
+	}
+
+	chgr.OpenStation()
+	chgr.OpenDoor()
+
+	var saved bytes.Buffer
+	if err := chgr.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := mock.Load(bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resaved bytes.Buffer
+	if err := loaded.Save(&resaved); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(saved.Bytes(), resaved.Bytes()) {
+		t.Errorf("state changed by Load:\n%s\nwant:\n%s", resaved.Bytes(), saved.Bytes())
+	}
+
+	if n := loaded.CleaningUses("CLN000L1"); n != 1 {
+		t.Errorf("CleaningUses = %d, want 1", n)
+	}
+
+	if !loaded.DoorOpen() || !loaded.StationOpen() {
+		t.Errorf("door open %t, station open %t, want both open", loaded.DoorOpen(), loaded.StationOpen())
+	}
+
+	loaded.CloseDoor()
+
+	if _, err := loaded.Do("transfer", "3", "6"); !errors.Is(err, mock.ErrMagazineMissing) {
+		t.Errorf("transfer into removed magazine: %v, want ErrMagazineMissing", err)
+	}
+
+	if err := loaded.SimulateWrite(1); !errors.Is(err, mock.ErrWORMWritten) {
+		t.Errorf("SimulateWrite: %v, want ErrWORMWritten", err)
+	}
+
+	// the changes out of band appear with an inventory
+	if _, err := loaded.Do("inventory"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := loaded.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vol := status.Slots[4].Vol; vol == nil || vol.Serial != "X00000L6" {
+		t.Errorf("slot 5 holds %v after inventory, want X00000L6", vol)
+	}
+
+	if vol := status.Slots[1].Vol; vol != nil {
+		t.Errorf("slot 2 holds %v after inventory, want empty", vol)
+	}
+}
@@ -0,0 +1,113 @@
+package mock
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// RandomSpec describes the geometry and population of a randomized mock
+// library.
+type RandomSpec struct {
+	NumDrives       int
+	NumStorageSlots int
+	NumMailSlots    int
+
+	// Occupancy is the fraction (0 to 1) of storage slots holding a volume.
+	Occupancy float64
+
+	// LoadedDrives is the number of drives loaded with a volume taken from
+	// a storage slot. It is capped by the number of drives and volumes.
+	LoadedDrives int
+
+	// Suffix is the media suffix of generated serials. Defaults to "L6".
+	Suffix string
+
+	// Quirks adds a few entries that commonly trip up applications: an
+	// unlabeled volume, a cleaning cartridge and a volume in an import/export
+	// slot duplicating the serial of a storage volume.
+	Quirks bool
+}
+
+// NewRandom returns a mock library auto changer populated pseudo-randomly
+// according to spec. The same seed and spec always produce the same layout.
+func NewRandom(seed int64, spec RandomSpec, opts ...Option) *Changer {
+	r := rand.New(rand.NewSource(seed))
+
+	suffix := spec.Suffix
+	if suffix == "" {
+		suffix = "L6"
+	}
+
+	b := NewBuilder().Options(opts...)
+
+	// populate storage slots, remembering occupied and empty slots
+	var occupied, empty []int
+	serials := make(map[int]string)
+	for i := 1; i <= spec.NumStorageSlots; i++ {
+		if r.Float64() < spec.Occupancy {
+			serials[i] = fmt.Sprintf("%c%05d%s", 'A'+r.Intn(26), i, suffix)
+			occupied = append(occupied, i)
+
+			continue
+		}
+
+		empty = append(empty, i)
+	}
+
+	if spec.Quirks && len(occupied) > 0 {
+		serials[occupied[r.Intn(len(occupied))]] = ""
+	}
+
+	if spec.Quirks && len(empty) > 0 {
+		k := r.Intn(len(empty))
+		serials[empty[k]] = fmt.Sprintf("CLN%03dL1", r.Intn(1000))
+		occupied = append(occupied, empty[k])
+		empty = append(empty[:k], empty[k+1:]...)
+	}
+
+	// load drives with volumes from random storage slots
+	loaded := make(map[int]bool)
+	perm := r.Perm(len(occupied))
+	for drv := 0; drv < spec.NumDrives; drv++ {
+		if drv >= spec.LoadedDrives || drv >= len(perm) {
+			b.Drive(drv)
+			continue
+		}
+
+		home := occupied[perm[drv]]
+		b.LoadedDrive(drv, serials[home], home)
+		loaded[home] = true
+	}
+
+	for i := 1; i <= spec.NumStorageSlots; i++ {
+		serial, ok := serials[i]
+		if !ok || loaded[i] {
+			b.Slot(i)
+			continue
+		}
+
+		b.Slot(i, serial)
+	}
+
+	dup := 0
+	if spec.Quirks && spec.NumMailSlots > 0 && len(occupied) > 0 {
+		dup = spec.NumStorageSlots + 1 + r.Intn(spec.NumMailSlots)
+	}
+
+	for i := spec.NumStorageSlots + 1; i <= spec.NumStorageSlots+spec.NumMailSlots; i++ {
+		if i == dup {
+			b.MailSlot(i, serials[occupied[r.Intn(len(occupied))]])
+			continue
+		}
+
+		b.MailSlot(i)
+	}
+
+	chgr, err := b.Build()
+	if err != nil {
+		// the layout above is always valid
+		panic(err)
+	}
+
+	return chgr
+}
@@ -0,0 +1,249 @@
+// Package msl implements the mtx.Interface for HPE StoreEver MSL series
+// libraries (MSL3040, MSL6480) using the RESTful interface of their remote
+// management interface, for hosts that cannot see the changer LUN.
+//
+// The backend logs in with the credentials of a management user and keeps
+// the session token, logging in again when the session has expired. The
+// following endpoints are used:
+//
+//	POST /rest/sessions           log in
+//	GET  /rest/library/inventory  drives and slots with their cartridges
+//	POST /rest/library/move       move a cartridge by element address
+package msl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/internal/elements"
+)
+
+// Changer represents an MSL library.
+type Changer struct {
+	url       string
+	user      string
+	password  string
+	client    *http.Client
+	partition string
+
+	mu    sync.Mutex
+	token string
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithClient makes the changer use the given HTTP client.
+func WithClient(client *http.Client) Option {
+	return func(chgr *Changer) {
+		chgr.client = client
+	}
+}
+
+// WithPartition restricts the changer to the drives and slots of the named
+// partition. The mail slots are shared by all partitions.
+func WithPartition(name string) Option {
+	return func(chgr *Changer) {
+		chgr.partition = name
+	}
+}
+
+// New returns a changer talking to the management interface at url (e.g.
+// "https://msl.example.com"), logging in as user.
+func New(url, user, password string, opts ...Option) *Changer {
+	chgr := &Changer{
+		url:      strings.TrimRight(url, "/"),
+		user:     user,
+		password: password,
+		client:   http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
+	}
+
+	return chgr
+}
+
+// Do performs the given operation. See DoContext.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext performs the given operation. The status, load, unload and
+// transfer commands are supported.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	return elements.Do(ctx, chgr, "msl", args...)
+}
+
+type element struct {
+	ElementAddress int    `json:"elementAddress"`
+	Full           bool   `json:"full"`
+	Barcode        string `json:"barcode"`
+	Partition      string `json:"partition"`
+
+	// SourceElementAddress is set for loaded drives.
+	SourceElementAddress int `json:"sourceElementAddress"`
+
+	// MailSlot is set for slots of the mail slot magazine.
+	MailSlot bool `json:"mailslot"`
+}
+
+type inventory struct {
+	Drives []element `json:"drives"`
+	Slots  []element `json:"slots"`
+}
+
+// Inventory implements elements.Library.
+func (chgr *Changer) Inventory(ctx context.Context) (*elements.Inventory, error) {
+	var inv inventory
+	if err := chgr.call(ctx, http.MethodGet, "/rest/library/inventory", nil, &inv); err != nil {
+		return nil, err
+	}
+
+	var elems []*elements.Element
+	for _, d := range inv.Drives {
+		if chgr.partition != "" && d.Partition != chgr.partition {
+			continue
+		}
+
+		elems = append(elems, &elements.Element{
+			Type:   mtx.DataTransferSlot,
+			Addr:   d.ElementAddress,
+			Full:   d.Full,
+			Volume: d.Barcode,
+			Source: d.SourceElementAddress,
+		})
+	}
+
+	for _, s := range inv.Slots {
+		if chgr.partition != "" && s.Partition != chgr.partition && !s.MailSlot {
+			continue
+		}
+
+		typ := mtx.StorageSlot
+		if s.MailSlot {
+			typ = mtx.MailSlot
+		}
+
+		elems = append(elems, &elements.Element{
+			Type:   typ,
+			Addr:   s.ElementAddress,
+			Full:   s.Full,
+			Volume: s.Barcode,
+		})
+	}
+
+	return elements.New(elems), nil
+}
+
+type moveRequest struct {
+	Source      int `json:"source"`
+	Destination int `json:"destination"`
+}
+
+// Move implements elements.Library. The management interface completes the
+// request once the move is done.
+func (chgr *Changer) Move(ctx context.Context, src, dst *elements.Element) error {
+	return chgr.call(ctx, http.MethodPost, "/rest/library/move", &moveRequest{Source: src.Addr, Destination: dst.Addr}, nil)
+}
+
+type session struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token,omitempty"`
+}
+
+// login starts a new session. It must be called with the mutex held.
+func (chgr *Changer) login(ctx context.Context) error {
+	var s session
+	if err := chgr.do(ctx, http.MethodPost, "/rest/sessions", "", &session{Username: chgr.user, Password: chgr.password}, &s); err != nil {
+		return err
+	}
+
+	chgr.token = s.Token
+
+	return nil
+}
+
+// call performs a request in the current session, logging in first if
+// there is no session or it has expired.
+func (chgr *Changer) call(ctx context.Context, method, path string, body, v interface{}) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if chgr.token == "" {
+		if err := chgr.login(ctx); err != nil {
+			return err
+		}
+	}
+
+	err := chgr.do(ctx, method, path, chgr.token, body, v)
+	if err == errUnauthorized {
+		if err := chgr.login(ctx); err != nil {
+			return err
+		}
+
+		err = chgr.do(ctx, method, path, chgr.token, body, v)
+	}
+
+	return err
+}
+
+var errUnauthorized = errors.New("mtx/msl: unauthorized")
+
+func (chgr *Changer) do(ctx context.Context, method, path, token string, body, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, chgr.url+path, r)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if token != "" {
+		req.Header.Set("X-Auth-Token", token)
+	}
+
+	resp, err := chgr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errUnauthorized
+	}
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mtx/msl: %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if v == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
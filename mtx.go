@@ -9,10 +9,14 @@ package mtx
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"regexp"
 	"strconv"
+	"sync"
+	"time"
 )
 
 // SlotType defines the type of slot.
@@ -25,13 +29,23 @@ const (
 	MailSlot
 )
 
+// The header is only parsed once per status and is matched with a regular
+// expression. Element lines are scanned by hand (see parseElement).
+var hdrRegexp = regexp.MustCompile(`\s*Storage Changer\s*(.*):(\d*) Drives, (\d*) Slots(?: \(\s*(\d*) Import/Export\s*\))?`)
+
+// Markers of the element lines.
 var (
-	hdrRegexp          = regexp.MustCompile(`\s*Storage Changer\s*(.*):(\d*) Drives, (\d*) Slots \((\d*) Import/Export \)`)
-	driveRegexp        = regexp.MustCompile(`Data Transfer Element (\d*):(.*)`)
-	driveElementRegexp = regexp.MustCompile(`Full \(Storage Element (\d*) Loaded\):VolumeTag = (.*)`)
-	slotRegexp         = regexp.MustCompile(`\s*Storage Element (\d*):(.*)`)
-	mailSlotRegexp     = regexp.MustCompile(`\s*Storage Element (\d*) IMPORT/EXPORT:(.*)`)
-	slotElementRegexp  = regexp.MustCompile(`Full :VolumeTag=(.*)`)
+	drivePrefix    = []byte("Data Transfer Element ")
+	slotPrefix     = []byte("Storage Element ")
+	mailSuffix     = []byte(" IMPORT/EXPORT:")
+	loadedPrefix   = []byte("Full (Storage Element ")
+	loadedSuffix   = []byte(" Loaded)")
+	driveVolumeTag = []byte(":VolumeTag = ")
+	slotFull       = []byte("Full")
+	slotVolumeTag  = []byte(":VolumeTag=")
+	colon          = []byte(":")
+	changerHeader  = []byte("Storage Changer ")
+	empty          = []byte("Empty")
 )
 
 // The Interface interface describes operations supported by a library auto
@@ -41,6 +55,14 @@ type Interface interface {
 	Do(args ...string) ([]byte, error)
 }
 
+// StatusProvider may be implemented by backends that can report the status
+// of the library directly. The Changer prefers it over parsing the output
+// of Do("status"). Every call must return a status not referenced by the
+// backend.
+type StatusProvider interface {
+	Status() (*Status, error)
+}
+
 type Status struct {
 	MaxDrives       int
 	NumSlots        int
@@ -49,15 +71,24 @@ type Status struct {
 
 	Drives []*Slot
 	Slots  []*Slot
+
+	// Enclosure is the physical state of the library, or nil if the
+	// backend does not report it.
+	Enclosure *Enclosure
 }
 
 // Volume represents a tape.
 type Volume struct {
-	// The VOLSER of the tape.
+	// The VOLSER of the tape. Serial is empty if the library does not report
+	// volume tags, e.g. if it has no barcode reader or the tape is unlabeled.
 	Serial string
 
 	// The home slot of this volume.
 	Home int
+
+	// LabelErr is the error reported by the label validator of the changer
+	// if Serial failed validation. See WithLabelValidator.
+	LabelErr error
 }
 
 // String returns a textual representation of the volume.
@@ -82,40 +113,246 @@ func (slot *Slot) String() string {
 	return fmt.Sprintf("%s[%d]: %s", slot.Type, slot.Num, slot.Vol)
 }
 
+// DriveNum is the number of a data transfer element. Drives are numbered
+// from 0.
+type DriveNum int
+
+// NewDriveNum returns n as a drive number.
+func NewDriveNum(n int) (DriveNum, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("%w: drive %d", ErrInvalidElement, n)
+	}
+
+	return DriveNum(n), nil
+}
+
+// SlotNum is the number of a storage or mail slot. Slots are numbered from
+// 1.
+type SlotNum int
+
+// NewSlotNum returns n as a slot number.
+func NewSlotNum(n int) (SlotNum, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("%w: slot %d", ErrInvalidElement, n)
+	}
+
+	return SlotNum(n), nil
+}
+
 // Changer represents a library changer.
 type Changer struct {
 	Interface
+
+	logger   *slog.Logger
+	validate func(serial string) error
+
+	// concurrent Status calls share a single status query
+	mu       sync.Mutex
+	inflight *statusCall
+
+	// cached status kept by Refresh, see refresh.go
+	cache      *Status
+	refreshers int
+	mutating   int
+	gen        int
+
+	// movements of volumes, see history.go
+	historySize int
+	history     map[string][]Movement
+	initiator   string
+
+	// elements moves may use, see policy.go
+	policy Policy
+
+	// drive generations checked on loads, see compat.go
+	driveGens        map[DriveNum]int
+	warnIncompatible bool
+
+	// status parsing adapted to the library, see quirks.go
+	quirk       *Quirk
+	quirkLookup bool
+
+	// token buckets by command class, see ratelimit.go
+	limits [len(commandClassNames)]*bucket
+
+	// moves done already succeed, see idempotent.go
+	idempotent bool
+	sources    map[Move]Volume
+}
+
+// statusCall is a status query shared by concurrent callers.
+type statusCall struct {
+	done   chan struct{}
+	status *Status
+	err    error
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithLogger makes the changer log every command it performs, with its
+// arguments, duration and result, to logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(chgr *Changer) {
+		chgr.logger = logger
+	}
+}
+
+// WithLabelValidator makes Status check the serial of every labeled volume
+// with fn and record failures in Volume.LabelErr, e.g. with volser.Validate.
+func WithLabelValidator(fn func(serial string) error) Option {
+	return func(chgr *Changer) {
+		chgr.validate = fn
+	}
 }
 
 // NewChanger returns a new library changer using the given implementation.
-func NewChanger(impl Interface) *Changer {
-	return &Changer{
+func NewChanger(impl Interface, opts ...Option) *Changer {
+	chgr := &Changer{
 		Interface: impl,
 	}
+
+	for _, opt := range opts {
+		opt(chgr)
+	}
+
+	return chgr
+}
+
+// Do performs the raw operation using the underlying implementation.
+// Failures are returned as a *CommandError. Moves forbidden by the policy of
+// the changer fail with ErrNotAllowed, and loads of media the drive cannot
+// read with ErrIncompatibleMedia, without being issued. With
+// WithIdempotentMoves, moves done already succeed without being issued.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.doBy(chgr.initiator, args...)
+}
+
+// doBy is like Do, recording by as the initiator of a move.
+func (chgr *Changer) doBy(by string, args ...string) ([]byte, error) {
+	// the checks of a move share a single status query
+	st := &lazyStatus{chgr: chgr}
+
+	if err := chgr.checkPolicy(args, st); err != nil {
+		return nil, commandError(args, err)
+	}
+
+	if chgr.checkSatisfied(args, st) {
+		return nil, nil
+	}
+
+	if err := chgr.checkMedia(args, st); err != nil {
+		return nil, commandError(args, err)
+	}
+
+	var mv *pendingMove
+	if chgr.historySize > 0 && !isQuery(args) {
+		// resolve the volume while the cached status, if any, is current
+		mv = chgr.resolveMove(args, st)
+	}
+
+	if !isQuery(args) {
+		chgr.beginMutation()
+		defer chgr.endMutation()
+	}
+
+	out, err := chgr.do(args...)
+	if err == nil && mv != nil {
+		chgr.record(mv, by)
+	}
+
+	if chgr.idempotent {
+		if move, ok := moveArgs(args); ok {
+			chgr.settle(move, err)
+		}
+	}
+
+	return out, commandError(args, err)
+}
+
+// lazyStatus is the status of a changer, queried on first use.
+type lazyStatus struct {
+	chgr   *Changer
+	status *Status
+	err    error
+	done   bool
+}
+
+func (st *lazyStatus) get() (*Status, error) {
+	if !st.done {
+		st.status, st.err = st.chgr.Status()
+		st.done = true
+	}
+
+	return st.status, st.err
+}
+
+func (chgr *Changer) do(args ...string) ([]byte, error) {
+	if err := chgr.throttle(context.Background(), args); err != nil {
+		return nil, err
+	}
+
+	if chgr.logger == nil {
+		return chgr.Interface.Do(args...)
+	}
+
+	start := time.Now()
+	out, err := chgr.Interface.Do(args...)
+
+	chgr.logCommand(args, start, len(out), err)
+
+	return out, err
+}
+
+// logCommand logs a command that started at start and returned n bytes of
+// output.
+func (chgr *Changer) logCommand(args []string, start time.Time, n int, err error) {
+	attrs := []slog.Attr{
+		slog.Any("args", args),
+		slog.Duration("duration", time.Since(start)),
+	}
+
+	if err != nil {
+		chgr.logger.LogAttrs(context.Background(), slog.LevelError, "mtx command failed",
+			append(attrs, slog.String("error", err.Error()))...,
+		)
+
+		return
+	}
+
+	// status queries are frequent; only log robot operations at info level
+	level := slog.LevelInfo
+	if isQuery(args) {
+		level = slog.LevelDebug
+	}
+
+	chgr.logger.LogAttrs(context.Background(), level, "mtx command",
+		append(attrs, slog.Int("output_bytes", n))...,
+	)
 }
 
 // Load drive with the volume from slot.
-func (chgr *Changer) Load(slotnum, drivenum int) error {
+func (chgr *Changer) Load(slot SlotNum, drive DriveNum) error {
 	_, err := chgr.Do(
-		"load", strconv.Itoa(slotnum), strconv.Itoa(drivenum),
+		"load", strconv.Itoa(int(slot)), strconv.Itoa(int(drive)),
 	)
 
 	return err
 }
 
 // Unload a volume from a drive and return it to a slot.
-func (chgr *Changer) Unload(slotnum, drivenum int) error {
+func (chgr *Changer) Unload(slot SlotNum, drive DriveNum) error {
 	_, err := chgr.Do(
-		"unload", strconv.Itoa(slotnum), strconv.Itoa(drivenum),
+		"unload", strconv.Itoa(int(slot)), strconv.Itoa(int(drive)),
 	)
 
 	return err
 }
 
 // Transfer moves a volume from one slot to another.
-func (chgr *Changer) Transfer(slotnum, drivenum int) error {
+func (chgr *Changer) Transfer(src, dst SlotNum) error {
 	_, err := chgr.Do(
-		"transfer", strconv.Itoa(slotnum), strconv.Itoa(drivenum),
+		"transfer", strconv.Itoa(int(src)), strconv.Itoa(int(dst)),
 	)
 
 	return err
@@ -125,288 +362,675 @@ func (chgr *Changer) Transfer(slotnum, drivenum int) error {
 // does not necessary correspond to the number of actual drives present in
 // the system.
 func (chgr *Changer) MaxDrives() (int, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return -1, err
-	}
-
-	params, err := chgr.params(status)
+	status, err := chgr.Status()
 	if err != nil {
 		return -1, err
 	}
 
-	return params["maxDrives"], nil
+	return status.MaxDrives, nil
 }
 
 // NumSlots returns the number of storage and mail slots.
 func (chgr *Changer) NumSlots() (int, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return -1, err
-	}
-
-	params, err := chgr.params(status)
+	status, err := chgr.Status()
 	if err != nil {
 		return -1, err
 	}
 
-	return params["numSlots"], nil
+	return status.NumSlots, nil
 }
 
 // NumStorageSlots returns the number of storage slots.
 func (chgr *Changer) NumStorageSlots() (int, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return -1, err
-	}
-
-	params, err := chgr.params(status)
+	status, err := chgr.Status()
 	if err != nil {
 		return -1, err
 	}
 
-	return params["numSlots"] - params["numMailSlots"], nil
+	return status.NumStorageSlots, nil
 }
 
 // NumMailSlots returns the number of mail slots.
 func (chgr *Changer) NumMailSlots() (int, error) {
-	status, err := chgr.Do("status")
+	status, err := chgr.Status()
 	if err != nil {
 		return -1, err
 	}
 
-	params, err := chgr.params(status)
-	if err != nil {
-		return -1, err
-	}
-
-	return params["numMailSlots"], nil
+	return status.NumMailSlots, nil
 }
 
 // Drives returns a slice of data transfer elements. Note that data transfer
 // slots typically start with slot id 0.
 func (chgr *Changer) Drives() ([]*Slot, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return nil, err
-	}
-
-	elements, err := chgr.elements(status)
+	status, err := chgr.Status()
 	if err != nil {
 		return nil, err
 	}
 
-	return elements["transfer"], nil
+	return status.Drives, nil
 }
 
 // Slots returns a slice of storage and mail elements. Note that storage
 // slots typically start with slot id 1 and not 0.
 func (chgr *Changer) Slots() ([]*Slot, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return nil, err
-	}
-
-	elems, err := chgr.elements(status)
+	status, err := chgr.Status()
 	if err != nil {
 		return nil, err
 	}
 
-	return append(elems["storage"], elems["mail"]...), nil
+	return status.Slots, nil
 }
 
 // StorageSlots returns a slice of storage elements. Note that storage
 // slots typically start with slot id 1 and not 0.
 func (chgr *Changer) StorageSlots() ([]*Slot, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return nil, err
-	}
-
-	elems, err := chgr.elements(status)
+	status, err := chgr.Status()
 	if err != nil {
 		return nil, err
 	}
 
-	return elems["storage"], nil
+	return status.StorageSlots(), nil
 }
 
 // MailSlots returns a slice of storage elements. Note that mail slots
 // typically start with slot ids counting from the id of the last storage
 // slot.
 func (chgr *Changer) MailSlots() ([]*Slot, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return nil, err
-	}
-
-	elems, err := chgr.elements(status)
+	status, err := chgr.Status()
 	if err != nil {
 		return nil, err
 	}
 
-	return elems["mail"], nil
+	return status.MailSlots(), nil
 }
 
 // Status returns a Status structure with combined information about the status
 // of the library.
+//
+// Concurrent calls are coalesced: while a status query is in progress,
+// further callers wait for it and receive their own copy of its result
+// instead of querying the library again. While Refresh runs, Status returns
+// a copy of the recently cached status.
 func (chgr *Changer) Status() (*Status, error) {
-	status, err := chgr.Do("status")
-	if err != nil {
-		return nil, err
+	chgr.mu.Lock()
+	if chgr.cache != nil {
+		status := chgr.cache.Clone()
+		chgr.mu.Unlock()
+
+		return status, nil
 	}
+	chgr.mu.Unlock()
 
-	params, err := chgr.params(status)
-	elems, err := chgr.elements(status)
+	return chgr.fetch()
+}
 
-	return &Status{
-		MaxDrives:       params["maxDrives"],
-		NumSlots:        params["numSlots"],
-		NumStorageSlots: params["numSlots"] - params["numMailSlots"],
-		NumMailSlots:    params["numMailSlots"],
+// fetch queries the status, coalescing concurrent calls.
+func (chgr *Changer) fetch() (*Status, error) {
+	chgr.mu.Lock()
+	if call := chgr.inflight; call != nil {
+		chgr.mu.Unlock()
+		<-call.done
 
-		Drives: elems["transfer"],
-		Slots:  append(elems["storage"], elems["mail"]...),
-	}, nil
+		if call.err != nil {
+			return nil, call.err
+		}
+
+		return call.status.Clone(), nil
+	}
+
+	call := &statusCall{done: make(chan struct{})}
+	chgr.inflight = call
+	gen := chgr.gen
+	chgr.mu.Unlock()
+
+	call.status, call.err = chgr.status()
+
+	chgr.mu.Lock()
+	chgr.inflight = nil
+	chgr.store(call.status, gen)
+	chgr.mu.Unlock()
+
+	// the caller owns the original; waiters copy it before it is handed out
+	var status *Status
+	if call.status != nil {
+		status = call.status
+		call.status = status.Clone()
+	}
+
+	close(call.done)
+
+	return status, call.err
 }
 
-func (chgr *Changer) elements(status []byte) (map[string][]*Slot, error) {
-	elements := map[string][]*Slot{
-		"transfer": make([]*Slot, 0),
-		"storage":  make([]*Slot, 0),
-		"mail":     make([]*Slot, 0),
+func (chgr *Changer) status() (*Status, error) {
+	return chgr.queryStatus(context.Background())
+}
+
+// queryStatus queries the status of the library, bypassing the cache.
+// Backends implementing StatusProvider are asked for the status directly;
+// for others the output of the status command is parsed.
+func (chgr *Changer) queryStatus(ctx context.Context) (*Status, error) {
+	q := chgr.currentQuirk()
+
+	var status *Status
+	if impl, ok := chgr.Interface.(StatusProvider); ok {
+		if err := chgr.throttle(ctx, []string{"status"}); err != nil {
+			return nil, commandError([]string{"status"}, err)
+		}
+
+		var err error
+		if status, err = impl.Status(); err != nil {
+			return nil, commandError([]string{"status"}, err)
+		}
+	} else {
+		out, err := chgr.doContext(ctx, "status")
+		if err != nil {
+			return nil, err
+		}
+
+		parse := ParseStatus
+		if q != nil && q.Parse != nil {
+			parse = q.Parse
+		}
+
+		if status, err = parse(out); err != nil {
+			return nil, err
+		}
 	}
 
-	scanner := bufio.NewScanner(bytes.NewReader(status))
+	if q != nil && q.Fixup != nil {
+		if err := q.Fixup(status); err != nil {
+			return nil, err
+		}
+	}
 
-	// skip header
-	scanner.Scan()
+	chgr.validateLabels(status)
 
-	// scan elements
-	var matches []string
-	for scanner.Scan() {
-		line := scanner.Text()
+	return status, nil
+}
 
-		// match data transfer elements
-		matches = driveRegexp.FindStringSubmatch(line)
-		if matches != nil {
-			elemnum, err := strconv.Atoi(matches[1])
-			if err != nil {
-				return nil, err
-			}
+// validateLabels runs the label validator, if any, on the volumes in status.
+func (chgr *Changer) validateLabels(status *Status) {
+	if chgr.validate == nil {
+		return
+	}
 
-			slot := &Slot{Num: elemnum, Type: DataTransferSlot}
+	for _, slot := range append(append([]*Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol != nil && slot.Vol.Serial != "" {
+			slot.Vol.LabelErr = chgr.validate(slot.Vol.Serial)
+		}
+	}
+}
 
-			if matches[2] != "Empty" {
-				matches = driveElementRegexp.FindStringSubmatch(matches[2])
-				if matches == nil {
-					return nil, errors.New("failed to parse transfer element")
-				}
+// Clone returns a deep copy of status. The slots and volumes of the copy
+// can be modified without affecting status, and vice versa.
+func (status *Status) Clone() *Status {
+	if status == nil {
+		return nil
+	}
 
-				home, err := strconv.Atoi(matches[1])
-				if err != nil {
-					return nil, err
-				}
+	c := *status
 
-				slot.Vol = &Volume{Serial: matches[2], Home: home}
+	copySlots := func(slots []*Slot) []*Slot {
+		out := make([]*Slot, len(slots))
+		for i, slot := range slots {
+			s := *slot
+			if slot.Vol != nil {
+				vol := *slot.Vol
+				s.Vol = &vol
 			}
 
-			elements["transfer"] = append(elements["transfer"], slot)
+			out[i] = &s
+		}
 
-			continue
+		return out
+	}
+
+	c.Drives = copySlots(status.Drives)
+	c.Slots = copySlots(status.Slots)
+
+	if status.Enclosure != nil {
+		enc := *status.Enclosure
+		enc.Magazines = append([]Magazine(nil), enc.Magazines...)
+		c.Enclosure = &enc
+	}
+
+	return &c
+}
+
+// Suspicious returns the slots holding volumes whose label failed
+// validation.
+func (status *Status) Suspicious() []*Slot {
+	var slots []*Slot
+	for _, slot := range append(append([]*Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol != nil && slot.Vol.LabelErr != nil {
+			slots = append(slots, slot)
 		}
+	}
+
+	return slots
+}
+
+// ParseStatus parses the output of 'mtx status' in a single pass. Output
+// reporting more than one changer fails with a *ParseError matching
+// ErrMultipleChangers; use ParseStatuses for it.
+func ParseStatus(status []byte) (*Status, error) {
+	return parseStatus(status, nil)
+}
 
-		// match storage elements
-		matches = slotRegexp.FindStringSubmatch(line)
-		if matches != nil {
-			elemnum, err := strconv.Atoi(matches[1])
-			if err != nil {
-				return nil, err
+// ParseStatuses parses status output that may report several changers, as
+// produced by some stacked libraries, into one status per changer header,
+// in order.
+func ParseStatuses(status []byte) ([]*Status, error) {
+	var statuses []*Status
+
+	// line numbers of errors are those of the whole output
+	offset := 0
+	for len(status) > 0 {
+		// the section ends before the next header line
+		n, lines := len(status), 0
+		for i := 0; i < len(status); lines++ {
+			next := len(status)
+			if j := bytes.IndexByte(status[i:], '\n'); j >= 0 {
+				next = i + j + 1
 			}
 
-			slot := &Slot{Num: elemnum, Type: StorageSlot}
+			if i > 0 && bytes.Contains(status[i:next], changerHeader) {
+				n = i
+				break
+			}
 
-			if matches[2] != "Empty" {
-				match := slotElementRegexp.FindStringSubmatch(matches[2])
-				if match == nil {
-					return nil, errors.New("failed to parse slot element: " + matches[2])
-				}
+			i = next
+		}
 
-				slot.Vol = &Volume{Serial: match[1], Home: elemnum}
+		st, err := ParseStatus(status[:n])
+		if err != nil {
+			var perr *ParseError
+			if errors.As(err, &perr) {
+				perr.Line += offset
 			}
 
-			elements["storage"] = append(elements["storage"], slot)
+			return nil, err
+		}
+
+		statuses = append(statuses, st)
+		status = status[n:]
+		offset += lines
+	}
 
-			continue
+	return statuses, nil
+}
+
+// parseStatus parses the output of 'mtx status', keeping only the elements
+// in r if it is non-nil. Skipped element lines are never allocated for.
+func parseStatus(status []byte, r *Range) (*Status, error) {
+	st := &Status{
+		Drives: make([]*Slot, 0),
+		Slots:  make([]*Slot, 0),
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+
+	if scanner.Scan() {
+		if err := st.parseHeader(scanner.Text()); err != nil {
+			return nil, &ParseError{Line: 1, Text: scanner.Text(), Err: err}
 		}
+	}
 
-		// match mailslot elements
-		matches = mailSlotRegexp.FindStringSubmatch(line)
-		if matches != nil {
-			elemnum, err := strconv.Atoi(matches[1])
-			if err != nil {
-				return nil, err
-			}
+	// size the element slices and slabs after the header, but never beyond
+	// what the output can hold, whatever the header claims
+	n := st.MaxDrives + st.NumSlots
+	if limit := len(status)/16 + 1; n > limit || n < 0 {
+		n = limit
+	}
+
+	if r != nil {
+		n = min(n, 64)
+	}
 
-			slot := &Slot{Num: elemnum, Type: MailSlot}
+	p := parser{
+		slots: make([]Slot, n),
+		vols:  make([]Volume, n),
+	}
 
-			if matches[2] != "Empty" {
-				matches = slotElementRegexp.FindStringSubmatch(matches[2])
-				if matches == nil {
-					return nil, errors.New("failed to parse slot element")
-				}
+	st.Drives = make([]*Slot, 0, min(st.MaxDrives, n))
+	st.Slots = make([]*Slot, 0, min(st.NumSlots, n))
 
-				slot.Vol = &Volume{Serial: matches[1], Home: elemnum}
+	// mail slots are listed after the storage slots by mtx, but keep them
+	// last regardless
+	var mail []*Slot
+	for line := 2; scanner.Scan(); line++ {
+		typ, num, rest, err := classify(scanner.Bytes())
+		if err != nil {
+			if bytes.Contains(scanner.Bytes(), changerHeader) {
+				err = ErrMultipleChangers
 			}
 
-			elements["mail"] = append(elements["mail"], slot)
+			return nil, &ParseError{Line: line, Text: scanner.Text(), Err: err}
+		}
 
+		if r != nil && !r.contains(typ, num) {
 			continue
 		}
 
-		return nil, errors.New("failed to parse slot")
+		slot, err := p.parseElement(typ, num, rest)
+		if err != nil {
+			return nil, &ParseError{Line: line, Text: scanner.Text(), Err: err}
+		}
+
+		switch slot.Type {
+		case DataTransferSlot:
+			st.Drives = append(st.Drives, slot)
+		case StorageSlot:
+			st.Slots = append(st.Slots, slot)
+		case MailSlot:
+			mail = append(mail, slot)
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	return elements, nil
+	st.Slots = append(st.Slots, mail...)
+
+	return st, nil
 }
 
-func (chgr *Changer) params(status []byte) (map[string]int, error) {
-	params := make(map[string]int)
+// RenderStatus formats status like 'mtx status' does, reporting device as
+// the changer device. It is the inverse of ParseStatus, for backends that
+// must answer Do("status") but hold the status in structured form.
+func RenderStatus(device string, status *Status) []byte {
+	var buf bytes.Buffer
 
-	scanner := bufio.NewScanner(bytes.NewReader(status))
+	fmt.Fprintf(&buf, "  Storage Changer %s:%d Drives, %d Slots ( %d Import/Export )\n",
+		device, status.MaxDrives, status.NumSlots, status.NumMailSlots,
+	)
 
-	var err error
-	if scanner.Scan() {
-		line := scanner.Text()
+	for _, slot := range status.Drives {
+		fmt.Fprintf(&buf, "Data Transfer Element %d:", slot.Num)
+		if slot.Vol == nil {
+			fmt.Fprintf(&buf, "Empty\n")
+			continue
+		}
+
+		fmt.Fprintf(&buf, "Full (Storage Element %d Loaded):VolumeTag = %s\n", slot.Vol.Home, slot.Vol.Serial)
+	}
 
-		matches := hdrRegexp.FindStringSubmatch(line)
-		if matches == nil {
-			return nil, errors.New("failed to match mtx status header")
+	for _, slot := range status.Slots {
+		fmt.Fprintf(&buf, "      Storage Element %d", slot.Num)
+		if slot.Type == MailSlot {
+			fmt.Fprintf(&buf, " IMPORT/EXPORT")
 		}
 
-		params["maxDrives"], err = strconv.Atoi(matches[2])
-		if err != nil {
-			return nil, err
+		if slot.Vol == nil {
+			fmt.Fprintf(&buf, ":Empty\n")
+			continue
 		}
 
-		params["numSlots"], err = strconv.Atoi(matches[3])
-		if err != nil {
-			return nil, err
+		fmt.Fprintf(&buf, ":Full :VolumeTag=%s\n", slot.Vol.Serial)
+	}
+
+	return buf.Bytes()
+}
+
+// StorageSlots returns the storage slots in status.
+func (status *Status) StorageSlots() []*Slot {
+	return Filter(status.Slots, IsStorage)
+}
+
+// MailSlots returns the mail slots in status.
+func (status *Status) MailSlots() []*Slot {
+	return Filter(status.Slots, IsMail)
+}
+
+// ErrVolumeNotFound is returned when a volume is not present in the library.
+var ErrVolumeNotFound = errors.New("mtx: volume not found")
+
+// Find returns the slot or data transfer element holding the volume with the
+// given serial. If the serial occurs more than once, the first occurrence is
+// returned, checking drives before slots.
+func (chgr *Changer) Find(serial string) (*Slot, error) {
+	status, err := chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if slot := status.Find(serial); slot != nil {
+		return slot, nil
+	}
+
+	return nil, ErrVolumeNotFound
+}
+
+// Export moves the volume with the given serial to the first empty mail slot
+// allowed by the policy of the changer and returns that slot. If the volume
+// already is in a mail slot, that slot is returned. The volume must not be
+// loaded in a drive.
+func (chgr *Changer) Export(serial string) (*Slot, error) {
+	status, err := chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var src, dst *Slot
+	for _, slot := range append(status.Drives, status.Slots...) {
+		if src == nil && slot.Vol != nil && slot.Vol.Serial == serial {
+			src = slot
+		}
+
+		if dst == nil && slot.Type == MailSlot && slot.Vol == nil && chgr.allowedSlot(slot) {
+			dst = slot
+		}
+	}
+
+	switch {
+	case src == nil:
+		return nil, ErrVolumeNotFound
+	case src.Type == DataTransferSlot:
+		return nil, fmt.Errorf("%w: %s in drive %d", ErrVolumeLoaded, serial, src.Num)
+	case src.Type == MailSlot:
+		return src, nil
+	case dst == nil:
+		return nil, ErrNoMailSlot
+	}
+
+	if err := chgr.Transfer(SlotNum(src.Num), SlotNum(dst.Num)); err != nil {
+		return nil, err
+	}
+
+	dst.Vol = src.Vol
+
+	return dst, nil
+}
+
+// parser allocates the slots and volumes of a status from slabs.
+type parser struct {
+	slots []Slot
+	vols  []Volume
+}
+
+func (p *parser) slot(num int, typ SlotType) *Slot {
+	if len(p.slots) == 0 {
+		p.slots = make([]Slot, 64)
+	}
+
+	slot := &p.slots[0]
+	p.slots = p.slots[1:]
+
+	slot.Num, slot.Type = num, typ
+
+	return slot
+}
+
+func (p *parser) volume(serial []byte, home int) *Volume {
+	if len(p.vols) == 0 {
+		p.vols = make([]Volume, 64)
+	}
+
+	vol := &p.vols[0]
+	p.vols = p.vols[1:]
+
+	vol.Serial, vol.Home = string(serial), home
+
+	return vol
+}
+
+// classify identifies a data transfer, storage or mail element line and
+// returns its type, element number and the description of its contents. The
+// line is matched like the regular expressions
+//
+//	Data Transfer Element (\d*):(.*)
+//	Storage Element (\d*):(.*)
+//	Storage Element (\d*) IMPORT/EXPORT:(.*)
+//
+// in that order, but without allocating.
+func classify(line []byte) (SlotType, int, []byte, error) {
+	typ := DataTransferSlot
+	num, rest, ok := cut(line, drivePrefix, colon)
+	if !ok {
+		typ = StorageSlot
+		num, rest, ok = cut(line, slotPrefix, colon)
+	}
+
+	if !ok {
+		typ = MailSlot
+		num, rest, ok = cut(line, slotPrefix, mailSuffix)
+	}
+
+	if !ok {
+		return 0, 0, nil, errors.New("failed to parse slot")
+	}
+
+	elemnum, err := atoi(num)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	return typ, elemnum, rest, nil
+}
+
+// parseElement parses the contents of an element as returned by classify.
+// Occupied elements are matched like
+//
+//	Full \(Storage Element (\d*) Loaded\)(?::VolumeTag = (.*))?
+//	Full\s*(?::VolumeTag=(.*))?
+//
+// for drives and slots respectively.
+func (p *parser) parseElement(typ SlotType, num int, rest []byte) (*Slot, error) {
+	slot := p.slot(num, typ)
+
+	if bytes.Equal(rest, empty) {
+		return slot, nil
+	}
+
+	if typ == DataTransferSlot {
+		home, rest, ok := cut(rest, loadedPrefix, loadedSuffix)
+		if !ok {
+			return nil, errors.New("failed to parse transfer element")
 		}
 
-		params["numMailSlots"], err = strconv.Atoi(matches[4])
+		homenum, err := atoi(home)
 		if err != nil {
 			return nil, err
 		}
+
+		var serial []byte
+		if bytes.HasPrefix(rest, driveVolumeTag) {
+			serial = rest[len(driveVolumeTag):]
+		}
+
+		slot.Vol = p.volume(serial, homenum)
+
+		return slot, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	i := bytes.Index(rest, slotFull)
+	if i < 0 {
+		return nil, errors.New("failed to parse slot element")
+	}
+
+	rest = bytes.TrimLeft(rest[i+len(slotFull):], " \t\n\f\r")
+
+	var serial []byte
+	if bytes.HasPrefix(rest, slotVolumeTag) {
+		serial = rest[len(slotVolumeTag):]
+	}
+
+	slot.Vol = p.volume(serial, num)
+
+	return slot, nil
+}
+
+// cut finds the first occurrence of prefix in line that is followed by a
+// (possibly empty) run of digits and sep. It returns the digits and the
+// remainder of the line after sep.
+func cut(line, prefix, sep []byte) (digits, rest []byte, ok bool) {
+	for off := 0; ; {
+		i := bytes.Index(line[off:], prefix)
+		if i < 0 {
+			return nil, nil, false
+		}
+
+		start := off + i + len(prefix)
+		end := start
+		for end < len(line) && '0' <= line[end] && line[end] <= '9' {
+			end++
+		}
+
+		if bytes.HasPrefix(line[end:], sep) {
+			return line[start:end], line[end+len(sep):], true
+		}
+
+		off += i + 1
 	}
+}
+
+// atoi is strconv.Atoi for runs of digits, without converting them to a
+// string first.
+func atoi(digits []byte) (int, error) {
+	// leave the empty and possibly overflowing cases, and their errors, to
+	// strconv
+	if len(digits) == 0 || len(digits) > 18 {
+		return strconv.Atoi(string(digits))
+	}
+
+	n := 0
+	for _, c := range digits {
+		n = n*10 + int(c-'0')
+	}
+
+	return n, nil
+}
+
+// parseHeader parses the 'Storage Changer' header line into status.
+func (status *Status) parseHeader(line string) error {
+	matches := hdrRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return errors.New("failed to match mtx status header")
+	}
+
+	var err error
+	status.MaxDrives, err = strconv.Atoi(matches[2])
+	if err != nil {
+		return err
+	}
+
+	status.NumSlots, err = strconv.Atoi(matches[3])
+	if err != nil {
+		return err
+	}
+
+	// the Import/Export clause is left out by some versions of mtx
+	if matches[4] != "" {
+		status.NumMailSlots, err = strconv.Atoi(matches[4])
+		if err != nil {
+			return err
+		}
+	}
+
+	status.NumStorageSlots = status.NumSlots - status.NumMailSlots
 
-	return params, nil
+	return nil
 }
@@ -0,0 +1,198 @@
+package mtx
+
+import (
+	"errors"
+	"testing"
+)
+
+const testStatus = `  Storage Changer /dev/sg3:2 Drives, 6 Slots ( 2 Import/Export )
+Data Transfer Element 0:Full (Storage Element 2 Loaded):VolumeTag = S00001L6
+Data Transfer Element 1:Empty
+      Storage Element 1:Full :VolumeTag=S00000L6
+      Storage Element 5 IMPORT/EXPORT:Full :VolumeTag=M00000L6
+      Storage Element 2:Empty
+      Storage Element 3:Full
+      Storage Element 4:Empty
+      Storage Element 6 IMPORT/EXPORT:Empty
+`
+
+func TestParseStatus(t *testing.T) {
+	status, err := ParseStatus([]byte(testStatus))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.MaxDrives != 2 || status.NumSlots != 6 || status.NumStorageSlots != 4 || status.NumMailSlots != 2 {
+		t.Errorf("counts = %d drives, %d slots (%d storage, %d mail), want 2, 6 (4, 2)",
+			status.MaxDrives, status.NumSlots, status.NumStorageSlots, status.NumMailSlots)
+	}
+
+	want := []struct {
+		typ    SlotType
+		num    int
+		serial string
+		home   int
+	}{
+		{DataTransferSlot, 0, "S00001L6", 2},
+		{DataTransferSlot, 1, "-", 0},
+		{StorageSlot, 1, "S00000L6", 1},
+		{StorageSlot, 2, "-", 0},
+		{StorageSlot, 3, "", 3},
+		{StorageSlot, 4, "-", 0},
+
+		// mail slots are kept last
+		{MailSlot, 5, "M00000L6", 5},
+		{MailSlot, 6, "-", 0},
+	}
+
+	slots := append(status.Drives[:len(status.Drives):len(status.Drives)], status.Slots...)
+	if len(slots) != len(want) {
+		t.Fatalf("got %d elements, want %d", len(slots), len(want))
+	}
+
+	for i, w := range want {
+		slot := slots[i]
+		if slot.Type != w.typ || slot.Num != w.num {
+			t.Errorf("element %d is %s %d, want %s %d", i, slot.Type, slot.Num, w.typ, w.num)
+			continue
+		}
+
+		switch {
+		case w.serial == "-":
+			if slot.Vol != nil {
+				t.Errorf("%s %d holds %q, want empty", slot.Type, slot.Num, slot.Vol.Serial)
+			}
+		case slot.Vol == nil:
+			t.Errorf("%s %d is empty, want %q", slot.Type, slot.Num, w.serial)
+		case slot.Vol.Serial != w.serial || slot.Vol.Home != w.home:
+			t.Errorf("%s %d holds %q home %d, want %q home %d",
+				slot.Type, slot.Num, slot.Vol.Serial, slot.Vol.Home, w.serial, w.home)
+		}
+	}
+}
+
+func TestParseStatusHeader(t *testing.T) {
+	for _, tc := range []struct {
+		header              string
+		drives, slots, mail int
+		wantErr             bool
+	}{
+		{"  Storage Changer /dev/sg3:4 Drives, 36 Slots ( 4 Import/Export )", 4, 36, 4, false},
+		{"  Storage Changer /dev/sg3:1 Drives, 8 Slots", 1, 8, 0, false},
+		{"Storage Changer /dev/changer:0 Drives, 0 Slots ( 0 Import/Export )", 0, 0, 0, false},
+		{"mtx: cannot open SCSI device", 0, 0, 0, true},
+	} {
+		status, err := ParseStatus([]byte(tc.header + "\n"))
+		if tc.wantErr {
+			var perr *ParseError
+			if !errors.As(err, &perr) || perr.Line != 1 || !errors.Is(err, ErrParse) {
+				t.Errorf("%q: error %v, want parse error on line 1", tc.header, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tc.header, err)
+			continue
+		}
+
+		if status.MaxDrives != tc.drives || status.NumSlots != tc.slots || status.NumMailSlots != tc.mail ||
+			status.NumStorageSlots != tc.slots-tc.mail {
+			t.Errorf("%q: %d drives, %d slots, %d mail, want %d, %d, %d",
+				tc.header, status.MaxDrives, status.NumSlots, status.NumMailSlots, tc.drives, tc.slots, tc.mail)
+		}
+	}
+}
+
+func TestParseStatusErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status string
+		line   int
+		err    error
+	}{
+		{
+			"malformed element",
+			"  Storage Changer /dev/sg3:1 Drives, 2 Slots ( 0 Import/Export )\n" +
+				"Data Transfer Element 0:Empty\n" +
+				"      Storage Element 1:Occupied\n",
+			3, ErrParse,
+		},
+		{
+			"malformed drive",
+			"  Storage Changer /dev/sg3:1 Drives, 2 Slots ( 0 Import/Export )\n" +
+				"Data Transfer Element 0:Full\n",
+			2, ErrParse,
+		},
+		{
+			"several changers",
+			testStatus + testStatus,
+			10, ErrMultipleChangers,
+		},
+	} {
+		_, err := ParseStatus([]byte(tc.status))
+
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Line != tc.line || !errors.Is(err, tc.err) {
+			t.Errorf("%s: error %v, want %v on line %d", tc.name, err, tc.err, tc.line)
+		}
+	}
+}
+
+func TestParseStatuses(t *testing.T) {
+	other := "  Storage Changer /dev/sg4:1 Drives, 2 Slots ( 0 Import/Export )\n" +
+		"Data Transfer Element 0:Empty\n" +
+		"      Storage Element 1:Full :VolumeTag=T00000L7\n" +
+		"      Storage Element 2:Empty\n"
+
+	statuses, err := ParseStatuses([]byte(testStatus + other))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 {
+		t.Fatalf("got %d statuses, want 2", len(statuses))
+	}
+
+	if n := len(statuses[0].Slots); n != 6 {
+		t.Errorf("first changer has %d slots, want 6", n)
+	}
+
+	if vol := statuses[1].Slots[0].Vol; statuses[1].MaxDrives != 1 || vol == nil || vol.Serial != "T00000L7" {
+		t.Errorf("second changer = %d drives, slot 1 %v, want 1 drive, T00000L7", statuses[1].MaxDrives, vol)
+	}
+
+	// line numbers are those of the whole output
+	_, err = ParseStatuses([]byte(testStatus + other + "garbage\n"))
+
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 14 {
+		t.Errorf("error %v, want parse error on line 14", err)
+	}
+}
+
+func TestParseStatusRange(t *testing.T) {
+	status, err := parseStatus([]byte(testStatus), &Range{Types: []SlotType{StorageSlot}, First: 2, Last: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(status.Drives) != 0 || len(status.Slots) != 2 || status.Slots[0].Num != 2 || status.Slots[1].Num != 3 {
+		t.Errorf("got %d drives and slots %v, want storage slots 2 and 3", len(status.Drives), status.Slots)
+	}
+
+	// the counts of the header are kept
+	if status.NumSlots != 6 {
+		t.Errorf("NumSlots = %d, want 6", status.NumSlots)
+	}
+
+	// elements out of the range are not parsed
+	if _, err := parseStatus([]byte(testStatus+"Data Transfer Element 2:Full\n"), &DriveRange); err == nil {
+		t.Error("malformed drive in range parsed")
+	}
+
+	if _, err := parseStatus([]byte(testStatus+"Data Transfer Element 2:Full\n"), &MailSlotRange); err != nil {
+		t.Errorf("malformed drive out of range: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package mtxgrpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mtxgrpc/mtxpb"
+)
+
+// Changer implements the mtx.Interface for a library changer exposed by a
+// Server on a remote host.
+type Changer struct {
+	client mtxpb.ChangerClient
+}
+
+// New returns a new changer implementation using the given connection.
+func New(conn grpc.ClientConnInterface) *Changer {
+	return &Changer{client: mtxpb.NewChangerClient(conn)}
+}
+
+// Do performs the given operation on the remote changer. The status, load,
+// unload and transfer commands are supported. The output of status is
+// rendered in the format of 'mtx status'.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext is like Do, but uses ctx for the remote call.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	if len(args) < 1 {
+		return nil, errors.New("no command given")
+	}
+
+	cmd := args[0]
+
+	if cmd == "status" {
+		st, err := chgr.client.GetStatus(ctx, &mtxpb.GetStatusRequest{})
+		if err != nil {
+			return nil, unwrap(err)
+		}
+
+		return mtx.RenderStatus("remote", FromProto(st)), nil
+	}
+
+	if len(args) != 3 {
+		return nil, errors.New("wrong number of arguments")
+	}
+
+	a, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := strconv.Atoi(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	switch cmd {
+	case "load":
+		_, err = chgr.client.Load(ctx, &mtxpb.LoadRequest{Slot: int32(a), Drive: int32(b)})
+	case "unload":
+		_, err = chgr.client.Unload(ctx, &mtxpb.LoadRequest{Slot: int32(a), Drive: int32(b)})
+	case "transfer":
+		_, err = chgr.client.Transfer(ctx, &mtxpb.TransferRequest{Src: int32(a), Dst: int32(b)})
+	default:
+		return nil, errors.New("mtx/mtxgrpc: unknown or unsupported mtx command")
+	}
+
+	return nil, unwrap(err)
+}
+
+// Status implements mtx.StatusProvider, returning the status reported by the
+// server without rendering it in the format of 'mtx status'.
+func (chgr *Changer) Status() (*mtx.Status, error) {
+	st, err := chgr.client.GetStatus(context.Background(), &mtxpb.GetStatusRequest{})
+	if err != nil {
+		return nil, unwrap(err)
+	}
+
+	return FromProto(st), nil
+}
+
+// Watch streams the status of the remote library, polled by the server at
+// the given interval, to fn until ctx is done or the stream fails.
+func (chgr *Changer) Watch(ctx context.Context, interval time.Duration, fn func(status *mtx.Status)) error {
+	stream, err := chgr.client.WatchStatus(ctx, &mtxpb.WatchStatusRequest{
+		IntervalMs: int64(interval / time.Millisecond),
+	})
+	if err != nil {
+		return unwrap(err)
+	}
+
+	for {
+		st, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return unwrap(err)
+		}
+
+		fn(FromProto(st))
+	}
+}
@@ -0,0 +1,110 @@
+package mtxgrpc
+
+import (
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mtxgrpc/mtxpb"
+)
+
+var slotTypes = map[mtx.SlotType]mtxpb.SlotType{
+	mtx.DataTransferSlot: mtxpb.SlotType_SLOT_TYPE_DRIVE,
+	mtx.StorageSlot:      mtxpb.SlotType_SLOT_TYPE_STORAGE,
+	mtx.MailSlot:         mtxpb.SlotType_SLOT_TYPE_MAIL,
+}
+
+func toProtoSlots(slots []*mtx.Slot) []*mtxpb.Slot {
+	out := make([]*mtxpb.Slot, 0, len(slots))
+	for _, slot := range slots {
+		s := &mtxpb.Slot{Num: int32(slot.Num), Type: slotTypes[slot.Type]}
+		if slot.Vol != nil {
+			s.Volume = &mtxpb.Volume{Serial: slot.Vol.Serial, Home: int32(slot.Vol.Home)}
+		}
+
+		out = append(out, s)
+	}
+
+	return out
+}
+
+func toProtoStatus(status *mtx.Status) *mtxpb.Status {
+	return &mtxpb.Status{
+		MaxDrives:       int32(status.MaxDrives),
+		NumSlots:        int32(status.NumSlots),
+		NumStorageSlots: int32(status.NumStorageSlots),
+		NumMailSlots:    int32(status.NumMailSlots),
+
+		Drives: toProtoSlots(status.Drives),
+		Slots:  toProtoSlots(status.Slots),
+
+		Enclosure: toProtoEnclosure(status.Enclosure),
+	}
+}
+
+func toProtoEnclosure(enc *mtx.Enclosure) *mtxpb.Enclosure {
+	if enc == nil {
+		return nil
+	}
+
+	out := &mtxpb.Enclosure{DoorOpen: enc.DoorOpen, StationOpen: enc.StationOpen}
+	for _, mag := range enc.Magazines {
+		out.Magazines = append(out.Magazines, &mtxpb.Magazine{
+			First:   int32(mag.First),
+			Last:    int32(mag.Last),
+			Present: mag.Present,
+		})
+	}
+
+	return out
+}
+
+func fromProtoSlots(slots []*mtxpb.Slot) []*mtx.Slot {
+	out := make([]*mtx.Slot, 0, len(slots))
+	for _, s := range slots {
+		slot := &mtx.Slot{Num: int(s.GetNum())}
+		for typ, pt := range slotTypes {
+			if pt == s.GetType() {
+				slot.Type = typ
+			}
+		}
+
+		if vol := s.GetVolume(); vol != nil {
+			slot.Vol = &mtx.Volume{Serial: vol.GetSerial(), Home: int(vol.GetHome())}
+		}
+
+		out = append(out, slot)
+	}
+
+	return out
+}
+
+// FromProto converts the protocol buffer representation of the status to an
+// mtx.Status.
+func FromProto(status *mtxpb.Status) *mtx.Status {
+	return &mtx.Status{
+		MaxDrives:       int(status.GetMaxDrives()),
+		NumSlots:        int(status.GetNumSlots()),
+		NumStorageSlots: int(status.GetNumStorageSlots()),
+		NumMailSlots:    int(status.GetNumMailSlots()),
+
+		Drives: fromProtoSlots(status.GetDrives()),
+		Slots:  fromProtoSlots(status.GetSlots()),
+
+		Enclosure: fromProtoEnclosure(status.GetEnclosure()),
+	}
+}
+
+func fromProtoEnclosure(enc *mtxpb.Enclosure) *mtx.Enclosure {
+	if enc == nil {
+		return nil
+	}
+
+	out := &mtx.Enclosure{DoorOpen: enc.GetDoorOpen(), StationOpen: enc.GetStationOpen()}
+	for _, mag := range enc.GetMagazines() {
+		out.Magazines = append(out.Magazines, mtx.Magazine{
+			First:   int(mag.GetFirst()),
+			Last:    int(mag.GetLast()),
+			Present: mag.GetPresent(),
+		})
+	}
+
+	return out
+}
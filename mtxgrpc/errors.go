@@ -0,0 +1,110 @@
+package mtxgrpc
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kbj/mtx"
+)
+
+// errorDomain is the domain of the ErrorInfo details identifying mtx
+// errors.
+const errorDomain = "mtx"
+
+// errorKinds are the mtx errors reported with their own status code. As
+// codes are shared, the error is identified by the reason of an ErrorInfo
+// detail.
+var errorKinds = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{mtx.ErrEmpty, codes.FailedPrecondition, "EMPTY"},
+	{mtx.ErrFull, codes.FailedPrecondition, "FULL"},
+	{mtx.ErrIncompatibleMedia, codes.FailedPrecondition, "INCOMPATIBLE_MEDIA"},
+	{mtx.ErrNotAllowed, codes.PermissionDenied, "NOT_ALLOWED"},
+	{mtx.ErrReadOnly, codes.PermissionDenied, "READ_ONLY"},
+	{mtx.ErrInvalidElement, codes.InvalidArgument, "INVALID_ELEMENT"},
+}
+
+// toStatusError returns the gRPC status error reporting err, a failed
+// command of the changer. Errors of unknown kind are internal errors.
+func toStatusError(err error) error {
+	for _, k := range errorKinds {
+		if !errors.Is(err, k.err) {
+			continue
+		}
+
+		st, derr := status.New(k.code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+			Reason: k.reason,
+			Domain: errorDomain,
+		})
+		if derr != nil {
+			return status.Error(k.code, err.Error())
+		}
+
+		return st.Err()
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// remoteError is an error of the remote changer. It matches the mtx error
+// it was reported as, if any.
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.kind
+}
+
+// unwrap turns a gRPC status error into an error carrying the message of the
+// remote changer and matching the mtx error it reports, e.g. mtx.ErrEmpty.
+func unwrap(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	return &remoteError{msg: st.Message(), kind: errorKind(st)}
+}
+
+// errorKind returns the mtx error reported by st, or nil.
+func errorKind(st *status.Status) error {
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != errorDomain {
+			continue
+		}
+
+		for _, k := range errorKinds {
+			if k.code == st.Code() && k.reason == info.GetReason() {
+				return k.err
+			}
+		}
+	}
+
+	// codes that identify the error by themselves, for servers not sending
+	// details
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return mtx.ErrInvalidElement
+	case codes.PermissionDenied:
+		return mtx.ErrNotAllowed
+	}
+
+	return nil
+}
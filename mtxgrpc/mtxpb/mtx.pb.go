@@ -0,0 +1,749 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: mtx.proto
+
+package mtxpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SlotType int32
+
+const (
+	SlotType_SLOT_TYPE_DRIVE   SlotType = 0
+	SlotType_SLOT_TYPE_STORAGE SlotType = 1
+	SlotType_SLOT_TYPE_MAIL    SlotType = 2
+)
+
+// Enum value maps for SlotType.
+var (
+	SlotType_name = map[int32]string{
+		0: "SLOT_TYPE_DRIVE",
+		1: "SLOT_TYPE_STORAGE",
+		2: "SLOT_TYPE_MAIL",
+	}
+	SlotType_value = map[string]int32{
+		"SLOT_TYPE_DRIVE":   0,
+		"SLOT_TYPE_STORAGE": 1,
+		"SLOT_TYPE_MAIL":    2,
+	}
+)
+
+func (x SlotType) Enum() *SlotType {
+	p := new(SlotType)
+	*p = x
+	return p
+}
+
+func (x SlotType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SlotType) Descriptor() protoreflect.EnumDescriptor {
+	return file_mtx_proto_enumTypes[0].Descriptor()
+}
+
+func (SlotType) Type() protoreflect.EnumType {
+	return &file_mtx_proto_enumTypes[0]
+}
+
+func (x SlotType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SlotType.Descriptor instead.
+func (SlotType) EnumDescriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{0}
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_mtx_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{0}
+}
+
+type Volume struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Serial        string                 `protobuf:"bytes,1,opt,name=serial,proto3" json:"serial,omitempty"`
+	Home          int32                  `protobuf:"varint,2,opt,name=home,proto3" json:"home,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Volume) Reset() {
+	*x = Volume{}
+	mi := &file_mtx_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Volume) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Volume) ProtoMessage() {}
+
+func (x *Volume) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Volume.ProtoReflect.Descriptor instead.
+func (*Volume) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Volume) GetSerial() string {
+	if x != nil {
+		return x.Serial
+	}
+	return ""
+}
+
+func (x *Volume) GetHome() int32 {
+	if x != nil {
+		return x.Home
+	}
+	return 0
+}
+
+type Slot struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Num   int32                  `protobuf:"varint,1,opt,name=num,proto3" json:"num,omitempty"`
+	Type  SlotType               `protobuf:"varint,2,opt,name=type,proto3,enum=mtx.v1.SlotType" json:"type,omitempty"`
+	// volume is unset if the slot is empty.
+	Volume        *Volume `protobuf:"bytes,3,opt,name=volume,proto3" json:"volume,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Slot) Reset() {
+	*x = Slot{}
+	mi := &file_mtx_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Slot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Slot) ProtoMessage() {}
+
+func (x *Slot) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Slot.ProtoReflect.Descriptor instead.
+func (*Slot) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Slot) GetNum() int32 {
+	if x != nil {
+		return x.Num
+	}
+	return 0
+}
+
+func (x *Slot) GetType() SlotType {
+	if x != nil {
+		return x.Type
+	}
+	return SlotType_SLOT_TYPE_DRIVE
+}
+
+func (x *Slot) GetVolume() *Volume {
+	if x != nil {
+		return x.Volume
+	}
+	return nil
+}
+
+type Magazine struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// first and last are the numbers of the first and last slot of the
+	// magazine.
+	First         int32 `protobuf:"varint,1,opt,name=first,proto3" json:"first,omitempty"`
+	Last          int32 `protobuf:"varint,2,opt,name=last,proto3" json:"last,omitempty"`
+	Present       bool  `protobuf:"varint,3,opt,name=present,proto3" json:"present,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Magazine) Reset() {
+	*x = Magazine{}
+	mi := &file_mtx_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Magazine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Magazine) ProtoMessage() {}
+
+func (x *Magazine) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Magazine.ProtoReflect.Descriptor instead.
+func (*Magazine) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Magazine) GetFirst() int32 {
+	if x != nil {
+		return x.First
+	}
+	return 0
+}
+
+func (x *Magazine) GetLast() int32 {
+	if x != nil {
+		return x.Last
+	}
+	return 0
+}
+
+func (x *Magazine) GetPresent() bool {
+	if x != nil {
+		return x.Present
+	}
+	return false
+}
+
+type Enclosure struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DoorOpen      bool                   `protobuf:"varint,1,opt,name=door_open,json=doorOpen,proto3" json:"door_open,omitempty"`
+	StationOpen   bool                   `protobuf:"varint,2,opt,name=station_open,json=stationOpen,proto3" json:"station_open,omitempty"`
+	Magazines     []*Magazine            `protobuf:"bytes,3,rep,name=magazines,proto3" json:"magazines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Enclosure) Reset() {
+	*x = Enclosure{}
+	mi := &file_mtx_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Enclosure) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Enclosure) ProtoMessage() {}
+
+func (x *Enclosure) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Enclosure.ProtoReflect.Descriptor instead.
+func (*Enclosure) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Enclosure) GetDoorOpen() bool {
+	if x != nil {
+		return x.DoorOpen
+	}
+	return false
+}
+
+func (x *Enclosure) GetStationOpen() bool {
+	if x != nil {
+		return x.StationOpen
+	}
+	return false
+}
+
+func (x *Enclosure) GetMagazines() []*Magazine {
+	if x != nil {
+		return x.Magazines
+	}
+	return nil
+}
+
+type Status struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	MaxDrives       int32                  `protobuf:"varint,1,opt,name=max_drives,json=maxDrives,proto3" json:"max_drives,omitempty"`
+	NumSlots        int32                  `protobuf:"varint,2,opt,name=num_slots,json=numSlots,proto3" json:"num_slots,omitempty"`
+	NumStorageSlots int32                  `protobuf:"varint,3,opt,name=num_storage_slots,json=numStorageSlots,proto3" json:"num_storage_slots,omitempty"`
+	NumMailSlots    int32                  `protobuf:"varint,4,opt,name=num_mail_slots,json=numMailSlots,proto3" json:"num_mail_slots,omitempty"`
+	Drives          []*Slot                `protobuf:"bytes,5,rep,name=drives,proto3" json:"drives,omitempty"`
+	Slots           []*Slot                `protobuf:"bytes,6,rep,name=slots,proto3" json:"slots,omitempty"`
+	// enclosure is the state of the door, magazines and import/export
+	// station. It is unset if the library does not report it.
+	Enclosure     *Enclosure `protobuf:"bytes,7,opt,name=enclosure,proto3" json:"enclosure,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Status) Reset() {
+	*x = Status{}
+	mi := &file_mtx_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Status) ProtoMessage() {}
+
+func (x *Status) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Status.ProtoReflect.Descriptor instead.
+func (*Status) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Status) GetMaxDrives() int32 {
+	if x != nil {
+		return x.MaxDrives
+	}
+	return 0
+}
+
+func (x *Status) GetNumSlots() int32 {
+	if x != nil {
+		return x.NumSlots
+	}
+	return 0
+}
+
+func (x *Status) GetNumStorageSlots() int32 {
+	if x != nil {
+		return x.NumStorageSlots
+	}
+	return 0
+}
+
+func (x *Status) GetNumMailSlots() int32 {
+	if x != nil {
+		return x.NumMailSlots
+	}
+	return 0
+}
+
+func (x *Status) GetDrives() []*Slot {
+	if x != nil {
+		return x.Drives
+	}
+	return nil
+}
+
+func (x *Status) GetSlots() []*Slot {
+	if x != nil {
+		return x.Slots
+	}
+	return nil
+}
+
+func (x *Status) GetEnclosure() *Enclosure {
+	if x != nil {
+		return x.Enclosure
+	}
+	return nil
+}
+
+type LoadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Slot          int32                  `protobuf:"varint,1,opt,name=slot,proto3" json:"slot,omitempty"`
+	Drive         int32                  `protobuf:"varint,2,opt,name=drive,proto3" json:"drive,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoadRequest) Reset() {
+	*x = LoadRequest{}
+	mi := &file_mtx_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadRequest) ProtoMessage() {}
+
+func (x *LoadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadRequest.ProtoReflect.Descriptor instead.
+func (*LoadRequest) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LoadRequest) GetSlot() int32 {
+	if x != nil {
+		return x.Slot
+	}
+	return 0
+}
+
+func (x *LoadRequest) GetDrive() int32 {
+	if x != nil {
+		return x.Drive
+	}
+	return 0
+}
+
+type TransferRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Src           int32                  `protobuf:"varint,1,opt,name=src,proto3" json:"src,omitempty"`
+	Dst           int32                  `protobuf:"varint,2,opt,name=dst,proto3" json:"dst,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferRequest) Reset() {
+	*x = TransferRequest{}
+	mi := &file_mtx_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferRequest) ProtoMessage() {}
+
+func (x *TransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferRequest.ProtoReflect.Descriptor instead.
+func (*TransferRequest) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TransferRequest) GetSrc() int32 {
+	if x != nil {
+		return x.Src
+	}
+	return 0
+}
+
+func (x *TransferRequest) GetDst() int32 {
+	if x != nil {
+		return x.Dst
+	}
+	return 0
+}
+
+type MoveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MoveResponse) Reset() {
+	*x = MoveResponse{}
+	mi := &file_mtx_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveResponse) ProtoMessage() {}
+
+func (x *MoveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveResponse.ProtoReflect.Descriptor instead.
+func (*MoveResponse) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{8}
+}
+
+type WatchStatusRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// interval_ms is the polling interval of the library in milliseconds. The
+	// server chooses a default if it is zero.
+	IntervalMs    int64 `protobuf:"varint,1,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchStatusRequest) Reset() {
+	*x = WatchStatusRequest{}
+	mi := &file_mtx_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchStatusRequest) ProtoMessage() {}
+
+func (x *WatchStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mtx_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchStatusRequest.ProtoReflect.Descriptor instead.
+func (*WatchStatusRequest) Descriptor() ([]byte, []int) {
+	return file_mtx_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchStatusRequest) GetIntervalMs() int64 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+var File_mtx_proto protoreflect.FileDescriptor
+
+const file_mtx_proto_rawDesc = "" +
+	"\n" +
+	"\tmtx.proto\x12\x06mtx.v1\"\x12\n" +
+	"\x10GetStatusRequest\"4\n" +
+	"\x06Volume\x12\x16\n" +
+	"\x06serial\x18\x01 \x01(\tR\x06serial\x12\x12\n" +
+	"\x04home\x18\x02 \x01(\x05R\x04home\"f\n" +
+	"\x04Slot\x12\x10\n" +
+	"\x03num\x18\x01 \x01(\x05R\x03num\x12$\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x10.mtx.v1.SlotTypeR\x04type\x12&\n" +
+	"\x06volume\x18\x03 \x01(\v2\x0e.mtx.v1.VolumeR\x06volume\"N\n" +
+	"\bMagazine\x12\x14\n" +
+	"\x05first\x18\x01 \x01(\x05R\x05first\x12\x12\n" +
+	"\x04last\x18\x02 \x01(\x05R\x04last\x12\x18\n" +
+	"\apresent\x18\x03 \x01(\bR\apresent\"{\n" +
+	"\tEnclosure\x12\x1b\n" +
+	"\tdoor_open\x18\x01 \x01(\bR\bdoorOpen\x12!\n" +
+	"\fstation_open\x18\x02 \x01(\bR\vstationOpen\x12.\n" +
+	"\tmagazines\x18\x03 \x03(\v2\x10.mtx.v1.MagazineR\tmagazines\"\x91\x02\n" +
+	"\x06Status\x12\x1d\n" +
+	"\n" +
+	"max_drives\x18\x01 \x01(\x05R\tmaxDrives\x12\x1b\n" +
+	"\tnum_slots\x18\x02 \x01(\x05R\bnumSlots\x12*\n" +
+	"\x11num_storage_slots\x18\x03 \x01(\x05R\x0fnumStorageSlots\x12$\n" +
+	"\x0enum_mail_slots\x18\x04 \x01(\x05R\fnumMailSlots\x12$\n" +
+	"\x06drives\x18\x05 \x03(\v2\f.mtx.v1.SlotR\x06drives\x12\"\n" +
+	"\x05slots\x18\x06 \x03(\v2\f.mtx.v1.SlotR\x05slots\x12/\n" +
+	"\tenclosure\x18\a \x01(\v2\x11.mtx.v1.EnclosureR\tenclosure\"7\n" +
+	"\vLoadRequest\x12\x12\n" +
+	"\x04slot\x18\x01 \x01(\x05R\x04slot\x12\x14\n" +
+	"\x05drive\x18\x02 \x01(\x05R\x05drive\"5\n" +
+	"\x0fTransferRequest\x12\x10\n" +
+	"\x03src\x18\x01 \x01(\x05R\x03src\x12\x10\n" +
+	"\x03dst\x18\x02 \x01(\x05R\x03dst\"\x0e\n" +
+	"\fMoveResponse\"5\n" +
+	"\x12WatchStatusRequest\x12\x1f\n" +
+	"\vinterval_ms\x18\x01 \x01(\x03R\n" +
+	"intervalMs*J\n" +
+	"\bSlotType\x12\x13\n" +
+	"\x0fSLOT_TYPE_DRIVE\x10\x00\x12\x15\n" +
+	"\x11SLOT_TYPE_STORAGE\x10\x01\x12\x12\n" +
+	"\x0eSLOT_TYPE_MAIL\x10\x022\xa0\x02\n" +
+	"\aChanger\x125\n" +
+	"\tGetStatus\x12\x18.mtx.v1.GetStatusRequest\x1a\x0e.mtx.v1.Status\x121\n" +
+	"\x04Load\x12\x13.mtx.v1.LoadRequest\x1a\x14.mtx.v1.MoveResponse\x123\n" +
+	"\x06Unload\x12\x13.mtx.v1.LoadRequest\x1a\x14.mtx.v1.MoveResponse\x129\n" +
+	"\bTransfer\x12\x17.mtx.v1.TransferRequest\x1a\x14.mtx.v1.MoveResponse\x12;\n" +
+	"\vWatchStatus\x12\x1a.mtx.v1.WatchStatusRequest\x1a\x0e.mtx.v1.Status0\x01B\"Z github.com/kbj/mtx/mtxgrpc/mtxpbb\x06proto3"
+
+var (
+	file_mtx_proto_rawDescOnce sync.Once
+	file_mtx_proto_rawDescData []byte
+)
+
+func file_mtx_proto_rawDescGZIP() []byte {
+	file_mtx_proto_rawDescOnce.Do(func() {
+		file_mtx_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_mtx_proto_rawDesc), len(file_mtx_proto_rawDesc)))
+	})
+	return file_mtx_proto_rawDescData
+}
+
+var file_mtx_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_mtx_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_mtx_proto_goTypes = []any{
+	(SlotType)(0),              // 0: mtx.v1.SlotType
+	(*GetStatusRequest)(nil),   // 1: mtx.v1.GetStatusRequest
+	(*Volume)(nil),             // 2: mtx.v1.Volume
+	(*Slot)(nil),               // 3: mtx.v1.Slot
+	(*Magazine)(nil),           // 4: mtx.v1.Magazine
+	(*Enclosure)(nil),          // 5: mtx.v1.Enclosure
+	(*Status)(nil),             // 6: mtx.v1.Status
+	(*LoadRequest)(nil),        // 7: mtx.v1.LoadRequest
+	(*TransferRequest)(nil),    // 8: mtx.v1.TransferRequest
+	(*MoveResponse)(nil),       // 9: mtx.v1.MoveResponse
+	(*WatchStatusRequest)(nil), // 10: mtx.v1.WatchStatusRequest
+}
+var file_mtx_proto_depIdxs = []int32{
+	0,  // 0: mtx.v1.Slot.type:type_name -> mtx.v1.SlotType
+	2,  // 1: mtx.v1.Slot.volume:type_name -> mtx.v1.Volume
+	4,  // 2: mtx.v1.Enclosure.magazines:type_name -> mtx.v1.Magazine
+	3,  // 3: mtx.v1.Status.drives:type_name -> mtx.v1.Slot
+	3,  // 4: mtx.v1.Status.slots:type_name -> mtx.v1.Slot
+	5,  // 5: mtx.v1.Status.enclosure:type_name -> mtx.v1.Enclosure
+	1,  // 6: mtx.v1.Changer.GetStatus:input_type -> mtx.v1.GetStatusRequest
+	7,  // 7: mtx.v1.Changer.Load:input_type -> mtx.v1.LoadRequest
+	7,  // 8: mtx.v1.Changer.Unload:input_type -> mtx.v1.LoadRequest
+	8,  // 9: mtx.v1.Changer.Transfer:input_type -> mtx.v1.TransferRequest
+	10, // 10: mtx.v1.Changer.WatchStatus:input_type -> mtx.v1.WatchStatusRequest
+	6,  // 11: mtx.v1.Changer.GetStatus:output_type -> mtx.v1.Status
+	9,  // 12: mtx.v1.Changer.Load:output_type -> mtx.v1.MoveResponse
+	9,  // 13: mtx.v1.Changer.Unload:output_type -> mtx.v1.MoveResponse
+	9,  // 14: mtx.v1.Changer.Transfer:output_type -> mtx.v1.MoveResponse
+	6,  // 15: mtx.v1.Changer.WatchStatus:output_type -> mtx.v1.Status
+	11, // [11:16] is the sub-list for method output_type
+	6,  // [6:11] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_mtx_proto_init() }
+func file_mtx_proto_init() {
+	if File_mtx_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mtx_proto_rawDesc), len(file_mtx_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mtx_proto_goTypes,
+		DependencyIndexes: file_mtx_proto_depIdxs,
+		EnumInfos:         file_mtx_proto_enumTypes,
+		MessageInfos:      file_mtx_proto_msgTypes,
+	}.Build()
+	File_mtx_proto = out.File
+	file_mtx_proto_goTypes = nil
+	file_mtx_proto_depIdxs = nil
+}
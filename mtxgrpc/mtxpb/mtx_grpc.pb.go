@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: mtx.proto
+
+package mtxpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Changer_GetStatus_FullMethodName   = "/mtx.v1.Changer/GetStatus"
+	Changer_Load_FullMethodName        = "/mtx.v1.Changer/Load"
+	Changer_Unload_FullMethodName      = "/mtx.v1.Changer/Unload"
+	Changer_Transfer_FullMethodName    = "/mtx.v1.Changer/Transfer"
+	Changer_WatchStatus_FullMethodName = "/mtx.v1.Changer/WatchStatus"
+)
+
+// ChangerClient is the client API for Changer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Changer controls an automated library changer.
+type ChangerClient interface {
+	// GetStatus returns the contents of the library.
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error)
+	// Load loads the volume in a slot into a drive.
+	Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*MoveResponse, error)
+	// Unload unloads the volume in a drive into a slot. A slot of 0 returns
+	// the volume to its home slot.
+	Unload(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*MoveResponse, error)
+	// Transfer moves the volume in one slot to another.
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*MoveResponse, error)
+	// WatchStatus streams the status of the library. The current status is
+	// sent immediately and then again whenever it changes.
+	WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Status], error)
+}
+
+type changerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChangerClient(cc grpc.ClientConnInterface) ChangerClient {
+	return &changerClient{cc}
+}
+
+func (c *changerClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, Changer_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *changerClient) Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*MoveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MoveResponse)
+	err := c.cc.Invoke(ctx, Changer_Load_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *changerClient) Unload(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*MoveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MoveResponse)
+	err := c.cc.Invoke(ctx, Changer_Unload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *changerClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*MoveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MoveResponse)
+	err := c.cc.Invoke(ctx, Changer_Transfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *changerClient) WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Status], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Changer_ServiceDesc.Streams[0], Changer_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStatusRequest, Status]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Changer_WatchStatusClient = grpc.ServerStreamingClient[Status]
+
+// ChangerServer is the server API for Changer service.
+// All implementations must embed UnimplementedChangerServer
+// for forward compatibility.
+//
+// Changer controls an automated library changer.
+type ChangerServer interface {
+	// GetStatus returns the contents of the library.
+	GetStatus(context.Context, *GetStatusRequest) (*Status, error)
+	// Load loads the volume in a slot into a drive.
+	Load(context.Context, *LoadRequest) (*MoveResponse, error)
+	// Unload unloads the volume in a drive into a slot. A slot of 0 returns
+	// the volume to its home slot.
+	Unload(context.Context, *LoadRequest) (*MoveResponse, error)
+	// Transfer moves the volume in one slot to another.
+	Transfer(context.Context, *TransferRequest) (*MoveResponse, error)
+	// WatchStatus streams the status of the library. The current status is
+	// sent immediately and then again whenever it changes.
+	WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[Status]) error
+	mustEmbedUnimplementedChangerServer()
+}
+
+// UnimplementedChangerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedChangerServer struct{}
+
+func (UnimplementedChangerServer) GetStatus(context.Context, *GetStatusRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedChangerServer) Load(context.Context, *LoadRequest) (*MoveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Load not implemented")
+}
+func (UnimplementedChangerServer) Unload(context.Context, *LoadRequest) (*MoveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unload not implemented")
+}
+func (UnimplementedChangerServer) Transfer(context.Context, *TransferRequest) (*MoveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedChangerServer) WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[Status]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedChangerServer) mustEmbedUnimplementedChangerServer() {}
+func (UnimplementedChangerServer) testEmbeddedByValue()                 {}
+
+// UnsafeChangerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChangerServer will
+// result in compilation errors.
+type UnsafeChangerServer interface {
+	mustEmbedUnimplementedChangerServer()
+}
+
+func RegisterChangerServer(s grpc.ServiceRegistrar, srv ChangerServer) {
+	// If the following call pancis, it indicates UnimplementedChangerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Changer_ServiceDesc, srv)
+}
+
+func _Changer_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChangerServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Changer_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChangerServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Changer_Load_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChangerServer).Load(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Changer_Load_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChangerServer).Load(ctx, req.(*LoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Changer_Unload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChangerServer).Unload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Changer_Unload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChangerServer).Unload(ctx, req.(*LoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Changer_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChangerServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Changer_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChangerServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Changer_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChangerServer).WatchStatus(m, &grpc.GenericServerStream[WatchStatusRequest, Status]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Changer_WatchStatusServer = grpc.ServerStreamingServer[Status]
+
+// Changer_ServiceDesc is the grpc.ServiceDesc for Changer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Changer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mtx.v1.Changer",
+	HandlerType: (*ChangerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStatus",
+			Handler:    _Changer_GetStatus_Handler,
+		},
+		{
+			MethodName: "Load",
+			Handler:    _Changer_Load_Handler,
+		},
+		{
+			MethodName: "Unload",
+			Handler:    _Changer_Unload_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _Changer_Transfer_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _Changer_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "mtx.proto",
+}
@@ -0,0 +1,164 @@
+// Package mtxgrpc exposes a library changer as a gRPC service and provides
+// the corresponding client backend.
+//
+// The service is defined in mtx.proto. The Go bindings in the mtxpb
+// subpackage are generated with protoc-gen-go and protoc-gen-go-grpc by
+// running go generate.
+//
+// If the changer keeps a history, see mtx.WithHistory, the moves requested
+// through a Server are recorded under the common name of the TLS client
+// certificate of the client, or else its address.
+package mtxgrpc
+
+//go:generate protoc --go_out=. --go_opt=module=github.com/kbj/mtx/mtxgrpc --go-grpc_out=. --go-grpc_opt=module=github.com/kbj/mtx/mtxgrpc mtx.proto
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mtxgrpc/mtxpb"
+)
+
+// DefaultWatchInterval is the polling interval used by WatchStatus if the
+// client does not request one.
+const DefaultWatchInterval = 10 * time.Second
+
+// Server implements the mtx.v1.Changer gRPC service.
+type Server struct {
+	mtxpb.UnimplementedChangerServer
+
+	chgr *mtx.Changer
+
+	// robot operations are serialized
+	mu sync.Mutex
+}
+
+// NewServer returns a new server for the given changer. Register it with
+// mtxpb.RegisterChangerServer.
+func NewServer(chgr *mtx.Changer) *Server {
+	return &Server{chgr: chgr}
+}
+
+func (srv *Server) status() (*mtxpb.Status, error) {
+	srv.mu.Lock()
+	st, err := srv.chgr.Status()
+	srv.mu.Unlock()
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return toProtoStatus(st), nil
+}
+
+// GetStatus returns the contents of the library.
+func (srv *Server) GetStatus(ctx context.Context, req *mtxpb.GetStatusRequest) (*mtxpb.Status, error) {
+	return srv.status()
+}
+
+// Load loads the volume in a slot into a drive.
+func (srv *Server) Load(ctx context.Context, req *mtxpb.LoadRequest) (*mtxpb.MoveResponse, error) {
+	return srv.move(func() error {
+		return srv.chgr.MoveBy(mtx.Move{Type: mtx.MoveLoad, Src: int(req.GetSlot()), Dst: int(req.GetDrive())}, initiator(ctx))
+	})
+}
+
+// Unload unloads the volume in a drive into a slot.
+func (srv *Server) Unload(ctx context.Context, req *mtxpb.LoadRequest) (*mtxpb.MoveResponse, error) {
+	return srv.move(func() error {
+		return srv.chgr.MoveBy(mtx.Move{Type: mtx.MoveUnload, Src: int(req.GetDrive()), Dst: int(req.GetSlot())}, initiator(ctx))
+	})
+}
+
+// Transfer moves the volume in one slot to another.
+func (srv *Server) Transfer(ctx context.Context, req *mtxpb.TransferRequest) (*mtxpb.MoveResponse, error) {
+	return srv.move(func() error {
+		return srv.chgr.MoveBy(mtx.Move{Type: mtx.MoveTransfer, Src: int(req.GetSrc()), Dst: int(req.GetDst())}, initiator(ctx))
+	})
+}
+
+// move performs a robot operation. Failures are reported with status codes
+// telling apart moves conflicting with the state of the library
+// (FailedPrecondition), forbidden moves (PermissionDenied) and moves naming
+// elements the library does not have (InvalidArgument) from failures of the
+// changer (Internal).
+func (srv *Server) move(fn func() error) (*mtxpb.MoveResponse, error) {
+	srv.mu.Lock()
+	err := fn()
+	srv.mu.Unlock()
+
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &mtxpb.MoveResponse{}, nil
+}
+
+// initiator returns the name the moves requested with ctx are recorded
+// under: the common name of the client certificate of the peer, else its
+// address.
+func initiator(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+		if cn := info.State.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return cn
+		}
+	}
+
+	if p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// WatchStatus polls the library and streams its status whenever it changes.
+func (srv *Server) WatchStatus(req *mtxpb.WatchStatusRequest, stream mtxpb.Changer_WatchStatusServer) error {
+	interval := time.Duration(req.GetIntervalMs()) * time.Millisecond
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *mtxpb.Status
+	for {
+		st, err := srv.status()
+		if err != nil {
+			return err
+		}
+
+		if last == nil || !proto.Equal(st, last) {
+			if err := stream.Send(st); err != nil {
+				return err
+			}
+
+			last = st
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
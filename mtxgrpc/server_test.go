@@ -0,0 +1,172 @@
+package mtxgrpc_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/mtxgrpc"
+	"github.com/kbj/mtx/mtxgrpc/mtxpb"
+)
+
+// dial serves chgr over an in-memory connection and returns a client
+// changer using it.
+func dial(t *testing.T, chgr *mtx.Changer) *mtx.Changer {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	mtxpb.RegisterChangerServer(gs, mtxgrpc.NewServer(chgr))
+
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return mtx.NewChanger(mtxgrpc.New(conn))
+}
+
+func TestMoveErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		chgr *mtx.Changer
+		mv   mtx.Move
+		code codes.Code
+		want error
+	}{
+		{"empty source", mtx.NewChanger(mock.New(2, 8, 1, 4)), mtx.Move{Type: mtx.MoveTransfer, Src: 6, Dst: 7}, codes.FailedPrecondition, mtx.ErrEmpty},
+		{"full destination", mtx.NewChanger(mock.New(2, 8, 1, 4)), mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 2}, codes.FailedPrecondition, mtx.ErrFull},
+		{"invalid element", mtx.NewChanger(mock.New(2, 8, 1, 4)), mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 99}, codes.InvalidArgument, mtx.ErrInvalidElement},
+		{
+			"not allowed",
+			mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithPolicy(mtx.OnlySlots(1, 4))),
+			mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 6},
+			codes.PermissionDenied, mtx.ErrNotAllowed,
+		},
+		{
+			"read-only",
+			mtx.NewChanger(mtx.ReadOnly(mock.New(2, 8, 1, 4))),
+			mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 6},
+			codes.PermissionDenied, mtx.ErrReadOnly,
+		},
+		{
+			"incompatible media",
+			mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithDriveGenerations(map[mtx.DriveNum]int{0: 8})),
+			mtx.Move{Type: mtx.MoveLoad, Src: 1, Dst: 0},
+			codes.FailedPrecondition, mtx.ErrIncompatibleMedia,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := mtxgrpc.NewServer(tc.chgr)
+
+			_, err := srv.Transfer(context.Background(), &mtxpb.TransferRequest{Src: int32(tc.mv.Src), Dst: int32(tc.mv.Dst)})
+			if tc.mv.Type == mtx.MoveLoad {
+				_, err = srv.Load(context.Background(), &mtxpb.LoadRequest{Slot: int32(tc.mv.Src), Drive: int32(tc.mv.Dst)})
+			}
+
+			if code := status.Code(err); code != tc.code {
+				t.Errorf("server error %v has code %s, want %s", err, code, tc.code)
+			}
+
+			if err := dial(t, tc.chgr).Move(tc.mv); !errors.Is(err, tc.want) {
+				t.Errorf("client error %v does not match %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestMove(t *testing.T) {
+	chgr := dial(t, mtx.NewChanger(mock.New(2, 8, 1, 4)))
+
+	if err := chgr.Load(1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vol := status.Drives[0].Vol; vol == nil || vol.Home != 1 {
+		t.Errorf("drive 0 holds %v, want the volume of slot 1", vol)
+	}
+}
+
+func TestEnclosure(t *testing.T) {
+	impl := mock.New(2, 8, 1, 4, mock.WithMagazines(4))
+	if err := impl.RemoveMagazine(1); err != nil {
+		t.Fatal(err)
+	}
+
+	impl.OpenStation()
+
+	status, err := dial(t, mtx.NewChanger(impl)).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := status.Enclosure
+	if enc == nil {
+		t.Fatal("no enclosure state")
+	}
+
+	want := []mtx.Magazine{{First: 1, Last: 4, Present: true}, {First: 5, Last: 8}}
+	if enc.DoorOpen || !enc.StationOpen || !slices.Equal(enc.Magazines, want) {
+		t.Errorf("enclosure = %+v, want station open and magazines %+v", enc, want)
+	}
+}
+
+func TestMoveInitiator(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4711}
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "backup01"}}
+
+	for _, tc := range []struct {
+		name string
+		peer *peer.Peer
+		want string
+	}{
+		{"certificate", &peer.Peer{Addr: addr, AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		}}, "backup01"},
+		{"address", &peer.Peer{Addr: addr}, "192.0.2.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chgr := mtx.NewChanger(mock.New(2, 8, 1, 4), mtx.WithHistory(10), mtx.WithInitiator("server"))
+			srv := mtxgrpc.NewServer(chgr)
+
+			ctx := peer.NewContext(context.Background(), tc.peer)
+			if _, err := srv.Transfer(ctx, &mtxpb.TransferRequest{Src: 1, Dst: 6}); err != nil {
+				t.Fatal(err)
+			}
+
+			if hist := chgr.History("S00000L6"); len(hist) != 1 || hist[0].By != tc.want {
+				t.Errorf("history = %+v, want a movement by %q", hist, tc.want)
+			}
+		})
+	}
+}
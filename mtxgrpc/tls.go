@@ -0,0 +1,30 @@
+package mtxgrpc
+
+import (
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServerCredentials returns a server option making a grpc.Server use TLS
+// configured by cfg, e.g. as returned by tlsconfig.Server:
+//
+//	gs := grpc.NewServer(mtxgrpc.ServerCredentials(cfg))
+//	mtxpb.RegisterChangerServer(gs, mtxgrpc.NewServer(chgr))
+func ServerCredentials(cfg *tls.Config) grpc.ServerOption {
+	return grpc.Creds(credentials.NewTLS(cfg))
+}
+
+// Dial returns a connection to the server at target for New, using TLS
+// configured by cfg, e.g. as returned by tlsconfig.Client. If cfg is nil,
+// the connection is not encrypted.
+func Dial(target string, cfg *tls.Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if cfg != nil {
+		creds = credentials.NewTLS(cfg)
+	}
+
+	return grpc.NewClient(target, append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)...)
+}
@@ -0,0 +1,261 @@
+package mtxtest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/kbj/mtx"
+)
+
+// Model is a reference implementation of the move semantics of mtx. A drive
+// reports the slot its volume was loaded from as the home slot; unloading to
+// slot 0 returns the volume there.
+type Model struct {
+	status *mtx.Status
+}
+
+// NewModel returns a model starting from the given library state.
+func NewModel(status *mtx.Status) *Model {
+	m := &Model{status: &mtx.Status{
+		MaxDrives:       status.MaxDrives,
+		NumSlots:        status.NumSlots,
+		NumStorageSlots: status.NumStorageSlots,
+		NumMailSlots:    status.NumMailSlots,
+	}}
+
+	clone := func(slot *mtx.Slot) *mtx.Slot {
+		c := &mtx.Slot{Num: slot.Num, Type: slot.Type}
+		if slot.Vol != nil {
+			c.Vol = &mtx.Volume{Serial: slot.Vol.Serial, Home: slot.Vol.Home}
+		}
+
+		return c
+	}
+
+	for _, drv := range status.Drives {
+		m.status.Drives = append(m.status.Drives, clone(drv))
+	}
+
+	for _, slot := range status.Slots {
+		m.status.Slots = append(m.status.Slots, clone(slot))
+	}
+
+	return m
+}
+
+// Status returns the current state of the model.
+func (m *Model) Status() *mtx.Status {
+	return m.status
+}
+
+func (m *Model) slot(num int) (*mtx.Slot, error) {
+	if num < 1 || num > len(m.status.Slots) {
+		return nil, fmt.Errorf("invalid slot %d", num)
+	}
+
+	return m.status.Slots[num-1], nil
+}
+
+func (m *Model) drive(num int) (*mtx.Slot, error) {
+	if num < 0 || num >= len(m.status.Drives) {
+		return nil, fmt.Errorf("invalid drive %d", num)
+	}
+
+	return m.status.Drives[num], nil
+}
+
+// Apply performs mv on the model. It returns an error, and leaves the model
+// unchanged, if mtx would refuse the move.
+func (m *Model) Apply(mv mtx.Move) error {
+	var src, dst *mtx.Slot
+	var err error
+
+	switch mv.Type {
+	case mtx.MoveLoad:
+		if src, err = m.slot(mv.Src); err != nil {
+			return err
+		}
+
+		if dst, err = m.drive(mv.Dst); err != nil {
+			return err
+		}
+	case mtx.MoveUnload:
+		if src, err = m.drive(mv.Src); err != nil {
+			return err
+		}
+
+		num := mv.Dst
+		if num == 0 && src.Vol != nil {
+			num = src.Vol.Home
+		}
+
+		if dst, err = m.slot(num); err != nil {
+			return err
+		}
+	case mtx.MoveTransfer:
+		if src, err = m.slot(mv.Src); err != nil {
+			return err
+		}
+
+		if dst, err = m.slot(mv.Dst); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown move type %d", int(mv.Type))
+	}
+
+	if src.Vol == nil {
+		return fmt.Errorf("%s %d is empty", src.Type, src.Num)
+	}
+
+	if dst.Vol != nil {
+		return fmt.Errorf("%s %d is full", dst.Type, dst.Num)
+	}
+
+	if mv.Type == mtx.MoveLoad {
+		src.Vol.Home = src.Num
+	}
+
+	dst.Vol, src.Vol = src.Vol, nil
+
+	return nil
+}
+
+// Invariants checks the consistency of a library state with
+// Status.Validate. The home slot of a loaded volume need not be empty;
+// another volume may have been moved there since the load.
+func Invariants(status *mtx.Status) error {
+	return status.Validate()
+}
+
+// A Divergence describes a step at which a changer and the model disagree.
+type Divergence struct {
+	// Step is the index of the offending move in Moves.
+	Step  int
+	Moves []mtx.Move
+
+	// Err describes the disagreement.
+	Err error
+}
+
+// Error returns the disagreement together with the moves leading up to it,
+// in a form that can be replayed with Run.
+func (d *Divergence) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "mtxtest: step %d (%s): %v\nmoves:\n", d.Step, d.Moves[d.Step], d.Err)
+	for _, mv := range d.Moves[:d.Step+1] {
+		fmt.Fprintf(&b, "\t%s\n", mv)
+	}
+
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (d *Divergence) Unwrap() error {
+	return d.Err
+}
+
+// RandomMove returns a random move for a library of the given geometry. Most
+// moves are valid in the given state; the others exercise the error paths.
+func RandomMove(rng *rand.Rand, status *mtx.Status) mtx.Move {
+	var full, empty, loaded, unloaded []int
+	for _, slot := range status.Slots {
+		if slot.Vol != nil {
+			full = append(full, slot.Num)
+		} else {
+			empty = append(empty, slot.Num)
+		}
+	}
+
+	for _, drv := range status.Drives {
+		if drv.Vol != nil {
+			loaded = append(loaded, drv.Num)
+		} else {
+			unloaded = append(unloaded, drv.Num)
+		}
+	}
+
+	pick := func(nums []int, n int) int {
+		// occasionally pick any element, full or not
+		if n < 0 {
+			return 0
+		}
+
+		if len(nums) == 0 || rng.Intn(10) == 0 {
+			return rng.Intn(n + 1)
+		}
+
+		return nums[rng.Intn(len(nums))]
+	}
+
+	switch rng.Intn(3) {
+	case 0:
+		return mtx.Move{Type: mtx.MoveLoad, Src: pick(full, len(status.Slots)), Dst: pick(unloaded, len(status.Drives)-1)}
+	case 1:
+		dst := 0
+		if rng.Intn(2) == 0 {
+			dst = pick(empty, len(status.Slots))
+		}
+
+		return mtx.Move{Type: mtx.MoveUnload, Src: pick(loaded, len(status.Drives)-1), Dst: dst}
+	}
+
+	return mtx.Move{Type: mtx.MoveTransfer, Src: pick(full, len(status.Slots)), Dst: pick(empty, len(status.Slots))}
+}
+
+// Verify performs steps random moves on chgr and checks that it behaves like
+// the model: every move succeeds or fails on both, the resulting layouts are
+// identical and the state reported by chgr satisfies Invariants. The first
+// disagreement is returned as a *Divergence.
+//
+// Verify moves volumes around; run it against a real library only if it may
+// be rearranged.
+func Verify(chgr *mtx.Changer, rng *rand.Rand, steps int) error {
+	status, err := chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	if err := Invariants(status); err != nil {
+		return fmt.Errorf("mtxtest: initial state: %v", err)
+	}
+
+	model := NewModel(status)
+
+	var moves []mtx.Move
+	for i := 0; i < steps; i++ {
+		mv := RandomMove(rng, model.Status())
+		moves = append(moves, mv)
+
+		diverge := func(err error) error {
+			return &Divergence{Step: i, Moves: moves, Err: err}
+		}
+
+		want := model.Apply(mv)
+		got := chgr.Move(mv)
+
+		switch {
+		case got == nil && want != nil:
+			return diverge(fmt.Errorf("changer accepted move, model refused: %v", want))
+		case got != nil && want == nil:
+			return diverge(fmt.Errorf("changer refused move, model accepted: %v", got))
+		}
+
+		status, err := chgr.Status()
+		if err != nil {
+			return diverge(err)
+		}
+
+		if err := Invariants(status); err != nil {
+			return diverge(err)
+		}
+
+		if got, want := Layout(status), Layout(model.Status()); got != want {
+			return diverge(errors.New("layout mismatch\nchanger:\n" + got + "model:\n" + want))
+		}
+	}
+
+	return nil
+}
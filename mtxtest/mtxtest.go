@@ -0,0 +1,287 @@
+// Package mtxtest provides utilities for testing code that uses changers.
+//
+// A test describes the initial library in a Fixture, creates a mock or vtl
+// backed changer from it, runs the operations under test and compares the
+// resulting library against the expected layout:
+//
+//	func TestRestore(t *testing.T) {
+//		chgr := mtxtest.NewMock(t, mtxtest.MustParse(`
+//			geometry 1 4 1
+//			drive 0 A00001L6 home 2
+//			slot 1 A00002L6
+//		`))
+//
+//		mtxtest.Run(t, chgr, "unload 2 0", "transfer 1 5")
+//
+//		mtxtest.Assert(t, chgr, `
+//			slot 2 A00001L6
+//			slot 5 A00002L6
+//		`)
+//	}
+package mtxtest
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/vtl"
+)
+
+// Placement puts a volume into an element.
+type Placement struct {
+	// Type is mtx.DataTransferSlot for drives; slots are storage or mail
+	// slots depending on their number.
+	Type   mtx.SlotType
+	Num    int
+	Serial string
+
+	// Home is the home slot of a volume in a drive.
+	Home int
+}
+
+// Fixture describes a library.
+type Fixture struct {
+	Drives       int
+	StorageSlots int
+	MailSlots    int
+
+	Volumes []Placement
+}
+
+// Parse parses a fixture description. Each line holds one of
+//
+//	geometry DRIVES STORAGE MAIL
+//	drive NUM SERIAL home SLOT
+//	slot NUM SERIAL
+//
+// where slots numbered above the storage slots are mail slots. Blank lines
+// and lines starting with '#' are ignored. Without a geometry line, the
+// fixture only describes volumes, as used by Assert.
+func Parse(s string) (*Fixture, error) {
+	f := &Fixture{}
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		bad := fmt.Errorf("mtxtest: line %d: invalid fixture line %q", n, scanner.Text())
+
+		nums := func(ss ...string) ([]int, error) {
+			var out []int
+			for _, s := range ss {
+				v, err := strconv.Atoi(s)
+				if err != nil {
+					return nil, bad
+				}
+
+				out = append(out, v)
+			}
+
+			return out, nil
+		}
+
+		switch {
+		case fields[0] == "geometry" && len(fields) == 4:
+			v, err := nums(fields[1:]...)
+			if err != nil {
+				return nil, err
+			}
+
+			f.Drives, f.StorageSlots, f.MailSlots = v[0], v[1], v[2]
+		case fields[0] == "drive" && len(fields) == 5 && fields[3] == "home":
+			v, err := nums(fields[1], fields[4])
+			if err != nil {
+				return nil, err
+			}
+
+			f.Volumes = append(f.Volumes, Placement{Type: mtx.DataTransferSlot, Num: v[0], Serial: fields[2], Home: v[1]})
+		case fields[0] == "slot" && len(fields) == 3:
+			v, err := nums(fields[1])
+			if err != nil {
+				return nil, err
+			}
+
+			f.Volumes = append(f.Volumes, Placement{Type: mtx.StorageSlot, Num: v[0], Serial: fields[2]})
+		default:
+			return nil, bad
+		}
+	}
+
+	return f, scanner.Err()
+}
+
+// MustParse is like Parse but panics on error.
+func MustParse(s string) *Fixture {
+	f, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+// Layout returns the volumes of status in fixture syntax, one per line,
+// drives first.
+func Layout(status *mtx.Status) string {
+	var b strings.Builder
+	for _, drv := range status.Drives {
+		if drv.Vol != nil {
+			fmt.Fprintf(&b, "drive %d %s home %d\n", drv.Num, drv.Vol.Serial, drv.Vol.Home)
+		}
+	}
+
+	for _, slot := range status.Slots {
+		if slot.Vol != nil {
+			fmt.Fprintf(&b, "slot %d %s\n", slot.Num, slot.Vol.Serial)
+		}
+	}
+
+	return b.String()
+}
+
+func (f *Fixture) layout() string {
+	vols := append([]Placement(nil), f.Volumes...)
+	sort.SliceStable(vols, func(i, j int) bool {
+		if (vols[i].Type == mtx.DataTransferSlot) != (vols[j].Type == mtx.DataTransferSlot) {
+			return vols[i].Type == mtx.DataTransferSlot
+		}
+
+		return vols[i].Num < vols[j].Num
+	})
+
+	var b strings.Builder
+	for _, p := range vols {
+		if p.Type == mtx.DataTransferSlot {
+			fmt.Fprintf(&b, "drive %d %s home %d\n", p.Num, p.Serial, p.Home)
+		} else {
+			fmt.Fprintf(&b, "slot %d %s\n", p.Num, p.Serial)
+		}
+	}
+
+	return b.String()
+}
+
+// NewMock returns a changer backed by a mock library populated from f.
+func NewMock(t testing.TB, f *Fixture, opts ...mock.Option) *mtx.Changer {
+	t.Helper()
+
+	drives := make(map[int]Placement)
+	slots := make(map[int]string)
+	for _, p := range f.Volumes {
+		switch {
+		case p.Type == mtx.DataTransferSlot && p.Num >= 0 && p.Num < f.Drives:
+			drives[p.Num] = p
+		case p.Type != mtx.DataTransferSlot && p.Num > 0 && p.Num <= f.StorageSlots+f.MailSlots:
+			slots[p.Num] = p.Serial
+		default:
+			t.Fatalf("mtxtest: volume %s placed outside of library geometry", p.Serial)
+		}
+	}
+
+	b := mock.NewBuilder().Options(opts...)
+	for i := 0; i < f.Drives; i++ {
+		if p, ok := drives[i]; ok {
+			b.LoadedDrive(i, p.Serial, p.Home)
+		} else {
+			b.Drive(i)
+		}
+	}
+
+	for i := 1; i <= f.StorageSlots+f.MailSlots; i++ {
+		var serial []string
+		if s, ok := slots[i]; ok {
+			serial = []string{s}
+		}
+
+		if i > f.StorageSlots {
+			b.MailSlot(i, serial...)
+		} else {
+			b.Slot(i, serial...)
+		}
+	}
+
+	impl, err := b.Build()
+	if err != nil {
+		t.Fatalf("mtxtest: %v", err)
+	}
+
+	return mtx.NewChanger(impl)
+}
+
+// NewVTL returns a changer backed by a vtl library in a temporary directory,
+// populated from f. The directory is removed when the test ends.
+func NewVTL(t testing.TB, f *Fixture) *mtx.Changer {
+	t.Helper()
+
+	lib, err := vtl.Create(t.TempDir(), f.Drives, f.StorageSlots, f.MailSlots)
+	if err != nil {
+		t.Fatalf("mtxtest: %v", err)
+	}
+
+	chgr := mtx.NewChanger(lib)
+
+	for _, p := range f.Volumes {
+		slot := p.Num
+		if p.Type == mtx.DataTransferSlot {
+			slot = p.Home
+		}
+
+		if err := lib.AddVolume(slot, p.Serial); err != nil {
+			t.Fatalf("mtxtest: %v", err)
+		}
+
+		if p.Type == mtx.DataTransferSlot {
+			if err := chgr.Load(mtx.SlotNum(slot), mtx.DriveNum(p.Num)); err != nil {
+				t.Fatalf("mtxtest: %v", err)
+			}
+		}
+	}
+
+	return chgr
+}
+
+// Run performs the given moves, in mtx command syntax, failing the test at
+// the first error.
+func Run(t testing.TB, chgr *mtx.Changer, moves ...string) {
+	t.Helper()
+
+	for i, s := range moves {
+		mv, err := mtx.ParseMove(s)
+		if err != nil {
+			t.Fatalf("mtxtest: step %d: %v", i, err)
+		}
+
+		if err := chgr.Move(mv); err != nil {
+			t.Fatalf("mtxtest: step %d (%s): %v", i, s, err)
+		}
+	}
+}
+
+// Assert fails the test unless the volumes in the library are exactly those
+// described by want, a fixture description without geometry.
+func Assert(t testing.TB, chgr *mtx.Changer, want string) {
+	t.Helper()
+
+	f, err := Parse(want)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		t.Fatalf("mtxtest: %v", err)
+	}
+
+	if got, want := Layout(status), f.layout(); got != want {
+		t.Errorf("mtxtest: library layout mismatch\ngot:\n%swant:\n%s", got, want)
+	}
+}
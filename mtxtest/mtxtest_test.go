@@ -0,0 +1,152 @@
+package mtxtest_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/mtxtest"
+)
+
+const fixture = `
+	geometry 1 4 1
+	drive 0 A00001L6 home 2
+	slot 1 A00002L6
+`
+
+func TestMock(t *testing.T) {
+	chgr := mtxtest.NewMock(t, mtxtest.MustParse(fixture))
+
+	mtxtest.Assert(t, chgr, `
+		drive 0 A00001L6 home 2
+		slot 1 A00002L6
+	`)
+
+	mtxtest.Run(t, chgr, "unload 2 0", "transfer 1 5")
+
+	mtxtest.Assert(t, chgr, `
+		slot 2 A00001L6
+		slot 5 A00002L6
+	`)
+}
+
+func TestVTL(t *testing.T) {
+	chgr := mtxtest.NewVTL(t, mtxtest.MustParse(fixture))
+
+	mtxtest.Run(t, chgr, "unload 2 0", "transfer 1 5")
+
+	mtxtest.Assert(t, chgr, `
+		slot 2 A00001L6
+		slot 5 A00002L6
+	`)
+}
+
+// recorder is a testing.TB recording failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failed = true
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failed = true
+	panic(r)
+}
+
+// fails reports whether fn fails the test it is given.
+func fails(t *testing.T, fn func(tb testing.TB)) (failed bool) {
+	r := &recorder{TB: t}
+
+	defer func() {
+		if v := recover(); v != nil && v != r {
+			panic(v)
+		}
+
+		failed = r.failed
+	}()
+
+	fn(r)
+
+	return
+}
+
+func TestAssertMismatch(t *testing.T) {
+	chgr := mtxtest.NewMock(t, mtxtest.MustParse(fixture))
+
+	for _, want := range []string{
+		"drive 0 A00001L6 home 2",
+		"drive 0 A00001L6 home 3\nslot 1 A00002L6",
+		"drive 0 A00001L6 home 2\nslot 1 A00002L6\nslot 3 A00003L6",
+	} {
+		if !fails(t, func(tb testing.TB) { mtxtest.Assert(tb, chgr, want) }) {
+			t.Errorf("Assert(%q) passed", want)
+		}
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	chgr := mtxtest.NewMock(t, mtxtest.MustParse(fixture))
+
+	for _, moves := range [][]string{
+		{"transfer 3 4"},
+		{"load 1 0"},
+		{"move 1 2"},
+	} {
+		if !fails(t, func(tb testing.TB) { mtxtest.Run(tb, chgr, moves...) }) {
+			t.Errorf("Run(%q) passed", moves)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := mtxtest.Parse(fixture + "\n# a comment\nslot 5 M00001L6\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Drives != 1 || f.StorageSlots != 4 || f.MailSlots != 1 || len(f.Volumes) != 3 {
+		t.Errorf("Parse = %+v", f)
+	}
+
+	want := mtxtest.Placement{Type: mtx.DataTransferSlot, Num: 0, Serial: "A00001L6", Home: 2}
+	if f.Volumes[0] != want {
+		t.Errorf("drive placement = %+v, want %+v", f.Volumes[0], want)
+	}
+
+	for _, s := range []string{
+		"geometry 1 4",
+		"drive 0 A00001L6",
+		"drive 0 A00001L6 slot 2",
+		"slot x A00001L6",
+		"shelf 1 A00001L6",
+	} {
+		if _, err := mtxtest.Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
+
+func TestNewMockOutsideGeometry(t *testing.T) {
+	f := mtxtest.MustParse("geometry 1 4 1\nslot 6 A00001L6")
+	if !fails(t, func(tb testing.TB) { mtxtest.NewMock(tb, f) }) {
+		t.Error("NewMock accepted a volume outside the library")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			chgr := mtx.NewChanger(mock.New(2, 8, 2, 5))
+			if err := mtxtest.Verify(chgr, rand.New(rand.NewSource(seed)), 200); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
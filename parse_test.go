@@ -0,0 +1,266 @@
+package mtx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The expressions of the element parser before it was rewritten, used as a
+// reference.
+var (
+	driveRegexp        = regexp.MustCompile(`Data Transfer Element (\d*):(.*)`)
+	driveElementRegexp = regexp.MustCompile(`Full \(Storage Element (\d*) Loaded\)(?::VolumeTag = (.*))?`)
+	slotRegexp         = regexp.MustCompile(`\s*Storage Element (\d*):(.*)`)
+	mailSlotRegexp     = regexp.MustCompile(`\s*Storage Element (\d*) IMPORT/EXPORT:(.*)`)
+	slotElementRegexp  = regexp.MustCompile(`Full\s*(?::VolumeTag=(.*))?`)
+)
+
+// parseElementRegexp is the element parser before it was rewritten.
+func parseElementRegexp(line string) (*Slot, error) {
+	if matches := driveRegexp.FindStringSubmatch(line); matches != nil {
+		elemnum, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, err
+		}
+
+		slot := &Slot{Num: elemnum, Type: DataTransferSlot}
+
+		if matches[2] != "Empty" {
+			matches = driveElementRegexp.FindStringSubmatch(matches[2])
+			if matches == nil {
+				return nil, errors.New("failed to parse transfer element")
+			}
+
+			home, err := strconv.Atoi(matches[1])
+			if err != nil {
+				return nil, err
+			}
+
+			slot.Vol = &Volume{Serial: matches[2], Home: home}
+		}
+
+		return slot, nil
+	}
+
+	for _, m := range []struct {
+		typ SlotType
+		re  *regexp.Regexp
+	}{
+		{StorageSlot, slotRegexp},
+		{MailSlot, mailSlotRegexp},
+	} {
+		matches := m.re.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		elemnum, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, err
+		}
+
+		slot := &Slot{Num: elemnum, Type: m.typ}
+
+		if matches[2] != "Empty" {
+			match := slotElementRegexp.FindStringSubmatch(matches[2])
+			if match == nil {
+				return nil, errors.New("failed to parse slot element")
+			}
+
+			slot.Vol = &Volume{Serial: match[1], Home: elemnum}
+		}
+
+		return slot, nil
+	}
+
+	return nil, errors.New("failed to parse slot")
+}
+
+// parseElementLine parses an element line like parseStatus does.
+func parseElementLine(line string) (*Slot, error) {
+	typ, num, rest, err := classify([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	var p parser
+
+	return p.parseElement(typ, num, rest)
+}
+
+var elementLines = []string{
+	// drives
+	"Data Transfer Element 0:Empty",
+	"Data Transfer Element 1:Full (Storage Element 5 Loaded):VolumeTag = S00004L6",
+	"Data Transfer Element 2:Full (Storage Element 7 Loaded)",
+	"Data Transfer Element 3:Full (Storage Element 7 Loaded):VolumeTag = ",
+	"Data Transfer Element 3:Full (Storage Element 7 Loaded):VolumeTag = A B ",
+	"Data Transfer Element 12:Full (Storage Element 1201 Loaded):VolumeTag = CLN001L1",
+
+	// storage slots
+	"      Storage Element 1:Empty",
+	"      Storage Element 2:Full :VolumeTag=S00001L6",
+	"      Storage Element 3:Full ",
+	"      Storage Element 4:Full",
+	"Storage Element 5:Full :VolumeTag=",
+	"\tStorage Element 6:Full\t:VolumeTag=S00005L6",
+	"      Storage Element 10000:Full :VolumeTag=S09999L8",
+
+	// mail slots
+	"      Storage Element 33 IMPORT/EXPORT:Empty",
+	"      Storage Element 34 IMPORT/EXPORT:Full :VolumeTag=M00000L6",
+	"      Storage Element 35 IMPORT/EXPORT:Full",
+
+	// unusual but accepted
+	"Data Transfer Element 0:Full (Storage Element 5 Loaded):VolumeTag=S00004L6",
+	"      Storage Element 2:Full  :VolumeTag=S00001L6:VolumeTag=X",
+	"      Storage Element 2:Full :VolumeTag = S00001L6",
+	"      Storage Element 2:Loaded Full",
+	"junk Data Transfer Element 0:Empty",
+	"      Storage Element 2:Full :VolumeTag=S00001L6 Storage Element 3:Empty",
+
+	// malformed
+	"",
+	"garbage",
+	"Data Transfer Element :Empty",
+	"Data Transfer Element x:Empty",
+	"Data Transfer Element 0 Empty",
+	"Data Transfer Element 0:Full",
+	"Data Transfer Element 0:Full (Storage Element x Loaded)",
+	"Data Transfer Element 0:Full (Storage Element  Loaded)",
+	"Data Transfer Element 99999999999999999999:Empty",
+	"      Storage Element :Empty",
+	"      Storage Element 2:Occupied",
+	"      Storage Element 33 IMPORT/EXPORT:Occupied",
+	"      Storage Element 33 IMPORT/EXPORT Empty",
+}
+
+func TestParseElementMatchesRegexp(t *testing.T) {
+	lines := elementLines
+
+	// the element lines of a fixture, skipping the header
+	fixture := strings.Split(strings.TrimSpace(string(statusFixture(4, 32, 4, 16))), "\n")
+	lines = append(lines[:len(lines):len(lines)], fixture[1:]...)
+
+	for _, line := range lines {
+		want, wantErr := parseElementRegexp(line)
+		got, err := parseElementLine(line)
+
+		if (err != nil) != (wantErr != nil) {
+			t.Errorf("%q: error %v, want %v", line, err, wantErr)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if got.Num != want.Num || got.Type != want.Type {
+			t.Errorf("%q: %s %d, want %s %d", line, got.Type, got.Num, want.Type, want.Num)
+		}
+
+		switch {
+		case (got.Vol == nil) != (want.Vol == nil):
+			t.Errorf("%q: volume %v, want %v", line, got.Vol, want.Vol)
+		case got.Vol != nil && (got.Vol.Serial != want.Vol.Serial || got.Vol.Home != want.Vol.Home):
+			t.Errorf("%q: volume %q home %d, want %q home %d",
+				line, got.Vol.Serial, got.Vol.Home, want.Vol.Serial, want.Vol.Home)
+		}
+	}
+}
+
+func TestCut(t *testing.T) {
+	for _, tc := range []struct {
+		line, prefix, sep string
+		digits, rest      string
+		ok                bool
+	}{
+		{"Element 12:Full", "Element ", ":", "12", "Full", true},
+		{"Element :Full", "Element ", ":", "", "Full", true},
+		{"Element 1x Element 2:Full", "Element ", ":", "2", "Full", true},
+		{"Element 12 Full", "Element ", ":", "", "", false},
+		{"Elem 12:Full", "Element ", ":", "", "", false},
+	} {
+		digits, rest, ok := cut([]byte(tc.line), []byte(tc.prefix), []byte(tc.sep))
+		if string(digits) != tc.digits || string(rest) != tc.rest || ok != tc.ok {
+			t.Errorf("cut(%q, %q, %q) = %q, %q, %t, want %q, %q, %t",
+				tc.line, tc.prefix, tc.sep, digits, rest, ok, tc.digits, tc.rest, tc.ok)
+		}
+	}
+}
+
+// statusFixture returns the status output of a library with the given
+// geometry, the first vols storage slots holding labeled volumes, every
+// other one of them loaded in a drive while drives are left, and every
+// tenth slot and the last mail slot holding unlabeled volumes.
+func statusFixture(drives, slots, mail, vols int) []byte {
+	status := &Status{
+		MaxDrives:       drives,
+		NumSlots:        slots + mail,
+		NumStorageSlots: slots,
+		NumMailSlots:    mail,
+	}
+
+	for i := range drives {
+		status.Drives = append(status.Drives, &Slot{Num: i, Type: DataTransferSlot})
+	}
+
+	loaded := 0
+	for i := 1; i <= slots; i++ {
+		slot := &Slot{Num: i, Type: StorageSlot}
+		status.Slots = append(status.Slots, slot)
+
+		if i > vols {
+			continue
+		}
+
+		vol := &Volume{Serial: fmt.Sprintf("S%05dL6", i-1), Home: i}
+		if i%10 == 0 {
+			vol.Serial = ""
+		}
+
+		if i%2 == 0 && loaded < drives {
+			status.Drives[loaded].Vol = vol
+			loaded++
+			continue
+		}
+
+		slot.Vol = vol
+	}
+
+	for i := 1; i <= mail; i++ {
+		slot := &Slot{Num: slots + i, Type: MailSlot}
+		if i == mail {
+			slot.Vol = &Volume{Home: slot.Num}
+		}
+
+		status.Slots = append(status.Slots, slot)
+	}
+
+	return RenderStatus("/dev/sg3", status)
+}
+
+func BenchmarkParseStatus(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		status []byte
+	}{
+		{"small", statusFixture(4, 32, 4, 16)},
+		{"large", statusFixture(16, 10000, 40, 7500)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bc.status)))
+
+			for b.Loop() {
+				if _, err := ParseStatus(bc.status); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package mtx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MoveType defines the type of move.
+type MoveType int
+
+const (
+	// MoveLoad loads the volume in slot Src into drive Dst.
+	MoveLoad MoveType = iota
+
+	// MoveUnload unloads the volume in drive Src into slot Dst.
+	MoveUnload
+
+	// MoveTransfer transfers the volume in slot Src to slot Dst.
+	MoveTransfer
+)
+
+var moveTypeNames = [...]string{
+	MoveLoad:     "load",
+	MoveUnload:   "unload",
+	MoveTransfer: "transfer",
+}
+
+// String returns the mtx command name of the move type.
+func (typ MoveType) String() string {
+	if typ < 0 || int(typ) >= len(moveTypeNames) {
+		return fmt.Sprintf("MoveType(%d)", int(typ))
+	}
+
+	return moveTypeNames[typ]
+}
+
+// Move describes a single robot operation.
+type Move struct {
+	Type MoveType
+
+	// Src and Dst are the source and destination element numbers. Whether
+	// they refer to a slot or a drive depends on Type.
+	Src, Dst int
+}
+
+// String returns a textual representation of the move using mtx command
+// syntax, e.g. "load 3 0".
+func (mv Move) String() string {
+	switch mv.Type {
+	case MoveLoad:
+		return fmt.Sprintf("load %d %d", mv.Src, mv.Dst)
+	case MoveUnload:
+		// mtx takes the slot number before the drive number
+		return fmt.Sprintf("unload %d %d", mv.Dst, mv.Src)
+	}
+
+	return fmt.Sprintf("%s %d %d", mv.Type, mv.Src, mv.Dst)
+}
+
+// ParseMove parses a move in mtx command syntax as returned by Move.String.
+func ParseMove(s string) (Move, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return Move{}, fmt.Errorf("%w %q", ErrInvalidMove, s)
+	}
+
+	a, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Move{}, fmt.Errorf("%w %q: %w", ErrInvalidMove, s, err)
+	}
+
+	b, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return Move{}, fmt.Errorf("%w %q: %w", ErrInvalidMove, s, err)
+	}
+
+	switch fields[0] {
+	case "load":
+		return Move{Type: MoveLoad, Src: a, Dst: b}, nil
+	case "unload":
+		return Move{Type: MoveUnload, Src: b, Dst: a}, nil
+	case "transfer":
+		return Move{Type: MoveTransfer, Src: a, Dst: b}, nil
+	}
+
+	return Move{}, fmt.Errorf("%w %q: unknown command", ErrInvalidMove, s)
+}
+
+// moveArgs parses the move command given by args.
+func moveArgs(args []string) (Move, bool) {
+	if len(args) != 3 {
+		return Move{}, false
+	}
+
+	mv, err := ParseMove(strings.Join(args, " "))
+
+	return mv, err == nil
+}
+
+// elements returns the source and destination elements of mv. Slots are
+// reported as storage slots, as their number does not tell storage and mail
+// slots apart.
+func (mv Move) elements() (src, dst Element) {
+	switch mv.Type {
+	case MoveLoad:
+		return Element{StorageSlot, mv.Src}, Element{DataTransferSlot, mv.Dst}
+	case MoveUnload:
+		return Element{DataTransferSlot, mv.Src}, Element{StorageSlot, mv.Dst}
+	}
+
+	return Element{StorageSlot, mv.Src}, Element{StorageSlot, mv.Dst}
+}
+
+// MovePlan is an ordered list of moves.
+type MovePlan struct {
+	Moves []Move
+}
+
+// Move performs a single move.
+func (chgr *Changer) Move(mv Move) error {
+	switch mv.Type {
+	case MoveLoad:
+		return chgr.Load(SlotNum(mv.Src), DriveNum(mv.Dst))
+	case MoveUnload:
+		return chgr.Unload(SlotNum(mv.Dst), DriveNum(mv.Src))
+	case MoveTransfer:
+		return chgr.Transfer(SlotNum(mv.Src), SlotNum(mv.Dst))
+	}
+
+	return fmt.Errorf("%w: unknown move type %d", ErrInvalidMove, int(mv.Type))
+}
+
+// Execute performs the moves of the plan in order, stopping at the first
+// failure. It returns the number of moves completed. If the policy of the
+// changer forbids any of the moves, none is performed.
+func (chgr *Changer) Execute(plan *MovePlan) (int, error) {
+	for i, mv := range plan.Moves {
+		if err := chgr.CheckMove(mv); err != nil {
+			return 0, fmt.Errorf("mtx: move %d (%s): %w", i, mv, err)
+		}
+	}
+
+	for i, mv := range plan.Moves {
+		if err := chgr.Move(mv); err != nil {
+			return i, fmt.Errorf("mtx: move %d (%s): %w", i, mv, err)
+		}
+	}
+
+	return len(plan.Moves), nil
+}
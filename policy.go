@@ -0,0 +1,161 @@
+package mtx
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Policy restricts the elements moves may use, e.g. to keep slots used by
+// another application sharing the library out of reach. Slots named in
+// commands are passed as storage slots, as their number does not tell
+// storage and mail slots apart; policies should select slots by number.
+type Policy interface {
+	// Allow reports whether e may be the source or destination of a move.
+	Allow(e Element) bool
+}
+
+// PolicyFunc adapts a function to the Policy interface.
+type PolicyFunc func(e Element) bool
+
+// Allow calls fn.
+func (fn PolicyFunc) Allow(e Element) bool {
+	return fn(e)
+}
+
+// ReserveSlots returns a policy forbidding the slots numbered first to
+// last.
+func ReserveSlots(first, last int) Policy {
+	return PolicyFunc(func(e Element) bool {
+		return e.IsDrive() || e.Num < first || e.Num > last
+	})
+}
+
+// ReserveDrives returns a policy forbidding the given drives.
+func ReserveDrives(nums ...int) Policy {
+	return PolicyFunc(func(e Element) bool {
+		if !e.IsDrive() {
+			return true
+		}
+
+		for _, num := range nums {
+			if e.Num == num {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// OnlySlots returns a policy forbidding all slots but those numbered first
+// to last. Drives are not restricted.
+func OnlySlots(first, last int) Policy {
+	return PolicyFunc(func(e Element) bool {
+		return e.IsDrive() || e.Num >= first && e.Num <= last
+	})
+}
+
+// OnlyDrives returns a policy forbidding all drives but the given ones.
+// Slots are not restricted.
+func OnlyDrives(nums ...int) Policy {
+	return PolicyFunc(func(e Element) bool {
+		return !e.IsDrive() || slices.Contains(nums, e.Num)
+	})
+}
+
+// AllOf returns a policy allowing the elements allowed by all of policies.
+func AllOf(policies ...Policy) Policy {
+	return PolicyFunc(func(e Element) bool {
+		for _, p := range policies {
+			if !p.Allow(e) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// AnyOf returns a policy allowing the elements allowed by any of policies,
+// e.g. the union of several OnlySlots ranges.
+func AnyOf(policies ...Policy) Policy {
+	return PolicyFunc(func(e Element) bool {
+		for _, p := range policies {
+			if p.Allow(e) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// WithPolicy restricts the elements moves may use to those allowed by p.
+// The policy is checked before commands are issued, and elements it
+// forbids are not chosen by Export, ExportSet and Import.
+func WithPolicy(p Policy) Option {
+	return func(chgr *Changer) {
+		chgr.policy = p
+	}
+}
+
+// Allowed reports whether the policy of the changer allows moves from or to
+// e.
+func (chgr *Changer) Allowed(e Element) bool {
+	return chgr.policy == nil || chgr.policy.Allow(e)
+}
+
+// allowedSlot is Allowed as a Predicate.
+func (chgr *Changer) allowedSlot(slot *Slot) bool {
+	return chgr.Allowed(slot.Element())
+}
+
+// CheckMove returns an error matching ErrNotAllowed if the policy of the
+// changer forbids mv.
+func (chgr *Changer) CheckMove(mv Move) error {
+	return chgr.checkMove(mv, &lazyStatus{chgr: chgr})
+}
+
+// checkMove implements CheckMove, taking the status from st if needed.
+func (chgr *Changer) checkMove(mv Move, st *lazyStatus) error {
+	if chgr.policy == nil {
+		return nil
+	}
+
+	src, dst := mv.elements()
+
+	if mv.Type == MoveUnload && dst.Num == 0 {
+		// the volume returns to its home slot
+		status, err := st.get()
+		if err != nil {
+			return err
+		}
+
+		if drv := status.element(src); drv != nil && drv.Vol != nil {
+			dst.Num = drv.Vol.Home
+		}
+	}
+
+	for _, e := range []Element{src, dst} {
+		if !chgr.Allowed(e) {
+			return fmt.Errorf("%w: %s", ErrNotAllowed, e)
+		}
+	}
+
+	return nil
+}
+
+// checkPolicy checks the command given by args against the policy of the
+// changer. Commands other than moves are not restricted.
+func (chgr *Changer) checkPolicy(args []string, st *lazyStatus) error {
+	if chgr.policy == nil {
+		return nil
+	}
+
+	mv, ok := moveArgs(args)
+	if !ok {
+		return nil
+	}
+
+	return chgr.checkMove(mv, st)
+}
@@ -0,0 +1,322 @@
+// Package pool groups the volumes of a library into named pools and hands
+// out scratch volumes from them.
+//
+// A volume is a member of a pool if its serial is explicitly listed or
+// matches one of the pool's patterns. Members that have not been allocated
+// are scratch volumes. Pool definitions and allocations are persisted in a
+// store.Store.
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/store"
+)
+
+var (
+	// ErrUnknownPool is returned when a pool is not defined.
+	ErrUnknownPool = errors.New("pool: unknown pool")
+
+	// ErrNoScratch is returned by AllocateScratch when the pool has no
+	// usable scratch volume.
+	ErrNoScratch = errors.New("pool: no scratch volume available")
+
+	// ErrNotAllocated is returned by Release for a volume that is not
+	// allocated.
+	ErrNotAllocated = errors.New("pool: volume not allocated")
+)
+
+// The key under which the pool state is stored.
+const storeKey = "pools"
+
+// Pool is a named set of volumes.
+type Pool struct {
+	Name string `json:"name"`
+
+	// Patterns are shell patterns, as understood by path.Match, matched
+	// against volume serials, e.g. "A0*L6".
+	Patterns []string `json:"patterns,omitempty"`
+
+	// Volumes lists member serials explicitly.
+	Volumes []string `json:"volumes,omitempty"`
+}
+
+// Contains returns whether the volume with the given serial is a member of
+// the pool.
+func (p *Pool) Contains(serial string) bool {
+	if serial == "" {
+		return false
+	}
+
+	for _, vol := range p.Volumes {
+		if vol == serial {
+			return true
+		}
+	}
+
+	for _, pattern := range p.Patterns {
+		if ok, _ := path.Match(pattern, serial); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Pool) validate() error {
+	if p.Name == "" {
+		return errors.New("pool: empty pool name")
+	}
+
+	for _, pattern := range p.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("pool: invalid pattern %q: %v", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// state is the persisted state of a Manager.
+type state struct {
+	Pools []*Pool `json:"pools"`
+
+	// Allocated maps the serials of allocated volumes to their pool.
+	Allocated map[string]string `json:"allocated"`
+}
+
+// Manager manages the pools of a library.
+type Manager struct {
+	chgr  *mtx.Changer
+	store store.Store
+
+	mu    sync.Mutex
+	state state
+}
+
+// New returns a Manager for chgr, restoring any state previously saved in
+// st.
+func New(chgr *mtx.Changer, st store.Store) (*Manager, error) {
+	mgr := &Manager{
+		chgr:  chgr,
+		store: st,
+		state: state{Allocated: make(map[string]string)},
+	}
+
+	buf, err := st.Get(storeKey)
+	if err == store.ErrNotFound {
+		return mgr, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, &mgr.state); err != nil {
+		return nil, fmt.Errorf("pool: corrupt state: %v", err)
+	}
+
+	if mgr.state.Allocated == nil {
+		mgr.state.Allocated = make(map[string]string)
+	}
+
+	return mgr, nil
+}
+
+func (mgr *Manager) save() error {
+	buf, err := json.Marshal(&mgr.state)
+	if err != nil {
+		return err
+	}
+
+	return mgr.store.Put(storeKey, buf)
+}
+
+func (mgr *Manager) lookup(name string) (int, *Pool) {
+	for i, p := range mgr.state.Pools {
+		if p.Name == name {
+			return i, p
+		}
+	}
+
+	return -1, nil
+}
+
+// Define adds a pool, or replaces the definition of an existing pool with
+// the same name. Allocations are kept.
+func (mgr *Manager) Define(p Pool) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	if i, _ := mgr.lookup(p.Name); i >= 0 {
+		mgr.state.Pools[i] = &p
+	} else {
+		mgr.state.Pools = append(mgr.state.Pools, &p)
+	}
+
+	return mgr.save()
+}
+
+// Remove deletes the named pool and releases its allocations.
+func (mgr *Manager) Remove(name string) error {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	i, _ := mgr.lookup(name)
+	if i < 0 {
+		return ErrUnknownPool
+	}
+
+	mgr.state.Pools = append(mgr.state.Pools[:i], mgr.state.Pools[i+1:]...)
+
+	for serial, pool := range mgr.state.Allocated {
+		if pool == name {
+			delete(mgr.state.Allocated, serial)
+		}
+	}
+
+	return mgr.save()
+}
+
+// Pool returns the definition of the named pool.
+func (mgr *Manager) Pool(name string) (*Pool, error) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	_, p := mgr.lookup(name)
+	if p == nil {
+		return nil, ErrUnknownPool
+	}
+
+	cp := *p
+
+	return &cp, nil
+}
+
+// Pools returns the names of all defined pools.
+func (mgr *Manager) Pools() []string {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	names := make([]string, len(mgr.state.Pools))
+	for i, p := range mgr.state.Pools {
+		names[i] = p.Name
+	}
+
+	return names
+}
+
+// Allocated returns the sorted serials of the volumes allocated from the
+// named pool.
+func (mgr *Manager) Allocated(name string) ([]string, error) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	if _, p := mgr.lookup(name); p == nil {
+		return nil, ErrUnknownPool
+	}
+
+	var serials []string
+	for serial, pool := range mgr.state.Allocated {
+		if pool == name {
+			serials = append(serials, serial)
+		}
+	}
+
+	sort.Strings(serials)
+
+	return serials, nil
+}
+
+// Members returns the slots currently holding members of the named pool.
+func (mgr *Manager) Members(name string) ([]*mtx.Slot, error) {
+	mgr.mu.Lock()
+	_, p := mgr.lookup(name)
+	mgr.mu.Unlock()
+
+	if p == nil {
+		return nil, ErrUnknownPool
+	}
+
+	status, err := mgr.chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var members []*mtx.Slot
+	for _, slot := range append(append([]*mtx.Slot{}, status.Drives...), status.Slots...) {
+		if slot.Vol != nil && p.Contains(slot.Vol.Serial) {
+			members = append(members, slot)
+		}
+	}
+
+	return members, nil
+}
+
+// AllocateScratch picks a scratch volume from the named pool and marks it as
+// allocated. Only volumes that the library reports in a storage slot are
+// considered, so the returned volume is physically present and not loaded
+// in a drive. The volume in the lowest numbered slot is picked.
+func (mgr *Manager) AllocateScratch(name string) (*mtx.Slot, error) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	_, p := mgr.lookup(name)
+	if p == nil {
+		return nil, ErrUnknownPool
+	}
+
+	status, err := mgr.chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, slot := range status.Slots {
+		if slot.Type != mtx.StorageSlot || slot.Vol == nil || !p.Contains(slot.Vol.Serial) {
+			continue
+		}
+
+		if _, ok := mgr.state.Allocated[slot.Vol.Serial]; ok {
+			continue
+		}
+
+		mgr.state.Allocated[slot.Vol.Serial] = name
+		if err := mgr.save(); err != nil {
+			delete(mgr.state.Allocated, slot.Vol.Serial)
+			return nil, err
+		}
+
+		return slot, nil
+	}
+
+	return nil, ErrNoScratch
+}
+
+// Release returns an allocated volume to the scratch set of its pool.
+func (mgr *Manager) Release(serial string) error {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	name, ok := mgr.state.Allocated[serial]
+	if !ok {
+		return ErrNotAllocated
+	}
+
+	delete(mgr.state.Allocated, serial)
+	if err := mgr.save(); err != nil {
+		mgr.state.Allocated[serial] = name
+		return err
+	}
+
+	return nil
+}
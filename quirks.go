@@ -0,0 +1,168 @@
+package mtx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Inquiry is the identification of a device, as reported by the inquiry
+// command.
+type Inquiry struct {
+	Vendor   string
+	Product  string
+	Revision string
+}
+
+// The fields of the inquiry output of mtx, e.g. "Vendor ID: 'IBM     '".
+var inquiryField = regexp.MustCompile(`(?m)^(Vendor ID|Product ID|Revision): '([^']*)'`)
+
+// ParseInquiry parses the output of 'mtx inquiry'. The padding of the fields
+// is removed.
+func ParseInquiry(out []byte) (*Inquiry, error) {
+	inq := &Inquiry{}
+
+	for _, m := range inquiryField.FindAllSubmatch(out, -1) {
+		value := strings.TrimSpace(string(m[2]))
+
+		switch string(m[1]) {
+		case "Vendor ID":
+			inq.Vendor = value
+		case "Product ID":
+			inq.Product = value
+		case "Revision":
+			inq.Revision = value
+		}
+	}
+
+	if inq.Vendor == "" && inq.Product == "" {
+		return nil, fmt.Errorf("%w: no vendor or product identification in inquiry output", ErrParse)
+	}
+
+	return inq, nil
+}
+
+// Inquiry returns the identification of the changer.
+func (chgr *Changer) Inquiry() (*Inquiry, error) {
+	out, err := chgr.Do("inquiry")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseInquiry(out)
+}
+
+// Quirk adapts the changer to a library whose status deviates from what
+// the parser expects, e.g. one that misreports the numbering of its mail
+// slots.
+type Quirk struct {
+	// Vendor and Product select the libraries the quirk applies to. They
+	// are matched as prefixes of the inquiry data; empty strings match any
+	// library.
+	Vendor, Product string
+
+	// Parse, if set, replaces ParseStatus for the output of the status
+	// command. It is not used for backends implementing StatusProvider.
+	Parse func(out []byte) (*Status, error)
+
+	// Fixup, if set, corrects every status of the library after parsing.
+	Fixup func(status *Status) error
+}
+
+// matches reports whether the quirk applies to the library identified by
+// inq.
+func (q *Quirk) matches(inq *Inquiry) bool {
+	return strings.HasPrefix(inq.Vendor, q.Vendor) && strings.HasPrefix(inq.Product, q.Product)
+}
+
+var (
+	quirksMu sync.RWMutex
+	quirks   []*Quirk
+)
+
+// RegisterQuirk registers q for the libraries it selects, for changers
+// created with WithQuirks. Quirks registered later take precedence. It is
+// meant to be called from init functions.
+func RegisterQuirk(q Quirk) {
+	quirksMu.Lock()
+	defer quirksMu.Unlock()
+
+	quirks = append(quirks, &q)
+}
+
+// LookupQuirk returns the registered quirk for the library identified by
+// inq, or nil if there is none.
+func LookupQuirk(inq *Inquiry) *Quirk {
+	quirksMu.RLock()
+	defer quirksMu.RUnlock()
+
+	for i := len(quirks) - 1; i >= 0; i-- {
+		if quirks[i].matches(inq) {
+			return quirks[i]
+		}
+	}
+
+	return nil
+}
+
+// WithQuirks makes the changer look up the registered quirk for the library
+// by its inquiry data, the first time it queries the status, and apply it.
+// If the inquiry fails, no quirk is applied and the lookup is retried with
+// the next status query.
+func WithQuirks() Option {
+	return func(chgr *Changer) {
+		chgr.quirkLookup = true
+	}
+}
+
+// WithQuirk makes the changer apply q, whatever library it drives.
+func WithQuirk(q Quirk) Option {
+	return func(chgr *Changer) {
+		chgr.quirk = &q
+	}
+}
+
+// TrailingMailSlots returns a fixup for libraries that do not flag their
+// mail slots: it reports the last n slots as mail slots.
+func TrailingMailSlots(n int) func(status *Status) error {
+	return func(status *Status) error {
+		if n > len(status.Slots) {
+			return fmt.Errorf("mtx: %d mail slots in a library of %d slots", n, len(status.Slots))
+		}
+
+		for _, slot := range status.Slots[len(status.Slots)-n:] {
+			slot.Type = MailSlot
+		}
+
+		status.NumMailSlots = n
+		status.NumStorageSlots = status.NumSlots - n
+
+		return nil
+	}
+}
+
+// currentQuirk returns the quirk of the changer, looking it up first if
+// needed.
+func (chgr *Changer) currentQuirk() *Quirk {
+	chgr.mu.Lock()
+	q, lookup := chgr.quirk, chgr.quirkLookup
+	chgr.mu.Unlock()
+
+	if q != nil || !lookup {
+		return q
+	}
+
+	inq, err := chgr.Inquiry()
+	if err != nil {
+		return nil
+	}
+
+	q = LookupQuirk(inq)
+
+	chgr.mu.Lock()
+	chgr.quirk, chgr.quirkLookup = q, false
+	chgr.mu.Unlock()
+
+	return q
+}
@@ -0,0 +1,162 @@
+package mtx
+
+import "context"
+
+// Range selects a subset of the elements of a library.
+type Range struct {
+	// Types restricts the range to the given element types. If empty,
+	// elements of all types are selected.
+	Types []SlotType
+
+	// First and Last restrict the range to element numbers in [First,
+	// Last]. A zero Last means no upper bound.
+	First, Last int
+}
+
+// DriveRange selects all data transfer elements.
+var DriveRange = Range{Types: []SlotType{DataTransferSlot}}
+
+// MailSlotRange selects all import/export elements.
+var MailSlotRange = Range{Types: []SlotType{MailSlot}}
+
+// SlotRange selects the storage and mail slots numbered first to last.
+func SlotRange(first, last int) Range {
+	return Range{Types: []SlotType{StorageSlot, MailSlot}, First: first, Last: last}
+}
+
+// Contains reports whether slot is in the range.
+func (r Range) Contains(slot *Slot) bool {
+	return r.contains(slot.Type, slot.Num)
+}
+
+func (r Range) contains(typ SlotType, num int) bool {
+	if num < r.First || (r.Last != 0 && num > r.Last) {
+		return false
+	}
+
+	if len(r.Types) == 0 {
+		return true
+	}
+
+	for _, t := range r.Types {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns the elements of status in r. The element counts of the
+// header are left as they are. The slots are shared with status; use Clone
+// to get an independent copy.
+func (status *Status) Filter(r Range) *Status {
+	c := *status
+	c.Drives = make([]*Slot, 0)
+	c.Slots = make([]*Slot, 0)
+
+	for _, slot := range status.Drives {
+		if r.Contains(slot) {
+			c.Drives = append(c.Drives, slot)
+		}
+	}
+
+	for _, slot := range status.Slots {
+		if r.Contains(slot) {
+			c.Slots = append(c.Slots, slot)
+		}
+	}
+
+	return &c
+}
+
+// SlotsPage returns up to limit slots of status.Slots, starting at index
+// offset. A limit of zero or less returns all slots from offset on. The page
+// shares the slots of status.
+func (status *Status) SlotsPage(offset, limit int) []*Slot {
+	if offset < 0 {
+		offset = 0
+	}
+
+	if offset > len(status.Slots) {
+		offset = len(status.Slots)
+	}
+
+	end := len(status.Slots)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	return status.Slots[offset:end:end]
+}
+
+// RangeInterface is implemented by backends that can report the status of
+// a subset of the elements without reading the full inventory, e.g. by
+// issuing READ ELEMENT STATUS for an element range.
+type RangeInterface interface {
+	// StatusRange returns the status of the elements in r in the format of
+	// 'mtx status'. The header reports the counts of the whole library.
+	StatusRange(r Range) ([]byte, error)
+}
+
+// ParseStatusRange parses the output of 'mtx status', keeping only the
+// elements in r. The lines of other elements are skipped without being
+// parsed beyond their element type and number.
+func ParseStatusRange(status []byte, r Range) (*Status, error) {
+	return parseStatus(status, &r)
+}
+
+// StatusRange returns the status of the elements in r. The element counts
+// are those of the whole library.
+//
+// A status cached by Refresh is used if present. Otherwise, backends
+// implementing RangeInterface are asked for the range only and backends
+// implementing StatusProvider for the full status; for other backends the
+// full status is queried and only the elements in r are parsed. If a quirk
+// applies to the library, the full status is always queried and parsed.
+func (chgr *Changer) StatusRange(r Range) (*Status, error) {
+	chgr.mu.Lock()
+	if chgr.cache != nil {
+		status := chgr.cache.Filter(r).Clone()
+		chgr.mu.Unlock()
+
+		return status, nil
+	}
+	chgr.mu.Unlock()
+
+	impl, ranged := chgr.Interface.(RangeInterface)
+	_, provider := chgr.Interface.(StatusProvider)
+
+	// quirks may depend on the whole status
+	if provider && !ranged || chgr.currentQuirk() != nil {
+		status, err := chgr.queryStatus(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		return status.Filter(r), nil
+	}
+
+	var out []byte
+	var err error
+	if ranged {
+		if err = chgr.throttle(context.Background(), []string{"status"}); err == nil {
+			out, err = impl.StatusRange(r)
+		}
+	} else {
+		out, err = chgr.Do("status")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := ParseStatusRange(out, r)
+	if err != nil {
+		return nil, err
+	}
+
+	chgr.validateLabels(status)
+
+	return status, nil
+}
@@ -0,0 +1,134 @@
+package mtx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CommandClass groups commands for rate limiting.
+type CommandClass int
+
+const (
+	// QueryClass covers the commands leaving the library unchanged, such
+	// as status and inquiry.
+	QueryClass CommandClass = iota
+
+	// MoveClass covers all other commands, such as moves and eject.
+	MoveClass
+)
+
+var commandClassNames = [...]string{
+	QueryClass: "query",
+	MoveClass:  "move",
+}
+
+// String returns a textual representation of the command class.
+func (class CommandClass) String() string {
+	if class < 0 || int(class) >= len(commandClassNames) {
+		return "unknown"
+	}
+
+	return commandClassNames[class]
+}
+
+// classOf returns the class of the command given by args.
+func classOf(args []string) CommandClass {
+	if isQuery(args) {
+		return QueryClass
+	}
+
+	return MoveClass
+}
+
+// WithRateLimit limits the commands of class sent to the library to rate
+// per second, allowing bursts of up to burst commands, e.g. to protect old
+// autoloaders that misbehave when sent status queries back to back. Commands
+// over the limit wait for their turn; commands given a context stop waiting
+// when it is done. Status queries shared by concurrent callers or answered
+// from the cache of Refresh do not count.
+//
+// A rate of zero or less leaves the class unlimited, lifting a limit set by
+// an earlier option. A burst below 1 is taken as 1, so that a command may
+// always be sent once a token is available.
+func WithRateLimit(class CommandClass, rate float64, burst int) Option {
+	return func(chgr *Changer) {
+		if rate <= 0 {
+			chgr.limits[class] = nil
+			return
+		}
+
+		if burst < 1 {
+			burst = 1
+		}
+
+		chgr.limits[class] = &bucket{
+			rate:   rate,
+			burst:  float64(burst),
+			tokens: float64(burst),
+		}
+	}
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token and returns how long to wait before it may be used.
+// Tokens taken ahead of time leave the bucket in debt.
+func (b *bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a token that was reserved but not used.
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// throttle waits until the rate limit for the command given by args, if
+// any, allows it to be sent, or until ctx is done.
+func (chgr *Changer) throttle(ctx context.Context, args []string) error {
+	b := chgr.limits[classOf(args)]
+	if b == nil {
+		return nil
+	}
+
+	d := b.reserve()
+	if d == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,26 @@
+package mtx
+
+import "testing"
+
+func TestWithRateLimit(t *testing.T) {
+	chgr := &Changer{}
+
+	WithRateLimit(QueryClass, 2, 0)(chgr)
+	if b := chgr.limits[QueryClass]; b == nil || b.burst != 1 {
+		t.Fatalf("limit = %+v, want burst 1", b)
+	}
+
+	// the first command is sent at once, the second waits for a token
+	if d := chgr.limits[QueryClass].reserve(); d != 0 {
+		t.Errorf("first reserve = %s, want 0", d)
+	}
+
+	if d := chgr.limits[QueryClass].reserve(); d <= 0 {
+		t.Errorf("second reserve = %s, want a wait", d)
+	}
+
+	WithRateLimit(QueryClass, 0, 10)(chgr)
+	if b := chgr.limits[QueryClass]; b != nil {
+		t.Errorf("limit = %+v after a zero rate, want none", b)
+	}
+}
@@ -0,0 +1,48 @@
+package mtx
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RawCommander may be implemented by backends that can send arbitrary SCSI
+// commands to the changer, e.g. vendor specific maintenance commands.
+type RawCommander interface {
+	// Raw sends the command descriptor block cdb. The data in out, if any,
+	// is sent to the device; data returned by the device is read into in,
+	// and the number of bytes read is returned.
+	Raw(ctx context.Context, cdb, out, in []byte) (int, error)
+}
+
+// Raw sends the command descriptor block cdb through the backend, which must
+// implement RawCommander. Like other commands, raw commands are logged, and
+// failures are returned as a *CommandError with the arguments "raw" and the
+// CDB in hex. As the changer cannot tell what a raw command does, any
+// cached status is dropped, and the policy of the changer is not checked.
+func (chgr *Changer) Raw(ctx context.Context, cdb, out, in []byte) (int, error) {
+	args := []string{"raw", hex.EncodeToString(cdb)}
+
+	impl, ok := chgr.Interface.(RawCommander)
+	if !ok {
+		return 0, commandError(args, fmt.Errorf("backend does not support raw commands: %w", errors.ErrUnsupported))
+	}
+
+	chgr.beginMutation()
+	defer chgr.endMutation()
+
+	if err := chgr.throttle(ctx, args); err != nil {
+		return 0, commandError(args, err)
+	}
+
+	start := time.Now()
+	n, err := impl.Raw(ctx, cdb, out, in)
+
+	if chgr.logger != nil {
+		chgr.logCommand(args, start, n, err)
+	}
+
+	return n, commandError(args, err)
+}
@@ -0,0 +1,68 @@
+package mtx
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReadOnly returns a changer implementation wrapping impl that fails every
+// command that could change the library, such as moves and eject, with
+// ErrReadOnly without passing it on. Status and inquiry commands are passed
+// on, as is the status of a StatusProvider. It suits monitoring daemons and
+// dashboards that must never move media.
+func ReadOnly(impl Interface) Interface {
+	ro := &readOnly{impl: impl}
+	if _, ok := impl.(StatusProvider); ok {
+		return &readOnlyStatus{ro}
+	}
+
+	return ro
+}
+
+// readOnly is the changer implementation returned by ReadOnly.
+type readOnly struct {
+	impl Interface
+}
+
+// check returns an error matching ErrReadOnly unless the command leaves the
+// library unchanged.
+func (ro *readOnly) check(args []string) error {
+	if isQuery(args) {
+		return nil
+	}
+
+	if len(args) == 0 {
+		return ErrReadOnly
+	}
+
+	return fmt.Errorf("%w: %s not permitted", ErrReadOnly, args[0])
+}
+
+func (ro *readOnly) Do(args ...string) ([]byte, error) {
+	if err := ro.check(args); err != nil {
+		return nil, err
+	}
+
+	return ro.impl.Do(args...)
+}
+
+func (ro *readOnly) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	if err := ro.check(args); err != nil {
+		return nil, err
+	}
+
+	if impl, ok := ro.impl.(contextDoer); ok {
+		return impl.DoContext(ctx, args...)
+	}
+
+	return ro.impl.Do(args...)
+}
+
+// readOnlyStatus is a readOnly passing on the status of a StatusProvider.
+type readOnlyStatus struct {
+	*readOnly
+}
+
+func (ro *readOnlyStatus) Status() (*Status, error) {
+	return ro.impl.(StatusProvider).Status()
+}
@@ -0,0 +1,44 @@
+package mtx
+
+import "fmt"
+
+// Reconcile returns the moves bringing the expected volumes back to their
+// elements, e.g. volumes to their assigned home slots and the cleaning
+// cartridge to its slot, as found misplaced by Audit of the current status.
+// Missing volumes, volumes that may be anywhere and loaded volumes expected
+// in their home slot are left alone. Volumes that were not expected and
+// hold an element an expected volume is assigned to are moved out of the way
+// to an empty storage slot, as are volumes blocking each other, see
+// PlanLayout.
+//
+// Nothing is moved by Reconcile: the plan can be reviewed and then performed
+// with Execute, or queued with a scheduler.
+func (chgr *Changer) Reconcile(expected []ExpectedVolume) (*MovePlan, error) {
+	assigned := make(map[Element]string)
+	for _, exp := range expected {
+		if exp.Element == nil {
+			continue
+		}
+
+		e := elementKey(*exp.Element)
+		if other, ok := assigned[e]; ok && other != exp.Serial {
+			return nil, fmt.Errorf("%w: %s and %s both expected in %s", ErrInvalidMove, other, exp.Serial, e)
+		}
+
+		assigned[e] = exp.Serial
+	}
+
+	status, err := chgr.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	report := Audit(status, expected)
+
+	layout := make(Layout, len(report.Misplaced))
+	for _, m := range report.Misplaced {
+		layout[m.Serial] = m.Expected
+	}
+
+	return planLayout(status, layout, chgr.allowedSlot, true)
+}
@@ -0,0 +1,87 @@
+package mtx
+
+import (
+	"context"
+	"time"
+)
+
+// isQuery reports whether the command leaves the library unchanged.
+func isQuery(args []string) bool {
+	return len(args) > 0 && (args[0] == "status" || args[0] == "inquiry")
+}
+
+// beginMutation drops the cached status and pauses refreshing until the
+// matching endMutation.
+func (chgr *Changer) beginMutation() {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	chgr.mutating++
+	chgr.gen++
+	chgr.cache = nil
+}
+
+func (chgr *Changer) endMutation() {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	chgr.mutating--
+	chgr.gen++
+	chgr.cache = nil
+}
+
+// store caches status if a refresher is running and no command changed the
+// library since the status query started in generation gen. A failed query
+// (nil status) drops the cache. The caller must hold chgr.mu.
+func (chgr *Changer) store(status *Status, gen int) {
+	switch {
+	case chgr.refreshers == 0:
+		return
+	case status == nil:
+		chgr.cache = nil
+	case chgr.mutating == 0 && chgr.gen == gen:
+		chgr.cache = status.Clone()
+	}
+}
+
+// Refresh keeps a recent status cached by querying the library every
+// interval until ctx is done. While Refresh runs, Status (and the getters
+// based on it) answer from the cache without querying the library.
+//
+// Commands that change the library, when run through Do, drop the cached
+// status and pause refreshing until they complete; the next Status call
+// queries the library again. Failed queries also drop the cache, so errors
+// reach the callers of Status. Refresh returns the context error.
+func (chgr *Changer) Refresh(ctx context.Context, interval time.Duration) error {
+	chgr.mu.Lock()
+	chgr.refreshers++
+	chgr.mu.Unlock()
+
+	defer func() {
+		chgr.mu.Lock()
+		chgr.refreshers--
+		if chgr.refreshers == 0 {
+			chgr.cache = nil
+		}
+		chgr.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		chgr.mu.Lock()
+		paused := chgr.mutating > 0
+		chgr.mu.Unlock()
+
+		if !paused {
+			chgr.fetch()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package replay records the commands performed on a library auto changer and
+// replays them later.
+//
+// A Recorder wraps a real implementation (typically scsi) and captures every
+// command and its output. The capture can be loaded by a Player which serves
+// the recorded output back, so that behavior observed on real hardware can be
+// reproduced without it.
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/kbj/mtx"
+)
+
+// Entry is a single recorded command.
+type Entry struct {
+	// Args are the arguments given to Do.
+	Args []string `json:"args"`
+
+	// Output is the output returned by Do.
+	Output string `json:"output,omitempty"`
+
+	// Err is the text of the error returned by Do, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// Recorder is an mtx.Interface that records commands performed by the
+// wrapped implementation.
+type Recorder struct {
+	impl mtx.Interface
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a recorder writing a capture of the commands performed
+// on impl to w. Entries are written as JSON, one per line.
+func NewRecorder(impl mtx.Interface, w io.Writer) *Recorder {
+	return &Recorder{
+		impl: impl,
+		enc:  json.NewEncoder(w),
+	}
+}
+
+// Do performs the command using the wrapped implementation and records it.
+func (rec *Recorder) Do(args ...string) ([]byte, error) {
+	out, err := rec.impl.Do(args...)
+
+	entry := &Entry{Args: args, Output: string(out)}
+	if err != nil {
+		entry.Err = err.Error()
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.err == nil {
+		rec.err = rec.enc.Encode(entry)
+	}
+
+	return out, err
+}
+
+// Err returns the first error encountered while writing the capture.
+func (rec *Recorder) Err() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return rec.err
+}
+
+// ErrExhausted is returned by a Player when all recorded commands have been
+// replayed.
+var ErrExhausted = errors.New("replay: no more recorded commands")
+
+// Player is an mtx.Interface that replays recorded commands in order.
+type Player struct {
+	mu      sync.Mutex
+	entries []*Entry
+	next    int
+}
+
+// NewPlayer returns a player for the capture read from r.
+func NewPlayer(r io.Reader) (*Player, error) {
+	var entries []*Entry
+
+	dec := json.NewDecoder(r)
+	for {
+		entry := new(Entry)
+		if err := dec.Decode(entry); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return &Player{entries: entries}, nil
+}
+
+// Do returns the output of the next recorded command. It fails if args do
+// not match those of the recorded command.
+func (p *Player) Do(args ...string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next >= len(p.entries) {
+		return nil, ErrExhausted
+	}
+
+	entry := p.entries[p.next]
+	if !equal(entry.Args, args) {
+		return nil, fmt.Errorf("replay: command %d: expected %q, got %q",
+			p.next, strings.Join(entry.Args, " "), strings.Join(args, " "),
+		)
+	}
+
+	p.next++
+
+	if entry.Err != "" {
+		return []byte(entry.Output), errors.New(entry.Err)
+	}
+
+	return []byte(entry.Output), nil
+}
+
+// Remaining returns the number of recorded commands not yet replayed.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.entries) - p.next
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,272 @@
+// Package report computes capacity and occupancy reports for a library.
+//
+// A Report summarizes a single status: drive and slot usage, the volumes
+// per media generation and the free slots per zone. Reports can be kept as
+// snapshots, and a series of snapshots yields the growth trend used for
+// capacity planning.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/store"
+	"github.com/kbj/mtx/volser"
+)
+
+// Zone is a named range of storage slots, e.g. a partition or a magazine.
+type Zone struct {
+	Name string
+
+	// First and Last are the first and last slot of the zone, inclusive.
+	First, Last int
+}
+
+// ZoneUsage is the usage of a zone.
+type ZoneUsage struct {
+	Name  string `json:"name"`
+	Slots int    `json:"slots"`
+	Free  int    `json:"free"`
+}
+
+// Generation counts the volumes of a media generation.
+type Generation struct {
+	// Name is e.g. "LTO-6", "LTO-6 WORM", "cleaning" or "unknown".
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Report is a capacity and occupancy report.
+type Report struct {
+	Time time.Time `json:"time"`
+
+	Drives       int `json:"drives"`
+	DrivesLoaded int `json:"drives_loaded"`
+
+	StorageSlots    int `json:"storage_slots"`
+	StorageOccupied int `json:"storage_occupied"`
+
+	MailSlots int `json:"mail_slots"`
+	MailFree  int `json:"mail_free"`
+
+	// Generations counts all volumes in the library, including those in
+	// drives and mail slots, sorted by name.
+	Generations []Generation `json:"generations"`
+
+	Zones []ZoneUsage `json:"zones,omitempty"`
+}
+
+// generation returns the generation name of a volume label.
+func generation(serial string) string {
+	label, err := volser.Parse(serial)
+	if err != nil {
+		return "unknown"
+	}
+
+	if label.Cleaning() {
+		return "cleaning"
+	}
+
+	name := fmt.Sprintf("LTO-%d", label.MediaType.Generation)
+	if label.MediaType.WORM {
+		name += " WORM"
+	}
+
+	return name
+}
+
+// New returns the report for status, computing the usage of the given
+// zones.
+func New(status *mtx.Status, zones ...Zone) *Report {
+	r := &Report{Time: time.Now()}
+	gens := make(map[string]int)
+
+	for _, drv := range status.Drives {
+		r.Drives++
+		if drv.Vol != nil {
+			r.DrivesLoaded++
+			gens[generation(drv.Vol.Serial)]++
+		}
+	}
+
+	for _, slot := range status.Slots {
+		if slot.Type == mtx.MailSlot {
+			r.MailSlots++
+			if slot.Vol == nil {
+				r.MailFree++
+			}
+		} else {
+			r.StorageSlots++
+			if slot.Vol != nil {
+				r.StorageOccupied++
+			}
+		}
+
+		if slot.Vol != nil {
+			gens[generation(slot.Vol.Serial)]++
+		}
+	}
+
+	for name, count := range gens {
+		r.Generations = append(r.Generations, Generation{Name: name, Count: count})
+	}
+
+	sort.Slice(r.Generations, func(i, j int) bool {
+		return r.Generations[i].Name < r.Generations[j].Name
+	})
+
+	for _, zone := range zones {
+		usage := ZoneUsage{Name: zone.Name}
+		for _, slot := range status.Slots {
+			if slot.Type != mtx.StorageSlot || slot.Num < zone.First || slot.Num > zone.Last {
+				continue
+			}
+
+			usage.Slots++
+			if slot.Vol == nil {
+				usage.Free++
+			}
+		}
+
+		r.Zones = append(r.Zones, usage)
+	}
+
+	return r
+}
+
+// StorageFree returns the number of empty storage slots.
+func (r *Report) StorageFree() int {
+	return r.StorageSlots - r.StorageOccupied
+}
+
+// WriteTable renders the report as text tables.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "ELEMENT\tTOTAL\tUSED\tFREE\n")
+	fmt.Fprintf(tw, "drives\t%d\t%d\t%d\n", r.Drives, r.DrivesLoaded, r.Drives-r.DrivesLoaded)
+	fmt.Fprintf(tw, "storage\t%d\t%d\t%d\n", r.StorageSlots, r.StorageOccupied, r.StorageFree())
+	fmt.Fprintf(tw, "mail\t%d\t%d\t%d\n", r.MailSlots, r.MailSlots-r.MailFree, r.MailFree)
+
+	if len(r.Zones) > 0 {
+		fmt.Fprintf(tw, "\nZONE\tSLOTS\tUSED\tFREE\n")
+		for _, z := range r.Zones {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", z.Name, z.Slots, z.Slots-z.Free, z.Free)
+		}
+	}
+
+	fmt.Fprintf(tw, "\nMEDIA\tVOLUMES\n")
+	for _, g := range r.Generations {
+		fmt.Fprintf(tw, "%s\t%d\n", g.Name, g.Count)
+	}
+
+	return tw.Flush()
+}
+
+// Trend is the change in storage occupancy over a series of reports.
+type Trend struct {
+	From, To time.Time
+
+	// Delta is the change in occupied storage slots from the first to the
+	// last report.
+	Delta int
+
+	// PerDay is the average change in occupied storage slots per day.
+	PerDay float64
+
+	// UntilFull is the projected time until the storage slots are full at
+	// the current rate. It is negative if occupancy is not growing.
+	UntilFull time.Duration
+}
+
+// ComputeTrend returns the trend over snapshots, which must span a period of
+// time.
+func ComputeTrend(snapshots []*Report) (*Trend, error) {
+	if len(snapshots) < 2 {
+		return nil, errors.New("report: at least two snapshots needed")
+	}
+
+	first, last := snapshots[0], snapshots[0]
+	for _, r := range snapshots[1:] {
+		if r.Time.Before(first.Time) {
+			first = r
+		}
+
+		if r.Time.After(last.Time) {
+			last = r
+		}
+	}
+
+	days := last.Time.Sub(first.Time).Hours() / 24
+	if days <= 0 {
+		return nil, errors.New("report: snapshots do not span any time")
+	}
+
+	t := &Trend{
+		From:      first.Time,
+		To:        last.Time,
+		Delta:     last.StorageOccupied - first.StorageOccupied,
+		UntilFull: -1,
+	}
+
+	t.PerDay = float64(t.Delta) / days
+
+	if t.PerDay > 0 {
+		d := float64(last.StorageFree()) / t.PerDay * 24 * float64(time.Hour)
+		if d < math.MaxInt64 {
+			t.UntilFull = time.Duration(d)
+		}
+	}
+
+	return t, nil
+}
+
+// The key under which snapshots are stored.
+const storeKey = "report-snapshots"
+
+// SaveSnapshot appends r to the snapshots kept in st, dropping the oldest
+// ones beyond max if max is positive.
+func SaveSnapshot(st store.Store, r *Report, max int) error {
+	snapshots, err := LoadSnapshots(st)
+	if err != nil {
+		return err
+	}
+
+	snapshots = append(snapshots, r)
+	if max > 0 && len(snapshots) > max {
+		snapshots = snapshots[len(snapshots)-max:]
+	}
+
+	buf, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+
+	return st.Put(storeKey, buf)
+}
+
+// LoadSnapshots returns the snapshots kept in st, oldest first.
+func LoadSnapshots(st store.Store) ([]*Report, error) {
+	buf, err := st.Get(storeKey)
+	if err == store.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*Report
+	if err := json.Unmarshal(buf, &snapshots); err != nil {
+		return nil, fmt.Errorf("report: corrupt snapshots: %v", err)
+	}
+
+	return snapshots, nil
+}
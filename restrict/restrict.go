@@ -0,0 +1,109 @@
+// Package restrict limits the commands that may be performed on a library
+// changer.
+//
+// A Changer wraps another mtx.Interface implementation and only passes on
+// the commands on its allow list, e.g. to hand a changer to semi-trusted
+// plugins or tenants that may query the library and load drives, but not
+// reorganize it:
+//
+//	impl := restrict.New(scsi.New("/dev/sg3"), "status", "load", "unload")
+//	chgr := mtx.NewChanger(impl)
+//
+// Other commands fail with a *PermissionError. Optional interfaces of the
+// wrapped implementation, such as mtx.RawCommander, are not passed on.
+package restrict
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kbj/mtx"
+)
+
+// ErrNotPermitted is matched by the errors returned for commands that are
+// not on the allow list.
+var ErrNotPermitted = errors.New("mtx/restrict: command not permitted")
+
+// PermissionError is returned for a command that is not on the allow list.
+type PermissionError struct {
+	Command string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("mtx/restrict: command %q not permitted", e.Command)
+}
+
+// Is reports whether target is ErrNotPermitted.
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrNotPermitted
+}
+
+// contextDoer is implemented by backends that accept a context.
+type contextDoer interface {
+	DoContext(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Changer is an mtx.Interface performing only the allowed commands with the
+// wrapped implementation.
+type Changer struct {
+	impl    mtx.Interface
+	allowed map[string]bool
+}
+
+// New returns a changer implementation wrapping impl that permits only the
+// given commands, e.g. "status" or "load".
+func New(impl mtx.Interface, commands ...string) *Changer {
+	chgr := &Changer{
+		impl:    impl,
+		allowed: make(map[string]bool, len(commands)),
+	}
+
+	for _, cmd := range commands {
+		chgr.allowed[cmd] = true
+	}
+
+	return chgr
+}
+
+// Allowed reports whether cmd is on the allow list.
+func (chgr *Changer) Allowed(cmd string) bool {
+	return chgr.allowed[cmd]
+}
+
+// check returns a *PermissionError unless the command is allowed.
+func (chgr *Changer) check(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no command given")
+	}
+
+	if !chgr.allowed[args[0]] {
+		return &PermissionError{Command: args[0]}
+	}
+
+	return nil
+}
+
+// Do performs the command using the wrapped implementation if it is
+// allowed.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	if err := chgr.check(args); err != nil {
+		return nil, err
+	}
+
+	return chgr.impl.Do(args...)
+}
+
+// DoContext is like Do, passing ctx on if the wrapped implementation
+// accepts a context.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	if err := chgr.check(args); err != nil {
+		return nil, err
+	}
+
+	if impl, ok := chgr.impl.(contextDoer); ok {
+		return impl.DoContext(ctx, args...)
+	}
+
+	return chgr.impl.Do(args...)
+}
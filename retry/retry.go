@@ -0,0 +1,117 @@
+// Package retry retries failed commands of a library changer.
+//
+// A Changer wraps another mtx.Interface implementation and tries failed
+// commands again after a delay, riding out transient failures such as a
+// busy robot or a bus reset:
+//
+//	impl := retry.New(scsi.New("/dev/sg3"), retry.Policy{Attempts: 3, Backoff: time.Second})
+//	chgr := mtx.NewChanger(impl)
+//
+// Failures that trying again cannot fix, such as an empty source element,
+// are returned right away, see mtx.IsPermanent. Raw commands are passed on
+// to a wrapped implementation supporting them, but never retried. Other
+// optional interfaces of the wrapped implementation are not passed on.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kbj/mtx"
+)
+
+// Policy configures when and how often commands are retried.
+type Policy struct {
+	// Attempts is the number of times a command is tried at most. Values
+	// below 1 mean 1.
+	Attempts int
+
+	// Backoff is the delay before the first retry. It doubles with every
+	// further retry.
+	Backoff time.Duration
+
+	// Moves makes moves and other commands changing the library retried
+	// too. A failed move may have got part of the way, so by default only
+	// queries are retried.
+	Moves bool
+}
+
+// contextDoer is implemented by backends that accept a context.
+type contextDoer interface {
+	DoContext(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Changer is an mtx.Interface retrying the failed commands of the wrapped
+// implementation.
+type Changer struct {
+	impl   mtx.Interface
+	policy Policy
+}
+
+// New returns a changer implementation wrapping impl that retries failed
+// commands according to p.
+func New(impl mtx.Interface, p Policy) *Changer {
+	return &Changer{impl: impl, policy: p}
+}
+
+// Do performs the command using the wrapped implementation, retrying it
+// according to the policy.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext is like Do, passing ctx on if the wrapped implementation
+// accepts a context. Retries stop when ctx is done.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	delay := chgr.policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		out, err := chgr.do(ctx, args)
+		if err == nil || attempt >= chgr.policy.Attempts || !chgr.retryable(args, err) {
+			return out, err
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return out, err
+		case <-t.C:
+		}
+
+		delay *= 2
+	}
+}
+
+// Raw sends the command descriptor block cdb through the wrapped
+// implementation, which must implement mtx.RawCommander. Raw commands are
+// tried once, as they may not be idempotent.
+func (chgr *Changer) Raw(ctx context.Context, cdb, out, in []byte) (int, error) {
+	impl, ok := chgr.impl.(mtx.RawCommander)
+	if !ok {
+		return 0, fmt.Errorf("backend does not support raw commands: %w", errors.ErrUnsupported)
+	}
+
+	return impl.Raw(ctx, cdb, out, in)
+}
+
+func (chgr *Changer) do(ctx context.Context, args []string) ([]byte, error) {
+	if impl, ok := chgr.impl.(contextDoer); ok {
+		return impl.DoContext(ctx, args...)
+	}
+
+	return chgr.impl.Do(args...)
+}
+
+// retryable reports whether the command given by args may be tried again
+// after failing with err.
+func (chgr *Changer) retryable(args []string, err error) bool {
+	query := len(args) > 0 && (args[0] == "status" || args[0] == "inquiry")
+	if !query && !chgr.policy.Moves {
+		return false
+	}
+
+	return !mtx.IsPermanent(err)
+}
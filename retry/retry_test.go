@@ -0,0 +1,41 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/retry"
+)
+
+// rawFailing fails every raw command, counting them.
+type rawFailing struct {
+	mtx.Interface
+	n int
+}
+
+func (impl *rawFailing) Raw(ctx context.Context, cdb, out, in []byte) (int, error) {
+	impl.n++
+	return 0, errors.New("bus reset")
+}
+
+func TestRaw(t *testing.T) {
+	impl := &rawFailing{Interface: mock.New(2, 8, 1, 4)}
+	chgr := mtx.NewChanger(retry.New(impl, retry.Policy{Attempts: 3, Moves: true}))
+
+	if _, err := chgr.Raw(context.Background(), []byte{0, 0, 0, 0, 0, 0}, nil, nil); err == nil {
+		t.Fatal("Raw succeeded, want bus reset")
+	}
+
+	if impl.n != 1 {
+		t.Errorf("raw command sent %d times, want once", impl.n)
+	}
+
+	// backends without raw commands report so through the wrapper
+	chgr = mtx.NewChanger(retry.New(mock.New(2, 8, 1, 4), retry.Policy{}))
+	if _, err := chgr.Raw(context.Background(), []byte{0, 0, 0, 0, 0, 0}, nil, nil); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Raw = %v, want ErrUnsupported", err)
+	}
+}
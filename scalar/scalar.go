@@ -0,0 +1,267 @@
+// Package scalar implements the mtx.Interface for Quantum Scalar i3 and i6
+// libraries using their web services API.
+//
+// A Scalar library is always divided into partitions, each presented to its
+// hosts as a separate changer, so a Changer addresses a single partition.
+// The I/E station is shared by all partitions; its slots are reported as mail
+// slots, and moves into or out of it fail with ErrStationOpen while its door
+// is open.
+//
+// The following endpoints are used:
+//
+//	POST /aml/users/login                     log in
+//	GET  /aml/partitions/{partition}/drives   drives of the partition
+//	GET  /aml/partitions/{partition}/slots    storage slots of the partition
+//	GET  /aml/ieStation                       I/E station state and slots
+//	POST /aml/partitions/{partition}/media/move
+package scalar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/internal/elements"
+)
+
+// ErrStationOpen is returned for moves involving the I/E station while its
+// door is open.
+var ErrStationOpen = errors.New("mtx/scalar: I/E station is open")
+
+var errUnauthorized = errors.New("mtx/scalar: unauthorized")
+
+// Changer represents a partition of a Scalar library.
+type Changer struct {
+	url       string
+	user      string
+	password  string
+	partition string
+	client    *http.Client
+
+	mu      sync.Mutex
+	cookies []*http.Cookie
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithClient makes the changer use the given HTTP client.
+func WithClient(client *http.Client) Option {
+	return func(chgr *Changer) {
+		chgr.client = client
+	}
+}
+
+// New returns a changer for the named partition of the library at url
+// (e.g. "https://scalar.example.com"), logging in as user.
+func New(url, user, password, partition string, opts ...Option) *Changer {
+	chgr := &Changer{
+		url:       strings.TrimRight(url, "/"),
+		user:      user,
+		password:  password,
+		partition: partition,
+		client:    http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
+	}
+
+	return chgr
+}
+
+// Do performs the given operation. See DoContext.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext performs the given operation. The status, load, unload and
+// transfer commands are supported.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	return elements.Do(ctx, chgr, "scalar", args...)
+}
+
+type element struct {
+	ElementAddress int    `json:"elementAddress"`
+	Barcode        string `json:"barcode"`
+	Full           bool   `json:"full"`
+
+	// SourceAddress is set for loaded drives.
+	SourceAddress int `json:"sourceAddress"`
+}
+
+type ieStation struct {
+	DoorOpen bool      `json:"doorOpen"`
+	Slots    []element `json:"slots"`
+}
+
+func (chgr *Changer) path(suffix string) string {
+	return "/aml/partitions/" + url.PathEscape(chgr.partition) + suffix
+}
+
+// Inventory implements elements.Library.
+func (chgr *Changer) Inventory(ctx context.Context) (*elements.Inventory, error) {
+	var drives, slots []element
+	var station ieStation
+
+	if err := chgr.call(ctx, http.MethodGet, chgr.path("/drives"), nil, &drives); err != nil {
+		return nil, err
+	}
+
+	if err := chgr.call(ctx, http.MethodGet, chgr.path("/slots"), nil, &slots); err != nil {
+		return nil, err
+	}
+
+	if err := chgr.call(ctx, http.MethodGet, "/aml/ieStation", nil, &station); err != nil {
+		return nil, err
+	}
+
+	var elems []*elements.Element
+	add := func(typ mtx.SlotType, list []element) {
+		for _, e := range list {
+			elems = append(elems, &elements.Element{
+				Type:   typ,
+				Addr:   e.ElementAddress,
+				Full:   e.Full || e.Barcode != "",
+				Volume: e.Barcode,
+				Source: e.SourceAddress,
+			})
+		}
+	}
+
+	add(mtx.DataTransferSlot, drives)
+	add(mtx.StorageSlot, slots)
+	add(mtx.MailSlot, station.Slots)
+
+	inv := elements.New(elems)
+	inv.Enclosure = &mtx.Enclosure{StationOpen: station.DoorOpen}
+
+	return inv, nil
+}
+
+type moveRequest struct {
+	SourceAddress      int `json:"sourceAddress"`
+	DestinationAddress int `json:"destinationAddress"`
+}
+
+// Move implements elements.Library.
+func (chgr *Changer) Move(ctx context.Context, src, dst *elements.Element) error {
+	if src.Type == mtx.MailSlot || dst.Type == mtx.MailSlot {
+		var station ieStation
+		if err := chgr.call(ctx, http.MethodGet, "/aml/ieStation", nil, &station); err != nil {
+			return err
+		}
+
+		if station.DoorOpen {
+			return ErrStationOpen
+		}
+	}
+
+	return chgr.call(ctx, http.MethodPost, chgr.path("/media/move"), &moveRequest{
+		SourceAddress:      src.Addr,
+		DestinationAddress: dst.Addr,
+	}, nil)
+}
+
+type login struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// call performs a request in the current session, logging in first if
+// there is no session or it has expired.
+func (chgr *Changer) call(ctx context.Context, method, path string, body, v interface{}) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if chgr.cookies == nil {
+		if err := chgr.login(ctx); err != nil {
+			return err
+		}
+	}
+
+	_, err := chgr.do(ctx, method, path, body, v)
+	if err == errUnauthorized {
+		if err := chgr.login(ctx); err != nil {
+			return err
+		}
+
+		_, err = chgr.do(ctx, method, path, body, v)
+	}
+
+	return err
+}
+
+// login starts a new session, keeping the session cookies. It must be
+// called with the mutex held.
+func (chgr *Changer) login(ctx context.Context) error {
+	chgr.cookies = nil
+
+	resp, err := chgr.do(ctx, http.MethodPost, "/aml/users/login", &login{Name: chgr.user, Password: chgr.password}, nil)
+	if err != nil {
+		return err
+	}
+
+	chgr.cookies = resp.Cookies()
+	if len(chgr.cookies) == 0 {
+		return errors.New("mtx/scalar: login returned no session")
+	}
+
+	return nil
+}
+
+func (chgr *Changer) do(ctx context.Context, method, path string, body, v interface{}) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		r = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, chgr.url+path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for _, c := range chgr.cookies {
+		req.AddCookie(c)
+	}
+
+	resp, err := chgr.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errUnauthorized
+	}
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("mtx/scalar: %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if v == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp, nil
+	}
+
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,494 @@
+// Package scheduler serializes robot operations on a changer.
+//
+// The robot arm of a library can only perform one move at a time. A
+// Scheduler owns a changer, queues moves submitted by any number of
+// goroutines and executes them one at a time, highest priority first, so
+// that e.g. restore mounts jump ahead of queued shelf reorganization. With
+// WithPreemption, urgent jobs also cancel the queued low-priority ones.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbj/mtx"
+)
+
+var (
+	// ErrClosed is the error of jobs that were still queued when the
+	// scheduler was closed, and of jobs submitted after.
+	ErrClosed = errors.New("scheduler: closed")
+
+	// ErrCanceled is the error of jobs removed with Cancel.
+	ErrCanceled = errors.New("scheduler: job canceled")
+
+	// ErrPreempted is the error of queued jobs canceled in favor of a job
+	// of higher priority, see WithPreemption.
+	ErrPreempted = errors.New("scheduler: job preempted")
+)
+
+// State is the state of a job.
+type State int
+
+const (
+	// Queued jobs are waiting to be executed.
+	Queued State = iota
+
+	// Running jobs are being executed.
+	Running
+
+	// Done jobs have finished, successfully or not.
+	Done
+)
+
+func (s State) String() string {
+	switch s {
+	case Queued:
+		return "queued"
+	case Running:
+		return "running"
+	case Done:
+		return "done"
+	}
+
+	return "unknown"
+}
+
+// Job is a submitted move.
+type Job struct {
+	move      mtx.Move
+	submitted time.Time
+	seq       uint64
+
+	// protected by the scheduler mutex
+	priority int
+	state    State
+	err      error
+
+	done chan struct{}
+}
+
+// Move returns the move performed by the job.
+func (job *Job) Move() mtx.Move {
+	return job.move
+}
+
+// Done returns a channel that is closed when the job has finished.
+func (job *Job) Done() <-chan struct{} {
+	return job.done
+}
+
+// Err returns the result of the job. It must only be called after Done is
+// closed.
+func (job *Job) Err() error {
+	return job.err
+}
+
+// Wait waits for the job to finish and returns its result, or the context
+// error if ctx is done first. The job is not canceled in the latter case.
+func (job *Job) Wait(ctx context.Context) error {
+	select {
+	case <-job.done:
+		return job.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// JobInfo describes a job in the queue.
+type JobInfo struct {
+	Move      mtx.Move
+	Priority  int
+	State     State
+	Submitted time.Time
+}
+
+// Scheduler executes moves on a changer one at a time.
+type Scheduler struct {
+	chgr *mtx.Changer
+
+	mu      sync.Mutex
+	queue   []*Job
+	running *Job
+	seq     uint64
+	closed  bool
+
+	draining    bool
+	drainPolicy DrainPolicy
+
+	preempt   bool
+	threshold int
+	observe   func(priority int, wait time.Duration)
+
+	wake chan struct{}
+	quit chan struct{}
+}
+
+// An Option configures a Scheduler.
+type Option func(sched *Scheduler)
+
+// DrainPolicy decides what Drain does with the queued jobs.
+type DrainPolicy int
+
+const (
+	// FinishQueued makes Drain run the queued jobs before stopping.
+	FinishQueued DrainPolicy = iota
+
+	// CancelQueued makes Drain fail the queued jobs with ErrClosed right
+	// away, only letting the running job finish.
+	CancelQueued
+)
+
+// WithDrainPolicy sets the drain policy of the scheduler. The default is
+// FinishQueued.
+func WithDrainPolicy(p DrainPolicy) Option {
+	return func(sched *Scheduler) {
+		sched.drainPolicy = p
+	}
+}
+
+// WithPreemption makes jobs submitted with a priority of at least threshold
+// cancel the queued jobs of priority below threshold with ErrPreempted, so
+// that e.g. restores are not delayed by reorganization moves that are going
+// to be replanned anyway. Running jobs are allowed to finish.
+func WithPreemption(threshold int) Option {
+	return func(sched *Scheduler) {
+		sched.preempt = true
+		sched.threshold = threshold
+	}
+}
+
+// WithWaitObserver makes the scheduler call observe with the priority of
+// every job it starts and the time the job was queued, e.g. to export
+// metrics.QueueWait.
+func WithWaitObserver(observe func(priority int, wait time.Duration)) Option {
+	return func(sched *Scheduler) {
+		sched.observe = observe
+	}
+}
+
+// New returns a scheduler for chgr. Moves are executed by Run.
+func New(chgr *mtx.Changer, opts ...Option) *Scheduler {
+	sched := &Scheduler{
+		chgr: chgr,
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(sched)
+	}
+
+	return sched
+}
+
+// Submit queues a move. Jobs with a higher priority run first and jobs of
+// equal priority run in submission order.
+//
+// If an identical move is already queued or running, no new job is created;
+// the existing job is returned instead, and its priority is raised to
+// priority if that is higher.
+//
+// With WithPreemption, submitting a job with a priority of at least the
+// threshold cancels the queued jobs of priority below it.
+func (sched *Scheduler) Submit(mv mtx.Move, priority int) *Job {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	if sched.closed || sched.draining {
+		job := &Job{move: mv, priority: priority, state: Done, err: ErrClosed, done: make(chan struct{})}
+		close(job.done)
+		return job
+	}
+
+	if sched.running != nil && sched.running.move == mv {
+		return sched.running
+	}
+
+	for _, job := range sched.queue {
+		if job.move == mv {
+			if priority > job.priority {
+				job.priority = priority
+				sched.preemptFor(job)
+			}
+
+			return job
+		}
+	}
+
+	sched.seq++
+	job := &Job{
+		move:      mv,
+		submitted: time.Now(),
+		seq:       sched.seq,
+		priority:  priority,
+		done:      make(chan struct{}),
+	}
+
+	sched.queue = append(sched.queue, job)
+	sched.preemptFor(job)
+
+	select {
+	case sched.wake <- struct{}{}:
+	default:
+	}
+
+	return job
+}
+
+// preemptFor cancels the queued jobs of priority below the threshold if job
+// preempts them. It must be called with the mutex held.
+func (sched *Scheduler) preemptFor(job *Job) {
+	if !sched.preempt || job.priority < sched.threshold {
+		return
+	}
+
+	queue := sched.queue[:0]
+	for _, j := range sched.queue {
+		if j.priority < sched.threshold {
+			sched.finish(j, ErrPreempted)
+			continue
+		}
+
+		queue = append(queue, j)
+	}
+
+	clear(sched.queue[len(queue):])
+	sched.queue = queue
+}
+
+// Move submits mv with priority 0 and waits for it to finish. It implements
+// mtx.Mover, so that e.g. Changer.ExportSet queues its moves with the
+// scheduler.
+func (sched *Scheduler) Move(mv mtx.Move) error {
+	return sched.Submit(mv, 0).Wait(context.Background())
+}
+
+// Execute performs the moves of plan in order with the given priority,
+// submitting each once the one before it has finished, so that other jobs
+// may run in between. Like Changer.Execute, it checks the policy of the
+// changer for all moves first, stops at the first failure and returns the
+// number of moves completed. If ctx is done, no further moves are
+// submitted, and the move waited for is canceled if it is still queued or
+// else allowed to finish.
+func (sched *Scheduler) Execute(ctx context.Context, plan *mtx.MovePlan, priority int) (int, error) {
+	for i, mv := range plan.Moves {
+		if err := sched.chgr.CheckMove(mv); err != nil {
+			return 0, fmt.Errorf("mtx: move %d (%s): %w", i, mv, err)
+		}
+	}
+
+	for i, mv := range plan.Moves {
+		job := sched.Submit(mv, priority)
+		if err := job.Wait(ctx); err != nil {
+			if ctx.Err() == nil {
+				return i, fmt.Errorf("mtx: move %d (%s): %w", i, mv, err)
+			}
+
+			if !sched.Cancel(job) {
+				// the move is running; its outcome decides the count
+				if <-job.Done(); job.Err() == nil {
+					i++
+				}
+			}
+
+			return i, ctx.Err()
+		}
+	}
+
+	return len(plan.Moves), nil
+}
+
+// Cancel removes a queued job. It returns false if the job is no longer
+// queued.
+func (sched *Scheduler) Cancel(job *Job) bool {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	for i, j := range sched.queue {
+		if j == job {
+			sched.queue = append(sched.queue[:i], sched.queue[i+1:]...)
+			sched.finish(job, ErrCanceled)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Len returns the number of queued jobs, not counting a running one.
+func (sched *Scheduler) Len() int {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	return len(sched.queue)
+}
+
+// Queue returns a snapshot of the running job, if any, followed by the
+// queued jobs in the order they will be executed.
+func (sched *Scheduler) Queue() []JobInfo {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	var infos []JobInfo
+	if sched.running != nil {
+		infos = append(infos, sched.running.info())
+	}
+
+	queue := append([]*Job(nil), sched.queue...)
+	for len(queue) > 0 {
+		i := next(queue)
+		infos = append(infos, queue[i].info())
+		queue = append(queue[:i], queue[i+1:]...)
+	}
+
+	return infos
+}
+
+func (job *Job) info() JobInfo {
+	return JobInfo{
+		Move:      job.move,
+		Priority:  job.priority,
+		State:     job.state,
+		Submitted: job.submitted,
+	}
+}
+
+// next returns the index of the job to run next.
+func next(queue []*Job) int {
+	best := 0
+	for i, job := range queue[1:] {
+		if job.priority > queue[best].priority ||
+			job.priority == queue[best].priority && job.seq < queue[best].seq {
+			best = i + 1
+		}
+	}
+
+	return best
+}
+
+// finish marks job as done. It must be called with the mutex held.
+func (sched *Scheduler) finish(job *Job, err error) {
+	job.state = Done
+	job.err = err
+	close(job.done)
+}
+
+// Run executes queued jobs until the scheduler is closed. It must only be
+// called once.
+func (sched *Scheduler) Run() error {
+	for {
+		sched.mu.Lock()
+		if sched.closed {
+			sched.mu.Unlock()
+			return nil
+		}
+
+		if len(sched.queue) == 0 {
+			sched.mu.Unlock()
+
+			select {
+			case <-sched.wake:
+			case <-sched.quit:
+			}
+
+			continue
+		}
+
+		i := next(sched.queue)
+		job := sched.queue[i]
+		sched.queue = append(sched.queue[:i], sched.queue[i+1:]...)
+		job.state = Running
+		sched.running = job
+		priority := job.priority
+		sched.mu.Unlock()
+
+		if sched.observe != nil {
+			sched.observe(priority, time.Since(job.submitted))
+		}
+
+		err := sched.chgr.Move(job.move)
+
+		sched.mu.Lock()
+		sched.running = nil
+		sched.finish(job, err)
+		sched.mu.Unlock()
+	}
+}
+
+// Drain stops the scheduler gracefully, so that a daemon embedding it can
+// restart without abandoning a move: new jobs fail with ErrClosed right
+// away, the queued jobs are run or canceled according to the drain policy,
+// and Drain waits for the running job to finish before closing the
+// scheduler.
+//
+// If ctx is done first, the jobs still queued fail with ErrClosed, the
+// scheduler is closed without waiting for a running job, and Drain returns
+// the context error. Run must be running for queued jobs to be run.
+func (sched *Scheduler) Drain(ctx context.Context) error {
+	sched.mu.Lock()
+	sched.draining = true
+	if sched.drainPolicy == CancelQueued {
+		sched.cancelQueued()
+	}
+	sched.mu.Unlock()
+
+	for {
+		sched.mu.Lock()
+		job := sched.running
+		if job == nil && len(sched.queue) > 0 {
+			job = sched.queue[next(sched.queue)]
+		}
+
+		if job == nil {
+			sched.closeLocked()
+			sched.mu.Unlock()
+
+			return nil
+		}
+		sched.mu.Unlock()
+
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			sched.Close()
+			return ctx.Err()
+		}
+	}
+}
+
+// cancelQueued fails the queued jobs with ErrClosed. It must be called with
+// the mutex held.
+func (sched *Scheduler) cancelQueued() {
+	for _, job := range sched.queue {
+		sched.finish(job, ErrClosed)
+	}
+
+	sched.queue = nil
+}
+
+// Close stops the scheduler. A running job is allowed to finish; queued jobs
+// fail with ErrClosed.
+func (sched *Scheduler) Close() error {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+
+	sched.closeLocked()
+
+	return nil
+}
+
+// closeLocked implements Close. It must be called with the mutex held.
+func (sched *Scheduler) closeLocked() {
+	if sched.closed {
+		return
+	}
+
+	sched.closed = true
+	close(sched.quit)
+
+	sched.cancelQueued()
+}
@@ -0,0 +1,232 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/mock"
+	"github.com/kbj/mtx/scheduler"
+)
+
+// held holds every move until it is released, announcing it on started.
+type held struct {
+	mtx.Interface
+	started, release chan struct{}
+}
+
+func (impl *held) Do(args ...string) ([]byte, error) {
+	if args[0] != "status" {
+		impl.started <- struct{}{}
+		<-impl.release
+	}
+
+	return impl.Interface.Do(args...)
+}
+
+// start returns a running scheduler for a mock library whose moves are
+// held, and the run error channel.
+func start(t *testing.T, opts ...scheduler.Option) (*scheduler.Scheduler, *held, <-chan error) {
+	t.Helper()
+
+	impl := &held{Interface: mock.New(2, 8, 1, 4), started: make(chan struct{}, 8), release: make(chan struct{})}
+	sched := scheduler.New(mtx.NewChanger(impl), opts...)
+
+	errc := make(chan error, 1)
+	go func() { errc <- sched.Run() }()
+
+	t.Cleanup(func() {
+		sched.Close()
+		close(impl.release)
+	})
+
+	return sched, impl, errc
+}
+
+var (
+	move1 = mtx.Move{Type: mtx.MoveTransfer, Src: 1, Dst: 5}
+	move2 = mtx.Move{Type: mtx.MoveTransfer, Src: 2, Dst: 6}
+	move3 = mtx.Move{Type: mtx.MoveTransfer, Src: 3, Dst: 7}
+)
+
+func TestDrainFinishQueued(t *testing.T) {
+	sched, impl, _ := start(t)
+
+	running := sched.Submit(move1, 0)
+	<-impl.started
+
+	queued := sched.Submit(move2, 0)
+
+	drained := make(chan error, 1)
+	go func() { drained <- sched.Drain(context.Background()) }()
+
+	// both jobs are run
+	impl.release <- struct{}{}
+	<-impl.started
+	impl.release <- struct{}{}
+
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain = %v", err)
+	}
+
+	for _, job := range []*scheduler.Job{running, queued} {
+		if err := job.Wait(context.Background()); err != nil {
+			t.Errorf("%s = %v, want done", job.Move(), err)
+		}
+	}
+
+	if err := sched.Submit(move3, 0).Wait(context.Background()); !errors.Is(err, scheduler.ErrClosed) {
+		t.Errorf("job submitted after Drain = %v, want ErrClosed", err)
+	}
+}
+
+func TestDrainCancelQueued(t *testing.T) {
+	sched, impl, _ := start(t, scheduler.WithDrainPolicy(scheduler.CancelQueued))
+
+	running := sched.Submit(move1, 0)
+	<-impl.started
+
+	queued := sched.Submit(move2, 0)
+
+	drained := make(chan error, 1)
+	go func() { drained <- sched.Drain(context.Background()) }()
+
+	// the queued job fails while the running one is still held
+	if err := queued.Wait(context.Background()); !errors.Is(err, scheduler.ErrClosed) {
+		t.Errorf("queued job = %v, want ErrClosed", err)
+	}
+
+	impl.release <- struct{}{}
+
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain = %v", err)
+	}
+
+	if err := running.Wait(context.Background()); err != nil {
+		t.Errorf("running job = %v, want done", err)
+	}
+}
+
+func TestDrainContextDone(t *testing.T) {
+	sched, impl, errc := start(t)
+
+	running := sched.Submit(move1, 0)
+	<-impl.started
+
+	queued := sched.Submit(move2, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	drained := make(chan error, 1)
+	go func() { drained <- sched.Drain(ctx) }()
+
+	cancel()
+
+	if err := <-drained; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Drain = %v, want canceled", err)
+	}
+
+	if err := queued.Wait(context.Background()); !errors.Is(err, scheduler.ErrClosed) {
+		t.Errorf("queued job = %v, want ErrClosed", err)
+	}
+
+	// the running job is still allowed to finish
+	impl.release <- struct{}{}
+
+	if err := running.Wait(context.Background()); err != nil {
+		t.Errorf("running job = %v, want done", err)
+	}
+
+	if err := <-errc; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+}
+
+func TestPreemptRaisedPriority(t *testing.T) {
+	sched, impl, _ := start(t, scheduler.WithPreemption(10))
+
+	running := sched.Submit(move1, 0)
+	<-impl.started
+
+	low := sched.Submit(move2, 0)
+	raised := sched.Submit(move3, 0)
+
+	if job := sched.Submit(move3, 10); job != raised {
+		t.Fatal("resubmitted move got a new job")
+	}
+
+	if err := low.Wait(context.Background()); !errors.Is(err, scheduler.ErrPreempted) {
+		t.Errorf("low priority job = %v, want ErrPreempted", err)
+	}
+
+	// the running job is not preempted
+	if q := sched.Queue(); len(q) != 2 || q[0].Move != move1 || q[1].Move != move3 || q[1].Priority != 10 {
+		t.Errorf("queue = %+v, want %s running and %s queued with priority 10", q, move1, move3)
+	}
+
+	impl.release <- struct{}{}
+	<-impl.started
+	impl.release <- struct{}{}
+
+	for _, job := range []*scheduler.Job{running, raised} {
+		if err := job.Wait(context.Background()); err != nil {
+			t.Errorf("%s = %v, want done", job.Move(), err)
+		}
+	}
+}
+
+func TestWaitObserver(t *testing.T) {
+	type observation struct {
+		priority int
+		wait     time.Duration
+	}
+
+	observed := make(chan observation, 2)
+	sched, impl, _ := start(t, scheduler.WithWaitObserver(func(priority int, wait time.Duration) {
+		observed <- observation{priority, wait}
+	}))
+
+	sched.Submit(move1, 1)
+	<-impl.started
+
+	if o := <-observed; o.priority != 1 {
+		t.Errorf("first job observed with priority %d, want 1", o.priority)
+	}
+
+	job := sched.Submit(move2, 3)
+	time.Sleep(20 * time.Millisecond)
+
+	impl.release <- struct{}{}
+	<-impl.started
+	impl.release <- struct{}{}
+
+	if err := job.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if o := <-observed; o.priority != 3 || o.wait < 20*time.Millisecond {
+		t.Errorf("second job observed with priority %d after %s, want 3 after at least 20ms", o.priority, o.wait)
+	}
+}
+
+// BenchmarkLargeMove measures a move through the scheduler on a large
+// library, moving a volume back and forth between two storage slots.
+func BenchmarkLargeMove(b *testing.B) {
+	sched := scheduler.New(mtx.NewChanger(mock.NewLarge()))
+	go sched.Run()
+	defer sched.Close()
+
+	moves := [2]mtx.Move{
+		{Type: mtx.MoveTransfer, Src: 1, Dst: 9000},
+		{Type: mtx.MoveTransfer, Src: 9000, Dst: 1},
+	}
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		if err := sched.Move(moves[i%2]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
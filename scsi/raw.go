@@ -0,0 +1,54 @@
+package scsi
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+)
+
+// Raw implements mtx.RawCommander by running 'sg_raw' from sg3_utils on the
+// changer device. Data to send is passed to it through a temporary file.
+func (chgr *Changer) Raw(ctx context.Context, cdb, out, in []byte) (int, error) {
+	if len(cdb) == 0 {
+		return 0, errors.New("mtx/scsi: empty CDB")
+	}
+
+	args := []string{"-b"}
+
+	if len(in) > 0 {
+		args = append(args, "-r", strconv.Itoa(len(in)))
+	}
+
+	if len(out) > 0 {
+		f, err := os.CreateTemp("", "mtx-raw-")
+		if err != nil {
+			return 0, err
+		}
+		defer os.Remove(f.Name())
+
+		if _, err := f.Write(out); err != nil {
+			f.Close()
+			return 0, err
+		}
+
+		if err := f.Close(); err != nil {
+			return 0, err
+		}
+
+		args = append(args, "-s", strconv.Itoa(len(out)), "-i", f.Name())
+	}
+
+	args = append(args, chgr.path)
+	for _, b := range cdb {
+		args = append(args, hex.EncodeToString([]byte{b}))
+	}
+
+	data, err := chgr.run(ctx, chgr.rawProg, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return copy(in, data), nil
+}
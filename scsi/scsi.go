@@ -4,47 +4,188 @@ package scsi
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os/exec"
+	"time"
 )
 
+// Executor runs external programs on behalf of a Changer. It can be replaced
+// to run 'mtx' inside a container, to audit invocations or to fake the
+// program entirely in tests.
+type Executor interface {
+	// Run executes the program name with the given arguments. A non-zero exit
+	// code is reported through exitcode and is not an error in itself; err
+	// is only non-nil if the program could not be run at all.
+	Run(ctx context.Context, name string, args ...string) (stdout, stderr []byte, exitcode int, err error)
+}
+
+// ExecExecutor is an Executor that runs programs on the local host using
+// os/exec.
+type ExecExecutor struct{}
+
+// Run executes the program using os/exec.
+func (ExecExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, int, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return stdout.Bytes(), stderr.Bytes(), exitError.ExitCode(), nil
+		}
+
+		return stdout.Bytes(), stderr.Bytes(), -1, err
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), 0, nil
+}
+
+// ExitError is returned when 'mtx' exits with a non-zero exit code.
+type ExitError struct {
+	// Code is the exit code of the program.
+	Code int
+
+	// Stderr holds the output of the program on standard error.
+	Stderr []byte
+
+	// Sense is the sense data of the failed command, if mtx reported it.
+	Sense *Sense
+}
+
+// Error returns the exit code and standard error output of the program.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d: %s", e.Code, e.Stderr)
+}
+
+// Is reports whether target is the mtx error kind of the sense data, see
+// Sense.Err.
+func (e *ExitError) Is(target error) bool {
+	return e.Sense != nil && e.Sense.Err() != nil && target == e.Sense.Err()
+}
+
 // Changer represents a library changer managed by the 'mtx' program.
 type Changer struct {
-	path string
-	prog string
+	path    string
+	prog    string
+	rawProg string
+	exec    Executor
+	logger  *slog.Logger
+	timeout time.Duration
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithLogger makes the changer log every invocation of 'mtx', with its
+// arguments, duration, exit code and standard error output, to logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(chgr *Changer) {
+		chgr.logger = logger
+	}
+}
+
+// WithProgram makes the changer run the 'mtx' program at path instead of
+// /usr/bin/mtx.
+func WithProgram(path string) Option {
+	return func(chgr *Changer) {
+		chgr.prog = path
+	}
+}
+
+// WithTimeout makes the changer kill programs that run for longer than d.
+// Moves of large libraries can take minutes, so d should be generous.
+func WithTimeout(d time.Duration) Option {
+	return func(chgr *Changer) {
+		chgr.timeout = d
+	}
 }
 
 // New returns a new changer implementation using 'mtx' for library operations.
-func New(path string) *Changer {
-	return &Changer{
-		path: path,
-		prog: "/usr/bin/mtx",
+func New(path string, opts ...Option) *Changer {
+	return NewWithExecutor(path, ExecExecutor{}, opts...)
+}
+
+// NewWithExecutor returns a new changer implementation that runs 'mtx'
+// through the given executor.
+func NewWithExecutor(path string, exec Executor, opts ...Option) *Changer {
+	chgr := &Changer{
+		path:    path,
+		prog:    "/usr/bin/mtx",
+		rawProg: "/usr/bin/sg_raw",
+		exec:    exec,
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
 	}
+
+	return chgr
 }
 
 // Do performs the given operation.
 func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext performs the given operation. If ctx is cancelled before the
+// operation completes, the 'mtx' process is killed.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
 	// this is a little bit wonky Go...
 	params := append([]string{"-f", chgr.path}, args...)
 
-	return run(exec.Command(chgr.prog, params...))
+	return chgr.run(ctx, chgr.prog, params...)
 }
 
-func run(cmd *exec.Cmd) ([]byte, error) {
-	var stderr bytes.Buffer
-
-	if cmd.Stderr == nil {
-		cmd.Stderr = &stderr
+func (chgr *Changer) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if chgr.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, chgr.timeout)
+		defer cancel()
 	}
 
-	out, err := cmd.Output()
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			return out, fmt.Errorf("%s: %s", exitError, stderr.String())
+	start := time.Now()
+	out, stderr, code, err := chgr.exec.Run(ctx, name, args...)
+
+	if chgr.logger != nil {
+		level := slog.LevelDebug
+		if err != nil || code != 0 {
+			level = slog.LevelWarn
 		}
 
+		attrs := []slog.Attr{
+			slog.String("prog", name),
+			slog.Any("args", args),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("exit_code", code),
+		}
+
+		if len(stderr) > 0 {
+			attrs = append(attrs, slog.String("stderr", string(stderr)))
+		}
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		chgr.logger.LogAttrs(ctx, level, "run mtx", attrs...)
+	}
+
+	if err != nil {
 		return out, err
 	}
 
+	if code != 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return out, fmt.Errorf("%s: %w", name, ctx.Err())
+	}
+
+	if code != 0 {
+		return out, &ExitError{Code: code, Stderr: stderr, Sense: parseSense(stderr)}
+	}
+
 	return out, nil
 }
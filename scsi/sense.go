@@ -0,0 +1,162 @@
+package scsi
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/kbj/mtx"
+)
+
+// SenseKey is the sense key of SCSI sense data.
+type SenseKey byte
+
+// Sense keys.
+const (
+	NoSense        SenseKey = 0x0
+	RecoveredError SenseKey = 0x1
+	NotReady       SenseKey = 0x2
+	MediumError    SenseKey = 0x3
+	HardwareError  SenseKey = 0x4
+	IllegalRequest SenseKey = 0x5
+	UnitAttention  SenseKey = 0x6
+	DataProtect    SenseKey = 0x7
+	BlankCheck     SenseKey = 0x8
+	AbortedCommand SenseKey = 0xb
+	VolumeOverflow SenseKey = 0xd
+	Miscompare     SenseKey = 0xe
+)
+
+// senseKeyNames are the sense key names printed by mtx.
+var senseKeyNames = [...]string{
+	NoSense:        "No Sense",
+	RecoveredError: "Recovered Error",
+	NotReady:       "Not Ready",
+	MediumError:    "Medium Error",
+	HardwareError:  "Hardware Error",
+	IllegalRequest: "Illegal Request",
+	UnitAttention:  "Unit Attention",
+	DataProtect:    "Data Protect",
+	BlankCheck:     "Blank Check",
+	0x9:            "0x09",
+	0xa:            "0x0a",
+	AbortedCommand: "Aborted Command",
+	0xc:            "0x0c",
+	VolumeOverflow: "Volume Overflow",
+	Miscompare:     "Miscompare",
+	0xf:            "0x0f",
+}
+
+// String returns the name of the sense key.
+func (key SenseKey) String() string {
+	if int(key) >= len(senseKeyNames) {
+		return fmt.Sprintf("0x%02x", byte(key))
+	}
+
+	return senseKeyNames[key]
+}
+
+// Sense is the sense data of a failed command: the sense key and the
+// additional sense code (ASC) and qualifier (ASCQ).
+type Sense struct {
+	Key       SenseKey
+	ASC, ASCQ byte
+}
+
+// String returns the sense key followed by the ASC/ASCQ pair and its
+// description, if known.
+func (s *Sense) String() string {
+	str := fmt.Sprintf("%s (ASC/ASCQ %02X/%02X", s.Key, s.ASC, s.ASCQ)
+	if desc := s.Description(); desc != "" {
+		str += ": " + desc
+	}
+
+	return str + ")"
+}
+
+// The ASC/ASCQ pairs commonly reported by medium changers.
+var senseDescriptions = map[[2]byte]string{
+	{0x04, 0x00}: "logical unit not ready",
+	{0x04, 0x01}: "logical unit becoming ready",
+	{0x04, 0x03}: "manual intervention required",
+	{0x15, 0x01}: "mechanical positioning error",
+	{0x1a, 0x00}: "parameter list length error",
+	{0x20, 0x00}: "invalid command operation code",
+	{0x21, 0x01}: "invalid element address",
+	{0x24, 0x00}: "invalid field in CDB",
+	{0x28, 0x01}: "import or export element accessed",
+	{0x29, 0x00}: "power on, reset or bus device reset occurred",
+	{0x30, 0x00}: "incompatible medium installed",
+	{0x3a, 0x00}: "medium not present",
+	{0x3b, 0x0d}: "medium destination element full",
+	{0x3b, 0x0e}: "medium source element empty",
+	{0x3b, 0x11}: "medium magazine not accessible",
+	{0x3b, 0x12}: "medium magazine removed",
+	{0x3b, 0x90}: "medium not loaded in drive",
+	{0x40, 0x00}: "diagnostic failure",
+	{0x44, 0x00}: "internal target failure",
+	{0x53, 0x02}: "medium removal prevented",
+	{0x83, 0x00}: "label missing or unreadable",
+}
+
+// Description returns a description of the ASC/ASCQ pair, or "" if it is
+// not known.
+func (s *Sense) Description() string {
+	return senseDescriptions[[2]byte{s.ASC, s.ASCQ}]
+}
+
+// Err returns the mtx error kind the sense data corresponds to: mtx.ErrEmpty,
+// mtx.ErrFull or mtx.ErrInvalidElement, or nil if there is none.
+func (s *Sense) Err() error {
+	switch [2]byte{s.ASC, s.ASCQ} {
+	case [2]byte{0x3b, 0x0e}:
+		return mtx.ErrEmpty
+	case [2]byte{0x3b, 0x0d}:
+		return mtx.ErrFull
+	case [2]byte{0x21, 0x01}:
+		return mtx.ErrInvalidElement
+	}
+
+	return nil
+}
+
+// The request sense report mtx prints on standard error when a command
+// fails.
+var (
+	senseKeyLine  = regexp.MustCompile(`Request Sense: Sense Key=(.+)`)
+	senseCodeLine = regexp.MustCompile(`Request Sense: Additional Sense Code = ([0-9A-Fa-f]{2})`)
+	senseQualLine = regexp.MustCompile(`Request Sense: Additional Sense Qualifier = ([0-9A-Fa-f]{2})`)
+)
+
+// parseSense returns the sense data reported in the standard error output
+// of mtx, or nil if there is none.
+func parseSense(stderr []byte) *Sense {
+	key := senseKeyLine.FindSubmatch(stderr)
+	asc := senseCodeLine.FindSubmatch(stderr)
+	ascq := senseQualLine.FindSubmatch(stderr)
+	if key == nil || asc == nil || ascq == nil {
+		return nil
+	}
+
+	s := &Sense{}
+
+	name := string(bytes.TrimSpace(key[1]))
+	found := false
+	for k, n := range senseKeyNames {
+		if n == name {
+			s.Key, found = SenseKey(k), true
+			break
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	c, _ := strconv.ParseUint(string(asc[1]), 16, 8)
+	q, _ := strconv.ParseUint(string(ascq[1]), 16, 8)
+	s.ASC, s.ASCQ = byte(c), byte(q)
+
+	return s
+}
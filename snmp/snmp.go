@@ -0,0 +1,252 @@
+// Package snmp turns SNMP reported library conditions into events.
+//
+// Libraries report hardware problems such as an open door, a picker fault
+// or a drive error through their SNMP agent, either when polled or as traps.
+// This package matches the reported variables against Conditions and
+// publishes Alert and AlertCleared events on an events.Bus, next to the
+// status change events.
+//
+// The package does not implement the SNMP protocol itself. Polling uses any
+// client that implements Getter, and traps are fed to HandleTrap, so any
+// SNMP library can be plugged in with a few lines of glue. The OIDs are
+// vendor specific and are found in the MIB of the library.
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbj/mtx/events"
+)
+
+// Kind classifies an alert.
+type Kind int
+
+const (
+	// Other is any condition not covered by the other kinds.
+	Other Kind = iota
+
+	// DoorOpen means a library door or magazine is open.
+	DoorOpen
+
+	// PickerFault means the robot failed.
+	PickerFault
+
+	// DriveError means a drive reported an error.
+	DriveError
+)
+
+func (k Kind) String() string {
+	switch k {
+	case DoorOpen:
+		return "door open"
+	case PickerFault:
+		return "picker fault"
+	case DriveError:
+		return "drive error"
+	}
+
+	return "other"
+}
+
+// Variable is an SNMP variable binding.
+type Variable struct {
+	OID string
+
+	// Value is the decoded value, typically an int64, uint64 or string.
+	Value interface{}
+}
+
+// Getter fetches SNMP variables.
+type Getter interface {
+	// Get returns the values of the given OIDs. OIDs the agent does not
+	// know are omitted from the result.
+	Get(ctx context.Context, oids []string) ([]Variable, error)
+}
+
+// Condition describes a problem reported through an OID.
+type Condition struct {
+	Kind Kind
+
+	// Name describes the condition, e.g. "front door".
+	Name string
+
+	OID string
+
+	// Active returns whether value indicates the condition.
+	Active func(value interface{}) bool
+}
+
+// Equals returns a condition that is active when the OID has the given
+// value. Integer values are compared regardless of their Go type.
+func Equals(kind Kind, name, oid string, value interface{}) Condition {
+	return Condition{
+		Kind: kind,
+		Name: name,
+		OID:  oid,
+		Active: func(v interface{}) bool {
+			if a, ok := toInt(v); ok {
+				b, ok := toInt(value)
+				return ok && a == b
+			}
+
+			return v == value
+		},
+	}
+}
+
+// NotEquals returns a condition that is active when the OID does not have
+// the given value, e.g. a status that is not "ok".
+func NotEquals(kind Kind, name, oid string, value interface{}) Condition {
+	cond := Equals(kind, name, oid, value)
+	eq := cond.Active
+	cond.Active = func(v interface{}) bool { return !eq(v) }
+
+	return cond
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	}
+
+	return 0, false
+}
+
+// Alert is published when a condition becomes active.
+type Alert struct {
+	Kind  Kind
+	Name  string
+	OID   string
+	Value interface{}
+
+	// Trap is true if the alert was raised by a trap rather than polling.
+	Trap bool
+}
+
+func (ev *Alert) String() string {
+	return fmt.Sprintf("alert: %s: %s (%s = %v)", ev.Kind, ev.Name, ev.OID, ev.Value)
+}
+
+// AlertCleared is published when a polled condition is no longer active.
+type AlertCleared struct {
+	Kind Kind
+	Name string
+	OID  string
+}
+
+func (ev *AlertCleared) String() string {
+	return fmt.Sprintf("alert cleared: %s: %s", ev.Kind, ev.Name)
+}
+
+// Monitor evaluates conditions and publishes alerts.
+type Monitor struct {
+	client Getter
+	bus    *events.Bus
+	conds  []Condition
+
+	mu     sync.Mutex
+	active map[int]bool
+}
+
+// NewMonitor returns a monitor publishing to bus. The client is only used
+// for polling and may be nil if only traps are handled.
+func NewMonitor(client Getter, bus *events.Bus, conds ...Condition) *Monitor {
+	return &Monitor{
+		client: client,
+		bus:    bus,
+		conds:  conds,
+		active: make(map[int]bool),
+	}
+}
+
+// Check polls the condition OIDs once. An Alert is published for every
+// condition that became active since the previous check, and an
+// AlertCleared for every condition that is no longer active.
+func (mon *Monitor) Check(ctx context.Context) error {
+	oids := make([]string, len(mon.conds))
+	for i, cond := range mon.conds {
+		oids[i] = cond.OID
+	}
+
+	vars, err := mon.client.Get(ctx, oids)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{}, len(vars))
+	for _, v := range vars {
+		values[v.OID] = v.Value
+	}
+
+	var evs []events.Event
+
+	mon.mu.Lock()
+	for i, cond := range mon.conds {
+		value, ok := values[cond.OID]
+		if !ok {
+			continue
+		}
+
+		active := cond.Active(value)
+		switch {
+		case active && !mon.active[i]:
+			evs = append(evs, &Alert{Kind: cond.Kind, Name: cond.Name, OID: cond.OID, Value: value})
+		case !active && mon.active[i]:
+			evs = append(evs, &AlertCleared{Kind: cond.Kind, Name: cond.Name, OID: cond.OID})
+		}
+
+		mon.active[i] = active
+	}
+	mon.mu.Unlock()
+
+	mon.bus.Publish(evs...)
+
+	return nil
+}
+
+// Poll calls Check every interval until ctx is done and returns the context
+// error. Failed checks are retried at the next interval.
+func (mon *Monitor) Poll(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		mon.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// HandleTrap publishes an Alert for every condition that is active
+// according to the variables of a received trap. Traps do not clear alerts;
+// that is left to polling.
+func (mon *Monitor) HandleTrap(vars []Variable) {
+	var evs []events.Event
+	for _, v := range vars {
+		for _, cond := range mon.conds {
+			if cond.OID == v.OID && cond.Active(v.Value) {
+				evs = append(evs, &Alert{Kind: cond.Kind, Name: cond.Name, OID: v.OID, Value: v.Value, Trap: true})
+			}
+		}
+	}
+
+	mon.bus.Publish(evs...)
+}
@@ -0,0 +1,298 @@
+// Package spectra implements the mtx.Interface for Spectra Logic libraries
+// using the XML command interface of their library control module.
+//
+// Spectra libraries hold cartridges in TeraPack magazines. Each slot is
+// identified by the magazine and the slot number within it; mtx numbering
+// follows the slot offsets reported for the partition. Slots of TeraPacks
+// in the entry/exit ports are reported as mail slots, so importing and
+// exporting work like they do with an import/export station: transfer a
+// cartridge into an entry/exit slot and have the operator remove the
+// TeraPack.
+//
+// The following commands are used:
+//
+//	/gf/login.xml                       log in
+//	/gf/inventory.xml?action=list       slots of a partition
+//	/gf/inventory.xml?action=move       start a move
+//	/gf/inventory.xml?action=moveResult result of the last move
+package spectra
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/internal/elements"
+)
+
+var errUnauthorized = errors.New("mtx/spectra: unauthorized")
+
+// Changer represents a partition of a Spectra Logic library.
+type Changer struct {
+	url       string
+	user      string
+	password  string
+	partition string
+	client    *http.Client
+	poll      time.Duration
+
+	mu      sync.Mutex
+	cookies []*http.Cookie
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithClient makes the changer use the given HTTP client.
+func WithClient(client *http.Client) Option {
+	return func(chgr *Changer) {
+		chgr.client = client
+	}
+}
+
+// New returns a changer for the named partition of the library at url
+// (e.g. "http://spectra.example.com"), logging in as user.
+func New(url, user, password, partition string, opts ...Option) *Changer {
+	chgr := &Changer{
+		url:       strings.TrimRight(url, "/"),
+		user:      user,
+		password:  password,
+		partition: partition,
+		client:    http.DefaultClient,
+		poll:      time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
+	}
+
+	return chgr
+}
+
+// Do performs the given operation. See DoContext.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext performs the given operation. The status, load, unload and
+// transfer commands are supported. Moves wait for the library to report
+// their result.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	return elements.Do(ctx, chgr, "spectra", args...)
+}
+
+type slot struct {
+	Offset  int    `xml:"offset"`
+	ID      string `xml:"id"`
+	Number  int    `xml:"number"`
+	Barcode string `xml:"barcode"`
+	Full    string `xml:"full"`
+
+	// SourceOffset is set for loaded drives.
+	SourceOffset int `xml:"sourceOffset"`
+}
+
+type inventory struct {
+	XMLName   xml.Name `xml:"inventory"`
+	Partition []struct {
+		Name    string `xml:"name"`
+		Storage []slot `xml:"storageSlot"`
+		EE      []slot `xml:"entryExitSlot"`
+		Drives  []slot `xml:"driveSlot"`
+	} `xml:"partition"`
+}
+
+// Inventory implements elements.Library.
+func (chgr *Changer) Inventory(ctx context.Context) (*elements.Inventory, error) {
+	var inv inventory
+	if err := chgr.call(ctx, "/gf/inventory.xml", url.Values{
+		"action":    {"list"},
+		"partition": {chgr.partition},
+	}, &inv); err != nil {
+		return nil, err
+	}
+
+	var elems []*elements.Element
+	add := func(typ mtx.SlotType, list []slot) {
+		for _, s := range list {
+			elems = append(elems, &elements.Element{
+				Type:   typ,
+				Addr:   s.Offset,
+				ID:     fmt.Sprintf("%s/%d", s.ID, s.Number),
+				Full:   s.Full == "yes",
+				Volume: s.Barcode,
+				Source: s.SourceOffset,
+			})
+		}
+	}
+
+	for _, p := range inv.Partition {
+		if p.Name != chgr.partition {
+			continue
+		}
+
+		add(mtx.DataTransferSlot, p.Drives)
+		add(mtx.StorageSlot, p.Storage)
+		add(mtx.MailSlot, p.EE)
+	}
+
+	return elements.New(elems), nil
+}
+
+type moveResult struct {
+	XMLName xml.Name `xml:"moveResult"`
+
+	// Status is "PENDING", "COMPLETE" or "ERROR".
+	Status string `xml:"status"`
+	Reason string `xml:"reason"`
+}
+
+// splitID splits an element ID into the TeraPack (or drive) ID and the slot
+// number.
+func splitID(id string) (string, string) {
+	i := strings.LastIndexByte(id, '/')
+	if i < 0 {
+		return id, "0"
+	}
+
+	return id[:i], id[i+1:]
+}
+
+// Move implements elements.Library.
+func (chgr *Changer) Move(ctx context.Context, src, dst *elements.Element) error {
+	srcID, srcNum := splitID(src.ID)
+	dstID, dstNum := splitID(dst.ID)
+
+	var res moveResult
+	if err := chgr.call(ctx, "/gf/inventory.xml", url.Values{
+		"action":            {"move"},
+		"partition":         {chgr.partition},
+		"sourceID":          {srcID},
+		"sourceNumber":      {srcNum},
+		"destinationID":     {dstID},
+		"destinationNumber": {dstNum},
+	}, nil); err != nil {
+		return err
+	}
+
+	for {
+		if err := chgr.call(ctx, "/gf/inventory.xml", url.Values{"action": {"moveResult"}}, &res); err != nil {
+			return err
+		}
+
+		switch res.Status {
+		case "COMPLETE":
+			return nil
+		case "ERROR":
+			return fmt.Errorf("mtx/spectra: move failed: %s", res.Reason)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(chgr.poll):
+		}
+	}
+}
+
+// call performs a command in the current session, logging in first if
+// there is no session or it has expired.
+func (chgr *Changer) call(ctx context.Context, path string, params url.Values, v interface{}) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if chgr.cookies == nil {
+		if err := chgr.login(ctx); err != nil {
+			return err
+		}
+	}
+
+	_, err := chgr.get(ctx, path, params, v)
+	if err == errUnauthorized {
+		if err := chgr.login(ctx); err != nil {
+			return err
+		}
+
+		_, err = chgr.get(ctx, path, params, v)
+	}
+
+	return err
+}
+
+// login starts a new session. It must be called with the mutex held.
+func (chgr *Changer) login(ctx context.Context) error {
+	chgr.cookies = nil
+
+	resp, err := chgr.get(ctx, "/gf/login.xml", url.Values{
+		"username": {chgr.user},
+		"password": {chgr.password},
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	chgr.cookies = resp.Cookies()
+	if len(chgr.cookies) == 0 {
+		return errors.New("mtx/spectra: login returned no session")
+	}
+
+	return nil
+}
+
+// errorResponse is returned by the library for failed commands.
+type errorResponse struct {
+	XMLName xml.Name `xml:"error"`
+	Message string   `xml:"message"`
+}
+
+func (chgr *Changer) get(ctx context.Context, path string, params url.Values, v interface{}) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, chgr.url+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range chgr.cookies {
+		req.AddCookie(c)
+	}
+
+	resp, err := chgr.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, errUnauthorized
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("mtx/spectra: %s: %s", path, resp.Status)
+	}
+
+	// the library reports failed commands with an <error> document
+	var e errorResponse
+	if xml.Unmarshal(body, &e) == nil {
+		return nil, fmt.Errorf("mtx/spectra: %s: %s", path, e.Message)
+	}
+
+	if v != nil {
+		if err := xml.Unmarshal(body, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
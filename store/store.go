@@ -0,0 +1,160 @@
+// Package store provides simple key/value persistence for state kept by
+// the higher level packages, such as volume pools.
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by Get when the key does not exist.
+var ErrNotFound = errors.New("store: key not found")
+
+// Store is a key/value store. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Get returns the value stored under key, or ErrNotFound.
+	Get(key string) ([]byte, error)
+
+	// Put stores value under key, replacing any previous value.
+	Put(key string, value []byte) error
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(key string) error
+}
+
+// Memory is an in-memory Store.
+type Memory struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{data: make(map[string][]byte)}
+}
+
+// Get implements Store.
+func (st *Memory) Get(key string) ([]byte, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	value, ok := st.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]byte(nil), value...), nil
+}
+
+// Put implements Store.
+func (st *Memory) Put(key string, value []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.data[key] = append([]byte(nil), value...)
+
+	return nil
+}
+
+// Delete implements Store.
+func (st *Memory) Delete(key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	delete(st.data, key)
+
+	return nil
+}
+
+// Dir is a Store keeping one file per key in a directory.
+type Dir struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewDir returns a store keeping its files in dir, which is created if it
+// does not exist.
+func NewDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Dir{dir: dir}, nil
+}
+
+func (st *Dir) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || key[0] == '.' {
+		return "", errors.New("store: invalid key " + key)
+	}
+
+	return filepath.Join(st.dir, key), nil
+}
+
+// Get implements Store.
+func (st *Dir) Get(key string) ([]byte, error) {
+	path, err := st.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	value, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return value, err
+}
+
+// Put implements Store. The value is written to a temporary file which is
+// then renamed into place, so a crash never leaves a partial value behind.
+func (st *Dir) Put(key string, value []byte) error {
+	path, err := st.path(key)
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	f, err := os.CreateTemp(st.dir, "."+key+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Delete implements Store.
+func (st *Dir) Delete(key string) error {
+	path, err := st.path(key)
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package tlsconfig builds TLS configurations for the servers exposing a
+// changer on the network (httpserver, mtxgrpc) and their client backends
+// (httpclient, mtxgrpc), including mutual TLS with client certificates:
+//
+//	cfg, err := tlsconfig.Server("server.crt", "server.key", "clients-ca.crt")
+//	...
+//	err = httpserver.New(chgr).ListenAndServeTLS(":8443", cfg)
+//
+//	cfg, err := tlsconfig.Client("ca.crt", "client.crt", "client.key")
+//	...
+//	impl := httpclient.New("https://tapehost:8443", httpclient.WithTLS(cfg))
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// Server returns a configuration for a server presenting the certificate
+// in certFile with the key in keyFile. If clientCAFile is not empty, clients
+// must present a certificate signed by one of the CAs in it.
+func Server(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		if cfg.ClientCAs, err = loadPool(clientCAFile); err != nil {
+			return nil, err
+		}
+
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// Client returns a configuration for a client trusting the CAs in caFile,
+// or the system CAs if it is empty. If certFile is not empty, the client
+// presents the certificate in it, with the key in keyFile, to servers
+// requiring client certificates.
+func Client(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pool, err := loadPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// loadPool returns a pool of the PEM encoded certificates in file.
+func loadPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("tlsconfig: no certificates in %s", file)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,142 @@
+// Package tracing instruments a library changer with OpenTelemetry spans.
+//
+// A Changer wraps another mtx.Interface implementation and records a span
+// for every command, carrying the command name, the device, the element
+// numbers involved and, for the scsi backend, the exit status of 'mtx'. Use
+// DoContext (or a context aware caller) to make the spans children of the
+// caller's trace, e.g. of the backup job mounting a tape.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/scsi"
+)
+
+const instrumentationName = "github.com/kbj/mtx/tracing"
+
+// contextDoer is implemented by backends that accept a context.
+type contextDoer interface {
+	DoContext(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Changer is an mtx.Interface recording spans for the commands performed
+// by the wrapped implementation.
+type Changer struct {
+	impl   mtx.Interface
+	device string
+	tracer trace.Tracer
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithTracerProvider sets the tracer provider used. Defaults to the global
+// provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(chgr *Changer) {
+		chgr.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// New returns a new traced changer implementation wrapping impl. The device
+// is recorded on every span to tell libraries apart.
+func New(impl mtx.Interface, device string, opts ...Option) *Changer {
+	chgr := &Changer{
+		impl:   impl,
+		device: device,
+		tracer: otel.Tracer(instrumentationName),
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
+	}
+
+	return chgr
+}
+
+// Do performs the command using the wrapped implementation in a new root
+// span.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext performs the command using the wrapped implementation in a span
+// that is a child of the span in ctx, if any. If the wrapped implementation
+// accepts a context, the span's context is passed on.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := "none"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	ctx, span := chgr.tracer.Start(ctx, "mtx "+cmd,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes(chgr.device, args)...),
+	)
+	defer span.End()
+
+	var out []byte
+	var err error
+	if impl, ok := chgr.impl.(contextDoer); ok {
+		out, err = impl.DoContext(ctx, args...)
+	} else {
+		out, err = chgr.impl.Do(args...)
+	}
+
+	var exitErr *scsi.ExitError
+	if errors.As(err, &exitErr) {
+		span.SetAttributes(attribute.Int("mtx.exit_status", exitErr.Code))
+
+		if exitErr.Sense != nil {
+			span.SetAttributes(attribute.String("mtx.sense", exitErr.Sense.String()))
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return out, err
+}
+
+// attributes returns the span attributes describing the command.
+func attributes(device string, args []string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("mtx.device", device),
+	}
+
+	if len(args) == 0 {
+		return attrs
+	}
+
+	attrs = append(attrs, attribute.String("mtx.command", args[0]))
+
+	if len(args) != 3 {
+		return attrs
+	}
+
+	a, errA := strconv.Atoi(args[1])
+	b, errB := strconv.Atoi(args[2])
+	if errA != nil || errB != nil {
+		return attrs
+	}
+
+	switch args[0] {
+	case "load", "unload":
+		attrs = append(attrs, attribute.Int("mtx.slot", a), attribute.Int("mtx.drive", b))
+	case "transfer":
+		attrs = append(attrs, attribute.Int("mtx.source_slot", a), attribute.Int("mtx.destination_slot", b))
+	}
+
+	return attrs
+}
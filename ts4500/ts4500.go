@@ -0,0 +1,236 @@
+// Package ts4500 implements the mtx.Interface for IBM TS4500 and TS4300
+// tape libraries using their REST API on the management network instead of
+// the SCSI medium changer.
+//
+// The library reports elements by location (e.g. "F1C2R3") and SCSI element
+// address; the elements of one logical library are numbered like mtx would
+// number them when talking to that logical library's changer LUN.
+//
+// The following endpoints of the v1 API are used:
+//
+//	GET  /v1/drives       drives with their loaded cartridge
+//	GET  /v1/slots        storage and I/O station slots
+//	POST /v1/tasks        moveCartridge task
+//	GET  /v1/tasks/{id}   task completion
+package ts4500
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/internal/elements"
+)
+
+// Changer represents a TS4500 or TS4300 library.
+type Changer struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+	library  string
+	poll     time.Duration
+}
+
+// An Option configures a Changer.
+type Option func(chgr *Changer)
+
+// WithClient makes the changer use the given HTTP client, e.g. one trusting
+// the certificate of the library.
+func WithClient(client *http.Client) Option {
+	return func(chgr *Changer) {
+		chgr.client = client
+	}
+}
+
+// WithLogicalLibrary restricts the changer to the elements of the named
+// logical library. By default all elements are used, which is only
+// meaningful for libraries with a single logical library.
+func WithLogicalLibrary(name string) Option {
+	return func(chgr *Changer) {
+		chgr.library = name
+	}
+}
+
+// New returns a changer talking to the library at url (e.g.
+// "https://ts4500.example.com/web/api"), authenticating as user.
+func New(url, user, password string, opts ...Option) *Changer {
+	chgr := &Changer{
+		url:      strings.TrimRight(url, "/"),
+		user:     user,
+		password: password,
+		client:   http.DefaultClient,
+		poll:     time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(chgr)
+	}
+
+	return chgr
+}
+
+// Do performs the given operation. See DoContext.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	return chgr.DoContext(context.Background(), args...)
+}
+
+// DoContext performs the given operation. The status, load, unload and
+// transfer commands are supported. Moves wait for the library task to
+// complete.
+func (chgr *Changer) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	return elements.Do(ctx, chgr, "ts4500", args...)
+}
+
+type drive struct {
+	Location       string `json:"location"`
+	ElementAddress int    `json:"elementAddress"`
+	LogicalLibrary string `json:"logicalLibrary"`
+	Cartridge      string `json:"cartridge"`
+}
+
+type slot struct {
+	Location       string `json:"location"`
+	ElementAddress int    `json:"elementAddress"`
+	LogicalLibrary string `json:"logicalLibrary"`
+	Cartridge      string `json:"cartridge"`
+
+	// Type is "storage" or "ioStation".
+	Type string `json:"type"`
+}
+
+// Inventory implements elements.Library.
+func (chgr *Changer) Inventory(ctx context.Context) (*elements.Inventory, error) {
+	var drives []drive
+	if err := chgr.call(ctx, http.MethodGet, "/v1/drives", nil, &drives); err != nil {
+		return nil, err
+	}
+
+	var slots []slot
+	if err := chgr.call(ctx, http.MethodGet, "/v1/slots", nil, &slots); err != nil {
+		return nil, err
+	}
+
+	var elems []*elements.Element
+	for _, d := range drives {
+		if chgr.library != "" && d.LogicalLibrary != chgr.library {
+			continue
+		}
+
+		elems = append(elems, &elements.Element{
+			Type:   mtx.DataTransferSlot,
+			Addr:   d.ElementAddress,
+			ID:     d.Location,
+			Full:   d.Cartridge != "",
+			Volume: d.Cartridge,
+		})
+	}
+
+	for _, s := range slots {
+		if chgr.library != "" && s.LogicalLibrary != chgr.library && s.Type != "ioStation" {
+			continue
+		}
+
+		typ := mtx.StorageSlot
+		if s.Type == "ioStation" {
+			typ = mtx.MailSlot
+		}
+
+		elems = append(elems, &elements.Element{
+			Type:   typ,
+			Addr:   s.ElementAddress,
+			ID:     s.Location,
+			Full:   s.Cartridge != "",
+			Volume: s.Cartridge,
+		})
+	}
+
+	return elements.New(elems), nil
+}
+
+type task struct {
+	ID     int    `json:"id"`
+	Cmd    string `json:"cmd,omitempty"`
+	Status string `json:"status,omitempty"`
+	Result string `json:"result,omitempty"`
+}
+
+// Move implements elements.Library by running a moveCartridge task and
+// waiting for it to complete.
+func (chgr *Changer) Move(ctx context.Context, src, dst *elements.Element) error {
+	if src.Volume == "" {
+		return errors.New("mtx/ts4500: cannot move unlabeled cartridge")
+	}
+
+	t := task{Cmd: fmt.Sprintf("moveCartridge(%s, %s)", src.Volume, dst.ID)}
+	if err := chgr.call(ctx, http.MethodPost, "/v1/tasks", &t, &t); err != nil {
+		return err
+	}
+
+	for {
+		switch t.Status {
+		case "succeeded", "completed":
+			return nil
+		case "failed":
+			return fmt.Errorf("mtx/ts4500: %s failed: %s", t.Cmd, t.Result)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(chgr.poll):
+		}
+
+		if err := chgr.call(ctx, http.MethodGet, fmt.Sprintf("/v1/tasks/%d", t.ID), nil, &t); err != nil {
+			return err
+		}
+	}
+}
+
+func (chgr *Changer) call(ctx context.Context, method, path string, body, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, chgr.url+path, r)
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(chgr.user, chgr.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := chgr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mtx/ts4500: %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if v == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,241 @@
+package mtx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Element identifies an element of the library.
+type Element struct {
+	Type SlotType
+	Num  int
+}
+
+// DriveElement returns the element of drive num.
+func DriveElement(num DriveNum) Element {
+	return Element{Type: DataTransferSlot, Num: int(num)}
+}
+
+// SlotElement returns the element of slot num. Storage and mail slots share
+// their numbering; the element is reported as a storage slot either way.
+func SlotElement(num SlotNum) Element {
+	return Element{Type: StorageSlot, Num: int(num)}
+}
+
+// Element returns the element of slot.
+func (slot *Slot) Element() Element {
+	return Element{Type: slot.Type, Num: slot.Num}
+}
+
+// IsDrive returns whether e is a data transfer element.
+func (e Element) IsDrive() bool {
+	return e.Type == DataTransferSlot
+}
+
+func (e Element) String() string {
+	if e.IsDrive() {
+		return fmt.Sprintf("drive %d", e.Num)
+	}
+
+	return fmt.Sprintf("slot %d", e.Num)
+}
+
+// InterfaceV2 is the structured successor of Interface. Backends receive
+// typed elements instead of command strings and can validate requests
+// before touching the hardware.
+//
+// FromV2 adapts an InterfaceV2 backend for use with NewChanger and the
+// wrappers built on Interface; ToV2 goes the other way.
+type InterfaceV2 interface {
+	// ReadInventory returns the status of all elements of the library.
+	ReadInventory(ctx context.Context) (*Status, error)
+
+	// Move moves the volume in src to dst. Either may be a drive or a
+	// slot, but not both drives.
+	Move(ctx context.Context, src, dst Element) error
+}
+
+// validMove checks src and dst without consulting the library.
+func validMove(src, dst Element) error {
+	switch {
+	case src.IsDrive() && dst.IsDrive():
+		return fmt.Errorf("%w: %s to %s: moves between drives are not supported", ErrInvalidMove, src, dst)
+	case src.IsDrive() && src.Num < 0, !src.IsDrive() && src.Num < 1:
+		return fmt.Errorf("%w: %s", ErrInvalidElement, src)
+	case dst.IsDrive() && dst.Num < 0, !dst.IsDrive() && dst.Num < 1:
+		return fmt.Errorf("%w: %s", ErrInvalidElement, dst)
+	}
+
+	return nil
+}
+
+// v2Adapter implements Interface on top of an InterfaceV2 backend.
+type v2Adapter struct {
+	impl InterfaceV2
+}
+
+// FromV2 returns an Interface performing the commands given to Do with
+// impl. The status, load, unload and transfer commands are supported; the
+// output of status is rendered in the format of 'mtx status'. The returned
+// Interface also implements StatusProvider and supports cancellation.
+func FromV2(impl InterfaceV2) Interface {
+	if up, ok := impl.(*v1Adapter); ok {
+		return up.impl
+	}
+
+	return &v2Adapter{impl: impl}
+}
+
+func (a *v2Adapter) Do(args ...string) ([]byte, error) {
+	return a.DoContext(context.Background(), args...)
+}
+
+func (a *v2Adapter) DoContext(ctx context.Context, args ...string) ([]byte, error) {
+	if len(args) < 1 {
+		return nil, errors.New("no command given")
+	}
+
+	cmd := args[0]
+
+	if cmd == "status" {
+		status, err := a.impl.ReadInventory(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return RenderStatus("v2", status), nil
+	}
+
+	if cmd != "load" && cmd != "unload" && cmd != "transfer" {
+		return nil, fmt.Errorf("%w: %s", errors.ErrUnsupported, cmd)
+	}
+
+	if len(args) != 3 {
+		return nil, errors.New("wrong number of arguments")
+	}
+
+	x, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := strconv.Atoi(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var src, dst Element
+
+	switch cmd {
+	case "load":
+		src, dst = Element{StorageSlot, x}, Element{DataTransferSlot, y}
+	case "unload":
+		src, dst = Element{DataTransferSlot, y}, Element{StorageSlot, x}
+
+		if x == 0 {
+			// slot 0 is the home slot of the volume
+			if dst.Num, err = a.home(ctx, y); err != nil {
+				return nil, err
+			}
+		}
+	case "transfer":
+		src, dst = Element{StorageSlot, x}, Element{StorageSlot, y}
+	}
+
+	if err := validMove(src, dst); err != nil {
+		return nil, err
+	}
+
+	return nil, a.impl.Move(ctx, src, dst)
+}
+
+// home returns the home slot of the volume in drive num.
+func (a *v2Adapter) home(ctx context.Context, num int) (int, error) {
+	status, err := a.impl.ReadInventory(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, drv := range status.Drives {
+		if drv.Num != num {
+			continue
+		}
+
+		if drv.Vol == nil {
+			return 0, fmt.Errorf("%w: drive %d", ErrEmpty, num)
+		}
+
+		return drv.Vol.Home, nil
+	}
+
+	return 0, fmt.Errorf("%w: drive %d", ErrInvalidElement, num)
+}
+
+// Status implements StatusProvider.
+func (a *v2Adapter) Status() (*Status, error) {
+	return a.impl.ReadInventory(context.Background())
+}
+
+// v1Adapter implements InterfaceV2 on top of an Interface backend.
+type v1Adapter struct {
+	impl Interface
+}
+
+// ToV2 returns an InterfaceV2 performing its operations with the commands
+// of impl. Moves are validated before they are issued. Backends that
+// implement StatusProvider are asked for the status directly, and
+// cancellation is passed on to backends that support it.
+func ToV2(impl Interface) InterfaceV2 {
+	if down, ok := impl.(*v2Adapter); ok {
+		return down.impl
+	}
+
+	return &v1Adapter{impl: impl}
+}
+
+func (a *v1Adapter) do(ctx context.Context, args ...string) ([]byte, error) {
+	var out []byte
+	var err error
+	if impl, ok := a.impl.(contextDoer); ok {
+		out, err = impl.DoContext(ctx, args...)
+	} else {
+		out, err = a.impl.Do(args...)
+	}
+
+	return out, commandError(args, err)
+}
+
+func (a *v1Adapter) ReadInventory(ctx context.Context) (*Status, error) {
+	if impl, ok := a.impl.(StatusProvider); ok {
+		return impl.Status()
+	}
+
+	out, err := a.do(ctx, "status")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseStatus(out)
+}
+
+func (a *v1Adapter) Move(ctx context.Context, src, dst Element) error {
+	if err := validMove(src, dst); err != nil {
+		return err
+	}
+
+	var args []string
+	switch {
+	case dst.IsDrive():
+		args = []string{"load", strconv.Itoa(src.Num), strconv.Itoa(dst.Num)}
+	case src.IsDrive():
+		args = []string{"unload", strconv.Itoa(dst.Num), strconv.Itoa(src.Num)}
+	default:
+		args = []string{"transfer", strconv.Itoa(src.Num), strconv.Itoa(dst.Num)}
+	}
+
+	_, err := a.do(ctx, args...)
+
+	return err
+}
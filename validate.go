@@ -0,0 +1,152 @@
+package mtx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ViolationKind is the kind of a consistency violation.
+type ViolationKind int
+
+const (
+	// CountMismatch means the elements do not match the counts of the
+	// header.
+	CountMismatch ViolationKind = iota
+
+	// DuplicateElement means an element number occurs more than once.
+	DuplicateElement
+
+	// InvalidHome means a volume reports a home slot that does not exist.
+	InvalidHome
+
+	// DuplicateSerial means a serial occurs in more than one element.
+	DuplicateSerial
+)
+
+var violationKindNames = [...]string{
+	CountMismatch:    "count mismatch",
+	DuplicateElement: "duplicate element",
+	InvalidHome:      "invalid home slot",
+	DuplicateSerial:  "duplicate serial",
+}
+
+// String returns the name of the kind.
+func (kind ViolationKind) String() string {
+	if kind < 0 || int(kind) >= len(violationKindNames) {
+		return fmt.Sprintf("ViolationKind(%d)", int(kind))
+	}
+
+	return violationKindNames[kind]
+}
+
+// Violation is an inconsistency found by Status.Validate.
+type Violation struct {
+	Kind ViolationKind
+
+	// Slot is the offending element, or nil for count mismatches.
+	Slot *Slot
+
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Kind.String() + ": " + v.Message
+}
+
+// ValidationError is returned by Status.Validate. It lists every violation
+// found.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+
+	return "mtx: inconsistent status: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the internal consistency of status: the elements match
+// the counts of the header, no element number occurs twice, every home slot
+// exists and no serial occurs twice. Unlabeled volumes are not compared. If
+// status is inconsistent, a *ValidationError is returned.
+func (status *Status) Validate() error {
+	var vs []Violation
+
+	count := func(format string, args ...any) {
+		vs = append(vs, Violation{Kind: CountMismatch, Message: fmt.Sprintf(format, args...)})
+	}
+
+	mail := len(Filter(status.Slots, IsMail))
+
+	if len(status.Drives) != status.MaxDrives {
+		count("%d drives reported, header says %d", len(status.Drives), status.MaxDrives)
+	}
+
+	if len(status.Slots) != status.NumSlots {
+		count("%d slots reported, header says %d", len(status.Slots), status.NumSlots)
+	}
+
+	if mail != status.NumMailSlots {
+		count("%d import/export slots reported, header says %d", mail, status.NumMailSlots)
+	}
+
+	if status.NumStorageSlots+status.NumMailSlots != status.NumSlots {
+		count("%d storage and %d import/export slots, header says %d slots",
+			status.NumStorageSlots, status.NumMailSlots, status.NumSlots)
+	}
+
+	drives := make(map[int]bool, len(status.Drives))
+	for _, drv := range status.Drives {
+		if drives[drv.Num] {
+			vs = append(vs, Violation{Kind: DuplicateElement, Slot: drv,
+				Message: fmt.Sprintf("drive %d occurs more than once", drv.Num)})
+		}
+
+		drives[drv.Num] = true
+	}
+
+	slots := make(map[int]bool, len(status.Slots))
+	for _, slot := range status.Slots {
+		if slots[slot.Num] {
+			vs = append(vs, Violation{Kind: DuplicateElement, Slot: slot,
+				Message: fmt.Sprintf("slot %d occurs more than once", slot.Num)})
+		}
+
+		slots[slot.Num] = true
+	}
+
+	seen := make(map[string]*Slot)
+	for _, slot := range append(append([]*Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol == nil {
+			continue
+		}
+
+		if !slots[slot.Vol.Home] {
+			vs = append(vs, Violation{Kind: InvalidHome, Slot: slot,
+				Message: fmt.Sprintf("volume in %s %d reports home slot %d", slot.Type, slot.Num, slot.Vol.Home)})
+		}
+
+		serial := slot.Vol.Serial
+		if serial == "" {
+			continue
+		}
+
+		if other, ok := seen[serial]; ok {
+			vs = append(vs, Violation{Kind: DuplicateSerial, Slot: slot,
+				Message: fmt.Sprintf("volume %s in both %s %d and %s %d", serial, other.Type, other.Num, slot.Type, slot.Num)})
+
+			continue
+		}
+
+		seen[serial] = slot
+	}
+
+	if len(vs) > 0 {
+		return &ValidationError{Violations: vs}
+	}
+
+	return nil
+}
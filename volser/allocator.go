@@ -0,0 +1,217 @@
+package volser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/store"
+)
+
+// ErrExhausted is returned by Allocator.Next when the serial range of the
+// allocator has no unused serials left.
+var ErrExhausted = errors.New("volser: serial range exhausted")
+
+// DuplicateError is returned by Allocator.Next when the library holds
+// several volumes with the same serial within the range of the allocator.
+type DuplicateError struct {
+	// Duplicates maps each duplicated volser to the slots holding it.
+	Duplicates map[string][]*mtx.Slot
+}
+
+func (e *DuplicateError) Error() string {
+	volsers := make([]string, 0, len(e.Duplicates))
+	for v := range e.Duplicates {
+		volsers = append(volsers, v)
+	}
+
+	sort.Strings(volsers)
+
+	return "volser: duplicate serials in library: " + strings.Join(volsers, ", ")
+}
+
+// Duplicates returns the volsers that occur on more than one volume in
+// status, mapped to the slots holding them. Labels are compared by their
+// first six characters, so the same volser with different media types counts
+// as a duplicate.
+func Duplicates(status *mtx.Status) map[string][]*mtx.Slot {
+	seen := make(map[string][]*mtx.Slot)
+	for _, slot := range append(append([]*mtx.Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol == nil || slot.Vol.Serial == "" {
+			continue
+		}
+
+		v := volserOf(slot.Vol.Serial)
+		seen[v] = append(seen[v], slot)
+	}
+
+	dups := make(map[string][]*mtx.Slot)
+	for v, slots := range seen {
+		if len(slots) > 1 {
+			dups[v] = slots
+		}
+	}
+
+	return dups
+}
+
+func volserOf(label string) string {
+	if len(label) > 6 {
+		return label[:6]
+	}
+
+	return label
+}
+
+// Allocator hands out unused volume serials for labeling new media. Serials
+// consist of a fixed prefix followed by a zero padded number, e.g. "A00042"
+// for the prefix "A". Issued serials are recorded in a ledger kept in a
+// store.Store, so a serial is never issued twice even if the labeled volume
+// has not been put into the library yet.
+type Allocator struct {
+	prefix    string
+	mediaType string
+	store     store.Store
+	key       string
+
+	mu     sync.Mutex
+	issued map[string]bool
+}
+
+// NewAllocator returns an allocator for serials starting with prefix, which
+// must be one to five upper case letters or digits. The returned labels carry
+// the given media type identifier, e.g. "L6", or no identifier if mediaType
+// is empty.
+func NewAllocator(prefix, mediaType string, st store.Store) (*Allocator, error) {
+	if len(prefix) < 1 || len(prefix) > 5 || !alnum(prefix) {
+		return nil, fmt.Errorf("volser: invalid prefix %q", prefix)
+	}
+
+	if _, ok := mediaTypes[mediaType]; mediaType != "" && !ok {
+		return nil, &Error{Label: mediaType, Err: ErrMediaType}
+	}
+
+	alloc := &Allocator{
+		prefix:    prefix,
+		mediaType: mediaType,
+		store:     st,
+		key:       "volser-" + prefix,
+		issued:    make(map[string]bool),
+	}
+
+	buf, err := st.Get(alloc.key)
+	if err == store.ErrNotFound {
+		return alloc, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var issued []string
+	if err := json.Unmarshal(buf, &issued); err != nil {
+		return nil, fmt.Errorf("volser: corrupt ledger: %v", err)
+	}
+
+	for _, v := range issued {
+		alloc.issued[v] = true
+	}
+
+	return alloc, nil
+}
+
+// Issued returns the sorted volsers recorded in the ledger.
+func (alloc *Allocator) Issued() []string {
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+
+	return alloc.sorted()
+}
+
+func (alloc *Allocator) sorted() []string {
+	issued := make([]string, 0, len(alloc.issued))
+	for v := range alloc.issued {
+		issued = append(issued, v)
+	}
+
+	sort.Strings(issued)
+
+	return issued
+}
+
+// Next returns n unused labels and records them in the ledger. A serial is
+// unused if it is neither in the ledger nor on any volume in status, which
+// may be nil. If status holds duplicate serials within the range of the
+// allocator, a *DuplicateError is returned and nothing is allocated.
+func (alloc *Allocator) Next(n int, status *mtx.Status) ([]string, error) {
+	used := make(map[string]bool)
+
+	if status != nil {
+		dups := Duplicates(status)
+		for v := range dups {
+			if !strings.HasPrefix(v, alloc.prefix) {
+				delete(dups, v)
+			}
+		}
+
+		if len(dups) > 0 {
+			return nil, &DuplicateError{Duplicates: dups}
+		}
+
+		for _, slot := range append(append([]*mtx.Slot(nil), status.Drives...), status.Slots...) {
+			if slot.Vol != nil {
+				used[volserOf(slot.Vol.Serial)] = true
+			}
+		}
+	}
+
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+
+	digits := 6 - len(alloc.prefix)
+	limit := 1
+	for i := 0; i < digits; i++ {
+		limit *= 10
+	}
+
+	var labels, volsers []string
+	for i := 0; i < limit && len(labels) < n; i++ {
+		num := strconv.Itoa(i)
+		v := alloc.prefix + strings.Repeat("0", digits-len(num)) + num
+
+		if used[v] || alloc.issued[v] {
+			continue
+		}
+
+		volsers = append(volsers, v)
+		labels = append(labels, v+alloc.mediaType)
+	}
+
+	if len(labels) < n {
+		return nil, ErrExhausted
+	}
+
+	for _, v := range volsers {
+		alloc.issued[v] = true
+	}
+
+	buf, err := json.Marshal(alloc.sorted())
+	if err == nil {
+		err = alloc.store.Put(alloc.key, buf)
+	}
+
+	if err != nil {
+		for _, v := range volsers {
+			delete(alloc.issued, v)
+		}
+
+		return nil, err
+	}
+
+	return labels, nil
+}
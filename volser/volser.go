@@ -0,0 +1,144 @@
+// Package volser validates tape barcode labels against the LTO labeling
+// rules.
+//
+// An LTO barcode consists of a six character volume serial (VOLSER) made of
+// upper case letters and digits, followed by a two character media type
+// identifier such as "L6" for LTO-6 data cartridges or "LW" for LTO-6 WORM
+// cartridges. Cleaning cartridges carry a "CLN" prefix.
+package volser
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrLength is returned for labels that are not eight characters long.
+	ErrLength = errors.New("volser: label must be 8 characters")
+
+	// ErrCharacter is returned for labels with characters other than upper
+	// case letters and digits.
+	ErrCharacter = errors.New("volser: invalid character")
+
+	// ErrMediaType is returned for labels with an unknown media type
+	// identifier.
+	ErrMediaType = errors.New("volser: unknown media type")
+
+	// ErrCleaning is returned for labels where the CLN prefix and the
+	// cleaning media type disagree.
+	ErrCleaning = errors.New("volser: inconsistent cleaning cartridge label")
+)
+
+// Error is a validation error for a label.
+type Error struct {
+	Label string
+
+	// Err is one of the errors defined in this package.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %q", e.Err, e.Label)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// MediaType describes an LTO media type identifier.
+type MediaType struct {
+	// ID is the two character identifier, e.g. "L6".
+	ID string
+
+	// Generation is the LTO generation, or 0 for universal cleaning
+	// cartridges.
+	Generation int
+
+	WORM     bool
+	Cleaning bool
+}
+
+var mediaTypes = map[string]MediaType{
+	"L1": {ID: "L1", Generation: 1},
+	"L2": {ID: "L2", Generation: 2},
+	"L3": {ID: "L3", Generation: 3},
+	"L4": {ID: "L4", Generation: 4},
+	"L5": {ID: "L5", Generation: 5},
+	"L6": {ID: "L6", Generation: 6},
+	"L7": {ID: "L7", Generation: 7},
+	"M8": {ID: "M8", Generation: 7},
+	"L8": {ID: "L8", Generation: 8},
+	"L9": {ID: "L9", Generation: 9},
+	"LT": {ID: "LT", Generation: 3, WORM: true},
+	"LU": {ID: "LU", Generation: 4, WORM: true},
+	"LV": {ID: "LV", Generation: 5, WORM: true},
+	"LW": {ID: "LW", Generation: 6, WORM: true},
+	"LX": {ID: "LX", Generation: 7, WORM: true},
+	"LY": {ID: "LY", Generation: 8, WORM: true},
+	"LZ": {ID: "LZ", Generation: 9, WORM: true},
+	"CU": {ID: "CU", Cleaning: true},
+}
+
+// Label is a parsed barcode label.
+type Label struct {
+	// Volser is the six character volume serial.
+	Volser string
+
+	MediaType MediaType
+}
+
+// String returns the barcode of the label.
+func (l *Label) String() string {
+	return l.Volser + l.MediaType.ID
+}
+
+// Cleaning returns whether the label identifies a cleaning cartridge.
+func (l *Label) Cleaning() bool {
+	return l.MediaType.Cleaning || l.Volser[:3] == "CLN"
+}
+
+// Parse validates and parses a barcode label.
+func Parse(label string) (*Label, error) {
+	if len(label) != 8 {
+		return nil, &Error{Label: label, Err: ErrLength}
+	}
+
+	if !alnum(label) {
+		return nil, &Error{Label: label, Err: ErrCharacter}
+	}
+
+	mt, ok := mediaTypes[label[6:]]
+	if !ok {
+		return nil, &Error{Label: label, Err: ErrMediaType}
+	}
+
+	if mt.Cleaning && label[:3] != "CLN" {
+		return nil, &Error{Label: label, Err: ErrCleaning}
+	}
+
+	return &Label{Volser: label[:6], MediaType: mt}, nil
+}
+
+// Validate returns an error if label is not a valid LTO barcode. It can be
+// passed to mtx.WithLabelValidator.
+func Validate(label string) error {
+	_, err := Parse(label)
+	return err
+}
+
+// IsValidVolser returns whether s is a valid six character volume serial.
+func IsValidVolser(s string) bool {
+	return len(s) == 6 && alnum(s)
+}
+
+// alnum returns whether s consists of upper case letters and digits only.
+func alnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
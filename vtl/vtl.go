@@ -0,0 +1,367 @@
+// Package vtl implements the mtx.Interface for a virtual tape library kept
+// in a directory tree.
+//
+// Unlike the in-memory mock, the library is persistent and can be inspected
+// and manipulated with ordinary file tools. A library rooted at root looks
+// like this:
+//
+//	root/library.json     the number of drives and slots
+//	root/volumes/SERIAL   the data of each volume
+//	root/slots/N/volume   symlink to the volume in slot N, if any
+//	root/drives/N/volume  symlink to the volume loaded into drive N, if any
+//	root/drives/N/home    the slot the loaded volume came from
+//
+// Slots are numbered from 1 with the import/export slots last; drives are
+// numbered from 0. Loading a volume moves its symlink from the slot to the
+// drive directory, so a program "using the drive" reads and writes
+// root/drives/N/volume.
+package vtl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kbj/mtx"
+)
+
+// config is the content of library.json.
+type config struct {
+	Drives       int `json:"drives"`
+	StorageSlots int `json:"storage_slots"`
+	MailSlots    int `json:"mail_slots"`
+}
+
+// Changer is a directory backed virtual library. It is safe for concurrent
+// use within a process, but not for use by several processes at once.
+type Changer struct {
+	root string
+	cfg  config
+
+	mu sync.Mutex
+}
+
+// Create creates an empty library in root, which must not exist or be an
+// empty directory.
+func Create(root string, numDrives, numStorageSlots, numMailSlots int) (*Changer, error) {
+	if entries, err := os.ReadDir(root); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("vtl: %s is not empty", root)
+	}
+
+	chgr := &Changer{
+		root: root,
+		cfg:  config{Drives: numDrives, StorageSlots: numStorageSlots, MailSlots: numMailSlots},
+	}
+
+	dirs := []string{filepath.Join(root, "volumes")}
+	for i := 0; i < numDrives; i++ {
+		dirs = append(dirs, chgr.drivePath(i))
+	}
+	for i := 1; i <= numStorageSlots+numMailSlots; i++ {
+		dirs = append(dirs, chgr.slotPath(i))
+	}
+
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, err := json.MarshalIndent(&chgr.cfg, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "library.json"), buf, 0o644); err != nil {
+		return nil, err
+	}
+
+	return chgr, nil
+}
+
+// Open opens the library in root.
+func Open(root string) (*Changer, error) {
+	buf, err := os.ReadFile(filepath.Join(root, "library.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	chgr := &Changer{root: root}
+	if err := json.Unmarshal(buf, &chgr.cfg); err != nil {
+		return nil, fmt.Errorf("vtl: invalid library.json: %v", err)
+	}
+
+	return chgr, nil
+}
+
+func (chgr *Changer) slotPath(num int) string {
+	return filepath.Join(chgr.root, "slots", strconv.Itoa(num))
+}
+
+func (chgr *Changer) drivePath(num int) string {
+	return filepath.Join(chgr.root, "drives", strconv.Itoa(num))
+}
+
+// VolumePath returns the path of the data file of the volume loaded into
+// the given drive, which is a symlink to the file under root/volumes.
+func (chgr *Changer) VolumePath(drivenum int) string {
+	return filepath.Join(chgr.drivePath(drivenum), "volume")
+}
+
+// AddVolume creates an empty volume with the given serial in slot. It is
+// the equivalent of an operator putting a new tape into the library.
+func (chgr *Changer) AddVolume(slotnum int, serial string) error {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if serial == "" || strings.ContainsAny(serial, `/\`) || serial[0] == '.' {
+		return fmt.Errorf("vtl: invalid serial %q", serial)
+	}
+
+	if err := chgr.checkSlot("add", slotnum); err != nil {
+		return err
+	}
+
+	if serial, _ := chgr.volume(chgr.slotPath(slotnum)); serial != "" {
+		return fmt.Errorf("Storage Element %d is Already Full", slotnum)
+	}
+
+	f, err := os.OpenFile(filepath.Join(chgr.root, "volumes", serial), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Symlink(filepath.Join("..", "..", "volumes", serial), filepath.Join(chgr.slotPath(slotnum), "volume"))
+}
+
+// volume returns the serial of the volume in the element directory dir, or
+// the empty string if the element is empty.
+func (chgr *Changer) volume(dir string) (string, error) {
+	target, err := os.Readlink(filepath.Join(dir, "volume"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Base(target), nil
+}
+
+// move moves the volume of the element directory src to dst.
+func move(src, dst string) error {
+	return os.Rename(filepath.Join(src, "volume"), filepath.Join(dst, "volume"))
+}
+
+func (chgr *Changer) checkSlot(cmd string, slotnum int) error {
+	if slotnum < 1 || slotnum > chgr.cfg.StorageSlots+chgr.cfg.MailSlots {
+		return fmt.Errorf("Invalid <slotno> argument '%d' to '%s' command", slotnum, cmd)
+	}
+
+	return nil
+}
+
+func (chgr *Changer) checkDrive(cmd string, drivenum int) error {
+	if drivenum < 0 || drivenum >= chgr.cfg.Drives {
+		return fmt.Errorf("Invalid <drvno> argument '%d' to '%s' command", drivenum, cmd)
+	}
+
+	return nil
+}
+
+func (chgr *Changer) home(drivenum int) int {
+	buf, err := os.ReadFile(filepath.Join(chgr.drivePath(drivenum), "home"))
+	if err != nil {
+		return 0
+	}
+
+	home, _ := strconv.Atoi(strings.TrimSpace(string(buf)))
+
+	return home
+}
+
+func (chgr *Changer) load(slotnum, drivenum int) error {
+	if err := chgr.checkSlot("load", slotnum); err != nil {
+		return err
+	}
+
+	if err := chgr.checkDrive("load", drivenum); err != nil {
+		return err
+	}
+
+	src, dst := chgr.slotPath(slotnum), chgr.drivePath(drivenum)
+
+	if serial, err := chgr.volume(src); err != nil {
+		return err
+	} else if serial == "" {
+		return fmt.Errorf("source Element Address %d is Empty", slotnum)
+	}
+
+	if serial, err := chgr.volume(dst); err != nil {
+		return err
+	} else if serial != "" {
+		return fmt.Errorf("Drive %d Full (Storage Element %d loaded)", drivenum, chgr.home(drivenum))
+	}
+
+	if err := os.WriteFile(filepath.Join(dst, "home"), []byte(strconv.Itoa(slotnum)+"\n"), 0o644); err != nil {
+		return err
+	}
+
+	return move(src, dst)
+}
+
+func (chgr *Changer) unload(slotnum, drivenum int) error {
+	if err := chgr.checkDrive("unload", drivenum); err != nil {
+		return err
+	}
+
+	src := chgr.drivePath(drivenum)
+
+	if serial, err := chgr.volume(src); err != nil {
+		return err
+	} else if serial == "" {
+		return fmt.Errorf("Data Transfer Element %d is Empty", drivenum)
+	}
+
+	if slotnum == 0 {
+		slotnum = chgr.home(drivenum)
+	}
+
+	if err := chgr.checkSlot("unload", slotnum); err != nil {
+		return err
+	}
+
+	dst := chgr.slotPath(slotnum)
+	if serial, err := chgr.volume(dst); err != nil {
+		return err
+	} else if serial != "" {
+		return fmt.Errorf("Storage Element %d is Already Full", slotnum)
+	}
+
+	if err := move(src, dst); err != nil {
+		return err
+	}
+
+	return os.Remove(filepath.Join(src, "home"))
+}
+
+func (chgr *Changer) transfer(from, to int) error {
+	if err := chgr.checkSlot("transfer", from); err != nil {
+		return err
+	}
+
+	if err := chgr.checkSlot("transfer", to); err != nil {
+		return err
+	}
+
+	src, dst := chgr.slotPath(from), chgr.slotPath(to)
+
+	if serial, err := chgr.volume(src); err != nil {
+		return err
+	} else if serial == "" {
+		return fmt.Errorf("source Element Address %d is Empty", from)
+	}
+
+	if serial, err := chgr.volume(dst); err != nil {
+		return err
+	} else if serial != "" {
+		return fmt.Errorf("destination Element Address %d is Already Full", to)
+	}
+
+	return move(src, dst)
+}
+
+func (chgr *Changer) status() ([]byte, error) {
+	numSlots := chgr.cfg.StorageSlots + chgr.cfg.MailSlots
+
+	status := &mtx.Status{
+		MaxDrives:       chgr.cfg.Drives,
+		NumSlots:        numSlots,
+		NumStorageSlots: chgr.cfg.StorageSlots,
+		NumMailSlots:    chgr.cfg.MailSlots,
+	}
+
+	for i := 0; i < chgr.cfg.Drives; i++ {
+		slot := &mtx.Slot{Num: i, Type: mtx.DataTransferSlot}
+
+		serial, err := chgr.volume(chgr.drivePath(i))
+		if err != nil {
+			return nil, err
+		}
+
+		if serial != "" {
+			slot.Vol = &mtx.Volume{Serial: serial, Home: chgr.home(i)}
+		}
+
+		status.Drives = append(status.Drives, slot)
+	}
+
+	for i := 1; i <= numSlots; i++ {
+		slot := &mtx.Slot{Num: i, Type: mtx.StorageSlot}
+		if i > chgr.cfg.StorageSlots {
+			slot.Type = mtx.MailSlot
+		}
+
+		serial, err := chgr.volume(chgr.slotPath(i))
+		if err != nil {
+			return nil, err
+		}
+
+		if serial != "" {
+			slot.Vol = &mtx.Volume{Serial: serial, Home: i}
+		}
+
+		status.Slots = append(status.Slots, slot)
+	}
+
+	return mtx.RenderStatus(chgr.root, status), nil
+}
+
+// Do performs the given mtx command on the virtual library.
+func (chgr *Changer) Do(args ...string) ([]byte, error) {
+	chgr.mu.Lock()
+	defer chgr.mu.Unlock()
+
+	if len(args) < 1 {
+		return nil, errors.New("no command given")
+	}
+
+	if args[0] == "status" {
+		return chgr.status()
+	}
+
+	if len(args) != 3 {
+		return nil, errors.New("wrong number of arguments")
+	}
+
+	a, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := strconv.Atoi(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	switch args[0] {
+	case "load":
+		return nil, chgr.load(a, b)
+	case "unload":
+		return nil, chgr.unload(a, b)
+	case "transfer":
+		return nil, chgr.transfer(a, b)
+	}
+
+	return nil, errors.New("mtx/vtl: unknown or unsupported mtx command")
+}
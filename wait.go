@@ -0,0 +1,73 @@
+package mtx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// drivePollInterval is the interval at which WaitDriveEmpty and
+// WaitDriveLoaded check the drive.
+const drivePollInterval = time.Second
+
+// WaitDriveEmpty polls the status until the drive is reported empty. Some
+// libraries report an unload complete before the drive has finished
+// ejecting. It returns ctx.Err() if ctx is done first.
+func (chgr *Changer) WaitDriveEmpty(ctx context.Context, drive DriveNum) error {
+	return chgr.waitDrive(ctx, drive, func(drv *Slot) bool {
+		return drv.Vol == nil
+	})
+}
+
+// WaitDriveLoaded polls the status until the drive is reported to hold the
+// volume with the given serial, or any volume if serial is empty. Some
+// libraries report a load complete before the drive has finished threading
+// the tape. It returns ctx.Err() if ctx is done first.
+func (chgr *Changer) WaitDriveLoaded(ctx context.Context, drive DriveNum, serial string) error {
+	return chgr.waitDrive(ctx, drive, func(drv *Slot) bool {
+		return drv.Vol != nil && (serial == "" || drv.Vol.Serial == serial)
+	})
+}
+
+// waitDrive polls the status until done returns true for the drive.
+func (chgr *Changer) waitDrive(ctx context.Context, drive DriveNum, done func(drv *Slot) bool) error {
+	missing := false
+
+	_, err := chgr.waitFor(ctx, drivePollInterval, func(status *Status) bool {
+		drv := status.element(DriveElement(drive))
+		if drv == nil {
+			missing = true
+			return true
+		}
+
+		return done(drv)
+	})
+	if err != nil {
+		return err
+	}
+
+	if missing {
+		return fmt.Errorf("%w: drive %d", ErrInvalidElement, drive)
+	}
+
+	return nil
+}
+
+// waitFor polls the status every interval until done returns true for it
+// and returns that status. Failed status queries are retried.
+func (chgr *Changer) waitFor(ctx context.Context, interval time.Duration, done func(*Status) bool) (*Status, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if status, err := chgr.statusContext(ctx); err == nil && done(status) {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
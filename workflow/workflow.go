@@ -0,0 +1,743 @@
+// Package workflow guides import and export tasks that involve an operator.
+//
+// Exporting volumes from a library takes more than moving them to the mail
+// slots: the import/export station has to be opened, the operator has to be
+// told which volumes to take out, the library must be rescanned once the
+// station is closed, and the result verified. When there are more volumes
+// than mail slots, this is repeated in rounds. An Engine performs these
+// steps, notifying the operator through a hook when their help is needed:
+//
+//	eng, err := workflow.New(chgr, st, workflow.WithNotifier(func(wf workflow.Workflow, msg string) {
+//		log.Printf("%s: %s", wf.ID, msg)
+//	}))
+//	...
+//	wf, err := eng.Export("A00001L6", "A00002L6")
+//	...
+//	err = eng.Run(ctx, wf.ID)
+//
+// The progress of every workflow is persisted in a store.Store after each
+// step, so a workflow interrupted by an error or a restart continues where
+// it left off when Run is called again.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbj/mtx"
+	"github.com/kbj/mtx/store"
+)
+
+var (
+	// ErrUnknownWorkflow is returned for workflow IDs not known to the
+	// engine.
+	ErrUnknownWorkflow = errors.New("workflow: unknown workflow")
+
+	// ErrRunning is returned by Run and Remove when the workflow is
+	// already being run.
+	ErrRunning = errors.New("workflow: workflow is running")
+
+	// ErrNoMailSlots is returned when the library has no import/export
+	// slots.
+	ErrNoMailSlots = errors.New("workflow: library has no import/export slots")
+)
+
+// The key under which the state is stored.
+const storeKey = "workflows"
+
+// DefaultPollInterval is the default interval at which the library is
+// polled while waiting for the operator.
+const DefaultPollInterval = 10 * time.Second
+
+// Kind is the kind of a workflow.
+type Kind int
+
+const (
+	// Export takes volumes out of the library.
+	Export Kind = iota
+
+	// Import brings volumes into the library.
+	Import
+)
+
+var kindNames = [...]string{
+	Export: "export",
+	Import: "import",
+}
+
+// String returns the name of the kind.
+func (kind Kind) String() string {
+	if kind < 0 || int(kind) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(kind))
+	}
+
+	return kindNames[kind]
+}
+
+// Step is a step of a workflow. An export round runs StepTransfer,
+// StepEject, StepWait, StepInventory and StepVerify in that order; an
+// import round runs StepEject, StepWait, StepInventory, StepTransfer and
+// StepVerify.
+type Step int
+
+const (
+	// StepTransfer moves the volumes of the round between storage and mail
+	// slots.
+	StepTransfer Step = iota
+
+	// StepEject opens the import/export station and notifies the operator.
+	StepEject
+
+	// StepWait waits for the operator to remove or insert the volumes.
+	StepWait
+
+	// StepInventory rescans the library.
+	StepInventory
+
+	// StepVerify checks the outcome of the round.
+	StepVerify
+
+	// StepDone marks a finished workflow.
+	StepDone
+)
+
+var stepNames = [...]string{
+	StepTransfer:  "transfer",
+	StepEject:     "eject",
+	StepWait:      "wait",
+	StepInventory: "inventory",
+	StepVerify:    "verify",
+	StepDone:      "done",
+}
+
+// String returns the name of the step.
+func (step Step) String() string {
+	if step < 0 || int(step) >= len(stepNames) {
+		return fmt.Sprintf("Step(%d)", int(step))
+	}
+
+	return stepNames[step]
+}
+
+// Workflow is the persisted state of a workflow.
+type Workflow struct {
+	ID   string `json:"id"`
+	Kind Kind   `json:"kind"`
+	Step Step   `json:"step"`
+
+	// Volumes are the volumes to export or import. An import without
+	// volumes imports whatever the operator inserts, in a single round.
+	Volumes []string `json:"volumes,omitempty"`
+
+	// Batch holds the volumes of the current round.
+	Batch []string `json:"batch,omitempty"`
+
+	// Done holds the volumes handled so far.
+	Done []string `json:"done,omitempty"`
+
+	// Confirmed is set by Engine.Confirm and ends the current wait.
+	Confirmed bool `json:"confirmed,omitempty"`
+
+	// Err is the error that last stopped the workflow, if any.
+	Err string `json:"error,omitempty"`
+
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Finished reports whether the workflow has completed.
+func (wf *Workflow) Finished() bool {
+	return wf.Step == StepDone
+}
+
+// Pending returns the volumes of the workflow that are neither done nor part
+// of the current round.
+func (wf *Workflow) Pending() []string {
+	skip := make(map[string]bool)
+	for _, serial := range append(append([]string(nil), wf.Done...), wf.Batch...) {
+		skip[serial] = true
+	}
+
+	var pending []string
+	for _, serial := range wf.Volumes {
+		if !skip[serial] {
+			pending = append(pending, serial)
+		}
+	}
+
+	return pending
+}
+
+func (wf *Workflow) clone() Workflow {
+	c := *wf
+	c.Volumes = append([]string(nil), wf.Volumes...)
+	c.Batch = append([]string(nil), wf.Batch...)
+	c.Done = append([]string(nil), wf.Done...)
+
+	return c
+}
+
+// A Notifier is called when the operator must act on a workflow.
+type Notifier func(wf Workflow, msg string)
+
+// state is the persisted state of an Engine.
+type state struct {
+	Next      int         `json:"next"`
+	Workflows []*Workflow `json:"workflows"`
+}
+
+// Engine runs workflows.
+type Engine struct {
+	chgr     *mtx.Changer
+	store    store.Store
+	notify   Notifier
+	interval time.Duration
+
+	mu      sync.Mutex
+	state   state
+	running map[string]chan struct{}
+}
+
+// An Option configures an Engine.
+type Option func(eng *Engine)
+
+// WithNotifier sets the function notified when the operator must act.
+func WithNotifier(fn Notifier) Option {
+	return func(eng *Engine) {
+		eng.notify = fn
+	}
+}
+
+// WithPollInterval sets the interval at which the library is polled while
+// waiting for the operator. The default is DefaultPollInterval.
+func WithPollInterval(d time.Duration) Option {
+	return func(eng *Engine) {
+		eng.interval = d
+	}
+}
+
+// New returns an Engine for chgr, restoring any workflows previously saved
+// in st.
+func New(chgr *mtx.Changer, st store.Store, opts ...Option) (*Engine, error) {
+	eng := &Engine{
+		chgr:     chgr,
+		store:    st,
+		notify:   func(Workflow, string) {},
+		interval: DefaultPollInterval,
+		running:  make(map[string]chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(eng)
+	}
+
+	buf, err := st.Get(storeKey)
+	if err == store.ErrNotFound {
+		return eng, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, &eng.state); err != nil {
+		return nil, fmt.Errorf("workflow: corrupt state: %v", err)
+	}
+
+	return eng, nil
+}
+
+func (eng *Engine) save() error {
+	buf, err := json.Marshal(&eng.state)
+	if err != nil {
+		return err
+	}
+
+	return eng.store.Put(storeKey, buf)
+}
+
+func (eng *Engine) lookup(id string) (int, *Workflow) {
+	for i, wf := range eng.state.Workflows {
+		if wf.ID == id {
+			return i, wf
+		}
+	}
+
+	return -1, nil
+}
+
+// Export creates a workflow exporting the given volumes. The volumes must be
+// in the library and not loaded in a drive. Use Run to perform it.
+func (eng *Engine) Export(serials ...string) (Workflow, error) {
+	if len(serials) == 0 {
+		return Workflow{}, errors.New("workflow: no volumes to export")
+	}
+
+	status, err := eng.chgr.Status()
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	if status.NumMailSlots == 0 {
+		return Workflow{}, ErrNoMailSlots
+	}
+
+	for _, serial := range serials {
+		slot := find(status, serial)
+		if slot == nil {
+			return Workflow{}, fmt.Errorf("workflow: %s: %v", serial, mtx.ErrVolumeNotFound)
+		}
+
+		if slot.Type == mtx.DataTransferSlot {
+			return Workflow{}, fmt.Errorf("workflow: volume %s is loaded in drive %d", serial, slot.Num)
+		}
+	}
+
+	return eng.create(Export, StepTransfer, serials)
+}
+
+// Import creates a workflow importing the given volumes, or whatever the
+// operator inserts if none are given. Use Run to perform it.
+func (eng *Engine) Import(serials ...string) (Workflow, error) {
+	status, err := eng.chgr.Status()
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	if status.NumMailSlots == 0 {
+		return Workflow{}, ErrNoMailSlots
+	}
+
+	for _, serial := range serials {
+		if slot := find(status, serial); slot != nil && slot.Type != mtx.MailSlot {
+			return Workflow{}, fmt.Errorf("workflow: volume %s is already in %s %d", serial, slot.Type, slot.Num)
+		}
+	}
+
+	return eng.create(Import, StepEject, serials)
+}
+
+func (eng *Engine) create(kind Kind, step Step, serials []string) (Workflow, error) {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	eng.state.Next++
+
+	now := time.Now()
+	wf := &Workflow{
+		ID:      fmt.Sprintf("%s-%d", kind, eng.state.Next),
+		Kind:    kind,
+		Step:    step,
+		Volumes: append([]string(nil), serials...),
+		Created: now,
+		Updated: now,
+	}
+
+	eng.state.Workflows = append(eng.state.Workflows, wf)
+
+	if err := eng.save(); err != nil {
+		return Workflow{}, err
+	}
+
+	return wf.clone(), nil
+}
+
+// Workflow returns the current state of the workflow with the given ID.
+func (eng *Engine) Workflow(id string) (Workflow, error) {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	_, wf := eng.lookup(id)
+	if wf == nil {
+		return Workflow{}, ErrUnknownWorkflow
+	}
+
+	return wf.clone(), nil
+}
+
+// Workflows returns the state of all workflows, in order of creation.
+func (eng *Engine) Workflows() []Workflow {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	wfs := make([]Workflow, 0, len(eng.state.Workflows))
+	for _, wf := range eng.state.Workflows {
+		wfs = append(wfs, wf.clone())
+	}
+
+	return wfs
+}
+
+// Remove forgets the workflow with the given ID. Volumes already moved are
+// left where they are.
+func (eng *Engine) Remove(id string) error {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	i, _ := eng.lookup(id)
+	if i < 0 {
+		return ErrUnknownWorkflow
+	}
+
+	if _, ok := eng.running[id]; ok {
+		return ErrRunning
+	}
+
+	eng.state.Workflows = append(eng.state.Workflows[:i], eng.state.Workflows[i+1:]...)
+
+	return eng.save()
+}
+
+// Confirm tells the engine that the operator has finished handling the
+// current round of the workflow, ending the wait even if the library does
+// not (yet) reflect it.
+func (eng *Engine) Confirm(id string) error {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	_, wf := eng.lookup(id)
+	if wf == nil {
+		return ErrUnknownWorkflow
+	}
+
+	wf.Confirmed = true
+
+	if wake, ok := eng.running[id]; ok {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return eng.save()
+}
+
+// update applies fn to the workflow and persists the result.
+func (eng *Engine) update(wf *Workflow, fn func(wf *Workflow)) error {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	fn(wf)
+	wf.Updated = time.Now()
+
+	return eng.save()
+}
+
+// Run performs the workflow with the given ID from its current step until
+// it is finished, ctx is done or a step fails. The workflow can be resumed
+// by calling Run again.
+func (eng *Engine) Run(ctx context.Context, id string) error {
+	wake := make(chan struct{}, 1)
+
+	eng.mu.Lock()
+	_, wf := eng.lookup(id)
+	if wf == nil {
+		eng.mu.Unlock()
+		return ErrUnknownWorkflow
+	}
+
+	if _, ok := eng.running[id]; ok {
+		eng.mu.Unlock()
+		return ErrRunning
+	}
+
+	eng.running[id] = wake
+	eng.mu.Unlock()
+
+	defer func() {
+		eng.mu.Lock()
+		delete(eng.running, id)
+		eng.mu.Unlock()
+	}()
+
+	for !wf.Finished() {
+		next, err := eng.step(ctx, wf, wake)
+		if err != nil {
+			err = fmt.Errorf("workflow: %s: %s: %v", wf.ID, wf.Step, err)
+			if serr := eng.update(wf, func(wf *Workflow) { wf.Err = err.Error() }); serr != nil {
+				return serr
+			}
+
+			return err
+		}
+
+		if err := eng.update(wf, func(wf *Workflow) {
+			wf.Step = next
+			wf.Err = ""
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// step performs the current step of wf and returns the next one.
+func (eng *Engine) step(ctx context.Context, wf *Workflow, wake chan struct{}) (Step, error) {
+	switch wf.Step {
+	case StepTransfer:
+		if wf.Kind == Export {
+			return StepEject, eng.exportTransfer(wf)
+		}
+
+		return StepVerify, eng.importTransfer(wf)
+	case StepEject:
+		if _, err := eng.chgr.Do("eject"); err != nil {
+			return 0, err
+		}
+
+		if err := eng.update(wf, func(wf *Workflow) { wf.Confirmed = false }); err != nil {
+			return 0, err
+		}
+
+		eng.notify(wf.clone(), eng.instructions(wf))
+
+		return StepWait, nil
+	case StepWait:
+		return StepInventory, eng.wait(ctx, wf, wake)
+	case StepInventory:
+		if _, err := eng.chgr.Do("inventory"); err != nil {
+			return 0, err
+		}
+
+		if wf.Kind == Export {
+			return StepVerify, nil
+		}
+
+		return StepTransfer, nil
+	case StepVerify:
+		return eng.verify(wf)
+	}
+
+	return 0, fmt.Errorf("invalid step %d", int(wf.Step))
+}
+
+func (eng *Engine) instructions(wf *Workflow) string {
+	if wf.Kind == Export {
+		return fmt.Sprintf("remove volumes %s from the import/export station", strings.Join(wf.Batch, ", "))
+	}
+
+	if pending := wf.Pending(); len(pending) > 0 {
+		return fmt.Sprintf("insert volumes %s into the import/export station", strings.Join(pending, ", "))
+	}
+
+	return "insert the volumes to import into the import/export station"
+}
+
+// exportTransfer moves the volumes of the next round to the mail slots. The
+// round is recorded first, so that a resumed transfer only completes it.
+func (eng *Engine) exportTransfer(wf *Workflow) error {
+	if len(wf.Batch) == 0 {
+		status, err := eng.chgr.Status()
+		if err != nil {
+			return err
+		}
+
+		free := len(mtx.Filter(status.Slots, mtx.IsMail, mtx.IsEmpty))
+		if free == 0 {
+			return errors.New("no empty import/export slot")
+		}
+
+		batch := wf.Pending()
+		if len(batch) > free {
+			batch = batch[:free]
+		}
+
+		if err := eng.update(wf, func(wf *Workflow) { wf.Batch = batch }); err != nil {
+			return err
+		}
+	}
+
+	for _, serial := range wf.Batch {
+		if _, err := eng.chgr.Export(serial); err != nil {
+			return fmt.Errorf("%s: %v", serial, err)
+		}
+	}
+
+	return nil
+}
+
+// importTransfer moves the volumes inserted by the operator to free storage
+// slots.
+func (eng *Engine) importTransfer(wf *Workflow) error {
+	status, err := eng.chgr.Status()
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, serial := range wf.Pending() {
+		wanted[serial] = true
+	}
+
+	free := mtx.Filter(status.Slots, mtx.IsStorage, mtx.IsEmpty)
+
+	batch := wf.Batch
+	for _, slot := range mtx.Filter(status.Slots, mtx.IsMail, mtx.IsFull) {
+		if len(wf.Volumes) > 0 && !wanted[slot.Vol.Serial] {
+			continue
+		}
+
+		if len(free) == 0 {
+			return fmt.Errorf("no free storage slot for %s", slot.Vol.Serial)
+		}
+
+		if err := eng.chgr.Transfer(mtx.SlotNum(slot.Num), mtx.SlotNum(free[0].Num)); err != nil {
+			return fmt.Errorf("%s: %v", slot.Vol.Serial, err)
+		}
+
+		free = free[1:]
+
+		batch = append(batch, slot.Vol.Serial)
+		if err := eng.update(wf, func(wf *Workflow) { wf.Batch = batch }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wait polls the library until the operator has handled the current round
+// or confirmed it.
+func (eng *Engine) wait(ctx context.Context, wf *Workflow, wake chan struct{}) error {
+	ticker := time.NewTicker(eng.interval)
+	defer ticker.Stop()
+
+	for {
+		eng.mu.Lock()
+		confirmed := wf.Confirmed
+		eng.mu.Unlock()
+
+		if confirmed {
+			return nil
+		}
+
+		status, err := eng.chgr.Status()
+		if err != nil {
+			return err
+		}
+
+		if eng.handled(wf, status) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// handled reports whether status shows that the operator has handled the
+// current round of wf.
+func (eng *Engine) handled(wf *Workflow, status *mtx.Status) bool {
+	if wf.Kind == Export {
+		for _, serial := range wf.Batch {
+			if find(status, serial) != nil {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	// an import round is complete when all pending volumes have been
+	// inserted or the station is full
+	var mail []*mtx.Slot
+	for _, slot := range status.Slots {
+		if slot.Type == mtx.MailSlot {
+			mail = append(mail, slot)
+		}
+	}
+
+	full := 0
+	present := make(map[string]bool)
+	for _, slot := range mail {
+		if slot.Vol != nil {
+			full++
+			present[slot.Vol.Serial] = true
+		}
+	}
+
+	if full == len(mail) {
+		return true
+	}
+
+	if len(wf.Volumes) == 0 {
+		return full > 0
+	}
+
+	for _, serial := range wf.Pending() {
+		if !present[serial] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// verify checks the outcome of the current round. Volumes not handled by the
+// operator are presented again; otherwise the round is completed and the
+// workflow continues with the next round or finishes.
+func (eng *Engine) verify(wf *Workflow) (Step, error) {
+	status, err := eng.chgr.Status()
+	if err != nil {
+		return 0, err
+	}
+
+	var done []string
+	for _, serial := range wf.Batch {
+		slot := find(status, serial)
+
+		switch {
+		case wf.Kind == Export && slot != nil:
+			// the operator left the volume in the station; present it again
+			eng.notify(wf.clone(), fmt.Sprintf("volume %s is still in %s %d", serial, slot.Type, slot.Num))
+			return StepEject, nil
+		case wf.Kind == Import && (slot == nil || slot.Type != mtx.StorageSlot):
+			return 0, fmt.Errorf("volume %s was not imported", serial)
+		}
+
+		done = append(done, serial)
+	}
+
+	if err := eng.update(wf, func(wf *Workflow) {
+		wf.Done = append(wf.Done, done...)
+		wf.Batch = nil
+	}); err != nil {
+		return 0, err
+	}
+
+	pending := wf.Pending()
+
+	switch {
+	case len(pending) == 0 || (wf.Kind == Import && len(wf.Volumes) == 0):
+		eng.notify(wf.clone(), fmt.Sprintf("%s of %d volumes completed", wf.Kind, len(wf.Done)))
+		return StepDone, nil
+	case wf.Kind == Export:
+		return StepTransfer, nil
+	}
+
+	if len(done) == 0 {
+		eng.notify(wf.clone(), fmt.Sprintf("volumes %s are still missing", strings.Join(pending, ", ")))
+	}
+
+	return StepEject, nil
+}
+
+func find(status *mtx.Status, serial string) *mtx.Slot {
+	for _, slot := range append(append([]*mtx.Slot(nil), status.Drives...), status.Slots...) {
+		if slot.Vol != nil && slot.Vol.Serial == serial {
+			return slot
+		}
+	}
+
+	return nil
+}